@@ -0,0 +1,213 @@
+package envelope
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"golang.org/x/crypto/chacha20poly1305"
+)
+
+const KeyLen = chacha20poly1305.KeySize
+
+var (
+	ErrUnknownKey    = errors.New("unknown master key")
+	ErrDecryptFailed = errors.New("failed to decrypt")
+)
+
+// KMS wraps and unwraps data encryption keys with master keys it holds.
+type KMS interface {
+	Wrap(dek, aad []byte) (keyID string, wrapped []byte, err error)
+	Unwrap(keyID string, wrapped, aad []byte) (dek []byte, err error)
+}
+
+// Envelope is a piece of data encrypted with a random data encryption key,
+// which in turn is encrypted with a master key identified by KeyID.
+type Envelope struct {
+	KeyID      string
+	WrappedKey []byte
+	Ciphertext []byte
+}
+
+func Seal(kms KMS, plaintext, aad []byte) (env Envelope, err error) {
+	dek := make([]byte, KeyLen)
+	if _, err := rand.Read(dek); err != nil {
+		return Envelope{}, fmt.Errorf("couldn't generate data key: %w", err)
+	}
+
+	env.Ciphertext, err = seal(dek, plaintext, aad)
+	if err != nil {
+		return Envelope{}, err
+	}
+
+	env.KeyID, env.WrappedKey, err = kms.Wrap(dek, aad)
+	if err != nil {
+		return Envelope{}, err
+	}
+
+	return env, nil
+}
+
+func Open(kms KMS, env *Envelope, aad []byte) (plaintext []byte, err error) {
+	dek, err := kms.Unwrap(env.KeyID, env.WrappedKey, aad)
+	if err != nil {
+		return nil, err
+	}
+	return open(dek, env.Ciphertext, aad)
+}
+
+// Keyring is a local stand-in for a KMS holding master keys in memory.
+// Keys are wrapped with the primary key and unwrapped with any known key,
+// which allows master keys to be rotated.
+type Keyring struct {
+	primary string
+	keys    map[string][]byte
+}
+
+func NewKeyring(primary string, keys map[string][]byte) (*Keyring, error) {
+	if _, ok := keys[primary]; !ok {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownKey, primary)
+	}
+
+	for id, key := range keys {
+		if len(key) != KeyLen {
+			return nil, fmt.Errorf("master key %q: incorrect key size: %d", id, len(key))
+		}
+	}
+
+	return &Keyring{
+		primary: primary,
+		keys:    keys,
+	}, nil
+}
+
+// NewSingleKeyring creates a keyring containing only the given key,
+// identified by its fingerprint.
+func NewSingleKeyring(key []byte) (*Keyring, error) {
+	id := Fingerprint(key)
+	return NewKeyring(id, map[string][]byte{id: key})
+}
+
+func (kr *Keyring) Primary() string {
+	return kr.primary
+}
+
+func (kr *Keyring) Wrap(dek, aad []byte) (keyID string, wrapped []byte, err error) {
+	wrapped, err = seal(kr.keys[kr.primary], dek, aad)
+	if err != nil {
+		return "", nil, err
+	}
+	return kr.primary, wrapped, nil
+}
+
+func (kr *Keyring) Unwrap(keyID string, wrapped, aad []byte) (dek []byte, err error) {
+	key, ok := kr.keys[keyID]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownKey, keyID)
+	}
+	return open(key, wrapped, aad)
+}
+
+func Fingerprint(key []byte) string {
+	sum := sha256.Sum256(key)
+	return hex.EncodeToString(sum[:8])
+}
+
+// ParseKey parses base64-encoded master key.
+func ParseKey(s string) (key []byte, err error) {
+	key, err = base64.StdEncoding.DecodeString(strings.TrimSpace(s))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse base64-encoded master key: %w", err)
+	}
+
+	if len(key) != KeyLen {
+		return nil, fmt.Errorf("incorrect master key size: %d", len(key))
+	}
+
+	return key, nil
+}
+
+// ReadKeyFile reads master key from the file,
+// which contains either raw or base64-encoded key.
+func ReadKeyFile(path string) (key []byte, err error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(b) == KeyLen {
+		return b, nil
+	}
+
+	return ParseKey(string(b))
+}
+
+type kmsFile struct {
+	Primary string            `json:"primary"`
+	Keys    map[string]string `json:"keys"`
+}
+
+// ReadKMSFile reads keyring from the JSON file of the following form:
+//
+//	{"primary": "2025-01", "keys": {"2024-06": "base64...", "2025-01": "base64..."}}
+func ReadKMSFile(path string) (kr *Keyring, err error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var file kmsFile
+	if err := json.Unmarshal(b, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse kms file: %w", err)
+	}
+
+	keys := make(map[string][]byte, len(file.Keys))
+	for id, s := range file.Keys {
+		keys[id], err = ParseKey(s)
+		if err != nil {
+			return nil, fmt.Errorf("master key %q: %w", id, err)
+		}
+	}
+
+	return NewKeyring(file.Primary, keys)
+}
+
+func seal(key, plaintext, aad []byte) (ciphertext []byte, err error) {
+	aead, err := chacha20poly1305.NewX(key)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(plaintext)+aead.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("couldn't generate nonce: %w", err)
+	}
+
+	return aead.Seal(nonce, nonce, plaintext, aad), nil
+}
+
+func open(key, ciphertext, aad []byte) (plaintext []byte, err error) {
+	aead, err := chacha20poly1305.NewX(key)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(ciphertext) < aead.NonceSize() {
+		return nil, ErrDecryptFailed
+	}
+
+	nonce, ciphertext := ciphertext[:aead.NonceSize()], ciphertext[aead.NonceSize():]
+
+	plaintext, err = aead.Open(nil, nonce, ciphertext, aad)
+	if err != nil {
+		return nil, ErrDecryptFailed
+	}
+
+	return plaintext, nil
+}
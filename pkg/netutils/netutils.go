@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"encoding/binary"
 	"errors"
+	"math"
 	"math/bits"
 	"net"
 	"strings"
@@ -108,6 +109,25 @@ func NextAddress(addr net.IPNet) (next net.IPNet, err error) {
 	}, nil
 }
 
+// HostCount returns the number of usable host addresses in the subnet,
+// saturating at math.MaxUint64.
+func HostCount(addr net.IPNet) uint64 {
+	ones, size := addr.Mask.Size()
+	hostBits := size - ones
+
+	if hostBits >= 64 {
+		return math.MaxUint64
+	}
+
+	count := uint64(1) << hostBits
+	if size == 8*net.IPv4len && hostBits >= 2 {
+		// Network and broadcast addresses.
+		count -= 2
+	}
+
+	return count
+}
+
 func FormatAddresses(s []net.IPNet, sep string) string {
 	var builder strings.Builder
 
@@ -176,3 +196,12 @@ func ParseIPs(in []string) (out []net.IP, err error) {
 
 	return out, nil
 }
+
+// IsLoopbackHost reports whether the host, a name or an IP address, refers to the local machine.
+func IsLoopbackHost(host string) bool {
+	if strings.EqualFold(host, "localhost") {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
@@ -0,0 +1,709 @@
+package wgtypes
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/guregu/null/v5"
+	"github.com/infastin/wg-wish/pkg/netutils"
+)
+
+// Config is a wg-quick config with an interface and any number of peers.
+// It covers every key documented in wg-quick(8) and wg(8).
+// Comments and unknown keys are preserved, so decoding and encoding a config
+// doesn't lose anything besides blank lines and formatting.
+type Config struct {
+	Interface Interface
+	Peers     []Peer
+	// Trailer holds comment lines following the last key.
+	Trailer []string
+}
+
+// Field is a key unknown to wg-quick.
+type Field struct {
+	// Comments are the comment lines preceding the key.
+	Comments []string
+	Key      string
+	Value    string
+}
+
+type Interface struct {
+	// Name is the first comment line preceding the section.
+	Name string
+	// Comments are the other comment lines preceding the section.
+	Comments   []string
+	Address    []net.IPNet
+	ListenPort null.Int
+	PrivateKey Key
+	DNS        []net.IP
+	// DNSSearch are the non-IP entries of DNS, which are used as search domains.
+	DNSSearch []string
+	MTU       null.Int
+	// Table is a routing table number, "auto" or "off".
+	Table null.String
+	// FwMark is a firewall mark, zero is written as "off".
+	FwMark     null.Int
+	SaveConfig bool
+	PreUp      []string
+	PostUp     []string
+	PreDown    []string
+	PostDown   []string
+	// KeyComments are the comment lines preceding the values of known keys,
+	// by canonical key name and index of the value.
+	KeyComments KeyComments
+	// Extra holds keys unknown to wg-quick in order of appearance.
+	Extra []Field
+}
+
+type Peer struct {
+	// Name is the first comment line preceding the section.
+	Name string
+	// Comments are the other comment lines preceding the section.
+	Comments     []string
+	PublicKey    Key
+	PresharedKey null.Value[Key]
+	AllowedIPs   []net.IPNet
+	// Endpoint is in host:port form.
+	Endpoint            string
+	PersistentKeepalive null.Int
+	// KeyComments are the comment lines preceding the values of known keys,
+	// by canonical key name and index of the value.
+	KeyComments KeyComments
+	// Extra holds keys unknown to wg-quick in order of appearance.
+	Extra []Field
+}
+
+// KeyComments are the comment lines preceding the values of known keys.
+// The comments of a line are stored for the first value it has:
+// the only value of single-valued keys, an item of lists like Address
+// or a line of repeated keys like PostUp. DNS is indexed by DNS followed by DNSSearch.
+// Comments of list lines without values are stored past the last value.
+type KeyComments map[string][][]string
+
+func (kc *KeyComments) add(key string, index int, comments []string) {
+	if len(comments) == 0 {
+		return
+	}
+
+	if *kc == nil {
+		*kc = make(KeyComments)
+	}
+
+	values := (*kc)[key]
+	if len(values) <= index {
+		values = append(values, make([][]string, index+1-len(values))...)
+	}
+	values[index] = append(values[index], comments...)
+
+	(*kc)[key] = values
+}
+
+func (kc KeyComments) get(key string, index int) []string {
+	if values := kc[key]; index < len(values) {
+		return values[index]
+	}
+	return nil
+}
+
+// endComments is the index standing for the end of a list
+// while the number of its values isn't known yet.
+const endComments = -1
+
+type pendingComments struct {
+	key      string
+	index    int
+	search   bool
+	comments []string
+}
+
+const (
+	sectionInterface = "Interface"
+	sectionPeer      = "Peer"
+)
+
+// Canonical names of the known keys.
+const (
+	keyAddress             = "Address"
+	keyListenPort          = "ListenPort"
+	keyPrivateKey          = "PrivateKey"
+	keyDNS                 = "DNS"
+	keyMTU                 = "MTU"
+	keyTable               = "Table"
+	keyFwMark              = "FwMark"
+	keySaveConfig          = "SaveConfig"
+	keyPreUp               = "PreUp"
+	keyPostUp              = "PostUp"
+	keyPreDown             = "PreDown"
+	keyPostDown            = "PostDown"
+	keyPublicKey           = "PublicKey"
+	keyPresharedKey        = "PresharedKey"
+	keyAllowedIPs          = "AllowedIPs"
+	keyEndpoint            = "Endpoint"
+	keyPersistentKeepalive = "PersistentKeepalive"
+)
+
+var interfaceKeys = canonicalKeys(keyAddress, keyListenPort, keyPrivateKey, keyDNS, keyMTU, keyTable,
+	keyFwMark, keySaveConfig, keyPreUp, keyPostUp, keyPreDown, keyPostDown)
+
+var peerKeys = canonicalKeys(keyPublicKey, keyPresharedKey, keyAllowedIPs, keyEndpoint, keyPersistentKeepalive)
+
+func canonicalKeys(keys ...string) map[string]string {
+	m := make(map[string]string, len(keys))
+	for _, key := range keys {
+		m[strings.ToLower(key)] = key
+	}
+	return m
+}
+
+type rawField struct {
+	line     int
+	comments []string
+	key      string
+	value    string
+}
+
+type rawSection struct {
+	line     int
+	comments []string
+	name     string
+	fields   []rawField
+}
+
+// parse splits the config into sections the same way wg-quick does:
+// everything after "#" is a comment and keys are separated from values by the first "=".
+func parse(reader io.Reader) (sections []rawSection, trailer []string, err error) {
+	var comments []string
+
+	scanner := bufio.NewScanner(reader)
+	for n := 1; scanner.Scan(); n++ {
+		line, comment, hasComment := strings.Cut(scanner.Text(), "#")
+		line = strings.TrimSpace(line)
+
+		if hasComment {
+			comments = append(comments, strings.TrimPrefix(comment, " "))
+		}
+
+		switch {
+		case line == "":
+			continue
+		case strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]"):
+			sections = append(sections, rawSection{
+				line:     n,
+				comments: comments,
+				name:     strings.TrimSpace(line[1 : len(line)-1]),
+				fields:   nil,
+			})
+		default:
+			if len(sections) == 0 {
+				return nil, nil, fmt.Errorf("line %d: key outside of section", n)
+			}
+
+			key, value, ok := strings.Cut(line, "=")
+			if !ok {
+				return nil, nil, fmt.Errorf("line %d: expected key = value", n)
+			}
+
+			section := &sections[len(sections)-1]
+			section.fields = append(section.fields, rawField{
+				line:     n,
+				comments: comments,
+				key:      strings.TrimSpace(key),
+				value:    strings.TrimSpace(value),
+			})
+		}
+
+		comments = nil
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	return sections, comments, nil
+}
+
+func (cfg *Config) Decode(reader io.Reader) (err error) {
+	sections, trailer, err := parse(reader)
+	if err != nil {
+		return err
+	}
+
+	*cfg = Config{
+		Interface: Interface{},
+		Peers:     nil,
+		Trailer:   trailer,
+	}
+
+	hasInterface := false
+
+	for i := range sections {
+		section := &sections[i]
+
+		switch {
+		case strings.EqualFold(section.name, sectionInterface):
+			if hasInterface {
+				return fmt.Errorf("line %d: duplicate [Interface] section", section.line)
+			}
+			hasInterface = true
+
+			if err := cfg.Interface.decode(section); err != nil {
+				return err
+			}
+		case strings.EqualFold(section.name, sectionPeer):
+			var peer Peer
+			if err := peer.decode(section); err != nil {
+				return err
+			}
+			cfg.Peers = append(cfg.Peers, peer)
+		default:
+			return fmt.Errorf("line %d: unknown section [%s]", section.line, section.name)
+		}
+	}
+
+	if !hasInterface {
+		return fmt.Errorf("missing [Interface] section")
+	}
+
+	return nil
+}
+
+func (cfg *Config) Encode(writer io.Writer) (err error) {
+	w := bufio.NewWriter(writer)
+
+	cfg.Interface.encode(w)
+
+	for i := range cfg.Peers {
+		w.WriteByte('\n')
+		cfg.Peers[i].encode(w)
+	}
+
+	if len(cfg.Trailer) != 0 {
+		w.WriteByte('\n')
+		writeComments(w, cfg.Trailer)
+	}
+
+	return w.Flush()
+}
+
+func (iface *Interface) decode(section *rawSection) (err error) {
+	iface.Name, iface.Comments = splitName(section.comments)
+
+	hasPrivateKey := false
+
+	// Indexes of DNS values and ends of lists are only known once all fields are decoded.
+	var pending []pendingComments
+
+	for i := range section.fields {
+		field := &section.fields[i]
+
+		key, known := interfaceKeys[strings.ToLower(field.key)]
+		if !known {
+			iface.Extra = append(iface.Extra, Field{
+				Comments: field.comments,
+				Key:      field.key,
+				Value:    field.value,
+			})
+			continue
+		}
+
+		comment := pendingComments{key: key, index: 0, search: false, comments: field.comments}
+
+		switch key {
+		case keyAddress:
+			addresses, err := parseAddresses(splitList(field.value))
+			if err != nil {
+				return fieldError(field, err)
+			}
+			comment.index = listIndex(len(iface.Address), len(addresses))
+			iface.Address = append(iface.Address, addresses...)
+		case keyListenPort:
+			iface.ListenPort, err = parsePort(field.value)
+		case keyPrivateKey:
+			iface.PrivateKey, err = ParseKey(field.value)
+			hasPrivateKey = true
+		case keyDNS:
+			comment.index = endComments
+			for i, entry := range splitList(field.value) {
+				if ip := net.ParseIP(entry); ip != nil {
+					if i == 0 {
+						comment.index = len(iface.DNS)
+					}
+					iface.DNS = append(iface.DNS, ip)
+				} else {
+					if i == 0 {
+						comment.index, comment.search = len(iface.DNSSearch), true
+					}
+					iface.DNSSearch = append(iface.DNSSearch, entry)
+				}
+			}
+		case keyMTU:
+			iface.MTU, err = parseInt(field.value)
+		case keyTable:
+			iface.Table = null.StringFrom(field.value)
+		case keyFwMark:
+			var fwMark int64
+			fwMark, err = parseFwMark(field.value)
+			iface.FwMark = null.IntFrom(fwMark)
+		case keySaveConfig:
+			iface.SaveConfig, err = strconv.ParseBool(field.value)
+		case keyPreUp:
+			comment.index = len(iface.PreUp)
+			iface.PreUp = append(iface.PreUp, field.value)
+		case keyPostUp:
+			comment.index = len(iface.PostUp)
+			iface.PostUp = append(iface.PostUp, field.value)
+		case keyPreDown:
+			comment.index = len(iface.PreDown)
+			iface.PreDown = append(iface.PreDown, field.value)
+		case keyPostDown:
+			comment.index = len(iface.PostDown)
+			iface.PostDown = append(iface.PostDown, field.value)
+		}
+
+		if err != nil {
+			return fieldError(field, err)
+		}
+
+		if len(comment.comments) != 0 {
+			pending = append(pending, comment)
+		}
+	}
+
+	if !hasPrivateKey {
+		return fmt.Errorf("line %d: [Interface] is missing PrivateKey", section.line)
+	}
+
+	for i := range pending {
+		comment := &pending[i]
+		switch {
+		case comment.key == keyDNS && comment.index == endComments:
+			comment.index = len(iface.DNS) + len(iface.DNSSearch)
+		case comment.key == keyDNS && comment.search:
+			comment.index += len(iface.DNS)
+		case comment.index == endComments:
+			comment.index = len(iface.Address)
+		}
+		iface.KeyComments.add(comment.key, comment.index, comment.comments)
+	}
+
+	return nil
+}
+
+func (iface *Interface) encode(w *bufio.Writer) {
+	var fields []Field
+
+	add := func(key string, values ...string) {
+		for i, value := range values {
+			fields = append(fields, Field{
+				Comments: iface.KeyComments.get(key, i),
+				Key:      key,
+				Value:    value,
+			})
+		}
+	}
+
+	addList := func(key string, values []string) {
+		fields = appendList(fields, iface.KeyComments, key, values)
+	}
+
+	addresses := make([]string, len(iface.Address))
+	for i := range iface.Address {
+		addresses[i] = iface.Address[i].String()
+	}
+	addList(keyAddress, addresses)
+	if iface.ListenPort.Valid {
+		add(keyListenPort, strconv.FormatInt(iface.ListenPort.Int64, 10))
+	}
+	add(keyPrivateKey, iface.PrivateKey.String())
+	dns := make([]string, 0, len(iface.DNS)+len(iface.DNSSearch))
+	for _, ip := range iface.DNS {
+		dns = append(dns, ip.String())
+	}
+	dns = append(dns, iface.DNSSearch...)
+	addList(keyDNS, dns)
+	if iface.MTU.Valid {
+		add(keyMTU, strconv.FormatInt(iface.MTU.Int64, 10))
+	}
+	if iface.Table.Valid {
+		add(keyTable, iface.Table.String)
+	}
+	if iface.FwMark.Valid {
+		add(keyFwMark, formatFwMark(iface.FwMark.Int64))
+	}
+	if iface.SaveConfig {
+		add(keySaveConfig, "true")
+	}
+	add(keyPreUp, iface.PreUp...)
+	add(keyPostUp, iface.PostUp...)
+	add(keyPreDown, iface.PreDown...)
+	add(keyPostDown, iface.PostDown...)
+
+	writeSection(w, sectionInterface, iface.Name, iface.Comments, append(fields, iface.Extra...))
+}
+
+func (peer *Peer) decode(section *rawSection) (err error) {
+	peer.Name, peer.Comments = splitName(section.comments)
+
+	hasPublicKey := false
+
+	// Comments of AllowedIPs lines without values go past the last one.
+	var pending []pendingComments
+
+	for i := range section.fields {
+		field := &section.fields[i]
+
+		key, known := peerKeys[strings.ToLower(field.key)]
+		if !known {
+			peer.Extra = append(peer.Extra, Field{
+				Comments: field.comments,
+				Key:      field.key,
+				Value:    field.value,
+			})
+			continue
+		}
+
+		index := 0
+
+		switch key {
+		case keyPublicKey:
+			peer.PublicKey, err = ParseKey(field.value)
+			hasPublicKey = true
+		case keyPresharedKey:
+			var psk Key
+			psk, err = ParseKey(field.value)
+			peer.PresharedKey = null.ValueFrom(psk)
+		case keyAllowedIPs:
+			ips, err := parseAddresses(splitList(field.value))
+			if err != nil {
+				return fieldError(field, err)
+			}
+			index = listIndex(len(peer.AllowedIPs), len(ips))
+			peer.AllowedIPs = append(peer.AllowedIPs, ips...)
+		case keyEndpoint:
+			_, _, err = net.SplitHostPort(field.value)
+			peer.Endpoint = field.value
+		case keyPersistentKeepalive:
+			if field.value == "off" {
+				peer.PersistentKeepalive = null.IntFrom(0)
+			} else {
+				peer.PersistentKeepalive, err = parsePort(field.value)
+			}
+		}
+
+		if err != nil {
+			return fieldError(field, err)
+		}
+
+		if len(field.comments) != 0 {
+			pending = append(pending, pendingComments{key: key, index: index, search: false, comments: field.comments})
+		}
+	}
+
+	if !hasPublicKey {
+		return fmt.Errorf("line %d: [Peer] is missing PublicKey", section.line)
+	}
+
+	for i := range pending {
+		if pending[i].index == endComments {
+			pending[i].index = len(peer.AllowedIPs)
+		}
+		peer.KeyComments.add(pending[i].key, pending[i].index, pending[i].comments)
+	}
+
+	return nil
+}
+
+func (peer *Peer) encode(w *bufio.Writer) {
+	var fields []Field
+
+	add := func(key, value string) {
+		fields = append(fields, Field{
+			Comments: peer.KeyComments.get(key, 0),
+			Key:      key,
+			Value:    value,
+		})
+	}
+
+	if peer.Endpoint != "" {
+		add(keyEndpoint, peer.Endpoint)
+	}
+	add(keyPublicKey, peer.PublicKey.String())
+	if peer.PresharedKey.Valid {
+		add(keyPresharedKey, peer.PresharedKey.V.String())
+	}
+	allowedIPs := make([]string, len(peer.AllowedIPs))
+	for i := range peer.AllowedIPs {
+		allowedIPs[i] = peer.AllowedIPs[i].String()
+	}
+	fields = appendList(fields, peer.KeyComments, keyAllowedIPs, allowedIPs)
+	if peer.PersistentKeepalive.Valid {
+		add(keyPersistentKeepalive, strconv.FormatInt(peer.PersistentKeepalive.Int64, 10))
+	}
+
+	writeSection(w, sectionPeer, peer.Name, peer.Comments, append(fields, peer.Extra...))
+}
+
+// listIndex returns the index comments of a list line are stored at,
+// given the number of values before the line and on it.
+func listIndex(before, values int) int {
+	if values == 0 {
+		return endComments
+	}
+	return before
+}
+
+// appendList appends the list as a single line, which is split
+// before the values with comments. Comments past the last value
+// are written on an empty line.
+func appendList(fields []Field, kc KeyComments, key string, values []string) []Field {
+	start := 0
+	for i := 1; i <= len(values); i++ {
+		if i == len(values) || len(kc.get(key, i)) != 0 {
+			fields = append(fields, Field{
+				Comments: kc.get(key, start),
+				Key:      key,
+				Value:    strings.Join(values[start:i], ","),
+			})
+			start = i
+		}
+	}
+
+	if comments := kc.get(key, len(values)); len(comments) != 0 {
+		fields = append(fields, Field{Comments: comments, Key: key, Value: ""})
+	}
+
+	return fields
+}
+
+// writeSection writes the section with values aligned by the longest key.
+func writeSection(w *bufio.Writer, name, title string, comments []string, fields []Field) {
+	// The title is the first comment line, so it's written even if empty
+	// to keep the other comments from taking its place.
+	if title != "" || len(comments) != 0 {
+		writeComments(w, []string{title})
+	}
+	writeComments(w, comments)
+
+	fmt.Fprintf(w, "[%s]\n", name)
+
+	width := 0
+	for i := range fields {
+		width = max(width, len(fields[i].Key))
+	}
+
+	for i := range fields {
+		writeComments(w, fields[i].Comments)
+		if fields[i].Value == "" {
+			fmt.Fprintf(w, "%-*s =\n", width, fields[i].Key)
+		} else {
+			fmt.Fprintf(w, "%-*s = %s\n", width, fields[i].Key, fields[i].Value)
+		}
+	}
+}
+
+func writeComments(w *bufio.Writer, comments []string) {
+	for _, comment := range comments {
+		if comment == "" {
+			w.WriteString("#\n")
+		} else {
+			fmt.Fprintf(w, "# %s\n", comment)
+		}
+	}
+}
+
+func splitName(comments []string) (name string, rest []string) {
+	if len(comments) <= 1 {
+		return strings.TrimSpace(strings.Join(comments, "")), nil
+	}
+	return strings.TrimSpace(comments[0]), comments[1:]
+}
+
+func splitList(s string) []string {
+	parts := strings.Split(s, ",")
+
+	list := parts[:0]
+	for _, part := range parts {
+		if part = strings.TrimSpace(part); part != "" {
+			list = append(list, part)
+		}
+	}
+
+	return list
+}
+
+// parseAddresses parses addresses in CIDR notation,
+// addresses without prefix length are treated as single hosts.
+func parseAddresses(list []string) (addresses []net.IPNet, err error) {
+	addresses = make([]net.IPNet, len(list))
+
+	for i, s := range list {
+		if strings.Contains(s, "/") {
+			addresses[i], err = netutils.ParseAddress(s)
+			if err != nil {
+				return nil, err
+			}
+			// Use the same form as addresses without prefix length.
+			if ip4 := addresses[i].IP.To4(); ip4 != nil && len(addresses[i].Mask) == net.IPv4len {
+				addresses[i].IP = ip4
+			}
+			continue
+		}
+
+		ip := net.ParseIP(s)
+		if ip == nil {
+			return nil, &net.ParseError{Type: "IP address", Text: s}
+		}
+
+		if ip4 := ip.To4(); ip4 != nil {
+			addresses[i] = net.IPNet{IP: ip4, Mask: net.CIDRMask(32, 32)}
+		} else {
+			addresses[i] = net.IPNet{IP: ip, Mask: net.CIDRMask(128, 128)}
+		}
+	}
+
+	return addresses, nil
+}
+
+func parseInt(s string) (n null.Int, err error) {
+	i, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return null.Int{}, err
+	}
+	return null.IntFrom(i), nil
+}
+
+func parsePort(s string) (n null.Int, err error) {
+	i, err := strconv.ParseUint(s, 10, 16)
+	if err != nil {
+		return null.Int{}, err
+	}
+	return null.IntFrom(int64(i)), nil
+}
+
+func formatFwMark(mark int64) string {
+	if mark == 0 {
+		return "off"
+	}
+	return "0x" + strconv.FormatInt(mark, 16)
+}
+
+func parseFwMark(s string) (mark int64, err error) {
+	if s == "off" {
+		return 0, nil
+	}
+
+	mark, err = strconv.ParseInt(s, 0, 64)
+	if err != nil || mark < 0 || mark > 0xffffffff {
+		return 0, fmt.Errorf("invalid firewall mark: %s", s)
+	}
+
+	return mark, nil
+}
+
+func fieldError(field *rawField, err error) error {
+	return fmt.Errorf("line %d: invalid %s: %w", field.line, field.key, err)
+}
@@ -0,0 +1,104 @@
+package wgtypes
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "update golden files")
+
+// TestConfigGolden decodes every testdata/*.conf, checks that encoding it
+// produces the matching .golden file and that the golden file decodes to the same config.
+func TestConfigGolden(t *testing.T) {
+	paths, err := filepath.Glob(filepath.Join("testdata", "*.conf"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, path := range paths {
+		name := strings.TrimSuffix(filepath.Base(path), ".conf")
+
+		t.Run(name, func(t *testing.T) {
+			input, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var cfg Config
+			if err := cfg.Decode(bytes.NewReader(input)); err != nil {
+				t.Fatalf("failed to decode: %v", err)
+			}
+
+			var output bytes.Buffer
+			if err := cfg.Encode(&output); err != nil {
+				t.Fatalf("failed to encode: %v", err)
+			}
+
+			goldenPath := strings.TrimSuffix(path, ".conf") + ".golden"
+			if *update {
+				if err := os.WriteFile(goldenPath, output.Bytes(), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			golden, err := os.ReadFile(goldenPath)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !bytes.Equal(output.Bytes(), golden) {
+				t.Fatalf("encoded config differs from %s:\n%s", goldenPath, output.Bytes())
+			}
+
+			var decoded Config
+			if err := decoded.Decode(bytes.NewReader(golden)); err != nil {
+				t.Fatalf("failed to decode golden file: %v", err)
+			}
+
+			if !reflect.DeepEqual(cfg, decoded) {
+				t.Fatalf("golden file decodes to a different config:\n got: %+v\nwant: %+v", decoded, cfg)
+			}
+		})
+	}
+}
+
+func FuzzConfigDecode(f *testing.F) {
+	paths, err := filepath.Glob(filepath.Join("testdata", "*.conf"))
+	if err != nil {
+		f.Fatal(err)
+	}
+
+	for _, path := range paths {
+		input, err := os.ReadFile(path)
+		if err != nil {
+			f.Fatal(err)
+		}
+		f.Add(input)
+	}
+
+	f.Fuzz(func(t *testing.T, input []byte) {
+		var cfg Config
+		if err := cfg.Decode(bytes.NewReader(input)); err != nil {
+			return
+		}
+
+		var output bytes.Buffer
+		if err := cfg.Encode(&output); err != nil {
+			t.Fatalf("failed to encode: %v", err)
+		}
+
+		var decoded Config
+		if err := decoded.Decode(bytes.NewReader(output.Bytes())); err != nil {
+			t.Fatalf("failed to decode encoded config: %v\n%s", err, output.Bytes())
+		}
+
+		if !reflect.DeepEqual(cfg, decoded) {
+			t.Fatalf("config changed after encoding:\n got: %+v\nwant: %+v\n%s", decoded, cfg, output.Bytes())
+		}
+	})
+}
@@ -7,12 +7,10 @@ import (
 	"io"
 	"net"
 	"strconv"
-	"strings"
 
 	"github.com/guregu/null/v5"
 	"github.com/infastin/wg-wish/pkg/netutils"
 	"golang.org/x/crypto/curve25519"
-	"gopkg.in/ini.v1"
 )
 
 const KeyLen = 32
@@ -61,8 +59,15 @@ type ServerConfig struct {
 type ServerConfigParams struct {
 	PrivateKey Key
 	Address    string
-	Device     string
 	ListenPort null.Int
+	MTU        null.Int
+	Table      null.String
+	FwMark     null.Int
+	SaveConfig bool
+	PreUp      []string
+	PostUp     []string
+	PreDown    []string
+	PostDown   []string
 }
 
 func NewServerConfig(params *ServerConfigParams) (cfg ServerConfig, err error) {
@@ -77,102 +82,91 @@ func NewServerConfig(params *ServerConfigParams) (cfg ServerConfig, err error) {
 	}
 
 	cfg.Interface.PrivateKey = params.PrivateKey
-
-	device := params.Device
-	if device == "" {
-		device = "eth0"
-	}
-
-	subnet := net.IPNet{
-		IP:   cfg.Interface.Address.IP.Mask(cfg.Interface.Address.Mask),
-		Mask: cfg.Interface.Address.Mask,
-	}
-
-	cfg.Interface.PostUp = []string{
-		fmt.Sprintf("iptables -t nat -A POSTROUTING -s %s -o %s -j MASQUERADE", subnet.String(), device),
-		fmt.Sprintf("iptables -A INPUT -i %s -p udp -m udp --dport %d -j ACCEPT", device, cfg.Interface.ListenPort.Int64),
-		fmt.Sprintf("iptables -A FORWARD -i wg0 -o %s -j ACCEPT", device),
-		fmt.Sprintf("iptables -A FORWARD -i %s -o wg0 -j ACCEPT", device),
-	}
-
-	cfg.Interface.PostDown = []string{
-		fmt.Sprintf("iptables -t nat -D POSTROUTING -s %s -o %s -j MASQUERADE", subnet.String(), device),
-		fmt.Sprintf("iptables -D INPUT -i %s -p udp -m udp --dport %d -j ACCEPT", device, cfg.Interface.ListenPort.Int64),
-		fmt.Sprintf("iptables -D FORWARD -i wg0 -o %s -j ACCEPT", device),
-		fmt.Sprintf("iptables -D FORWARD -i %s -o wg0 -j ACCEPT", device),
-	}
+	cfg.Interface.MTU = params.MTU
+	cfg.Interface.Table = params.Table
+	cfg.Interface.FwMark = params.FwMark
+	cfg.Interface.SaveConfig = params.SaveConfig
+	cfg.Interface.PreUp = params.PreUp
+	cfg.Interface.PostUp = params.PostUp
+	cfg.Interface.PreDown = params.PreDown
+	cfg.Interface.PostDown = params.PostDown
 
 	return cfg, nil
 }
 
-func (cfg *ServerConfig) Encode(writer io.Writer) (err error) {
-	file := ini.Empty(ini.LoadOptions{
-		AllowShadows:           true,
-		AllowNonUniqueSections: true,
-	})
-
-	ifaceSection, err := file.NewSection("Interface")
-	if err != nil {
-		return err
-	}
-
-	err = cfg.Interface.store(ifaceSection)
-	if err != nil {
-		return err
+// Config returns the generic representation of the config.
+func (cfg *ServerConfig) Config() (config Config) {
+	config = Config{
+		Interface: Interface{
+			Name:        cfg.Interface.Name,
+			Comments:    nil,
+			Address:     []net.IPNet{cfg.Interface.Address},
+			ListenPort:  cfg.Interface.ListenPort,
+			PrivateKey:  cfg.Interface.PrivateKey,
+			DNS:         nil,
+			DNSSearch:   nil,
+			MTU:         cfg.Interface.MTU,
+			Table:       cfg.Interface.Table,
+			FwMark:      cfg.Interface.FwMark,
+			SaveConfig:  cfg.Interface.SaveConfig,
+			PreUp:       cfg.Interface.PreUp,
+			PostUp:      cfg.Interface.PostUp,
+			PreDown:     cfg.Interface.PreDown,
+			PostDown:    cfg.Interface.PostDown,
+			KeyComments: nil,
+			Extra:       nil,
+		},
+		Peers:   make([]Peer, len(cfg.Peers)),
+		Trailer: nil,
 	}
 
 	for i := range cfg.Peers {
-		peerSection, err := file.NewSection("Peer")
-		if err != nil {
-			return err
-		}
-
-		err = cfg.Peers[i].store(peerSection)
-		if err != nil {
-			return err
-		}
+		config.Peers[i] = cfg.Peers[i].peer()
 	}
 
-	_, err = file.WriteTo(writer)
-	if err != nil {
-		return err
-	}
+	return config
+}
 
-	return nil
+func (cfg *ServerConfig) Encode(writer io.Writer) (err error) {
+	config := cfg.Config()
+	return config.Encode(writer)
 }
 
 func (cfg *ServerConfig) Decode(reader io.Reader) (err error) {
-	file, err := ini.LoadSources(ini.LoadOptions{
-		AllowShadows:           true,
-		AllowNonUniqueSections: true,
-	}, reader)
-
-	if err != nil {
-		return err
-	}
-
-	ifaceSection, err := file.GetSection("Interface")
-	if err != nil {
-		return err
-	}
-
-	err = cfg.Interface.load(ifaceSection)
-	if err != nil {
-		return err
-	}
-
-	if file.HasSection("Peer") {
-		peerSections, err := file.SectionsByName("Peer")
-		if err != nil {
-			return err
-		}
-
-		cfg.Peers = make([]ServerPeer, len(peerSections))
-		for i := range cfg.Peers {
-			err = cfg.Peers[i].load(peerSections[i])
-			if err != nil {
-				return err
-			}
+	var config Config
+	if err := config.Decode(reader); err != nil {
+		return err
+	}
+
+	if len(config.Interface.Address) == 0 {
+		return fmt.Errorf("[Interface] is missing Address")
+	}
+
+	cfg.Interface = ServerInterface{
+		Name:       config.Interface.Name,
+		Address:    config.Interface.Address[0],
+		ListenPort: config.Interface.ListenPort,
+		PrivateKey: config.Interface.PrivateKey,
+		MTU:        config.Interface.MTU,
+		Table:      config.Interface.Table,
+		FwMark:     config.Interface.FwMark,
+		SaveConfig: config.Interface.SaveConfig,
+		PreUp:      config.Interface.PreUp,
+		PostUp:     config.Interface.PostUp,
+		PreDown:    config.Interface.PreDown,
+		PostDown:   config.Interface.PostDown,
+	}
+
+	cfg.Peers = make([]ServerPeer, len(config.Peers))
+	for i := range config.Peers {
+		peer := &config.Peers[i]
+		cfg.Peers[i] = ServerPeer{
+			Name:                peer.Name,
+			PublicKey:           peer.PublicKey,
+			PresharedKey:        peer.PresharedKey,
+			AllowedIPs:          peer.AllowedIPs,
+			Endpoint:            peer.Endpoint,
+			PersistentKeepalive: peer.PersistentKeepalive,
 		}
 	}
 
@@ -184,291 +178,140 @@ type ServerInterface struct {
 	Address    net.IPNet
 	ListenPort null.Int
 	PrivateKey Key
+	MTU        null.Int
+	// Table is a routing table number, "auto" or "off".
+	Table null.String
+	// FwMark is a firewall mark, zero is written as "off".
+	FwMark     null.Int
+	SaveConfig bool
+	PreUp      []string
 	PostUp     []string
+	PreDown    []string
 	PostDown   []string
 }
 
-func (si *ServerInterface) store(section *ini.Section) (err error) {
-	if si.Name != "" {
-		section.Comment = "# " + si.Name
-	}
-
-	_, err = section.NewKey("Address", si.Address.String())
-	if err != nil {
-		return err
-	}
-
-	if si.ListenPort.Valid {
-		_, err = section.NewKey("ListenPort", strconv.FormatInt(si.ListenPort.Int64, 10))
-		if err != nil {
-			return err
-		}
-	}
-
-	_, err = section.NewKey("PrivateKey", si.PrivateKey.String())
-	if err != nil {
-		return err
-	}
-
-	if len(si.PostUp) != 0 {
-		for _, cmd := range si.PostUp {
-			_, err = section.NewKey("PostUp", cmd)
-			if err != nil {
-				return err
-			}
-		}
-	}
-
-	if len(si.PostDown) != 0 {
-		for _, cmd := range si.PostDown {
-			_, err = section.NewKey("PostDown", cmd)
-			if err != nil {
-				return err
-			}
-		}
-	}
-
-	return nil
-}
-
-func (si *ServerInterface) load(section *ini.Section) (err error) {
-	si.Name = strings.TrimLeft(section.Comment, "# ")
-
-	addressKey, err := section.GetKey("Address")
-	if err != nil {
-		return err
-	}
-
-	si.Address, err = netutils.ParseAddress(addressKey.Value())
-	if err != nil {
-		return err
-	}
-
-	if section.HasKey("ListenPort") {
-		portKey, err := section.GetKey("ListenPort")
-		if err != nil {
-			return err
-		}
-
-		port, err := portKey.Int64()
-		if err != nil {
-			return err
-		}
-
-		si.ListenPort = null.IntFrom(port)
-	}
-
-	pkKey, err := section.GetKey("PrivateKey")
-	if err != nil {
-		return err
-	}
-
-	si.PrivateKey, err = ParseKey(pkKey.Value())
-	if err != nil {
-		return err
-	}
-
-	if section.HasKey("PostUp") {
-		postUpKey, err := section.GetKey("PostUp")
-		if err != nil {
-			return err
-		}
-		si.PostUp = postUpKey.ValueWithShadows()
-	}
-
-	if section.HasKey("PostDown") {
-		postDownKey, err := section.GetKey("PostDown")
-		if err != nil {
-			return err
-		}
-		si.PostDown = postDownKey.ValueWithShadows()
-	}
-
-	return nil
-}
-
 type ServerPeer struct {
-	Name       string
-	PublicKey  Key
-	AllowedIPs []net.IPNet
-}
-
-func (sp *ServerPeer) store(section *ini.Section) (err error) {
-	if sp.Name != "" {
-		section.Comment = "# " + sp.Name
-	}
-
-	_, err = section.NewKey("PublicKey", sp.PublicKey.String())
-	if err != nil {
-		return err
-	}
-
-	_, err = section.NewKey("AllowedIPs", netutils.FormatAddresses(sp.AllowedIPs, ","))
-	if err != nil {
-		return err
-	}
-
-	return nil
+	Name         string
+	PublicKey    Key
+	PresharedKey null.Value[Key]
+	AllowedIPs   []net.IPNet
+	// Endpoint is in host:port form, empty if the peer connects first.
+	Endpoint            string
+	PersistentKeepalive null.Int
 }
 
-func (sp *ServerPeer) load(section *ini.Section) (err error) {
-	sp.Name = strings.TrimLeft(section.Comment, "# ")
-
-	pkKey, err := section.GetKey("PublicKey")
-	if err != nil {
-		return err
-	}
-
-	sp.PublicKey, err = ParseKey(pkKey.Value())
-	if err != nil {
-		return err
-	}
-
-	ipsKey, err := section.GetKey("AllowedIPs")
-	if err != nil {
-		return err
+func (sp *ServerPeer) peer() Peer {
+	return Peer{
+		Name:                sp.Name,
+		Comments:            nil,
+		PublicKey:           sp.PublicKey,
+		PresharedKey:        sp.PresharedKey,
+		AllowedIPs:          sp.AllowedIPs,
+		Endpoint:            sp.Endpoint,
+		PersistentKeepalive: sp.PersistentKeepalive,
+		KeyComments:         nil,
+		Extra:               nil,
 	}
-
-	sp.AllowedIPs, err = netutils.ParseAddresses(ipsKey.Strings(","))
-	if err != nil {
-		return err
-	}
-
-	return nil
 }
 
 type ClientConfig struct {
 	Interface ClientInterface
-	Peer      ClientPeer
+	// Peers are the peers of the client, the first of which is the server.
+	Peers []ClientPeer
 }
 
-func (cfg *ClientConfig) Encode(writer io.Writer) (err error) {
-	file := ini.Empty(ini.LoadOptions{
-		AllowShadows: true,
-	})
-
-	ifaceSection, err := file.NewSection("Interface")
-	if err != nil {
-		return err
-	}
-
-	err = cfg.Interface.store(ifaceSection)
-	if err != nil {
-		return err
-	}
-
-	peerSection, err := file.NewSection("Peer")
-	if err != nil {
-		return err
+// Config returns the generic representation of the config.
+func (cfg *ClientConfig) Config() (config Config) {
+	config = Config{
+		Interface: Interface{
+			Name:        cfg.Interface.Name,
+			Comments:    nil,
+			Address:     []net.IPNet{cfg.Interface.Address},
+			ListenPort:  cfg.Interface.ListenPort,
+			PrivateKey:  cfg.Interface.PrivateKey,
+			DNS:         cfg.Interface.DNS,
+			DNSSearch:   nil,
+			MTU:         cfg.Interface.MTU,
+			Table:       cfg.Interface.Table,
+			FwMark:      null.Int{},
+			SaveConfig:  false,
+			PreUp:       nil,
+			PostUp:      nil,
+			PreDown:     nil,
+			PostDown:    nil,
+			KeyComments: nil,
+			Extra:       nil,
+		},
+		Peers:   make([]Peer, len(cfg.Peers)),
+		Trailer: nil,
 	}
 
-	err = cfg.Peer.store(peerSection)
-	if err != nil {
-		return err
+	for i := range cfg.Peers {
+		config.Peers[i] = cfg.Peers[i].peer()
 	}
 
-	_, err = file.WriteTo(writer)
-	if err != nil {
-		return err
-	}
+	return config
+}
 
-	return nil
+func (cfg *ClientConfig) Encode(writer io.Writer) (err error) {
+	config := cfg.Config()
+	return config.Encode(writer)
 }
 
 func (cfg *ClientConfig) Decode(reader io.Reader) (err error) {
-	file, err := ini.LoadSources(ini.LoadOptions{
-		AllowShadows: true,
-	}, reader)
-
-	if err != nil {
-		return err
-	}
-
-	ifaceSection, err := file.GetSection("Interface")
-	if err != nil {
+	var config Config
+	if err := config.Decode(reader); err != nil {
 		return err
 	}
 
-	err = cfg.Interface.load(ifaceSection)
-	if err != nil {
-		return err
+	if len(config.Interface.Address) == 0 {
+		return fmt.Errorf("[Interface] is missing Address")
 	}
 
-	peerSection, err := file.GetSection("Peer")
-	if err != nil {
-		return err
+	if len(config.Peers) == 0 {
+		return fmt.Errorf("missing [Peer] section")
 	}
 
-	err = cfg.Peer.load(peerSection)
-	if err != nil {
-		return err
-	}
-
-	return nil
-}
-
-type ClientInterface struct {
-	Name       string
-	Address    net.IPNet
-	PrivateKey Key
-	DNS        []net.IP
-}
-
-func (ci *ClientInterface) store(section *ini.Section) (err error) {
-	if ci.Name != "" {
-		section.Comment = "# " + ci.Name
+	cfg.Interface = ClientInterface{
+		Name:       config.Interface.Name,
+		Address:    config.Interface.Address[0],
+		ListenPort: config.Interface.ListenPort,
+		PrivateKey: config.Interface.PrivateKey,
+		DNS:        config.Interface.DNS,
+		MTU:        config.Interface.MTU,
+		Table:      config.Interface.Table,
 	}
 
-	_, err = section.NewKey("Address", ci.Address.String())
-	if err != nil {
-		return err
+	if config.Peers[0].Endpoint == "" {
+		return fmt.Errorf("[Peer] of the server is missing Endpoint")
 	}
 
-	_, err = section.NewKey("PrivateKey", ci.PrivateKey.String())
-	if err != nil {
-		return err
-	}
+	cfg.Peers = make([]ClientPeer, len(config.Peers))
+	for i := range config.Peers {
+		peer := &config.Peers[i]
 
-	if len(ci.DNS) != 0 {
-		_, err = section.NewKey("DNS", netutils.FormatIPs(ci.DNS, ","))
-		if err != nil {
-			return err
+		cfg.Peers[i] = ClientPeer{
+			Name:                peer.Name,
+			EndpointHost:        "",
+			EndpointPort:        0,
+			PublicKey:           peer.PublicKey,
+			PresharedKey:        peer.PresharedKey,
+			AllowedIPs:          peer.AllowedIPs,
+			PersistentKeepalive: peer.PersistentKeepalive,
 		}
-	}
-
-	return nil
-}
-
-func (ci *ClientInterface) load(section *ini.Section) (err error) {
-	ci.Name = strings.TrimLeft(section.Comment, "# ")
-
-	addressKey, err := section.GetKey("Address")
-	if err != nil {
-		return err
-	}
-
-	ci.Address, err = netutils.ParseAddress(addressKey.Value())
-	if err != nil {
-		return err
-	}
 
-	pkKey, err := section.GetKey("PrivateKey")
-	if err != nil {
-		return err
-	}
-
-	ci.PrivateKey, err = ParseKey(pkKey.Value())
-	if err != nil {
-		return err
-	}
+		if peer.Endpoint == "" {
+			continue
+		}
 
-	if section.HasKey("DNS") {
-		dnsKey, err := section.GetKey("DNS")
+		endpointHost, endpointPort, err := net.SplitHostPort(peer.Endpoint)
 		if err != nil {
 			return err
 		}
 
-		ci.DNS, err = netutils.ParseIPs(dnsKey.Strings(","))
+		cfg.Peers[i].EndpointHost = endpointHost
+
+		cfg.Peers[i].EndpointPort, err = strconv.Atoi(endpointPort)
 		if err != nil {
 			return err
 		}
@@ -477,96 +320,42 @@ func (ci *ClientInterface) load(section *ini.Section) (err error) {
 	return nil
 }
 
+type ClientInterface struct {
+	Name       string
+	Address    net.IPNet
+	ListenPort null.Int
+	PrivateKey Key
+	DNS        []net.IP
+	MTU        null.Int
+	// Table is a routing table number, "auto" or "off".
+	Table null.String
+}
+
 type ClientPeer struct {
-	Name                string
+	Name string
+	// EndpointHost is empty if the peer connects first.
 	EndpointHost        string
 	EndpointPort        int
 	PublicKey           Key
+	PresharedKey        null.Value[Key]
 	AllowedIPs          []net.IPNet
 	PersistentKeepalive null.Int
 }
 
-func (cp *ClientPeer) store(section *ini.Section) (err error) {
-	if cp.Name != "" {
-		section.Comment = "# " + cp.Name
-	}
-
-	_, err = section.NewKey("Endpoint", cp.EndpointHost+":"+strconv.Itoa(cp.EndpointPort))
-	if err != nil {
-		return err
-	}
-
-	_, err = section.NewKey("PublicKey", cp.PublicKey.String())
-	if err != nil {
-		return err
-	}
-
-	_, err = section.NewKey("AllowedIPs", netutils.FormatAddresses(cp.AllowedIPs, ","))
-	if err != nil {
-		return err
-	}
-
-	if cp.PersistentKeepalive.Valid {
-		_, err = section.NewKey("PersistentKeepalive", strconv.FormatInt(cp.PersistentKeepalive.Int64, 10))
-		if err != nil {
-			return err
-		}
-	}
-
-	return nil
-}
-
-func (cp *ClientPeer) load(section *ini.Section) (err error) {
-	cp.Name = strings.TrimLeft(section.Comment, "# ")
-
-	endpointKey, err := section.GetKey("Endpoint")
-	if err != nil {
-		return err
-	}
-
-	endpointHost, endpointPort, err := net.SplitHostPort(endpointKey.Value())
-	if err != nil {
-		return err
-	}
-
-	cp.EndpointHost = endpointHost
-
-	cp.EndpointPort, err = strconv.Atoi(endpointPort)
-	if err != nil {
-		return err
-	}
-
-	pkKey, err := section.GetKey("PublicKey")
-	if err != nil {
-		return err
-	}
-
-	cp.PublicKey, err = ParseKey(pkKey.Value())
-	if err != nil {
-		return err
-	}
-
-	ipsKey, err := section.GetKey("AllowedIPs")
-	if err != nil {
-		return err
-	}
-
-	cp.AllowedIPs, err = netutils.ParseAddresses(ipsKey.Strings(","))
-	if err != nil {
-		return err
-	}
-
-	keepaliveKey, err := section.GetKey("PersistentKeepalive")
-	if err != nil {
-		return err
-	}
-
-	keepalive, err := keepaliveKey.Int64()
-	if err != nil {
-		return err
-	}
-
-	cp.PersistentKeepalive = null.IntFrom(keepalive)
-
-	return nil
+func (cp *ClientPeer) peer() Peer {
+	peer := Peer{
+		Name:                cp.Name,
+		Comments:            nil,
+		PublicKey:           cp.PublicKey,
+		PresharedKey:        cp.PresharedKey,
+		AllowedIPs:          cp.AllowedIPs,
+		Endpoint:            "",
+		PersistentKeepalive: cp.PersistentKeepalive,
+		KeyComments:         nil,
+		Extra:               nil,
+	}
+	if cp.EndpointHost != "" {
+		peer.Endpoint = net.JoinHostPort(cp.EndpointHost, strconv.Itoa(cp.EndpointPort))
+	}
+	return peer
 }
@@ -0,0 +1,308 @@
+package wgtypes
+
+import (
+	"fmt"
+	"net"
+)
+
+type Severity int
+
+const (
+	SeverityInfo Severity = iota
+	SeverityWarning
+	SeverityError
+)
+
+func (s Severity) String() string {
+	switch s {
+	case SeverityInfo:
+		return "info"
+	case SeverityWarning:
+		return "warning"
+	case SeverityError:
+		return "error"
+	}
+	return fmt.Sprintf("Severity(%d)", int(s))
+}
+
+// Issue is a problem found in a config.
+type Issue struct {
+	Severity Severity
+	// Section is either "Interface" or "Peer" followed by the peer's name or public key.
+	Section string
+	Message string
+}
+
+func (issue *Issue) String() string {
+	return fmt.Sprintf("%s: [%s] %s", issue.Severity, issue.Section, issue.Message)
+}
+
+// MTU bounds: IPv4 requires at least 576 bytes and IPv6 at least 1280 bytes.
+// WireGuard adds up to 80 bytes of overhead, so 1420 is the most that fits into a 1500-byte link.
+const (
+	minMTUv4     = 576
+	minMTUv6     = 1280
+	maxSafeMTU   = 1420
+	maxMTU       = 65535
+	maxKeepalive = 65535
+)
+
+// Validate checks the config for mistakes that wg-quick either doesn't report
+// or reports only when the interface is being brought up.
+func (cfg *Config) Validate() (issues []Issue) {
+	iface := &cfg.Interface
+
+	if len(iface.Address) == 0 {
+		issues = append(issues, Issue{
+			Severity: SeverityWarning,
+			Section:  sectionInterface,
+			Message:  "no Address, interface won't have any address",
+		})
+	}
+
+	if iface.ListenPort.Valid && (iface.ListenPort.Int64 < 0 || iface.ListenPort.Int64 > 65535) {
+		issues = append(issues, Issue{
+			Severity: SeverityError,
+			Section:  sectionInterface,
+			Message:  fmt.Sprintf("ListenPort %d is out of range 0-65535", iface.ListenPort.Int64),
+		})
+	}
+
+	issues = append(issues, iface.validateMTU()...)
+
+	publicKey := iface.PrivateKey.PublicKey()
+	peersByKey := make(map[Key]string, len(cfg.Peers))
+
+	for i := range cfg.Peers {
+		peer := &cfg.Peers[i]
+		section := peer.section()
+
+		if peer.PublicKey == publicKey {
+			issues = append(issues, Issue{
+				Severity: SeverityError,
+				Section:  section,
+				Message:  "PublicKey is the interface's own public key",
+			})
+		}
+
+		if other, ok := peersByKey[peer.PublicKey]; ok {
+			issues = append(issues, Issue{
+				Severity: SeverityError,
+				Section:  section,
+				Message:  fmt.Sprintf("PublicKey is the same as of [%s]", other),
+			})
+		} else {
+			peersByKey[peer.PublicKey] = section
+		}
+
+		if peer.PersistentKeepalive.Valid &&
+			(peer.PersistentKeepalive.Int64 < 0 || peer.PersistentKeepalive.Int64 > maxKeepalive) {
+			issues = append(issues, Issue{
+				Severity: SeverityError,
+				Section:  section,
+				Message:  fmt.Sprintf("PersistentKeepalive %d is out of range 0-%d", peer.PersistentKeepalive.Int64, maxKeepalive),
+			})
+		}
+
+		if peer.Endpoint != "" {
+			if _, _, err := net.SplitHostPort(peer.Endpoint); err != nil {
+				issues = append(issues, Issue{
+					Severity: SeverityError,
+					Section:  section,
+					Message:  fmt.Sprintf("invalid Endpoint %q: %v", peer.Endpoint, err),
+				})
+			}
+		}
+
+		if len(peer.AllowedIPs) == 0 {
+			issues = append(issues, Issue{
+				Severity: SeverityWarning,
+				Section:  section,
+				Message:  "no AllowedIPs, no traffic will be routed to the peer",
+			})
+		}
+
+		// Each network can be routed to a single peer only, wg silently moves it to the last one.
+		// Overlapping networks of different sizes work, as the most specific one wins,
+		// but the larger network silently loses a part of its traffic.
+		// A single host inside a larger network is a deliberate route to another peer, e.g. in a mesh.
+		for j := range i {
+			other := &cfg.Peers[j]
+			for _, a := range peer.AllowedIPs {
+				for _, b := range other.AllowedIPs {
+					switch {
+					case sameNetwork(a, b):
+						issues = append(issues, Issue{
+							Severity: SeverityError,
+							Section:  section,
+							Message:  fmt.Sprintf("AllowedIPs %s are the same as of [%s]", a.String(), other.section()),
+						})
+					case contains(a, b) && !singleHost(b), contains(b, a) && !singleHost(a):
+						issues = append(issues, Issue{
+							Severity: SeverityWarning,
+							Section:  section,
+							Message: fmt.Sprintf("AllowedIPs %s overlap %s of [%s], the smaller network is routed to its peer",
+								a.String(), b.String(), other.section()),
+						})
+					}
+				}
+			}
+		}
+	}
+
+	for _, dns := range iface.DNS {
+		if !cfg.routed(dns) {
+			issues = append(issues, Issue{
+				Severity: SeverityWarning,
+				Section:  sectionInterface,
+				Message:  fmt.Sprintf("DNS %s is not reachable through AllowedIPs of any peer", dns.String()),
+			})
+		}
+	}
+
+	return issues
+}
+
+func (iface *Interface) validateMTU() (issues []Issue) {
+	if !iface.MTU.Valid {
+		return nil
+	}
+
+	mtu := iface.MTU.Int64
+
+	hasIPv6 := false
+	for i := range iface.Address {
+		if iface.Address[i].IP.To4() == nil {
+			hasIPv6 = true
+		}
+	}
+
+	switch {
+	case mtu < minMTUv4 || mtu > maxMTU:
+		issues = append(issues, Issue{
+			Severity: SeverityError,
+			Section:  sectionInterface,
+			Message:  fmt.Sprintf("MTU %d is out of range %d-%d", mtu, minMTUv4, maxMTU),
+		})
+	case hasIPv6 && mtu < minMTUv6:
+		issues = append(issues, Issue{
+			Severity: SeverityError,
+			Section:  sectionInterface,
+			Message:  fmt.Sprintf("MTU %d is less than %d required by IPv6", mtu, minMTUv6),
+		})
+	case mtu > maxSafeMTU:
+		issues = append(issues, Issue{
+			Severity: SeverityWarning,
+			Section:  sectionInterface,
+			Message:  fmt.Sprintf("MTU %d is more than %d, packets will be fragmented on 1500-byte links", mtu, maxSafeMTU),
+		})
+	}
+
+	return issues
+}
+
+// routed reports whether traffic to the ip goes through the tunnel.
+func (cfg *Config) routed(ip net.IP) bool {
+	for i := range cfg.Interface.Address {
+		if cfg.Interface.Address[i].Contains(ip) {
+			return true
+		}
+	}
+
+	for i := range cfg.Peers {
+		for _, allowed := range cfg.Peers[i].AllowedIPs {
+			if allowed.Contains(ip) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+func (peer *Peer) section() string {
+	if peer.Name != "" {
+		return sectionPeer + " " + peer.Name
+	}
+	return sectionPeer + " " + peer.PublicKey.String()
+}
+
+// Validate checks the generic config as well as that the peers' addresses are inside the server's subnet.
+func (cfg *ServerConfig) Validate() (issues []Issue) {
+	config := cfg.Config()
+	issues = config.Validate()
+
+	subnet := net.IPNet{
+		IP:   cfg.Interface.Address.IP.Mask(cfg.Interface.Address.Mask),
+		Mask: cfg.Interface.Address.Mask,
+	}
+
+	for i := range config.Peers {
+		peer := &config.Peers[i]
+		for _, allowed := range peer.AllowedIPs {
+			if !contains(subnet, allowed) {
+				issues = append(issues, Issue{
+					Severity: SeverityError,
+					Section:  peer.section(),
+					Message:  fmt.Sprintf("address %s is outside of the server subnet %s", allowed.String(), subnet.String()),
+				})
+			}
+			if allowed.IP.Equal(cfg.Interface.Address.IP) {
+				issues = append(issues, Issue{
+					Severity: SeverityError,
+					Section:  peer.section(),
+					Message:  fmt.Sprintf("address %s is the server's address", allowed.IP.String()),
+				})
+			}
+		}
+	}
+
+	return issues
+}
+
+// Validate checks the generic config as well as that the server has an endpoint to connect to.
+func (cfg *ClientConfig) Validate() (issues []Issue) {
+	config := cfg.Config()
+	issues = config.Validate()
+
+	if len(cfg.Peers) == 0 {
+		return append(issues, Issue{
+			Severity: SeverityError,
+			Section:  sectionInterface,
+			Message:  "no [Peer] of the server",
+		})
+	}
+
+	for i := range cfg.Peers {
+		peer := &cfg.Peers[i]
+		if (i == 0 || peer.EndpointHost != "") && (peer.EndpointHost == "" || peer.EndpointPort <= 0 || peer.EndpointPort > 65535) {
+			issues = append(issues, Issue{
+				Severity: SeverityError,
+				Section:  config.Peers[i].section(),
+				Message:  "missing or invalid Endpoint",
+			})
+		}
+	}
+
+	return issues
+}
+
+// sameNetwork reports whether the networks are equal.
+func sameNetwork(a, b net.IPNet) bool {
+	aOnes, aBits := a.Mask.Size()
+	bOnes, bBits := b.Mask.Size()
+	return aOnes == bOnes && aBits == bBits && a.IP.Mask(a.Mask).Equal(b.IP.Mask(b.Mask))
+}
+
+// singleHost reports whether the network consists of a single address.
+func singleHost(n net.IPNet) bool {
+	ones, bits := n.Mask.Size()
+	return ones == bits
+}
+
+// contains reports whether the network b is inside the network a.
+func contains(a, b net.IPNet) bool {
+	aOnes, aBits := a.Mask.Size()
+	bOnes, bBits := b.Mask.Size()
+	return aBits == bBits && aOnes <= bOnes && a.Contains(b.IP)
+}
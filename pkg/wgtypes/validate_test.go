@@ -0,0 +1,110 @@
+package wgtypes
+
+import (
+	"net"
+	"reflect"
+	"testing"
+)
+
+func mustParseCIDR(t *testing.T, s string) net.IPNet {
+	t.Helper()
+
+	ip, network, err := net.ParseCIDR(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	network.IP = ip
+
+	return *network
+}
+
+func newPeer(t *testing.T, name string, allowedIPs ...string) Peer {
+	t.Helper()
+
+	privateKey, err := GeneratePrivateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	peer := Peer{ //nolint:exhaustruct
+		Name:      name,
+		PublicKey: privateKey.PublicKey(),
+	}
+	for _, s := range allowedIPs {
+		peer.AllowedIPs = append(peer.AllowedIPs, mustParseCIDR(t, s))
+	}
+
+	return peer
+}
+
+func TestConfigValidateAllowedIPs(t *testing.T) {
+	tests := []struct {
+		name  string
+		first []string
+		last  []string
+		want  []Issue
+	}{
+		{
+			name:  "same network",
+			first: []string{"10.0.1.0/24"},
+			last:  []string{"10.0.1.0/24"},
+			want: []Issue{{
+				Severity: SeverityError,
+				Section:  "Peer last",
+				Message:  "AllowedIPs 10.0.1.0/24 are the same as of [Peer first]",
+			}},
+		},
+		{
+			name:  "host inside subnet",
+			first: []string{"10.0.0.0/24"},
+			last:  []string{"10.0.0.2/32"},
+			want:  nil,
+		},
+		{
+			name:  "subnet around host",
+			first: []string{"fd00::2/128"},
+			last:  []string{"fd00::/64"},
+			want:  nil,
+		},
+		{
+			name:  "nested subnets",
+			first: []string{"10.0.0.0/16"},
+			last:  []string{"10.0.1.0/24"},
+			want: []Issue{{
+				Severity: SeverityWarning,
+				Section:  "Peer last",
+				Message:  "AllowedIPs 10.0.1.0/24 overlap 10.0.0.0/16 of [Peer first], the smaller network is routed to its peer",
+			}},
+		},
+		{
+			name:  "disjoint subnets",
+			first: []string{"10.0.0.0/24"},
+			last:  []string{"10.0.1.0/24"},
+			want:  nil,
+		},
+	}
+
+	privateKey, err := GeneratePrivateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := Config{ //nolint:exhaustruct
+				Interface: Interface{ //nolint:exhaustruct
+					Address:    []net.IPNet{mustParseCIDR(t, "10.0.0.1/24")},
+					PrivateKey: privateKey,
+				},
+				Peers: []Peer{
+					newPeer(t, "first", tt.first...),
+					newPeer(t, "last", tt.last...),
+				},
+			}
+
+			if got := cfg.Validate(); !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
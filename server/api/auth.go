@@ -0,0 +1,57 @@
+package api
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+)
+
+type authenticator struct {
+	tokens [][]byte
+}
+
+func newAuthenticator(tokens []string) *authenticator {
+	a := &authenticator{
+		tokens: make([][]byte, len(tokens)),
+	}
+	for i := range tokens {
+		a.tokens[i] = []byte(tokens[i])
+	}
+	return a
+}
+
+// wrap allows the request if it either presents a client certificate
+// verified against the configured CA or carries one of the bearer tokens.
+func (a *authenticator) wrap(h handlerFunc) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !a.authenticate(r) {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="wg-wish"`)
+			writeError(w, r, errUnauthorized)
+			return
+		}
+
+		if err := h(w, r); err != nil {
+			writeError(w, r, err)
+		}
+	})
+}
+
+func (a *authenticator) authenticate(r *http.Request) bool {
+	if r.TLS != nil && len(r.TLS.VerifiedChains) != 0 {
+		return true
+	}
+
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok {
+		return false
+	}
+
+	valid := false
+	for i := range a.tokens {
+		if subtle.ConstantTimeCompare([]byte(token), a.tokens[i]) == 1 {
+			valid = true
+		}
+	}
+
+	return valid
+}
@@ -0,0 +1,85 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/infastin/wg-wish/server/errors"
+)
+
+var (
+	errUnauthorized = &httpError{status: http.StatusUnauthorized, msg: "unauthorized"}
+	errNameRequired = &httpError{status: http.StatusBadRequest, msg: "name is required"}
+	errInvalidStale = &httpError{status: http.StatusBadRequest, msg: "stale must be a non-negative duration"}
+	errInvalidLimit = &httpError{status: http.StatusBadRequest, msg: "limit must be a non-negative integer"}
+)
+
+// httpError is an error produced by the API layer itself,
+// e.g. when the request body can't be decoded.
+type httpError struct {
+	status int
+	msg    string
+}
+
+func badRequest(err error) error {
+	return &httpError{
+		status: http.StatusBadRequest,
+		msg:    err.Error(),
+	}
+}
+
+func (e *httpError) Error() string {
+	return e.msg
+}
+
+var domainErrorStatus = map[error]int{
+	errors.ErrPublicKeyExists:                http.StatusConflict,
+	errors.ErrPublicKeyNotFound:              http.StatusNotFound,
+	errors.ErrWireGuardClientExists:          http.StatusConflict,
+	errors.ErrWireGuardClientNotFound:        http.StatusNotFound,
+	errors.ErrWireGuardClientAddressOverlaps: http.StatusConflict,
+	errors.ErrWireGuardClientAddressExists:   http.StatusConflict,
+	errors.ErrWireGuardClientPublicKeyExists: http.StatusConflict,
+	errors.ErrWireGuardInterfaceNotFound:     http.StatusNotFound,
+	errors.ErrWireGuardProfileNotFound:       http.StatusNotFound,
+	errors.ErrWireGuardClientInvalidSort:     http.StatusBadRequest,
+	errors.ErrWireGuardClientInvalidPattern:  http.StatusBadRequest,
+	errors.ErrWireGuardClientInvalidEndpoint: http.StatusBadRequest,
+}
+
+type errorResponse struct {
+	Error  string `json:"error"`
+	Domain string `json:"domain,omitempty"`
+}
+
+type errorContextKey struct{}
+
+func writeError(w http.ResponseWriter, r *http.Request, err error) {
+	if p, ok := r.Context().Value(errorContextKey{}).(*error); ok {
+		*p = err
+	}
+
+	switch e := err.(type) {
+	case *httpError:
+		writeJSON(w, e.status, &errorResponse{Error: e.msg})
+	case errors.DomainError:
+		status, ok := domainErrorStatus[err]
+		if !ok {
+			status = http.StatusUnprocessableEntity
+		}
+		writeJSON(w, status, &errorResponse{Error: e.Error(), Domain: e.Domain()})
+	default:
+		writeJSON(w, http.StatusInternalServerError, &errorResponse{Error: "internal error"})
+	}
+}
+
+func withErrorSlot(ctx context.Context, slot *error) context.Context {
+	return context.WithValue(ctx, errorContextKey{}, slot)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
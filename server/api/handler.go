@@ -0,0 +1,53 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/infastin/wg-wish/server/errors"
+	"github.com/infastin/wg-wish/server/service"
+	"github.com/rs/zerolog"
+)
+
+type handlerFunc func(w http.ResponseWriter, r *http.Request) error
+
+type handler struct {
+	lg               zerolog.Logger
+	publicKeyService service.PublicKeyService
+	interfaces       []Interface
+}
+
+// Interface holds the service bound to a single WireGuard interface.
+type Interface struct {
+	Name             string
+	WireGuardService service.WireGuardService
+}
+
+// wireguardService returns the service of the interface selected
+// with the iface query parameter, or of the default interface.
+func (h *handler) wireguardService(r *http.Request) (service.WireGuardService, error) {
+	name := r.URL.Query().Get("iface")
+	if name == "" {
+		return h.interfaces[0].WireGuardService, nil
+	}
+
+	for i := range h.interfaces {
+		if h.interfaces[i].Name == name {
+			return h.interfaces[i].WireGuardService, nil
+		}
+	}
+
+	return nil, errors.ErrWireGuardInterfaceNotFound
+}
+
+func decodeJSON(r *http.Request, v any) error {
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+
+	if err := dec.Decode(v); err != nil {
+		return badRequest(fmt.Errorf("failed to decode request body: %w", err))
+	}
+
+	return nil
+}
@@ -0,0 +1,73 @@
+package api
+
+import (
+	"net/http"
+	"runtime"
+	"time"
+
+	"github.com/infastin/wg-wish/server/errors"
+	"github.com/rs/zerolog"
+)
+
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+// newLoggerMiddleware logs every request along with the error returned by its handler
+// and turns panics into internal errors.
+func newLoggerMiddleware(lg zerolog.Logger, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ct := time.Now()
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+
+		var err error
+		r = r.WithContext(withErrorSlot(r.Context(), &err))
+
+		func() {
+			defer func() {
+				if p := recover(); p != nil {
+					stack := make([]byte, 1<<16)
+					stack = stack[:runtime.Stack(stack, false)]
+					writeError(rec, r, errors.NewPanicError(p, stack))
+				}
+			}()
+			next.ServeHTTP(rec, r)
+		}()
+
+		lgCtx := lg.With().
+			Str("method", r.Method).
+			Str("path", r.URL.Path).
+			Str("addr", r.RemoteAddr).
+			Int("status", rec.status).
+			Dur("elapsed", time.Since(ct))
+
+		switch e := err.(type) {
+		case *errors.PanicError:
+			lg := lgCtx.
+				Any("panic", e.Panic).
+				Bytes("stack", e.Stack).
+				Logger()
+			lg.Error().Msg("request panic")
+		case errors.InternalError:
+			lg := lgCtx.Logger()
+			lg.Err(e.Internal()).Msg("request error")
+		case errors.DomainError:
+			lg := lgCtx.
+				Str("domain", e.Domain()).
+				Logger()
+			lg.Err(e).Msg("request error")
+		case error:
+			lg := lgCtx.Logger()
+			lg.Err(e).Msg("request error")
+		case nil:
+			lg := lgCtx.Logger()
+			lg.Info().Msg("request ok")
+		}
+	})
+}
@@ -0,0 +1,339 @@
+package api
+
+import (
+	"bytes"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/guregu/null/v5"
+	"github.com/infastin/wg-wish/pkg/netutils"
+	"github.com/infastin/wg-wish/pkg/wgtypes"
+	"github.com/infastin/wg-wish/server/entity"
+	"github.com/infastin/wg-wish/server/service"
+)
+
+type peer struct {
+	Name                string            `json:"name"`
+	Address             string            `json:"address"`
+	PublicKey           string            `json:"public_key"`
+	DNS                 []string          `json:"dns"`
+	AllowedIPs          []string          `json:"allowed_ips"`
+	PersistentKeepalive *int64            `json:"persistent_keepalive,omitempty"`
+	Disabled            bool              `json:"disabled"`
+	Quota               *peerQuota        `json:"quota,omitempty"`
+	RateLimit           uint64            `json:"rate_limit,omitempty"`
+	Group               string            `json:"group,omitempty"`
+	Profile             string            `json:"profile,omitempty"`
+	ExpiresAt           *time.Time        `json:"expires_at,omitempty"`
+	Labels              map[string]string `json:"labels,omitempty"`
+	Description         string            `json:"description,omitempty"`
+	Mesh                bool              `json:"mesh,omitempty"`
+	Endpoint            string            `json:"endpoint,omitempty"`
+	LastEndpoint        string            `json:"last_endpoint,omitempty"`
+	Stats               *peerStats        `json:"stats,omitempty"`
+	Config              string            `json:"config,omitempty"`
+}
+
+type peerStats struct {
+	Received        uint64     `json:"received"`
+	Sent            uint64     `json:"sent"`
+	LatestHandshake *time.Time `json:"latest_handshake,omitempty"`
+}
+
+type peerQuota struct {
+	Limit     uint64    `json:"limit"`
+	Period    string    `json:"period"`
+	Used      uint64    `json:"used"`
+	Remaining uint64    `json:"remaining"`
+	ResetsAt  time.Time `json:"resets_at"`
+}
+
+type addPeerRequest struct {
+	Name                string            `json:"name"`
+	Address             *string           `json:"address"`
+	DNS                 []string          `json:"dns"`
+	AllowedIPs          []string          `json:"allowed_ips"`
+	PersistentKeepalive *int64            `json:"persistent_keepalive"`
+	RateLimit           uint64            `json:"rate_limit"`
+	Group               string            `json:"group"`
+	Profile             string            `json:"profile"`
+	ExpiresAt           *time.Time        `json:"expires_at"`
+	Labels              map[string]string `json:"labels"`
+	Description         string            `json:"description"`
+	Mesh                bool              `json:"mesh"`
+	Endpoint            string            `json:"endpoint"`
+}
+
+func newPeer(cfg *wgtypes.ClientConfig) peer {
+	return peer{
+		Name:                cfg.Interface.Name,
+		Address:             cfg.Interface.Address.String(),
+		PublicKey:           cfg.Interface.PrivateKey.PublicKey().String(),
+		DNS:                 formatIPs(cfg.Interface.DNS),
+		AllowedIPs:          formatAddresses(cfg.Peers[0].AllowedIPs),
+		PersistentKeepalive: cfg.Peers[0].PersistentKeepalive.Ptr(),
+		Disabled:            false,
+		Quota:               nil,
+		RateLimit:           0,
+		Group:               "",
+		Profile:             "",
+		ExpiresAt:           nil,
+		Labels:              nil,
+		Description:         "",
+		Mesh:                false,
+		Endpoint:            "",
+		LastEndpoint:        "",
+		Stats:               nil,
+		Config:              "",
+	}
+}
+
+func newPeerWithConfig(cfg *wgtypes.ClientConfig) (p peer, err error) {
+	var conf bytes.Buffer
+	if err := cfg.Encode(&conf); err != nil {
+		return peer{}, err
+	}
+
+	p = newPeer(cfg)
+	p.Config = conf.String()
+
+	return p, nil
+}
+
+func newPeerFromInfo(info *entity.WireGuardClientInfo) peer {
+	p := newPeer(&info.Config)
+	p.Disabled = info.Disabled
+	p.RateLimit = info.RateLimit
+	p.Group = info.Group
+	p.Profile = info.Profile
+	p.ExpiresAt = info.ExpiresAt.Ptr()
+	p.Labels = info.Labels
+	p.Description = info.Description
+	p.Mesh = info.Mesh
+	p.Endpoint = info.Endpoint
+	p.LastEndpoint = info.LastEndpoint
+	if info.Quota.Valid {
+		p.Quota = &peerQuota{
+			Limit:     info.Quota.V.Limit,
+			Period:    string(info.Quota.V.Period),
+			Used:      info.Quota.V.Used,
+			Remaining: info.Quota.V.Remaining(),
+			ResetsAt:  info.Quota.V.Period.Next(info.Quota.V.PeriodStart),
+		}
+	}
+	if info.Stats.Valid {
+		p.Stats = &peerStats{
+			Received:        info.Stats.V.Received,
+			Sent:            info.Stats.V.Sent,
+			LatestHandshake: info.Stats.V.LatestHandshake.Ptr(),
+		}
+	}
+	return p
+}
+
+func (h *handler) listPeers(w http.ResponseWriter, r *http.Request) (err error) {
+	wireguardService, err := h.wireguardService(r)
+	if err != nil {
+		return err
+	}
+
+	query := r.URL.Query()
+
+	opts := service.ListClientsOptions{
+		Labels: nil,
+		Online: null.Bool{},
+		Stale:  0,
+		Name:   query.Get("name"),
+		Sort:   service.ClientSort(query.Get("sort")),
+		Limit:  0,
+	}
+
+	if online := query.Get("online"); online != "" {
+		v, err := strconv.ParseBool(online)
+		if err != nil {
+			return badRequest(err)
+		}
+		opts.Online = null.BoolFrom(v)
+	}
+
+	if stale := query.Get("stale"); stale != "" {
+		opts.Stale, err = time.ParseDuration(stale)
+		if err != nil || opts.Stale < 0 {
+			return errInvalidStale
+		}
+	}
+
+	if limit := query.Get("limit"); limit != "" {
+		opts.Limit, err = strconv.Atoi(limit)
+		if err != nil || opts.Limit < 0 {
+			return errInvalidLimit
+		}
+	}
+
+	if labels := query["label"]; len(labels) != 0 {
+		opts.Labels = make(map[string]null.String, len(labels))
+		for _, label := range labels {
+			key, value, ok := strings.Cut(label, "=")
+			opts.Labels[key] = null.NewString(value, ok)
+		}
+	}
+
+	infos, err := wireguardService.ListClients(r.Context(), &opts)
+	if err != nil {
+		return err
+	}
+
+	peers := make([]peer, len(infos))
+	for i := range infos {
+		peers[i] = newPeerFromInfo(&infos[i])
+	}
+
+	writeJSON(w, http.StatusOK, peers)
+	return nil
+}
+
+func (h *handler) addPeer(w http.ResponseWriter, r *http.Request) (err error) {
+	wireguardService, err := h.wireguardService(r)
+	if err != nil {
+		return err
+	}
+
+	var req addPeerRequest
+	if err := decodeJSON(r, &req); err != nil {
+		return err
+	}
+
+	if req.Name == "" {
+		return errNameRequired
+	}
+
+	var opts service.AddClientOptions
+
+	if req.Address != nil {
+		addr, err := netutils.ParseAddress(*req.Address)
+		if err != nil {
+			return badRequest(err)
+		}
+		opts.Address = null.ValueFrom(addr)
+	}
+
+	if req.DNS != nil {
+		opts.DNS, err = netutils.ParseIPs(req.DNS)
+		if err != nil {
+			return badRequest(err)
+		}
+	}
+
+	if req.AllowedIPs != nil {
+		opts.AllowedIPs, err = netutils.ParseAddresses(req.AllowedIPs)
+		if err != nil {
+			return badRequest(err)
+		}
+	}
+
+	opts.PersistentKeepalive = null.IntFromPtr(req.PersistentKeepalive)
+	opts.RateLimit = req.RateLimit
+	opts.Group = req.Group
+	opts.Profile = req.Profile
+	opts.ExpiresAt = null.TimeFromPtr(req.ExpiresAt)
+	opts.Labels = req.Labels
+	opts.Description = req.Description
+	opts.Mesh = req.Mesh
+	opts.Endpoint = req.Endpoint
+
+	cfg, err := wireguardService.AddClient(r.Context(), req.Name, &opts)
+	if err != nil {
+		return err
+	}
+
+	p, err := newPeerWithConfig(&cfg)
+	if err != nil {
+		return err
+	}
+
+	p.RateLimit = opts.RateLimit
+	p.Group = opts.Group
+	p.Profile = opts.Profile
+	p.ExpiresAt = opts.ExpiresAt.Ptr()
+	p.Labels = opts.Labels
+	p.Description = opts.Description
+	p.Mesh = opts.Mesh
+	p.Endpoint = opts.Endpoint
+
+	writeJSON(w, http.StatusCreated, &p)
+	return nil
+}
+
+func (h *handler) getPeer(w http.ResponseWriter, r *http.Request) (err error) {
+	wireguardService, err := h.wireguardService(r)
+	if err != nil {
+		return err
+	}
+
+	cfg, err := wireguardService.GetClient(r.Context(), r.PathValue("name"))
+	if err != nil {
+		return err
+	}
+
+	p, err := newPeerWithConfig(&cfg)
+	if err != nil {
+		return err
+	}
+
+	writeJSON(w, http.StatusOK, &p)
+	return nil
+}
+
+func (h *handler) getPeerConfig(w http.ResponseWriter, r *http.Request) (err error) {
+	wireguardService, err := h.wireguardService(r)
+	if err != nil {
+		return err
+	}
+
+	cfg, err := wireguardService.GetClient(r.Context(), r.PathValue("name"))
+	if err != nil {
+		return err
+	}
+
+	var conf bytes.Buffer
+	if err := cfg.Encode(&conf); err != nil {
+		return err
+	}
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	_, _ = w.Write(conf.Bytes())
+
+	return nil
+}
+
+func (h *handler) removePeer(w http.ResponseWriter, r *http.Request) (err error) {
+	wireguardService, err := h.wireguardService(r)
+	if err != nil {
+		return err
+	}
+
+	if err := wireguardService.RemoveClient(r.Context(), r.PathValue("name")); err != nil {
+		return err
+	}
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
+
+func formatIPs(ips []net.IP) []string {
+	s := make([]string, len(ips))
+	for i := range ips {
+		s[i] = ips[i].String()
+	}
+	return s
+}
+
+func formatAddresses(addrs []net.IPNet) []string {
+	s := make([]string, len(addrs))
+	for i := range addrs {
+		s[i] = addrs[i].String()
+	}
+	return s
+}
@@ -0,0 +1,92 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/charmbracelet/ssh"
+	"github.com/infastin/gorack/fastconv"
+	"github.com/infastin/wg-wish/server/entity"
+	"github.com/infastin/wg-wish/server/errors"
+	gossh "golang.org/x/crypto/ssh"
+)
+
+type publicKey struct {
+	Key         string `json:"key"`
+	Comment     string `json:"comment,omitempty"`
+	Fingerprint string `json:"fingerprint"`
+}
+
+type addPublicKeyRequest struct {
+	Key string `json:"key"`
+}
+
+func newPublicKey(pkey *entity.PublicKey) publicKey {
+	key := gossh.MarshalAuthorizedKey(pkey.Key)
+	return publicKey{
+		Key:         string(key[:len(key)-1]),
+		Comment:     pkey.Comment,
+		Fingerprint: gossh.FingerprintSHA256(pkey.Key),
+	}
+}
+
+func (h *handler) listPublicKeys(w http.ResponseWriter, r *http.Request) (err error) {
+	pkeys, err := h.publicKeyService.GetPublicKeys(r.Context())
+	if err != nil {
+		return err
+	}
+
+	keys := make([]publicKey, len(pkeys))
+	for i := range pkeys {
+		keys[i] = newPublicKey(&pkeys[i])
+	}
+
+	writeJSON(w, http.StatusOK, keys)
+	return nil
+}
+
+func (h *handler) addPublicKey(w http.ResponseWriter, r *http.Request) (err error) {
+	var req addPublicKeyRequest
+	if err := decodeJSON(r, &req); err != nil {
+		return err
+	}
+
+	pkey, comment, _, _, err := ssh.ParseAuthorizedKey(fastconv.Bytes(req.Key))
+	if err != nil {
+		return badRequest(err)
+	}
+
+	entry := entity.PublicKey{
+		Key:     pkey,
+		Comment: comment,
+	}
+
+	if err := h.publicKeyService.AddPublicKey(r.Context(), &entry); err != nil {
+		return err
+	}
+
+	writeJSON(w, http.StatusCreated, newPublicKey(&entry))
+	return nil
+}
+
+func (h *handler) removePublicKey(w http.ResponseWriter, r *http.Request) (err error) {
+	pkeys, err := h.publicKeyService.GetPublicKeys(r.Context())
+	if err != nil {
+		return err
+	}
+
+	fingerprint := r.PathValue("fingerprint")
+	for i := range pkeys {
+		if gossh.FingerprintSHA256(pkeys[i].Key) != fingerprint {
+			continue
+		}
+
+		if err := h.publicKeyService.RemovePublicKey(r.Context(), pkeys[i].Key); err != nil {
+			return err
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+		return nil
+	}
+
+	return errors.ErrPublicKeyNotFound
+}
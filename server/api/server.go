@@ -0,0 +1,142 @@
+package api
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	_ "embed"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/infastin/wg-wish/server/service"
+	"github.com/rs/zerolog"
+)
+
+//go:embed openapi.yaml
+var openapi []byte
+
+type Server struct {
+	lg     zerolog.Logger
+	server *http.Server
+}
+
+type ServerParams struct {
+	Logger zerolog.Logger
+	Port   int
+	Tokens []string
+	// Without CertFile the server only listens on loopback,
+	// since tokens would cross the network in cleartext.
+	CertFile         string
+	KeyFile          string
+	ClientCAFile     string
+	PublicKeyService service.PublicKeyService
+	// Interfaces must contain at least one interface, the first one is used by default.
+	Interfaces []Interface
+}
+
+func New(params *ServerParams) (srv *Server, err error) {
+	h := &handler{
+		lg:               params.Logger,
+		publicKeyService: params.PublicKeyService,
+		interfaces:       params.Interfaces,
+	}
+
+	auth := newAuthenticator(params.Tokens)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/v1/openapi.yaml", serveOpenAPI)
+	mux.Handle("GET /api/v1/server", auth.wrap(h.getServer))
+	mux.Handle("POST /api/v1/server/reload", auth.wrap(h.reloadServer))
+	mux.Handle("GET /api/v1/peers", auth.wrap(h.listPeers))
+	mux.Handle("POST /api/v1/peers", auth.wrap(h.addPeer))
+	mux.Handle("GET /api/v1/peers/{name}", auth.wrap(h.getPeer))
+	mux.Handle("GET /api/v1/peers/{name}/config", auth.wrap(h.getPeerConfig))
+	mux.Handle("DELETE /api/v1/peers/{name}", auth.wrap(h.removePeer))
+	mux.Handle("GET /api/v1/public-keys", auth.wrap(h.listPublicKeys))
+	mux.Handle("POST /api/v1/public-keys", auth.wrap(h.addPublicKey))
+	mux.Handle("DELETE /api/v1/public-keys/{fingerprint...}", auth.wrap(h.removePublicKey))
+
+	host := "0.0.0.0"
+	if params.CertFile == "" {
+		host = "127.0.0.1"
+		params.Logger.Warn().Msg("api: TLS is not configured, listening on loopback only")
+	}
+
+	srv = &Server{
+		lg: params.Logger,
+		server: &http.Server{ //nolint:exhaustruct
+			Addr:              net.JoinHostPort(host, strconv.Itoa(params.Port)),
+			Handler:           newLoggerMiddleware(params.Logger, mux),
+			ReadHeaderTimeout: 10 * time.Second,
+		},
+	}
+
+	if params.CertFile != "" {
+		srv.server.TLSConfig, err = newTLSConfig(params.CertFile, params.KeyFile, params.ClientCAFile)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return srv, nil
+}
+
+func newTLSConfig(certFile, keyFile, clientCAFile string) (config *tls.Config, err error) {
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("api: failed to load certificate: %w", err)
+	}
+
+	config = &tls.Config{ //nolint:exhaustruct
+		MinVersion:   tls.VersionTLS12,
+		Certificates: []tls.Certificate{cert},
+	}
+
+	if clientCAFile != "" {
+		b, err := os.ReadFile(clientCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("api: failed to read client CA: %w", err)
+		}
+
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(b) {
+			return nil, fmt.Errorf("api: no certificates found in %q", clientCAFile)
+		}
+
+		// Client certificates are optional, since requests
+		// can also be authenticated with a token.
+		config.ClientCAs = pool
+		config.ClientAuth = tls.VerifyClientCertIfGiven
+	}
+
+	return config, nil
+}
+
+func (s *Server) Run() error {
+	var err error
+	if s.server.TLSConfig != nil {
+		err = s.server.ListenAndServeTLS("", "")
+	} else {
+		err = s.server.ListenAndServe()
+	}
+	if err != nil {
+		return fmt.Errorf("api: failed to serve: %w", err)
+	}
+	return nil
+}
+
+func (s *Server) Shutdown(ctx context.Context) error {
+	if err := s.server.Shutdown(ctx); err != nil {
+		return fmt.Errorf("api: failed to shutdown server: %w", err)
+	}
+	return nil
+}
+
+func serveOpenAPI(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "application/yaml")
+	_, _ = w.Write(openapi)
+}
@@ -0,0 +1,54 @@
+package api
+
+import (
+	"net/http"
+)
+
+type serverInfo struct {
+	Interface       string `json:"interface"`
+	Host            string `json:"host"`
+	Port            int    `json:"port"`
+	Address         string `json:"address"`
+	PublicKey       string `json:"public_key"`
+	Peers           int    `json:"peers"`
+	AddressPoolSize uint64 `json:"address_pool_size"`
+	AddressPoolUsed uint64 `json:"address_pool_used"`
+}
+
+func (h *handler) getServer(w http.ResponseWriter, r *http.Request) (err error) {
+	wireguardService, err := h.wireguardService(r)
+	if err != nil {
+		return err
+	}
+
+	info, err := wireguardService.GetServerInfo(r.Context())
+	if err != nil {
+		return err
+	}
+
+	writeJSON(w, http.StatusOK, &serverInfo{
+		Interface:       info.Interface,
+		Host:            info.Host,
+		Port:            info.Port,
+		Address:         info.Address.String(),
+		PublicKey:       info.PublicKey.String(),
+		Peers:           info.Clients,
+		AddressPoolSize: info.AddressPoolSize,
+		AddressPoolUsed: info.AddressPoolUsed,
+	})
+
+	return nil
+}
+
+func (h *handler) reloadServer(w http.ResponseWriter, r *http.Request) (err error) {
+	wireguardService, err := h.wireguardService(r)
+	if err != nil {
+		return err
+	}
+
+	if err := wireguardService.ReloadServer(r.Context()); err != nil {
+		return err
+	}
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
@@ -6,6 +6,18 @@ import (
 
 type CLI struct {
 	Config string `optional:"" short:"c" type:"existingfile" placeholder:"PATH" help:"Path to the config file."`
+
+	Run struct{} `cmd:"" default:"1" help:"Run the server."`
+
+	DB struct {
+		Encrypt struct{} `cmd:"" help:"Encrypt private keys stored in the database with the configured master key."`
+
+		Migrate struct {
+			From string `required:"" type:"existingfile" placeholder:"PATH" help:"Path to the bbolt database to copy data from."`
+		} `cmd:"" help:"Copy data from a bbolt database to the configured database."`
+	} `cmd:"" name:"db" help:"Manage the database."`
+
+	Command string `kong:"-"`
 }
 
 func NewCLI(args []string) (cli CLI, err error) {
@@ -14,10 +26,12 @@ func NewCLI(args []string) (cli CLI, err error) {
 		return CLI{}, err
 	}
 
-	_, err = k.Parse(args[1:])
+	kctx, err := k.Parse(args[1:])
 	if err != nil {
 		return CLI{}, err
 	}
 
+	cli.Command = kctx.Command()
+
 	return cli, nil
 }
@@ -1,20 +1,34 @@
 package app
 
 import (
+	"errors"
 	"fmt"
+	"net"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/ilyakaznacheev/cleanenv"
 	"github.com/infastin/gorack/validation"
 	isint "github.com/infastin/gorack/validation/is/int"
 	isstr "github.com/infastin/gorack/validation/is/str"
+	"github.com/infastin/wg-wish/server/entity"
 	"github.com/rs/zerolog"
 )
 
 type Config struct {
-	Logger    LoggerConfig    `env-prefix:"LOGGER_" yaml:"logger"`
-	Database  DatabaseConfig  `env-prefix:"DB_" yaml:"db"`
-	WireGuard WireGuardConfig `env-prefix:"WG_" yaml:"wireguard"`
-	SSH       SSHConfig       `env-prefix:"SSH_" yaml:"ssh"`
+	Logger     LoggerConfig     `env-prefix:"LOGGER_" yaml:"logger"`
+	Database   DatabaseConfig   `env-prefix:"DB_" yaml:"db"`
+	WireGuard  WireGuardConfig  `env-prefix:"WG_" yaml:"wireguard"`
+	SSH        SSHConfig        `env-prefix:"SSH_" yaml:"ssh"`
+	Metrics    MetricsConfig    `env-prefix:"METRICS_" yaml:"metrics"`
+	API        APIConfig        `env-prefix:"API_" yaml:"api"`
+	Webhooks   WebhooksConfig   `env-prefix:"WEBHOOKS_" yaml:"webhooks"`
+	Stats      StatsConfig      `env-prefix:"STATS_" yaml:"stats"`
+	Federation FederationConfig `env-prefix:"FEDERATION_" yaml:"federation"`
+	Cluster    ClusterConfig    `env-prefix:"CLUSTER_" yaml:"cluster"`
 }
 
 func (cfg *Config) Default() {
@@ -22,13 +36,29 @@ func (cfg *Config) Default() {
 	cfg.Database.Default()
 	cfg.WireGuard.Default()
 	cfg.SSH.Default()
+	cfg.Metrics.Default()
+	cfg.API.Default()
+	cfg.Webhooks.Default()
+	cfg.Stats.Default()
+	cfg.Federation.Default()
+	cfg.Cluster.Default()
 }
 
 func (cfg *Config) Validate() error {
 	return validation.All(
 		validation.Ptr(&cfg.Logger, "logger").With(validation.Custom),
+		validation.Ptr(&cfg.Database, "db").With(validation.Custom),
 		validation.Ptr(&cfg.WireGuard, "wg").With(validation.Custom),
 		validation.Ptr(&cfg.SSH, "ssh").With(validation.Custom),
+		validation.Ptr(&cfg.Metrics, "metrics").With(validation.Custom),
+		validation.Ptr(&cfg.API, "api").With(validation.Custom),
+		validation.Ptr(&cfg.Webhooks, "webhooks").With(validation.Custom),
+		validation.Ptr(&cfg.Stats, "stats").With(validation.Custom),
+		validation.Ptr(&cfg.Federation, "federation").With(validation.Custom),
+		validation.Ptr(&cfg.Cluster, "cluster").With(validation.Custom),
+		// Replication ships the bbolt file as is.
+		validation.Comparable(cfg.Database.Driver, "db.driver").
+			If(cfg.Cluster.Role != "").In(DatabaseDriverBolt).EndIf(),
 	)
 }
 
@@ -58,25 +88,84 @@ func (cfg *LoggerConfig) Validate() error {
 	)
 }
 
+const (
+	DatabaseDriverBolt     = "bbolt"
+	DatabaseDriverSQLite   = "sqlite"
+	DatabaseDriverPostgres = "postgres"
+)
+
 type DatabaseConfig struct {
-	Path string `env:"PATH" yaml:"path"`
+	Driver     string                   `env:"DRIVER" yaml:"driver"`
+	Path       string                   `env:"PATH" yaml:"path"`
+	DSN        string                   `env:"DSN" yaml:"dsn"`
+	Encryption DatabaseEncryptionConfig `env-prefix:"ENCRYPTION_" yaml:"encryption"`
 }
 
 func (cfg *DatabaseConfig) Default() {
+	if cfg.Driver == "" {
+		cfg.Driver = DatabaseDriverBolt
+	}
+
 	if cfg.Path == "" {
-		cfg.Path = "/var/lib/wg-wish/wg-wish.db"
+		switch cfg.Driver {
+		case DatabaseDriverBolt:
+			cfg.Path = "/var/lib/wg-wish/wg-wish.db"
+		case DatabaseDriverSQLite:
+			cfg.Path = "/var/lib/wg-wish/wg-wish.sqlite"
+		}
 	}
 }
 
+func (cfg *DatabaseConfig) Validate() error {
+	return validation.All(
+		validation.Comparable(cfg.Driver, "driver").In(DatabaseDriverBolt, DatabaseDriverSQLite, DatabaseDriverPostgres),
+		validation.String(cfg.Path, "path").Required(cfg.Driver != DatabaseDriverPostgres),
+		validation.String(cfg.DSN, "dsn").Required(cfg.Driver == DatabaseDriverPostgres),
+		validation.Ptr(&cfg.Encryption, "encryption").With(validation.Custom),
+	)
+}
+
+type DatabaseEncryptionConfig struct {
+	Key     string `env:"KEY" yaml:"key"`
+	KeyFile string `env:"KEY_FILE" yaml:"key_file"`
+	KMSFile string `env:"KMS_FILE" yaml:"kms_file"`
+}
+
+func (cfg *DatabaseEncryptionConfig) Validate() error {
+	return validation.All(
+		validation.String(cfg.KeyFile, "key_file").If(cfg.Key != "").By(validation.Empty[string](true)).EndIf(),
+		validation.String(cfg.KMSFile, "kms_file").If(cfg.Key != "" || cfg.KeyFile != "").By(validation.Empty[string](true)).EndIf(),
+	)
+}
+
+const (
+	FirewallBackendIPTables    = "iptables"
+	FirewallBackendIPTablesNFT = "iptables-nft"
+	FirewallBackendNFTables    = "nftables"
+	FirewallBackendNone        = "none"
+)
+
 type WireGuardConfig struct {
-	Host                string   `env:"HOST" yaml:"host"`
-	Path                string   `env:"PATH" yaml:"path"`
-	Address             string   `env:"ADDRESS" yaml:"address"`
-	Port                int      `env:"PORT" yaml:"port"`
-	Device              string   `env:"DEVICE" yaml:"device"`
-	AllowedIPs          []string `env:"ALLOWED_IPS" yaml:"allowed_ips"`
-	PersistentKeepalive int      `env:"PERSISTENT_KEEPALIVE" yaml:"persistent_keepalive"`
-	DNS                 []string `env:"DNS" yaml:"dns"`
+	Host                string        `env:"HOST" yaml:"host"`
+	Path                string        `env:"PATH" yaml:"path"`
+	Address             string        `env:"ADDRESS" yaml:"address"`
+	Port                int           `env:"PORT" yaml:"port"`
+	Device              string        `env:"DEVICE" yaml:"device"`
+	AllowedIPs          []string      `env:"ALLOWED_IPS" yaml:"allowed_ips"`
+	PersistentKeepalive int           `env:"PERSISTENT_KEEPALIVE" yaml:"persistent_keepalive"`
+	DNS                 []string      `env:"DNS" yaml:"dns"`
+	QuotaInterval       time.Duration `env:"QUOTA_INTERVAL" yaml:"quota_interval"`
+	Firewall            string        `env:"FIREWALL" yaml:"firewall"`
+	MTU                 int           `env:"MTU" yaml:"mtu"`
+	Table               string        `env:"TABLE" yaml:"table"`
+	FwMark              uint32        `env:"FWMARK" yaml:"fwmark"`
+	SaveConfig          bool          `env:"SAVE_CONFIG" yaml:"save_config"`
+	PreUp               []string      `yaml:"pre_up"`
+	PostUp              []string      `yaml:"post_up"`
+	PreDown             []string      `yaml:"pre_down"`
+	PostDown            []string      `yaml:"post_down"`
+	// Interfaces are served in addition to the primary one described by the fields above.
+	Interfaces []WireGuardInterfaceConfig `yaml:"interfaces"`
 }
 
 func (cfg *WireGuardConfig) Default() {
@@ -107,6 +196,18 @@ func (cfg *WireGuardConfig) Default() {
 	if len(cfg.DNS) == 0 {
 		cfg.DNS = []string{"1.1.1.1", "8.8.8.8"}
 	}
+
+	if cfg.QuotaInterval == 0 {
+		cfg.QuotaInterval = time.Minute
+	}
+
+	if cfg.Firewall == "" {
+		cfg.Firewall = FirewallBackendIPTables
+	}
+
+	for i := range cfg.Interfaces {
+		cfg.Interfaces[i].inherit(cfg)
+	}
 }
 
 func (cfg *WireGuardConfig) Validate() error {
@@ -119,9 +220,155 @@ func (cfg *WireGuardConfig) Validate() error {
 		validation.Slice(cfg.AllowedIPs, "allowed_ips").Required(true).ValuesWith(isstr.CIDR),
 		validation.Number(cfg.PersistentKeepalive, "persistent_keepalive").GreaterEqual(0),
 		validation.Slice(cfg.DNS, "dns").Required(true).ValuesWith(isstr.IP),
+		validation.Number(cfg.QuotaInterval, "quota_interval").Greater(0),
+		validation.Comparable(cfg.Firewall, "firewall").In(FirewallBackendIPTables, FirewallBackendIPTablesNFT,
+			FirewallBackendNFTables, FirewallBackendNone),
+		validation.Number(cfg.MTU, "mtu").If(cfg.MTU != 0).BetweenEqual(576, 65535).EndIf(),
+		validation.String(cfg.Table, "table").If(cfg.Table != "").With(isRoutingTable).EndIf(),
+		validation.Slice(cfg.Interfaces, "interfaces").ValuesPtrWith(validation.Custom),
+		validation.Slice(cfg.AllInterfaces(), "interfaces").With(areDistinctInterfaces),
+	)
+}
+
+// Name returns the name of the primary interface, which wg-quick derives from the config path.
+func (cfg *WireGuardConfig) Name() string {
+	return strings.TrimSuffix(path.Base(cfg.Path), ".conf")
+}
+
+// AllInterfaces returns the primary interface followed by the additional ones.
+func (cfg *WireGuardConfig) AllInterfaces() []WireGuardInterfaceConfig {
+	ifaces := make([]WireGuardInterfaceConfig, 0, len(cfg.Interfaces)+1)
+	ifaces = append(ifaces, WireGuardInterfaceConfig{
+		Name:                cfg.Name(),
+		Path:                cfg.Path,
+		Address:             cfg.Address,
+		Port:                cfg.Port,
+		AllowedIPs:          cfg.AllowedIPs,
+		PersistentKeepalive: cfg.PersistentKeepalive,
+		DNS:                 cfg.DNS,
+		MTU:                 cfg.MTU,
+		Table:               cfg.Table,
+		FwMark:              cfg.FwMark,
+		SaveConfig:          cfg.SaveConfig,
+		PreUp:               cfg.PreUp,
+		PostUp:              cfg.PostUp,
+		PreDown:             cfg.PreDown,
+		PostDown:            cfg.PostDown,
+	})
+	return append(ifaces, cfg.Interfaces...)
+}
+
+// WireGuardInterfaceConfig describes an additional interface.
+// Peer defaults and MTU not set explicitly are inherited from the primary interface.
+type WireGuardInterfaceConfig struct {
+	Name                string   `yaml:"name"`
+	Path                string   `yaml:"path"`
+	Address             string   `yaml:"address"`
+	Port                int      `yaml:"port"`
+	AllowedIPs          []string `yaml:"allowed_ips"`
+	PersistentKeepalive int      `yaml:"persistent_keepalive"`
+	DNS                 []string `yaml:"dns"`
+	MTU                 int      `yaml:"mtu"`
+	Table               string   `yaml:"table"`
+	FwMark              uint32   `yaml:"fwmark"`
+	SaveConfig          bool     `yaml:"save_config"`
+	PreUp               []string `yaml:"pre_up"`
+	PostUp              []string `yaml:"post_up"`
+	PreDown             []string `yaml:"pre_down"`
+	PostDown            []string `yaml:"post_down"`
+}
+
+func (cfg *WireGuardInterfaceConfig) inherit(primary *WireGuardConfig) {
+	if cfg.Path == "" && cfg.Name != "" {
+		cfg.Path = path.Join(path.Dir(primary.Path), cfg.Name+".conf")
+	}
+
+	if len(cfg.AllowedIPs) == 0 {
+		cfg.AllowedIPs = primary.AllowedIPs
+	}
+
+	if cfg.PersistentKeepalive == 0 {
+		cfg.PersistentKeepalive = primary.PersistentKeepalive
+	}
+
+	if len(cfg.DNS) == 0 {
+		cfg.DNS = primary.DNS
+	}
+
+	if cfg.MTU == 0 {
+		cfg.MTU = primary.MTU
+	}
+}
+
+func (cfg *WireGuardInterfaceConfig) Validate() error {
+	return validation.All(
+		validation.String(cfg.Name, "name").Required(true).With(isInterfaceName),
+		validation.String(cfg.Path, "path").Required(true).With(func(p string) error {
+			if strings.TrimSuffix(path.Base(p), ".conf") != cfg.Name {
+				return errors.New("file name must be the interface name followed by .conf")
+			}
+			return nil
+		}),
+		validation.String(cfg.Address, "address").Required(true).With(isstr.CIDR),
+		validation.Number(cfg.Port, "port").Required(true).With(isint.Port),
+		validation.Slice(cfg.AllowedIPs, "allowed_ips").Required(true).ValuesWith(isstr.CIDR),
+		validation.Number(cfg.PersistentKeepalive, "persistent_keepalive").GreaterEqual(0),
+		validation.Slice(cfg.DNS, "dns").Required(true).ValuesWith(isstr.IP),
+		validation.Number(cfg.MTU, "mtu").If(cfg.MTU != 0).BetweenEqual(576, 65535).EndIf(),
+		validation.String(cfg.Table, "table").If(cfg.Table != "").With(isRoutingTable).EndIf(),
 	)
 }
 
+// isInterfaceName checks the name against the rules of the kernel and wg-quick.
+func isInterfaceName(name string) error {
+	if len(name) > 15 {
+		return errors.New("must be at most 15 characters long")
+	}
+	for _, c := range name {
+		if !('a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9' || strings.ContainsRune("_=+.-", c)) {
+			return errors.New("must consist of letters, digits and _=+.- characters")
+		}
+	}
+	return nil
+}
+
+// areDistinctInterfaces checks that the interfaces don't share names, ports or subnets.
+func areDistinctInterfaces(ifaces []WireGuardInterfaceConfig) error {
+	for i := range ifaces {
+		_, a, err := net.ParseCIDR(ifaces[i].Address)
+		if err != nil {
+			// Reported by the interface validation.
+			continue
+		}
+
+		for j := range i {
+			if ifaces[i].Name == ifaces[j].Name {
+				return fmt.Errorf("interface %s is defined more than once", ifaces[i].Name)
+			}
+
+			if ifaces[i].Port == ifaces[j].Port {
+				return fmt.Errorf("interfaces %s and %s use the same port", ifaces[j].Name, ifaces[i].Name)
+			}
+
+			_, b, err := net.ParseCIDR(ifaces[j].Address)
+			if err == nil && (a.Contains(b.IP) || b.Contains(a.IP)) {
+				return fmt.Errorf("subnets of interfaces %s and %s overlap", ifaces[j].Name, ifaces[i].Name)
+			}
+		}
+	}
+	return nil
+}
+
+func isRoutingTable(table string) error {
+	if table == "auto" || table == "off" {
+		return nil
+	}
+	if _, err := strconv.ParseUint(table, 10, 32); err != nil {
+		return errors.New("must be auto, off or a routing table number")
+	}
+	return nil
+}
+
 type SSHConfig struct {
 	Port        int      `env:"PORT" yaml:"port"`
 	HostKeyPath string   `env:"HOST_KEY_PATH" yaml:"host_key_path"`
@@ -145,6 +392,301 @@ func (cfg *SSHConfig) Validate() error {
 	)
 }
 
+type MetricsConfig struct {
+	Enabled bool   `env:"ENABLED" yaml:"enabled"`
+	Port    int    `env:"PORT" yaml:"port"`
+	Path    string `env:"PATH" yaml:"path"`
+}
+
+func (cfg *MetricsConfig) Default() {
+	if cfg.Port == 0 {
+		cfg.Port = 9586
+	}
+
+	if cfg.Path == "" {
+		cfg.Path = "/metrics"
+	}
+}
+
+func (cfg *MetricsConfig) Validate() error {
+	return validation.All(
+		validation.Number(cfg.Port, "port").If(cfg.Enabled).Required(true).With(isint.Port).EndIf(),
+		validation.String(cfg.Path, "path").If(cfg.Enabled).Required(true).EndIf(),
+	)
+}
+
+type APIConfig struct {
+	Enabled bool         `env:"ENABLED" yaml:"enabled"`
+	Port    int          `env:"PORT" yaml:"port"`
+	Tokens  []string     `env:"TOKENS" yaml:"tokens"`
+	TLS     APITLSConfig `env-prefix:"TLS_" yaml:"tls"`
+}
+
+func (cfg *APIConfig) Default() {
+	if cfg.Port == 0 {
+		cfg.Port = 51823
+	}
+}
+
+func (cfg *APIConfig) Validate() error {
+	return validation.All(
+		validation.Number(cfg.Port, "port").If(cfg.Enabled).Required(true).With(isint.Port).EndIf(),
+		validation.Slice(cfg.Tokens, "tokens").If(cfg.Enabled).Required(cfg.TLS.ClientCAFile == "").EndIf(),
+		validation.Ptr(&cfg.TLS, "tls").With(validation.Custom),
+	)
+}
+
+type APITLSConfig struct {
+	CertFile     string `env:"CERT_FILE" yaml:"cert_file"`
+	KeyFile      string `env:"KEY_FILE" yaml:"key_file"`
+	ClientCAFile string `env:"CLIENT_CA_FILE" yaml:"client_ca_file"`
+}
+
+func (cfg *APITLSConfig) Validate() error {
+	return validation.All(
+		validation.String(cfg.CertFile, "cert_file").Required(cfg.KeyFile != "" || cfg.ClientCAFile != ""),
+		validation.String(cfg.KeyFile, "key_file").Required(cfg.CertFile != ""),
+	)
+}
+
+type WebhooksConfig struct {
+	OutboxPath          string                  `env:"OUTBOX_PATH" yaml:"outbox_path"`
+	MaxAttempts         int                     `env:"MAX_ATTEMPTS" yaml:"max_attempts"`
+	MinBackoff          time.Duration           `env:"MIN_BACKOFF" yaml:"min_backoff"`
+	MaxBackoff          time.Duration           `env:"MAX_BACKOFF" yaml:"max_backoff"`
+	Timeout             time.Duration           `env:"TIMEOUT" yaml:"timeout"`
+	PeerPollInterval    time.Duration           `env:"PEER_POLL_INTERVAL" yaml:"peer_poll_interval"`
+	HandshakeStaleAfter time.Duration           `env:"HANDSHAKE_STALE_AFTER" yaml:"handshake_stale_after"`
+	Endpoints           []WebhookEndpointConfig `yaml:"endpoints"`
+}
+
+func (cfg *WebhooksConfig) Default() {
+	if cfg.OutboxPath == "" {
+		cfg.OutboxPath = "/var/lib/wg-wish/outbox.db"
+	}
+
+	if cfg.MaxAttempts == 0 {
+		cfg.MaxAttempts = 10
+	}
+
+	if cfg.MinBackoff == 0 {
+		cfg.MinBackoff = 5 * time.Second
+	}
+
+	if cfg.MaxBackoff == 0 {
+		cfg.MaxBackoff = time.Hour
+	}
+
+	if cfg.Timeout == 0 {
+		cfg.Timeout = 10 * time.Second
+	}
+
+	if cfg.PeerPollInterval == 0 {
+		cfg.PeerPollInterval = 30 * time.Second
+	}
+
+	if cfg.HandshakeStaleAfter == 0 {
+		cfg.HandshakeStaleAfter = entity.PeerOnlineTimeout
+	}
+}
+
+func (cfg *WebhooksConfig) Validate() error {
+	enabled := len(cfg.Endpoints) != 0
+	return validation.All(
+		validation.String(cfg.OutboxPath, "outbox_path").If(enabled).Required(true).EndIf(),
+		validation.Number(cfg.MaxAttempts, "max_attempts").If(enabled).GreaterEqual(1).EndIf(),
+		validation.Number(cfg.MinBackoff, "min_backoff").If(enabled).Greater(0).EndIf(),
+		validation.Number(cfg.MaxBackoff, "max_backoff").If(enabled).GreaterEqual(cfg.MinBackoff).EndIf(),
+		validation.Number(cfg.Timeout, "timeout").If(enabled).Greater(0).EndIf(),
+		validation.Number(cfg.PeerPollInterval, "peer_poll_interval").If(enabled).Greater(0).EndIf(),
+		validation.Number(cfg.HandshakeStaleAfter, "handshake_stale_after").If(enabled).Greater(0).EndIf(),
+		validation.Slice(cfg.Endpoints, "endpoints").ValuesPtrWith(validation.Custom),
+	)
+}
+
+type WebhookEndpointConfig struct {
+	URL    string   `yaml:"url"`
+	Secret string   `yaml:"secret"`
+	Events []string `yaml:"events"`
+}
+
+func (cfg *WebhookEndpointConfig) Validate() error {
+	return validation.All(
+		validation.String(cfg.URL, "url").Required(true).With(isstr.URL),
+		validation.Slice(cfg.Events, "events").ValuesWith(isEventPattern),
+	)
+}
+
+func isEventPattern(pattern string) error {
+	_, err := path.Match(pattern, "")
+	return err
+}
+
+type StatsConfig struct {
+	Enabled        bool          `env:"ENABLED" yaml:"enabled"`
+	Path           string        `env:"PATH" yaml:"path"`
+	SampleInterval time.Duration `env:"SAMPLE_INTERVAL" yaml:"sample_interval"`
+}
+
+func (cfg *StatsConfig) Default() {
+	if cfg.Path == "" {
+		cfg.Path = "/var/lib/wg-wish/stats.db"
+	}
+
+	if cfg.SampleInterval == 0 {
+		cfg.SampleInterval = time.Minute
+	}
+}
+
+func (cfg *StatsConfig) Validate() error {
+	return validation.All(
+		validation.String(cfg.Path, "path").If(cfg.Enabled).Required(true).EndIf(),
+		validation.Number(cfg.SampleInterval, "sample_interval").If(cfg.Enabled).Greater(0).EndIf(),
+	)
+}
+
+type FederationConfig struct {
+	Enabled bool   `env:"ENABLED" yaml:"enabled"`
+	Name    string `env:"NAME" yaml:"name"`
+	Port    int    `env:"PORT" yaml:"port"`
+	// Timeout limits requests to the linked servers.
+	Timeout time.Duration            `env:"TIMEOUT" yaml:"timeout"`
+	TLS     FederationTLSConfig      `env-prefix:"TLS_" yaml:"tls"`
+	Servers []FederationServerConfig `yaml:"servers"`
+}
+
+func (cfg *FederationConfig) Default() {
+	if cfg.Port == 0 {
+		cfg.Port = 51824
+	}
+
+	if cfg.Timeout == 0 {
+		cfg.Timeout = 10 * time.Second
+	}
+}
+
+func (cfg *FederationConfig) Validate() error {
+	return validation.All(
+		validation.String(cfg.Name, "name").If(cfg.Enabled).Required(true).EndIf(),
+		validation.Number(cfg.Port, "port").If(cfg.Enabled).Required(true).With(isint.Port).EndIf(),
+		validation.Number(cfg.Timeout, "timeout").If(cfg.Enabled).Greater(0).EndIf(),
+		validation.Ptr(&cfg.TLS, "tls").With(validation.Custom),
+		validation.Slice(cfg.Servers, "servers").ValuesPtrWith(validation.Custom),
+		validation.Slice(cfg.Servers, "servers").With(func(servers []FederationServerConfig) error {
+			for i := range servers {
+				if servers[i].Name == cfg.Name {
+					return fmt.Errorf("server %s has the name of the local server", servers[i].Name)
+				}
+				for j := range i {
+					if servers[i].Name == servers[j].Name {
+						return fmt.Errorf("server %s is defined more than once", servers[i].Name)
+					}
+				}
+			}
+			return nil
+		}),
+	)
+}
+
+type FederationTLSConfig struct {
+	CertFile string `env:"CERT_FILE" yaml:"cert_file"`
+	KeyFile  string `env:"KEY_FILE" yaml:"key_file"`
+}
+
+func (cfg *FederationTLSConfig) Validate() error {
+	return validation.All(
+		validation.String(cfg.CertFile, "cert_file").Required(cfg.KeyFile != ""),
+		validation.String(cfg.KeyFile, "key_file").Required(cfg.CertFile != ""),
+	)
+}
+
+// FederationServerConfig describes a linked server.
+// Both servers must list each other with the same secret.
+type FederationServerConfig struct {
+	Name   string `yaml:"name"`
+	URL    string `yaml:"url"`
+	Secret string `yaml:"secret"`
+	// CAFile verifies the server's certificate, the system pool is used if empty.
+	CAFile string `yaml:"ca_file"`
+}
+
+func (cfg *FederationServerConfig) Validate() error {
+	return validation.All(
+		validation.String(cfg.Name, "name").Required(true),
+		validation.String(cfg.URL, "url").Required(true).With(isstr.URL),
+		validation.String(cfg.Secret, "secret").Required(true),
+	)
+}
+
+const (
+	ClusterRolePrimary = "primary"
+	ClusterRoleStandby = "standby"
+)
+
+type ClusterConfig struct {
+	// Role is empty if replication is disabled.
+	Role string `env:"ROLE" yaml:"role"`
+	// Name identifies the standby to the primary, the hostname by default.
+	Name string `env:"NAME" yaml:"name"`
+	// Port is served by the primary, including a promoted standby.
+	Port   int    `env:"PORT" yaml:"port"`
+	Secret string `env:"SECRET" yaml:"secret"`
+	// PrimaryURL is the URL of the primary's replication endpoint the standby replicates from.
+	PrimaryURL string           `env:"PRIMARY_URL" yaml:"primary_url"`
+	Interval   time.Duration    `env:"INTERVAL" yaml:"interval"`
+	Timeout    time.Duration    `env:"TIMEOUT" yaml:"timeout"`
+	TLS        ClusterTLSConfig `env-prefix:"TLS_" yaml:"tls"`
+	// Insecure allows replicating without TLS, e.g. between instances on loopback.
+	Insecure bool `env:"INSECURE" yaml:"insecure"`
+}
+
+func (cfg *ClusterConfig) Default() {
+	if cfg.Name == "" {
+		cfg.Name, _ = os.Hostname()
+	}
+
+	if cfg.Port == 0 {
+		cfg.Port = 51825
+	}
+
+	if cfg.Interval == 0 {
+		cfg.Interval = 5 * time.Second
+	}
+
+	if cfg.Timeout == 0 {
+		cfg.Timeout = 10 * time.Second
+	}
+}
+
+func (cfg *ClusterConfig) Validate() error {
+	enabled := cfg.Role != ""
+	standby := cfg.Role == ClusterRoleStandby
+	return validation.All(
+		validation.Comparable(cfg.Role, "role").If(enabled).In(ClusterRolePrimary, ClusterRoleStandby).EndIf(),
+		validation.String(cfg.Name, "name").If(standby).Required(true).EndIf(),
+		validation.Number(cfg.Port, "port").If(enabled).Required(true).With(isint.Port).EndIf(),
+		validation.String(cfg.Secret, "secret").If(enabled).Required(true).EndIf(),
+		validation.String(cfg.PrimaryURL, "primary_url").If(standby).Required(true).With(isstr.URL).EndIf(),
+		validation.Number(cfg.Interval, "interval").If(standby).Greater(0).EndIf(),
+		validation.Number(cfg.Timeout, "timeout").If(standby).Greater(0).EndIf(),
+		validation.Ptr(&cfg.TLS, "tls").With(validation.Custom),
+	)
+}
+
+type ClusterTLSConfig struct {
+	CertFile string `env:"CERT_FILE" yaml:"cert_file"`
+	KeyFile  string `env:"KEY_FILE" yaml:"key_file"`
+	// CAFile verifies the primary's certificate on the standby, the system pool is used if empty.
+	CAFile string `env:"CA_FILE" yaml:"ca_file"`
+}
+
+func (cfg *ClusterTLSConfig) Validate() error {
+	return validation.All(
+		validation.String(cfg.CertFile, "cert_file").Required(cfg.KeyFile != ""),
+		validation.String(cfg.KeyFile, "key_file").Required(cfg.CertFile != ""),
+	)
+}
+
 func NewConfig(configPath string) (cfg Config, err error) {
 	if configPath != "" {
 		err = cleanenv.ReadConfig(configPath, &cfg)
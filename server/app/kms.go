@@ -0,0 +1,27 @@
+package app
+
+import (
+	"github.com/infastin/wg-wish/pkg/envelope"
+)
+
+// NewKMS returns KMS for the configured master key,
+// or nil if database encryption is disabled.
+func NewKMS(cfg *DatabaseEncryptionConfig) (envelope.KMS, error) {
+	switch {
+	case cfg.Key != "":
+		key, err := envelope.ParseKey(cfg.Key)
+		if err != nil {
+			return nil, err
+		}
+		return envelope.NewSingleKeyring(key)
+	case cfg.KeyFile != "":
+		key, err := envelope.ReadKeyFile(cfg.KeyFile)
+		if err != nil {
+			return nil, err
+		}
+		return envelope.NewSingleKeyring(key)
+	case cfg.KMSFile != "":
+		return envelope.ReadKMSFile(cfg.KMSFile)
+	}
+	return nil, nil
+}
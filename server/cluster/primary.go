@@ -0,0 +1,240 @@
+package cluster
+
+import (
+	"context"
+	"crypto/subtle"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/guregu/null/v5"
+	"github.com/infastin/wg-wish/server/entity"
+	"github.com/infastin/wg-wish/server/errors"
+	"github.com/rs/zerolog"
+)
+
+// Database is the database replicated to the standbys.
+type Database interface {
+	// Snapshot writes a consistent copy of the database to the writer.
+	Snapshot(ctx context.Context, w io.Writer) (txID uint64, err error)
+	// TxID returns the ID of the last committed transaction.
+	TxID(ctx context.Context) (txID uint64, err error)
+}
+
+type PrimaryParams struct {
+	Logger   zerolog.Logger
+	Port     int
+	Secret   string
+	CertFile string
+	KeyFile  string
+	// Insecure allows serving snapshots without TLS, which is required otherwise,
+	// since snapshots contain private keys.
+	Insecure bool
+	Database Database
+}
+
+// Primary serves snapshots of the database to the standbys
+// and keeps track of how far behind each of them is.
+type Primary struct {
+	lg     zerolog.Logger
+	server *http.Server
+	db     Database
+	secret []byte
+	now    func() time.Time
+
+	mu       sync.Mutex
+	standbys map[string]*entity.ClusterStandby
+}
+
+func NewPrimary(params *PrimaryParams) (p *Primary, err error) {
+	if params.CertFile == "" && !params.Insecure {
+		return nil, fmt.Errorf("cluster: snapshots contain private keys, so TLS is required unless insecure is set")
+	}
+
+	p = &Primary{
+		lg:       params.Logger,
+		server:   nil,
+		db:       params.Database,
+		secret:   []byte(params.Secret),
+		now:      time.Now,
+		mu:       sync.Mutex{},
+		standbys: make(map[string]*entity.ClusterStandby),
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("GET /cluster/v1/status", p.wrap(p.getStatus))
+	mux.Handle("GET /cluster/v1/snapshot", p.wrap(p.getSnapshot))
+
+	p.server = &http.Server{ //nolint:exhaustruct
+		Addr:              "0.0.0.0:" + strconv.Itoa(params.Port),
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	if params.CertFile != "" {
+		cert, err := tls.LoadX509KeyPair(params.CertFile, params.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("cluster: failed to load certificate: %w", err)
+		}
+
+		p.server.TLSConfig = &tls.Config{ //nolint:exhaustruct
+			MinVersion:   tls.VersionTLS12,
+			Certificates: []tls.Certificate{cert},
+		}
+	}
+
+	return p, nil
+}
+
+func (p *Primary) Run() error {
+	var err error
+	if p.server.TLSConfig != nil {
+		err = p.server.ListenAndServeTLS("", "")
+	} else {
+		err = p.server.ListenAndServe()
+	}
+	if err != nil {
+		return fmt.Errorf("cluster: failed to serve: %w", err)
+	}
+	return nil
+}
+
+func (p *Primary) Shutdown(ctx context.Context) error {
+	if err := p.server.Shutdown(ctx); err != nil {
+		return fmt.Errorf("cluster: failed to shutdown server: %w", err)
+	}
+	return nil
+}
+
+func (p *Primary) GetStatus(ctx context.Context) (status entity.ClusterStatus, err error) {
+	txID, err := p.db.TxID(ctx)
+	if err != nil {
+		return entity.ClusterStatus{}, err
+	}
+
+	p.mu.Lock()
+	standbys := make([]entity.ClusterStandby, 0, len(p.standbys))
+	for _, standby := range p.standbys {
+		standbys = append(standbys, *standby)
+	}
+	p.mu.Unlock()
+
+	slices.SortFunc(standbys, func(a, b entity.ClusterStandby) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+
+	return entity.ClusterStatus{
+		Role:        entity.ClusterRolePrimary,
+		TxID:        txID,
+		Standbys:    standbys,
+		Replication: null.Value[entity.ClusterReplication]{},
+	}, nil
+}
+
+func (*Primary) Promote(context.Context) (err error) {
+	return errors.ErrClusterNotStandby
+}
+
+// wrap authenticates the request with the shared secret
+// and logs it along with the error returned by the handler.
+func (p *Primary) wrap(fn func(w http.ResponseWriter, r *http.Request) error) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ct := time.Now()
+
+		var err error
+		if p.authenticate(r) {
+			err = fn(w, r)
+		} else {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="wg-wish"`)
+			writeJSON(w, http.StatusUnauthorized, &Error{Error: "unauthorized"})
+		}
+
+		lg := p.lg.With().
+			Str("method", r.Method).
+			Str("path", r.URL.Path).
+			Str("addr", r.RemoteAddr).
+			Str("standby", r.Header.Get(HeaderStandby)).
+			Dur("elapsed", time.Since(ct)).
+			Logger()
+
+		if err != nil {
+			lg.Err(err).Msg("request error")
+			return
+		}
+
+		lg.Debug().Msg("request ok")
+	})
+}
+
+func (p *Primary) authenticate(r *http.Request) bool {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(token), p.secret) == 1
+}
+
+func (p *Primary) getStatus(w http.ResponseWriter, r *http.Request) (err error) {
+	name := r.Header.Get(HeaderStandby)
+	if name == "" {
+		writeJSON(w, http.StatusBadRequest, &Error{Error: "missing " + HeaderStandby + " header"})
+		return nil
+	}
+
+	standbyTxID, err := strconv.ParseUint(r.Header.Get(HeaderTxID), 10, 64)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, &Error{Error: "invalid " + HeaderTxID + " header"})
+		return nil
+	}
+
+	txID, err := p.db.TxID(r.Context())
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, &Error{Error: "internal error"})
+		return err
+	}
+
+	now := p.now()
+
+	p.mu.Lock()
+	standby, ok := p.standbys[name]
+	if !ok {
+		standby = &entity.ClusterStandby{Name: name} //nolint:exhaustruct
+		p.standbys[name] = standby
+	}
+	standby.Address = r.RemoteAddr
+	standby.TxID = standbyTxID
+	standby.LastSeen = now
+	if standbyTxID == txID {
+		standby.SyncedAt = null.TimeFrom(now)
+	}
+	p.mu.Unlock()
+
+	writeJSON(w, http.StatusOK, &Status{TxID: txID})
+	return nil
+}
+
+func (p *Primary) getSnapshot(w http.ResponseWriter, r *http.Request) (err error) {
+	w.Header().Set("Content-Type", "application/octet-stream")
+
+	if _, err := p.db.Snapshot(r.Context(), w); err != nil {
+		p.lg.Err(err).Str("addr", r.RemoteAddr).Msg("failed to stream snapshot")
+		// The response has already started, aborting it
+		// is the only way to let the standby know the snapshot is incomplete.
+		panic(http.ErrAbortHandler)
+	}
+
+	return nil
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
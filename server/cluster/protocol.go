@@ -0,0 +1,45 @@
+package cluster
+
+import (
+	"os"
+)
+
+// Headers of the requests the standby sends to the primary,
+// which must also carry the shared secret as a bearer token.
+const (
+	// HeaderStandby is the name of the standby.
+	HeaderStandby = "X-Wg-Wish-Standby"
+	// HeaderTxID is the ID of the last transaction the standby has.
+	HeaderTxID = "X-Wg-Wish-Tx"
+)
+
+// Status is the response of the primary to the status request.
+type Status struct {
+	// TxID is the ID of the last transaction committed on the primary.
+	TxID uint64 `json:"tx_id"`
+}
+
+// Error is the response body of failed requests.
+type Error struct {
+	Error string `json:"error"`
+}
+
+// PromotedPath returns the path of the marker left next to the database
+// once the standby has been promoted.
+func PromotedPath(path string) string {
+	return path + ".promoted"
+}
+
+// IsPromoted reports whether the standby using the database at the path has been promoted,
+// in which case it must run as the primary from then on.
+func IsPromoted(path string) (bool, error) {
+	_, err := os.Stat(PromotedPath(path))
+	switch {
+	case err == nil:
+		return true, nil
+	case os.IsNotExist(err):
+		return false, nil
+	default:
+		return false, err
+	}
+}
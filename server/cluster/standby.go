@@ -0,0 +1,378 @@
+package cluster
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/guregu/null/v5"
+	"github.com/infastin/wg-wish/server/entity"
+	"github.com/rs/zerolog"
+	"go.etcd.io/bbolt"
+)
+
+type StandbyParams struct {
+	Logger zerolog.Logger
+	// Name identifies the standby to the primary.
+	Name string
+	// PrimaryURL is the base URL of the primary's replication endpoint.
+	PrimaryURL string
+	Secret     string
+	// CAFile is the certificate authority the primary's certificate is verified with,
+	// the system pool is used if empty.
+	CAFile string
+	// Insecure allows replicating from a PrimaryURL without https.
+	Insecure bool
+	// Path is the path of the database replicated into.
+	Path     string
+	Interval time.Duration
+	Timeout  time.Duration
+}
+
+// Standby keeps a copy of the primary's database up to date
+// by downloading a snapshot of it whenever it has changed.
+type Standby struct {
+	lg       zerolog.Logger
+	name     string
+	url      string
+	secret   string
+	path     string
+	interval time.Duration
+	client   *http.Client
+
+	// syncMu is held while replicating, so that promotion waits for the snapshot being applied.
+	syncMu   sync.Mutex
+	promoted chan struct{}
+
+	mu          sync.Mutex
+	txID        uint64
+	replication entity.ClusterReplication
+}
+
+func NewStandby(params *StandbyParams) (s *Standby, err error) {
+	if !strings.HasPrefix(params.PrimaryURL, "https://") && !params.Insecure {
+		return nil, fmt.Errorf("cluster: snapshots contain private keys, so primary url %q must use https unless insecure is set",
+			params.PrimaryURL)
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+
+	if params.CAFile != "" {
+		b, err := os.ReadFile(params.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("cluster: failed to read CA: %w", err)
+		}
+
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(b) {
+			return nil, fmt.Errorf("cluster: no certificates found in %q", params.CAFile)
+		}
+
+		transport.TLSClientConfig = &tls.Config{ //nolint:exhaustruct
+			MinVersion: tls.VersionTLS12,
+			RootCAs:    pool,
+		}
+	}
+
+	txID, err := readTxID(params.Path)
+	switch {
+	case err == nil:
+	case os.IsNotExist(err):
+		txID = 0
+	default:
+		return nil, fmt.Errorf("cluster: failed to read database: %w", err)
+	}
+
+	url := strings.TrimSuffix(params.PrimaryURL, "/")
+
+	return &Standby{
+		lg:       params.Logger,
+		name:     params.Name,
+		url:      url,
+		secret:   params.Secret,
+		path:     params.Path,
+		interval: params.Interval,
+		client: &http.Client{ //nolint:exhaustruct
+			Transport: transport,
+			Timeout:   params.Timeout,
+		},
+		syncMu:   sync.Mutex{},
+		promoted: make(chan struct{}),
+		mu:       sync.Mutex{},
+		txID:     txID,
+		replication: entity.ClusterReplication{
+			PrimaryURL:  url,
+			PrimaryTxID: 0,
+			SyncedAt:    null.Time{},
+			Error:       "",
+		},
+	}, nil
+}
+
+// Run replicates the database until the context is canceled or the standby is promoted.
+func (s *Standby) Run(ctx context.Context) error {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		s.replicate(ctx)
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-s.promoted:
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// Promoted returns a channel that is closed once the standby has been promoted.
+func (s *Standby) Promoted() <-chan struct{} {
+	return s.promoted
+}
+
+func (s *Standby) GetStatus(context.Context) (status entity.ClusterStatus, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return entity.ClusterStatus{
+		Role:        entity.ClusterRoleStandby,
+		TxID:        s.txID,
+		Standbys:    nil,
+		Replication: null.ValueFrom(s.replication),
+	}, nil
+}
+
+// Promote stops replication and leaves a marker next to the database,
+// so that the server runs as the primary from then on, even after a restart.
+func (s *Standby) Promote(context.Context) (err error) {
+	s.syncMu.Lock()
+	defer s.syncMu.Unlock()
+
+	select {
+	case <-s.promoted:
+		return nil
+	default:
+	}
+
+	if _, err := os.Stat(s.path); err != nil {
+		return fmt.Errorf("cluster: nothing has been replicated: %w", err)
+	}
+
+	if err := os.WriteFile(PromotedPath(s.path), nil, 0600); err != nil {
+		return fmt.Errorf("cluster: failed to mark standby as promoted: %w", err)
+	}
+
+	close(s.promoted)
+	s.lg.Warn().Msg("standby promoted")
+
+	return nil
+}
+
+func (s *Standby) replicate(ctx context.Context) {
+	s.syncMu.Lock()
+	defer s.syncMu.Unlock()
+
+	select {
+	case <-s.promoted:
+		return
+	default:
+	}
+
+	startedAt := time.Now()
+
+	s.mu.Lock()
+	txID := s.txID
+	s.mu.Unlock()
+
+	primaryTxID, err := s.fetchStatus(ctx, txID)
+	if err == nil && primaryTxID != txID {
+		txID, err = s.fetchSnapshot(ctx)
+		if err == nil {
+			s.lg.Info().
+				Uint64("tx_id", txID).
+				Dur("elapsed", time.Since(startedAt)).
+				Msg("applied snapshot")
+		}
+	}
+
+	if ctx.Err() != nil {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err != nil {
+		s.lg.Err(err).Msg("failed to replicate")
+		s.replication.Error = err.Error()
+		return
+	}
+
+	s.txID = txID
+	s.replication.PrimaryTxID = primaryTxID
+	s.replication.Error = ""
+	// Transactions committed after the status was fetched may be in the snapshot,
+	// but the standby is only known to have everything committed before.
+	if txID >= primaryTxID {
+		s.replication.SyncedAt = null.TimeFrom(startedAt)
+	}
+}
+
+func (s *Standby) newRequest(ctx context.Context, path string) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.url+path, http.NoBody)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+s.secret)
+	req.Header.Set(HeaderStandby, s.name)
+	return req, nil
+}
+
+// fetchStatus reports the last transaction the standby has to the primary
+// and returns the last transaction of the primary.
+func (s *Standby) fetchStatus(ctx context.Context, txID uint64) (primaryTxID uint64, err error) {
+	req, err := s.newRequest(ctx, "/cluster/v1/status")
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set(HeaderTxID, strconv.FormatUint(txID, 10))
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if err := checkResponse(resp); err != nil {
+		return 0, err
+	}
+
+	var status Status
+	if err := json.NewDecoder(resp.Body).Decode(&status); err != nil {
+		return 0, fmt.Errorf("invalid response: %w", err)
+	}
+
+	return status.TxID, nil
+}
+
+// fetchSnapshot downloads the snapshot next to the database, verifies it
+// and replaces the database with it, returning the ID of its last transaction.
+func (s *Standby) fetchSnapshot(ctx context.Context) (txID uint64, err error) {
+	req, err := s.newRequest(ctx, "/cluster/v1/snapshot")
+	if err != nil {
+		return 0, err
+	}
+
+	// The timeout is meant for the status requests, the snapshot may take longer to download.
+	client := *s.client
+	client.Timeout = 0
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if err := checkResponse(resp); err != nil {
+		return 0, err
+	}
+
+	tmpPath := s.path + ".replica"
+	defer os.Remove(tmpPath)
+
+	if err := writeFile(tmpPath, resp.Body); err != nil {
+		return 0, fmt.Errorf("failed to download snapshot: %w", err)
+	}
+
+	txID, err = readTxID(tmpPath)
+	if err != nil {
+		return 0, fmt.Errorf("invalid snapshot: %w", err)
+	}
+
+	if err := os.Rename(tmpPath, s.path); err != nil {
+		return 0, fmt.Errorf("failed to replace database: %w", err)
+	}
+
+	if dir, err := os.Open(filepath.Dir(s.path)); err == nil {
+		_ = dir.Sync()
+		_ = dir.Close()
+	}
+
+	return txID, nil
+}
+
+func checkResponse(resp *http.Response) error {
+	if resp.StatusCode == http.StatusOK {
+		return nil
+	}
+
+	var e Error
+	if err := json.NewDecoder(resp.Body).Decode(&e); err != nil || e.Error == "" {
+		return fmt.Errorf("primary responded with %s", resp.Status)
+	}
+
+	return fmt.Errorf("primary responded with %s: %s", resp.Status, e.Error)
+}
+
+func writeFile(path string, r io.Reader) (err error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.Copy(f, r); err != nil {
+		_ = f.Close()
+		return err
+	}
+
+	if err := f.Sync(); err != nil {
+		_ = f.Close()
+		return err
+	}
+
+	return f.Close()
+}
+
+// readTxID checks the consistency of the database at the path
+// and returns the ID of its last transaction.
+func readTxID(path string) (txID uint64, err error) {
+	if _, err := os.Stat(path); err != nil {
+		return 0, err
+	}
+
+	db, err := bbolt.Open(path, 0600, &bbolt.Options{ //nolint:exhaustruct
+		ReadOnly: true,
+		Timeout:  time.Second,
+	})
+	if err != nil {
+		return 0, err
+	}
+	defer db.Close()
+
+	err = db.View(func(tx *bbolt.Tx) error {
+		txID = uint64(tx.ID())
+
+		var checkErr error
+		for err := range tx.Check() {
+			if checkErr == nil {
+				checkErr = err
+			}
+		}
+
+		return checkErr
+	})
+
+	return txID, err
+}
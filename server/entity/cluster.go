@@ -0,0 +1,63 @@
+package entity
+
+import (
+	"time"
+
+	"github.com/guregu/null/v5"
+)
+
+type ClusterRole string
+
+const (
+	ClusterRolePrimary ClusterRole = "primary"
+	ClusterRoleStandby ClusterRole = "standby"
+)
+
+// ClusterStatus describes the replication state as seen by the local server.
+type ClusterStatus struct {
+	Role ClusterRole
+	// TxID is the ID of the last transaction in the local database.
+	TxID uint64
+	// Standbys are the standbys that have contacted the primary, only set on the primary.
+	Standbys []ClusterStandby
+	// Replication is only set on a standby.
+	Replication null.Value[ClusterReplication]
+}
+
+// ClusterStandby is a standby as seen by the primary.
+type ClusterStandby struct {
+	Name    string
+	Address string
+	// TxID is the ID of the last transaction the standby has.
+	TxID     uint64
+	LastSeen time.Time
+	// SyncedAt is the last time the standby had every transaction of the primary.
+	SyncedAt null.Time
+}
+
+// Lag returns how long the standby may have been missing transactions of the primary.
+func (s *ClusterStandby) Lag(now time.Time) null.Value[time.Duration] {
+	if !s.SyncedAt.Valid {
+		return null.Value[time.Duration]{}
+	}
+	return null.ValueFrom(now.Sub(s.SyncedAt.Time))
+}
+
+// ClusterReplication is the state of replication from the primary to the standby.
+type ClusterReplication struct {
+	PrimaryURL string
+	// PrimaryTxID is the ID of the last transaction the primary reported.
+	PrimaryTxID uint64
+	// SyncedAt is the last time the standby had every transaction of the primary.
+	SyncedAt null.Time
+	// Error describes why the last attempt to replicate failed, empty if it succeeded.
+	Error string
+}
+
+// Lag returns how long the standby may have been missing transactions of the primary.
+func (r *ClusterReplication) Lag(now time.Time) null.Value[time.Duration] {
+	if !r.SyncedAt.Valid {
+		return null.Value[time.Duration]{}
+	}
+	return null.ValueFrom(now.Sub(r.SyncedAt.Time))
+}
@@ -0,0 +1,49 @@
+package entity
+
+import (
+	"net"
+
+	"github.com/guregu/null/v5"
+	"github.com/infastin/wg-wish/pkg/wgtypes"
+)
+
+// FederationServer is a wg-wish server linked with the local one.
+type FederationServer struct {
+	Name string
+	// URL is empty for the local server.
+	URL   string
+	Local bool
+	// Interfaces are the WireGuard interfaces served by the server,
+	// the primary one first. Empty if the server can't be reached.
+	Interfaces []string
+	// Clients is the number of clients on the primary interface.
+	Clients int
+	// Error describes why the server can't be reached, empty if it can.
+	Error string
+}
+
+// FederatedClient is a client of one of the linked servers.
+type FederatedClient struct {
+	Server          string
+	Name            string
+	Address         net.IPNet
+	PublicKey       wgtypes.Key
+	Disabled        bool
+	LatestHandshake null.Time
+}
+
+// FederationResult is the outcome of an operation on one of the servers.
+type FederationResult struct {
+	Server string
+	// Error describes why the operation failed, empty on success.
+	Error string
+}
+
+// FederatedClientSync is the outcome of syncing a client identity to a server.
+type FederatedClientSync struct {
+	FederationResult
+	// Address is the address the client was given on the server.
+	Address net.IPNet
+	// Created is false if the server already had the client.
+	Created bool
+}
@@ -0,0 +1,166 @@
+package entity
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/infastin/wg-wish/pkg/netutils"
+)
+
+type FirewallProtocol string
+
+const (
+	FirewallProtocolAny  FirewallProtocol = ""
+	FirewallProtocolTCP  FirewallProtocol = "tcp"
+	FirewallProtocolUDP  FirewallProtocol = "udp"
+	FirewallProtocolICMP FirewallProtocol = "icmp"
+)
+
+// FirewallRule allows traffic to the destination network,
+// optionally restricted to a protocol and a range of ports.
+type FirewallRule struct {
+	Destination net.IPNet
+	Protocol    FirewallProtocol
+	// Ports are only meaningful for TCP and UDP. Zero means any port.
+	PortFrom uint16
+	PortTo   uint16
+}
+
+// ErrFirewallRuleNotIPv4 is returned by ParseFirewallRule for IPv6 destinations.
+var ErrFirewallRuleNotIPv4 = errors.New("only IPv4 destinations are supported")
+
+// ParseFirewallRule parses a rule in the [PROTO:]CIDR[:PORT[-PORT]] format,
+// e.g. "10.0.5.0/24:443", "udp:10.0.0.53:53" or "icmp:10.0.0.0/8".
+// Rules with ports and without protocol match TCP.
+func ParseFirewallRule(s string) (rule FirewallRule, err error) {
+	rest := s
+	if proto, after, ok := strings.Cut(s, ":"); ok {
+		switch FirewallProtocol(proto) {
+		case FirewallProtocolTCP, FirewallProtocolUDP, FirewallProtocolICMP:
+			rule.Protocol = FirewallProtocol(proto)
+			rest = after
+		}
+	}
+
+	// An IPv4 destination is followed by at most one colon separating the ports.
+	if strings.Count(rest, ":") > 1 || strings.HasPrefix(rest, "[") {
+		return FirewallRule{}, fmt.Errorf("invalid firewall rule %q: %w", s, ErrFirewallRuleNotIPv4)
+	}
+
+	destination, ports, hasPorts := strings.Cut(rest, ":")
+	if !strings.Contains(destination, "/") {
+		destination += "/32"
+	}
+
+	rule.Destination, err = netutils.ParseAddress(destination)
+	if err != nil {
+		return FirewallRule{}, fmt.Errorf("invalid firewall rule %q: %w", s, err)
+	}
+
+	if rule.Destination.IP.To4() == nil {
+		return FirewallRule{}, fmt.Errorf("invalid firewall rule %q: %w", s, ErrFirewallRuleNotIPv4)
+	}
+
+	rule.Destination.IP = rule.Destination.IP.Mask(rule.Destination.Mask)
+
+	if !hasPorts {
+		return rule, nil
+	}
+
+	switch rule.Protocol {
+	case FirewallProtocolAny:
+		rule.Protocol = FirewallProtocolTCP
+	case FirewallProtocolICMP:
+		return FirewallRule{}, fmt.Errorf("invalid firewall rule %q: icmp has no ports", s)
+	}
+
+	from, to, ok := strings.Cut(ports, "-")
+	if !ok {
+		to = from
+	}
+
+	portFrom, err := strconv.ParseUint(from, 10, 16)
+	if err != nil || portFrom == 0 {
+		return FirewallRule{}, fmt.Errorf("invalid firewall rule %q: invalid port %q", s, from)
+	}
+
+	portTo, err := strconv.ParseUint(to, 10, 16)
+	if err != nil || portTo < portFrom {
+		return FirewallRule{}, fmt.Errorf("invalid firewall rule %q: invalid port %q", s, to)
+	}
+
+	rule.PortFrom, rule.PortTo = uint16(portFrom), uint16(portTo)
+
+	return rule, nil
+}
+
+func (r *FirewallRule) String() string {
+	var b strings.Builder
+
+	if r.Protocol != FirewallProtocolAny && (r.Protocol != FirewallProtocolTCP || r.PortFrom == 0) {
+		b.WriteString(string(r.Protocol))
+		b.WriteByte(':')
+	}
+
+	b.WriteString(r.Destination.String())
+
+	if r.PortFrom != 0 {
+		b.WriteByte(':')
+		b.WriteString(strconv.FormatUint(uint64(r.PortFrom), 10))
+		if r.PortTo != r.PortFrom {
+			b.WriteByte('-')
+			b.WriteString(strconv.FormatUint(uint64(r.PortTo), 10))
+		}
+	}
+
+	return b.String()
+}
+
+// FirewallGroup is a set of rules applied to the peers assigned to it.
+// Traffic of the group's members not allowed by the rules is dropped.
+type FirewallGroup struct {
+	Name string
+	// AllowPeers allows the members to reach other peers inside the tunnel.
+	AllowPeers bool
+	Rules      []FirewallRule
+}
+
+// FirewallPolicy is the set of rules applied to a single peer.
+type FirewallPolicy struct {
+	Name string
+	// Address must be an IPv4 address, as the rules only match IPv4 traffic.
+	Address    net.IPNet
+	AllowPeers bool
+	Rules      []FirewallRule
+}
+
+// FirewallInterface is a WireGuard interface along with the policies of its peers.
+type FirewallInterface struct {
+	// Name is the WireGuard interface, e.g. wg0.
+	Name string
+	// Port is the WireGuard listen port.
+	Port int
+	// Subnet is the tunnel subnet.
+	Subnet   net.IPNet
+	Policies []FirewallPolicy
+}
+
+// FirewallRuleset is what gets rendered into the firewall.
+// Traffic between different interfaces is dropped.
+type FirewallRuleset struct {
+	// Device is the interface peers' traffic is forwarded and masqueraded to.
+	Device     string
+	Interfaces []FirewallInterface
+}
+
+// FirewallState describes the rules programmed by the firewall backend.
+type FirewallState struct {
+	Backend string
+	// Applied is the listing of the rules captured right after they were last applied.
+	Applied string
+	// Current is the listing of the rules as they are now.
+	Current string
+}
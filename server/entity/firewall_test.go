@@ -0,0 +1,122 @@
+package entity
+
+import (
+	"errors"
+	"net"
+	"testing"
+)
+
+func TestParseFirewallRule(t *testing.T) {
+	tests := []struct {
+		in   string
+		want FirewallRule
+	}{
+		{
+			in:   "10.0.5.0/24",
+			want: FirewallRule{Destination: mustParseCIDR(t, "10.0.5.0/24"), Protocol: FirewallProtocolAny, PortFrom: 0, PortTo: 0},
+		},
+		{
+			// Host bits are cleared.
+			in:   "10.0.5.7/24",
+			want: FirewallRule{Destination: mustParseCIDR(t, "10.0.5.0/24"), Protocol: FirewallProtocolAny, PortFrom: 0, PortTo: 0},
+		},
+		{
+			// A bare address is a single host.
+			in:   "10.0.0.53",
+			want: FirewallRule{Destination: mustParseCIDR(t, "10.0.0.53/32"), Protocol: FirewallProtocolAny, PortFrom: 0, PortTo: 0},
+		},
+		{
+			// Ports without a protocol match TCP.
+			in:   "10.0.5.0/24:443",
+			want: FirewallRule{Destination: mustParseCIDR(t, "10.0.5.0/24"), Protocol: FirewallProtocolTCP, PortFrom: 443, PortTo: 443},
+		},
+		{
+			in:   "udp:10.0.0.53:53",
+			want: FirewallRule{Destination: mustParseCIDR(t, "10.0.0.53/32"), Protocol: FirewallProtocolUDP, PortFrom: 53, PortTo: 53},
+		},
+		{
+			in:   "tcp:10.0.5.0/24:8000-8080",
+			want: FirewallRule{Destination: mustParseCIDR(t, "10.0.5.0/24"), Protocol: FirewallProtocolTCP, PortFrom: 8000, PortTo: 8080},
+		},
+		{
+			in:   "udp:0.0.0.0/0",
+			want: FirewallRule{Destination: mustParseCIDR(t, "0.0.0.0/0"), Protocol: FirewallProtocolUDP, PortFrom: 0, PortTo: 0},
+		},
+		{
+			in:   "icmp:10.0.0.0/8",
+			want: FirewallRule{Destination: mustParseCIDR(t, "10.0.0.0/8"), Protocol: FirewallProtocolICMP, PortFrom: 0, PortTo: 0},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := ParseFirewallRule(tt.in)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got.Protocol != tt.want.Protocol || got.PortFrom != tt.want.PortFrom || got.PortTo != tt.want.PortTo ||
+				got.Destination.String() != tt.want.Destination.String() {
+				t.Fatalf("expected %+v, got %+v", tt.want, got)
+			}
+
+			// Formatting the rule gives back an equivalent one.
+			again, err := ParseFirewallRule(got.String())
+			if err != nil {
+				t.Fatalf("failed to parse %q: %v", got.String(), err)
+			}
+			if again.String() != got.String() {
+				t.Fatalf("expected %q, got %q", got.String(), again.String())
+			}
+		})
+	}
+}
+
+func TestParseFirewallRuleErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		// notIPv4 is set for the rules that must fail with ErrFirewallRuleNotIPv4.
+		notIPv4 bool
+	}{
+		{name: "empty", in: ""},
+		{name: "protocol only", in: "tcp"},
+		{name: "unknown protocol", in: "sctp:10.0.0.0/8"},
+		{name: "invalid address", in: "10.0.0.256"},
+		{name: "invalid prefix", in: "10.0.0.0/33"},
+		{name: "icmp with port", in: "icmp:10.0.0.0/8:80"},
+		{name: "zero port", in: "10.0.0.1:0"},
+		{name: "port out of range", in: "10.0.0.1:65536"},
+		{name: "empty port", in: "10.0.0.1:"},
+		{name: "reversed range", in: "tcp:10.0.0.1:8080-8000"},
+		{name: "open range", in: "tcp:10.0.0.1:8000-"},
+		{name: "ipv6 address", in: "fd00::1", notIPv4: true},
+		{name: "ipv6 network", in: "fd00::/64", notIPv4: true},
+		{name: "ipv6 with protocol", in: "udp:fd00::53", notIPv4: true},
+		{name: "bracketed ipv6 with port", in: "[fd00::1]:443", notIPv4: true},
+		{name: "ipv4-mapped ipv6", in: "::ffff:10.0.0.1", notIPv4: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseFirewallRule(tt.in)
+			if err == nil {
+				t.Fatalf("expected %q to be rejected", tt.in)
+			}
+			if got := errors.Is(err, ErrFirewallRuleNotIPv4); got != tt.notIPv4 {
+				t.Fatalf("expected IPv4 error to be %t, got %v", tt.notIPv4, err)
+			}
+		})
+	}
+}
+
+func mustParseCIDR(t *testing.T, s string) net.IPNet {
+	t.Helper()
+
+	_, ipNet, err := net.ParseCIDR(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return *ipNet
+}
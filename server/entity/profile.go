@@ -0,0 +1,16 @@
+package entity
+
+import (
+	"net"
+
+	"github.com/guregu/null/v5"
+)
+
+// WireGuardProfile is a named set of client settings shared by many clients.
+// Unset fields fall back to the server defaults.
+type WireGuardProfile struct {
+	Name                string
+	DNS                 []net.IP
+	AllowedIPs          []net.IPNet
+	PersistentKeepalive null.Int
+}
@@ -0,0 +1,79 @@
+package entity
+
+import (
+	"time"
+
+	"github.com/guregu/null/v5"
+)
+
+type QuotaPeriod string
+
+const (
+	QuotaPeriodDaily   QuotaPeriod = "daily"
+	QuotaPeriodWeekly  QuotaPeriod = "weekly"
+	QuotaPeriodMonthly QuotaPeriod = "monthly"
+)
+
+func (p QuotaPeriod) Valid() bool {
+	switch p {
+	case QuotaPeriodDaily, QuotaPeriodWeekly, QuotaPeriodMonthly:
+		return true
+	}
+	return false
+}
+
+// Start returns the start of the period containing t.
+// Periods start at midnight UTC, weeks start on Monday.
+func (p QuotaPeriod) Start(t time.Time) time.Time {
+	y, m, d := t.UTC().Date()
+	switch p {
+	case QuotaPeriodWeekly:
+		weekday := (int(t.UTC().Weekday()) + 6) % 7
+		return time.Date(y, m, d-weekday, 0, 0, 0, 0, time.UTC)
+	case QuotaPeriodMonthly:
+		return time.Date(y, m, 1, 0, 0, 0, 0, time.UTC)
+	}
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
+
+// Next returns the start of the period following the one starting at start.
+func (p QuotaPeriod) Next(start time.Time) time.Time {
+	switch p {
+	case QuotaPeriodWeekly:
+		return start.AddDate(0, 0, 7)
+	case QuotaPeriodMonthly:
+		return start.AddDate(0, 1, 0)
+	}
+	return start.AddDate(0, 0, 1)
+}
+
+type WireGuardClientQuota struct {
+	Limit       uint64
+	Period      QuotaPeriod
+	PeriodStart time.Time
+	Used        uint64
+	// LastCounter is the sum of the peer's kernel counters seen the last time.
+	// It's invalid until the counters are sampled for the first time.
+	LastCounter null.Value[uint64]
+}
+
+func (q *WireGuardClientQuota) Exceeded() bool {
+	return q.Used >= q.Limit
+}
+
+func (q *WireGuardClientQuota) Remaining() uint64 {
+	if q.Exceeded() {
+		return 0
+	}
+	return q.Limit - q.Used
+}
+
+// CounterDelta returns the difference between two values of a kernel counter.
+// Counters are reset when the interface is restarted,
+// so a value less than the previous one is counted from zero.
+func CounterDelta(prev, cur uint64) uint64 {
+	if cur < prev {
+		return cur
+	}
+	return cur - prev
+}
@@ -0,0 +1,11 @@
+package entity
+
+import "github.com/guregu/null/v5"
+
+type Snapshot struct {
+	Server         null.Value[WireGuardServerConfig]
+	Clients        []WireGuardClient
+	PublicKeys     []PublicKey
+	FirewallGroups []FirewallGroup
+	Profiles       []WireGuardProfile
+}
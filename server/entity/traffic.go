@@ -0,0 +1,24 @@
+package entity
+
+import (
+	"time"
+
+	"github.com/guregu/null/v5"
+)
+
+type TrafficSample struct {
+	Start           time.Time
+	Received        uint64
+	Sent            uint64
+	LatestHandshake null.Time
+}
+
+type TrafficStats struct {
+	Since           time.Time
+	Until           time.Time
+	Interval        time.Duration
+	Received        uint64
+	Sent            uint64
+	LatestHandshake null.Time
+	Samples         []TrafficSample
+}
@@ -2,6 +2,7 @@ package entity
 
 import (
 	"net"
+	"time"
 
 	"github.com/guregu/null/v5"
 	"github.com/infastin/wg-wish/pkg/wgtypes"
@@ -15,15 +16,97 @@ type WireGuardClient struct {
 	DNS                 []net.IP
 	AllowedIPs          []net.IPNet
 	PersistentKeepalive null.Int
+	Disabled            bool
+	Quota               null.Value[WireGuardClientQuota]
+	// RateLimit is the bandwidth limit in bits per second in each direction.
+	// Zero means no limit.
+	RateLimit uint64
+	// Group is the name of the firewall group the client belongs to.
+	// Clients without a group are not restricted.
+	Group string
+	// Profile is the name of the profile the client follows.
+	// Settings the client still shares with the profile are updated along with it.
+	Profile string
+	// ExpiresAt is the time the client gets disabled at.
+	ExpiresAt null.Time
+	// Labels are arbitrary key/value pairs attached to the client.
+	Labels map[string]string
+	// Description is a free-form note about the client.
+	Description string
+	// Mesh makes the client peer directly with the other mesh clients.
+	Mesh bool
+	// Endpoint is the host:port the other mesh clients reach the client at.
+	// The endpoint the server has last seen the client at is used if empty.
+	Endpoint string
+	// LastEndpoint is the endpoint the server has last seen the client at.
+	LastEndpoint string
+}
+
+// MeshEndpoint returns the endpoint the other mesh clients reach the client at,
+// empty if it isn't known.
+func (c *WireGuardClient) MeshEndpoint() string {
+	if c.Endpoint != "" {
+		return c.Endpoint
+	}
+	return c.LastEndpoint
+}
+
+// Expired reports whether the client has expired by the given time.
+func (c *WireGuardClient) Expired(now time.Time) bool {
+	return c.ExpiresAt.Valid && !now.Before(c.ExpiresAt.Time)
+}
+
+// ValidLabelKey reports whether the label key is non-empty
+// and consists of letters, digits, '-', '_', '.' or '/'.
+func ValidLabelKey(key string) bool {
+	if key == "" {
+		return false
+	}
+	for _, r := range key {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+		case r == '-', r == '_', r == '.', r == '/':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+type WireGuardRateLimit struct {
+	Address net.IPNet
+	Rate    uint64
 }
 
 type WireGuardPeerStats struct {
 	Received        uint64
 	Sent            uint64
 	LatestHandshake null.Time
+	// Endpoint is the host:port the peer has last connected from, empty if it hasn't.
+	Endpoint string
+}
+
+// PeerOnlineTimeout is how long a peer is considered online after its latest handshake.
+// WireGuard makes a new handshake every two minutes while the peer is active.
+const PeerOnlineTimeout = 3 * time.Minute
+
+// Online reports whether the peer has made a handshake recently.
+func (s *WireGuardPeerStats) Online(now time.Time) bool {
+	return s.LatestHandshake.Valid && now.Sub(s.LatestHandshake.Time) < PeerOnlineTimeout
 }
 
 type WireGuardClientInfo struct {
-	Config wgtypes.ClientConfig
-	Stats  null.Value[WireGuardPeerStats]
+	Config       wgtypes.ClientConfig
+	Stats        null.Value[WireGuardPeerStats]
+	Disabled     bool
+	Quota        null.Value[WireGuardClientQuota]
+	RateLimit    uint64
+	Group        string
+	Profile      string
+	ExpiresAt    null.Time
+	Labels       map[string]string
+	Description  string
+	Mesh         bool
+	Endpoint     string
+	LastEndpoint string
 }
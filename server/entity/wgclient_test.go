@@ -0,0 +1,32 @@
+package entity
+
+import (
+	"testing"
+	"time"
+
+	"github.com/guregu/null/v5"
+)
+
+func TestPeerStatsOnline(t *testing.T) {
+	handshake := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name      string
+		handshake null.Time
+		now       time.Time
+		want      bool
+	}{
+		{"never", null.Time{}, handshake, false},
+		{"just now", null.TimeFrom(handshake), handshake, true},
+		{"before timeout", null.TimeFrom(handshake), handshake.Add(PeerOnlineTimeout - time.Nanosecond), true},
+		{"at timeout", null.TimeFrom(handshake), handshake.Add(PeerOnlineTimeout), false},
+		{"after timeout", null.TimeFrom(handshake), handshake.Add(PeerOnlineTimeout + time.Second), false},
+	}
+
+	for _, tt := range tests {
+		stats := WireGuardPeerStats{Received: 0, Sent: 0, LatestHandshake: tt.handshake, Endpoint: ""}
+		if got := stats.Online(tt.now); got != tt.want {
+			t.Errorf("%s: expected %t, got %t", tt.name, tt.want, got)
+		}
+	}
+}
@@ -1,7 +1,29 @@
 package entity
 
-import "github.com/infastin/wg-wish/pkg/wgtypes"
+import (
+	"net"
+
+	"github.com/infastin/wg-wish/pkg/wgtypes"
+)
 
 type WireGuardServerConfig struct {
 	PrivateKey wgtypes.Key
 }
+
+type WireGuardServerInfo struct {
+	Interface       string
+	Host            string
+	Port            int
+	Address         net.IPNet
+	PublicKey       wgtypes.Key
+	Clients         int
+	AddressPoolSize uint64
+	AddressPoolUsed uint64
+}
+
+// WireGuardConfigIssue is a problem found in the server config or in a client config.
+type WireGuardConfigIssue struct {
+	// Config is empty for the server config, otherwise it is the name of the client.
+	Config string
+	wgtypes.Issue
+}
@@ -11,9 +11,36 @@ var (
 	ErrWireGuardClientExists          = NewDomainError("wg", "wireguard client already exists")
 	ErrWireGuardClientNotFound        = NewDomainError("wg", "wireguard client not found")
 	ErrWireGuardClientAddressOverlaps = NewDomainError("wg", "wireguard client address overlaps with wireguard server address")
+	ErrWireGuardClientAddressExists   = NewDomainError("wg", "wireguard client with this address already exists")
+	ErrWireGuardClientPublicKeyExists = NewDomainError("wg", "wireguard client with this public key already exists")
 	ErrWireGuardServerPeerExists      = NewInternalError(NewDomainError("wg", "wireguard server peer already exists"))
 	ErrWireGuardServerPeerNotFound    = NewInternalError(NewDomainError("wg", "wireguard server peer not found"))
 	ErrWireGuardServerConfigNotFound  = NewInternalError(NewDomainError("wg", "wireguard server config not found"))
+	ErrWireGuardClientInvalidQuota    = NewDomainError("wg", "quota period must be daily, weekly or monthly")
+	ErrWireGuardClientNoQuota         = NewDomainError("wg", "wireguard client has no quota, specify its limit")
+	ErrWireGuardClientExpired         = NewDomainError("wg", "wireguard client expiry time has already passed")
+	ErrWireGuardClientInvalidLabel    = NewDomainError("wg", "label keys must consist of letters, digits, '-', '_', '.' or '/'")
+	ErrWireGuardClientInvalidSort     = NewDomainError("wg", "clients can be sorted by name, address, traffic or handshake")
+	ErrWireGuardClientInvalidPattern  = NewDomainError("wg", "invalid client name pattern")
+	ErrWireGuardClientInvalidEndpoint = NewDomainError("wg", "wireguard client endpoint must be host:port")
+	ErrWireGuardInterfaceNotFound     = NewDomainError("wg", "wireguard interface not found")
+	ErrWireGuardProfileExists         = NewDomainError("profile", "profile already exists")
+	ErrWireGuardProfileNotFound       = NewDomainError("profile", "profile not found")
+	ErrWireGuardProfileInUse          = NewDomainError("profile", "profile is followed by clients")
+	ErrFirewallGroupExists            = NewDomainError("firewall", "firewall group already exists")
+	ErrFirewallGroupNotFound          = NewDomainError("firewall", "firewall group not found")
+	ErrFirewallGroupInUse             = NewDomainError("firewall", "firewall group is assigned to wireguard clients")
+	ErrFirewallGroupNotIPv4           = NewDomainError("firewall", "firewall groups can only be assigned to IPv4 clients")
+	ErrImportDuplicateName            = NewDomainError("admin", "imported document contains duplicate peer names")
+	ErrImportAddressConflict          = NewDomainError("admin", "imported peer address is already in use")
+	ErrImportPublicKeyConflict        = NewDomainError("admin", "imported peer public key is already in use")
+	ErrTrafficStatsDisabled           = NewDomainError("stats", "traffic statistics are disabled")
+	ErrTrafficStatsInvalidRange       = NewDomainError("stats", "period and interval must be positive")
+	ErrFederationDisabled             = NewDomainError("federation", "federation is not configured")
+	ErrFederationServerNotFound       = NewDomainError("federation", "federation server not found")
+	ErrFederationKeyMismatch          = NewDomainError("federation", "client exists on the server with a different key")
+	ErrClusterDisabled                = NewDomainError("cluster", "replication is not configured")
+	ErrClusterNotStandby              = NewDomainError("cluster", "server is not a standby")
 )
 
 type PanicError struct {
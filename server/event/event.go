@@ -0,0 +1,173 @@
+package event
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"path"
+	"sync"
+	"time"
+
+	"github.com/infastin/wg-wish/pkg/wgtypes"
+	"github.com/infastin/wg-wish/server/entity"
+	gossh "golang.org/x/crypto/ssh"
+)
+
+type Type string
+
+const (
+	PeerAdded   Type = "peer.added"
+	PeerRemoved Type = "peer.removed"
+	PeerEdited  Type = "peer.edited"
+	// PeerExpired is emitted when a peer gets disabled after running out of its allowance.
+	PeerExpired Type = "peer.expired"
+	// PeerConnected is emitted every time a peer, which has never connected
+	// or whose latest handshake has gone stale, performs a handshake.
+	PeerConnected Type = "peer.connected"
+	// PeerFirstConnected is emitted along with PeerConnected when a peer performs
+	// its first handshake since the interface has been brought up.
+	PeerFirstConnected Type = "peer.first_connected"
+	// PeerHandshakeStale is emitted once the latest handshake
+	// of a previously connected peer gets older than the stale threshold.
+	PeerHandshakeStale Type = "peer.handshake_stale"
+	// PeerTraffic describes traffic rates of a peer. It is never published on the bus
+	// and is only produced by consumers sampling peer stats themselves.
+	PeerTraffic      Type = "peer.traffic"
+	ServerReloaded   Type = "server.reloaded"
+	PublicKeyAdded   Type = "publickey.added"
+	PublicKeyRemoved Type = "publickey.removed"
+)
+
+// Match reports whether the event type matches the pattern,
+// which is either an exact type or a glob, e.g. "peer.*".
+func (t Type) Match(pattern string) bool {
+	ok, _ := path.Match(pattern, string(t))
+	return ok
+}
+
+type Event struct {
+	ID   string    `json:"id"`
+	Type Type      `json:"type"`
+	Time time.Time `json:"time"`
+	// Interface is the WireGuard interface the event is related to, if any.
+	Interface string     `json:"interface,omitempty"`
+	Peer      *Peer      `json:"peer,omitempty"`
+	PublicKey *PublicKey `json:"public_key,omitempty"`
+	Traffic   *Traffic   `json:"traffic,omitempty"`
+}
+
+type Peer struct {
+	Name            string     `json:"name"`
+	Address         string     `json:"address"`
+	PublicKey       string     `json:"public_key"`
+	LatestHandshake *time.Time `json:"latest_handshake,omitempty"`
+}
+
+type Traffic struct {
+	Received uint64 `json:"received"`
+	Sent     uint64 `json:"sent"`
+	// Rates are in bytes per second.
+	ReceiveRate float64 `json:"receive_rate"`
+	SendRate    float64 `json:"send_rate"`
+}
+
+type PublicKey struct {
+	Fingerprint string `json:"fingerprint"`
+	Comment     string `json:"comment,omitempty"`
+}
+
+func New(typ Type) Event {
+	var id [16]byte
+	_, _ = rand.Read(id[:])
+
+	return Event{
+		ID:        hex.EncodeToString(id[:]),
+		Type:      typ,
+		Time:      time.Now().UTC(),
+		Interface: "",
+		Peer:      nil,
+		PublicKey: nil,
+		Traffic:   nil,
+	}
+}
+
+func NewPeerEvent(typ Type, cfg *wgtypes.ClientConfig) Event {
+	e := New(typ)
+	e.Peer = &Peer{
+		Name:            cfg.Interface.Name,
+		Address:         cfg.Interface.Address.String(),
+		PublicKey:       cfg.Interface.PrivateKey.PublicKey().String(),
+		LatestHandshake: nil,
+	}
+	return e
+}
+
+func NewClientEvent(typ Type, client *entity.WireGuardClient) Event {
+	e := New(typ)
+	e.Peer = &Peer{
+		Name:            client.Name,
+		Address:         client.Address.String(),
+		PublicKey:       client.PublicKey.String(),
+		LatestHandshake: nil,
+	}
+	return e
+}
+
+func NewPublicKeyEvent(typ Type, pkey *entity.PublicKey) Event {
+	e := New(typ)
+	e.PublicKey = &PublicKey{
+		Fingerprint: gossh.FingerprintSHA256(pkey.Key),
+		Comment:     pkey.Comment,
+	}
+	return e
+}
+
+type Handler func(e *Event)
+
+// Bus delivers published events to subscribed handlers synchronously.
+// All methods are safe to call on nil Bus.
+type Bus struct {
+	mu       sync.RWMutex
+	handlers map[int]Handler
+	nextID   int
+}
+
+func NewBus() *Bus {
+	return &Bus{
+		mu:       sync.RWMutex{},
+		handlers: make(map[int]Handler),
+		nextID:   0,
+	}
+}
+
+// Subscribe registers the handler and returns a function unregistering it.
+// Handlers must not block, since they are called by the publisher.
+func (b *Bus) Subscribe(h Handler) (unsubscribe func()) {
+	if b == nil {
+		return func() {}
+	}
+
+	b.mu.Lock()
+	id := b.nextID
+	b.handlers[id] = h
+	b.nextID++
+	b.mu.Unlock()
+
+	return func() {
+		b.mu.Lock()
+		delete(b.handlers, id)
+		b.mu.Unlock()
+	}
+}
+
+func (b *Bus) Publish(e Event) {
+	if b == nil {
+		return
+	}
+
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	for _, h := range b.handlers {
+		h(&e)
+	}
+}
@@ -0,0 +1,137 @@
+package event
+
+import (
+	"context"
+	"time"
+
+	"github.com/infastin/wg-wish/server/entity"
+	"github.com/infastin/wg-wish/server/service"
+	"github.com/rs/zerolog"
+)
+
+type PeerWatcherParams struct {
+	Logger           zerolog.Logger
+	Bus              *Bus
+	Interface        string
+	WireGuardService service.WireGuardService
+	Interval         time.Duration
+	StaleAfter       time.Duration
+}
+
+// PeerWatcher periodically samples peer stats and publishes
+// PeerConnected, PeerFirstConnected and PeerHandshakeStale events.
+type PeerWatcher struct {
+	lg               zerolog.Logger
+	bus              *Bus
+	iface            string
+	wireguardService service.WireGuardService
+	interval         time.Duration
+	staleAfter       time.Duration
+}
+
+func NewPeerWatcher(params *PeerWatcherParams) *PeerWatcher {
+	return &PeerWatcher{
+		lg:               params.Logger,
+		bus:              params.Bus,
+		iface:            params.Interface,
+		wireguardService: params.WireGuardService,
+		interval:         params.Interval,
+		staleAfter:       params.StaleAfter,
+	}
+}
+
+func (w *PeerWatcher) Run(ctx context.Context) error {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	tracker := NewPeerTracker(w.staleAfter)
+
+	for {
+		infos, err := w.wireguardService.GetClientInfos(ctx)
+		if err != nil {
+			w.lg.Err(err).Msg("failed to sample peer stats")
+		} else {
+			for _, e := range tracker.Update(infos, time.Now()) {
+				e.Interface = w.iface
+				w.bus.Publish(e)
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+type peerState int
+
+const (
+	peerNeverConnected peerState = iota
+	peerOnline
+	peerStale
+)
+
+// PeerTracker turns consecutive samples of peer stats into
+// PeerConnected, PeerFirstConnected and PeerHandshakeStale events.
+type PeerTracker struct {
+	staleAfter time.Duration
+	states     map[string]peerState
+}
+
+func NewPeerTracker(staleAfter time.Duration) *PeerTracker {
+	return &PeerTracker{
+		staleAfter: staleAfter,
+		states:     nil,
+	}
+}
+
+// Update records the sample and returns events for peers whose state has changed.
+// The first sample only seeds the states, so that e.g. a restart
+// doesn't produce events for every connected peer.
+func (t *PeerTracker) Update(infos []entity.WireGuardClientInfo, now time.Time) (events []Event) {
+	states := make(map[string]peerState, len(infos))
+
+	for i := range infos {
+		info := &infos[i]
+		name := info.Config.Interface.Name
+
+		state := t.peerState(info, now)
+		states[name] = state
+
+		if t.states == nil {
+			continue
+		}
+
+		var types []Type
+		switch prevState := t.states[name]; {
+		case prevState == peerNeverConnected && state == peerOnline:
+			types = []Type{PeerConnected, PeerFirstConnected}
+		case prevState == peerStale && state == peerOnline:
+			types = []Type{PeerConnected}
+		case prevState == peerOnline && state == peerStale:
+			types = []Type{PeerHandshakeStale}
+		}
+
+		for _, typ := range types {
+			e := NewPeerEvent(typ, &info.Config)
+			e.Peer.LatestHandshake = info.Stats.V.LatestHandshake.Ptr()
+			events = append(events, e)
+		}
+	}
+
+	t.states = states
+
+	return events
+}
+
+func (t *PeerTracker) peerState(info *entity.WireGuardClientInfo, now time.Time) peerState {
+	if !info.Stats.Valid || !info.Stats.V.LatestHandshake.Valid {
+		return peerNeverConnected
+	}
+	if now.Sub(info.Stats.V.LatestHandshake.Time) >= t.staleAfter {
+		return peerStale
+	}
+	return peerOnline
+}
@@ -0,0 +1,122 @@
+package event
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/guregu/null/v5"
+	"github.com/infastin/wg-wish/pkg/wgtypes"
+	"github.com/infastin/wg-wish/server/entity"
+)
+
+func newInfo(t *testing.T, name string, handshake time.Time) entity.WireGuardClientInfo {
+	t.Helper()
+
+	privateKey, err := wgtypes.GeneratePrivateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	info := entity.WireGuardClientInfo{ //nolint:exhaustruct
+		Config: wgtypes.ClientConfig{ //nolint:exhaustruct
+			Interface: wgtypes.ClientInterface{ //nolint:exhaustruct
+				Name:       name,
+				PrivateKey: privateKey,
+			},
+		},
+	}
+
+	if !handshake.IsZero() {
+		info.Stats = null.ValueFrom(entity.WireGuardPeerStats{ //nolint:exhaustruct
+			LatestHandshake: null.TimeFrom(handshake),
+		})
+	}
+
+	return info
+}
+
+func eventTypes(events []Event) []Type {
+	types := make([]Type, len(events))
+	for i := range events {
+		types[i] = events[i].Type
+	}
+	return types
+}
+
+func TestPeerTracker(t *testing.T) {
+	const staleAfter = 3 * time.Minute
+
+	start := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	info := newInfo(t, "alice", time.Time{})
+	tracker := NewPeerTracker(staleAfter)
+
+	steps := []struct {
+		name      string
+		now       time.Time
+		handshake time.Time
+		want      []Type
+	}{
+		{"seed", start, time.Time{}, nil},
+		{"first handshake", start.Add(time.Minute), start.Add(time.Minute), []Type{PeerConnected, PeerFirstConnected}},
+		{"still online", start.Add(2 * time.Minute), start.Add(time.Minute), nil},
+		{"stale", start.Add(time.Minute + staleAfter), start.Add(time.Minute), []Type{PeerHandshakeStale}},
+		{"still stale", start.Add(10 * time.Minute), start.Add(time.Minute), nil},
+		{"reconnected", start.Add(11 * time.Minute), start.Add(11 * time.Minute), []Type{PeerConnected}},
+	}
+
+	for _, step := range steps {
+		if step.handshake.IsZero() {
+			info.Stats = null.Value[entity.WireGuardPeerStats]{}
+		} else {
+			info.Stats = null.ValueFrom(entity.WireGuardPeerStats{ //nolint:exhaustruct
+				LatestHandshake: null.TimeFrom(step.handshake),
+			})
+		}
+
+		got := eventTypes(tracker.Update([]entity.WireGuardClientInfo{info}, step.now))
+		if !slices.Equal(got, step.want) {
+			t.Fatalf("%s: expected %v, got %v", step.name, step.want, got)
+		}
+	}
+}
+
+func TestPeerTrackerSeed(t *testing.T) {
+	now := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	tracker := NewPeerTracker(3 * time.Minute)
+
+	// Peers connected before the first sample don't produce events.
+	infos := []entity.WireGuardClientInfo{newInfo(t, "alice", now)}
+	if got := tracker.Update(infos, now); len(got) != 0 {
+		t.Fatalf("expected no events on the first sample, got %v", eventTypes(got))
+	}
+
+	// A peer added after the first sample connects for the first time.
+	infos = append(infos, newInfo(t, "bob", now.Add(time.Second)))
+	got := eventTypes(tracker.Update(infos, now.Add(time.Second)))
+	if want := []Type{PeerConnected, PeerFirstConnected}; !slices.Equal(got, want) {
+		t.Fatalf("expected %v, got %v", want, got)
+	}
+}
+
+func TestPeerTrackerOnlineTimeout(t *testing.T) {
+	handshake := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	info := newInfo(t, "alice", handshake)
+	tracker := NewPeerTracker(entity.PeerOnlineTimeout)
+
+	tracker.Update([]entity.WireGuardClientInfo{info}, handshake)
+
+	// The peer goes stale at the moment its stats stop reporting it online.
+	for _, now := range []time.Time{
+		handshake.Add(entity.PeerOnlineTimeout - time.Nanosecond),
+		handshake.Add(entity.PeerOnlineTimeout),
+	} {
+		online := info.Stats.V.Online(now)
+
+		got := eventTypes(tracker.Update([]entity.WireGuardClientInfo{info}, now))
+		if stale := slices.Equal(got, []Type{PeerHandshakeStale}); stale == online {
+			t.Fatalf("%s after the handshake: online is %t, but got events %v",
+				now.Sub(handshake), online, got)
+		}
+	}
+}
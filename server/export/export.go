@@ -0,0 +1,381 @@
+package export
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"time"
+
+	"github.com/charmbracelet/ssh"
+	"github.com/guregu/null/v5"
+	"github.com/infastin/gorack/fastconv"
+	"github.com/infastin/wg-wish/pkg/netutils"
+	"github.com/infastin/wg-wish/pkg/wgtypes"
+	"github.com/infastin/wg-wish/server/entity"
+	gossh "golang.org/x/crypto/ssh"
+)
+
+// Version is the version of the document produced by Encode.
+// Documents with a greater version are rejected by Decode.
+const Version = 1
+
+type Document struct {
+	Version        int             `json:"version"`
+	ExportedAt     time.Time       `json:"exported_at"`
+	Server         *Server         `json:"server,omitempty"`
+	Peers          []Peer          `json:"peers"`
+	PublicKeys     []PublicKey     `json:"public_keys"`
+	FirewallGroups []FirewallGroup `json:"firewall_groups,omitempty"`
+	Profiles       []Profile       `json:"profiles,omitempty"`
+}
+
+type Server struct {
+	PrivateKey string `json:"private_key"`
+	PublicKey  string `json:"public_key,omitempty"`
+}
+
+type Peer struct {
+	Name                string            `json:"name"`
+	Address             string            `json:"address"`
+	PrivateKey          string            `json:"private_key"`
+	PublicKey           string            `json:"public_key,omitempty"`
+	DNS                 []string          `json:"dns"`
+	AllowedIPs          []string          `json:"allowed_ips"`
+	PersistentKeepalive *int64            `json:"persistent_keepalive,omitempty"`
+	Disabled            bool              `json:"disabled,omitempty"`
+	Quota               *Quota            `json:"quota,omitempty"`
+	RateLimit           uint64            `json:"rate_limit,omitempty"`
+	Group               string            `json:"group,omitempty"`
+	Profile             string            `json:"profile,omitempty"`
+	ExpiresAt           *time.Time        `json:"expires_at,omitempty"`
+	Labels              map[string]string `json:"labels,omitempty"`
+	Description         string            `json:"description,omitempty"`
+	Mesh                bool              `json:"mesh,omitempty"`
+	Endpoint            string            `json:"endpoint,omitempty"`
+}
+
+type Quota struct {
+	Limit       uint64    `json:"limit"`
+	Period      string    `json:"period"`
+	PeriodStart time.Time `json:"period_start"`
+	Used        uint64    `json:"used"`
+}
+
+type FirewallGroup struct {
+	Name       string   `json:"name"`
+	AllowPeers bool     `json:"allow_peers,omitempty"`
+	Rules      []string `json:"rules"`
+}
+
+type Profile struct {
+	Name                string   `json:"name"`
+	DNS                 []string `json:"dns,omitempty"`
+	AllowedIPs          []string `json:"allowed_ips,omitempty"`
+	PersistentKeepalive *int64   `json:"persistent_keepalive,omitempty"`
+}
+
+type PublicKey struct {
+	Key     string `json:"key"`
+	Comment string `json:"comment,omitempty"`
+}
+
+func Encode(writer io.Writer, snapshot *entity.Snapshot) (err error) {
+	doc := Document{
+		Version:    Version,
+		ExportedAt: time.Now().UTC(),
+		Server:     nil,
+		Peers:      make([]Peer, len(snapshot.Clients)),
+		PublicKeys: make([]PublicKey, len(snapshot.PublicKeys)),
+	}
+
+	if snapshot.Server.Valid {
+		doc.Server = &Server{
+			PrivateKey: snapshot.Server.V.PrivateKey.String(),
+			PublicKey:  snapshot.Server.V.PrivateKey.PublicKey().String(),
+		}
+	}
+
+	for i := range snapshot.Clients {
+		client := &snapshot.Clients[i]
+
+		dns := make([]string, len(client.DNS))
+		for j := range client.DNS {
+			dns[j] = client.DNS[j].String()
+		}
+
+		ips := make([]string, len(client.AllowedIPs))
+		for j := range client.AllowedIPs {
+			ips[j] = client.AllowedIPs[j].String()
+		}
+
+		doc.Peers[i] = Peer{
+			Name:                client.Name,
+			Address:             client.Address.String(),
+			PrivateKey:          client.PrivateKey.String(),
+			PublicKey:           client.PublicKey.String(),
+			DNS:                 dns,
+			AllowedIPs:          ips,
+			PersistentKeepalive: client.PersistentKeepalive.Ptr(),
+			Disabled:            client.Disabled,
+			Quota:               nil,
+			RateLimit:           client.RateLimit,
+			Group:               client.Group,
+			Profile:             client.Profile,
+			ExpiresAt:           client.ExpiresAt.Ptr(),
+			Labels:              client.Labels,
+			Description:         client.Description,
+			Mesh:                client.Mesh,
+			Endpoint:            client.Endpoint,
+		}
+
+		if client.Quota.Valid {
+			doc.Peers[i].Quota = &Quota{
+				Limit:       client.Quota.V.Limit,
+				Period:      string(client.Quota.V.Period),
+				PeriodStart: client.Quota.V.PeriodStart,
+				Used:        client.Quota.V.Used,
+			}
+		}
+	}
+
+	for i := range snapshot.PublicKeys {
+		doc.PublicKeys[i] = PublicKey{
+			Key:     string(trimNewline(gossh.MarshalAuthorizedKey(snapshot.PublicKeys[i].Key))),
+			Comment: snapshot.PublicKeys[i].Comment,
+		}
+	}
+
+	for i := range snapshot.FirewallGroups {
+		group := &snapshot.FirewallGroups[i]
+
+		rules := make([]string, len(group.Rules))
+		for j := range group.Rules {
+			rules[j] = group.Rules[j].String()
+		}
+
+		doc.FirewallGroups = append(doc.FirewallGroups, FirewallGroup{
+			Name:       group.Name,
+			AllowPeers: group.AllowPeers,
+			Rules:      rules,
+		})
+	}
+
+	for i := range snapshot.Profiles {
+		profile := &snapshot.Profiles[i]
+
+		dns := make([]string, len(profile.DNS))
+		for j := range profile.DNS {
+			dns[j] = profile.DNS[j].String()
+		}
+
+		ips := make([]string, len(profile.AllowedIPs))
+		for j := range profile.AllowedIPs {
+			ips[j] = profile.AllowedIPs[j].String()
+		}
+
+		doc.Profiles = append(doc.Profiles, Profile{
+			Name:                profile.Name,
+			DNS:                 dns,
+			AllowedIPs:          ips,
+			PersistentKeepalive: profile.PersistentKeepalive.Ptr(),
+		})
+	}
+
+	enc := json.NewEncoder(writer)
+	enc.SetIndent("", "  ")
+
+	return enc.Encode(&doc)
+}
+
+func Decode(reader io.Reader) (snapshot entity.Snapshot, err error) {
+	var doc Document
+
+	dec := json.NewDecoder(reader)
+	dec.DisallowUnknownFields()
+
+	if err := dec.Decode(&doc); err != nil {
+		return entity.Snapshot{}, fmt.Errorf("failed to decode document: %w", err)
+	}
+
+	if doc.Version < 1 || doc.Version > Version {
+		return entity.Snapshot{}, fmt.Errorf("unsupported document version: %d", doc.Version)
+	}
+
+	if doc.Server != nil {
+		privateKey, err := wgtypes.ParseKey(doc.Server.PrivateKey)
+		if err != nil {
+			return entity.Snapshot{}, fmt.Errorf("server: %w", err)
+		}
+		snapshot.Server = null.ValueFrom(entity.WireGuardServerConfig{
+			PrivateKey: privateKey,
+		})
+	}
+
+	snapshot.Clients = make([]entity.WireGuardClient, len(doc.Peers))
+	for i := range doc.Peers {
+		snapshot.Clients[i], err = decodePeer(&doc.Peers[i])
+		if err != nil {
+			return entity.Snapshot{}, fmt.Errorf("peer %q: %w", doc.Peers[i].Name, err)
+		}
+	}
+
+	snapshot.PublicKeys = make([]entity.PublicKey, len(doc.PublicKeys))
+	for i := range doc.PublicKeys {
+		pkey, comment, _, _, err := ssh.ParseAuthorizedKey(fastconv.Bytes(doc.PublicKeys[i].Key))
+		if err != nil {
+			return entity.Snapshot{}, fmt.Errorf("public key #%d: %w", i+1, err)
+		}
+
+		if doc.PublicKeys[i].Comment != "" {
+			comment = doc.PublicKeys[i].Comment
+		}
+
+		snapshot.PublicKeys[i] = entity.PublicKey{
+			Key:     pkey,
+			Comment: comment,
+		}
+	}
+
+	snapshot.FirewallGroups = make([]entity.FirewallGroup, len(doc.FirewallGroups))
+	for i := range doc.FirewallGroups {
+		snapshot.FirewallGroups[i], err = decodeFirewallGroup(&doc.FirewallGroups[i])
+		if err != nil {
+			return entity.Snapshot{}, fmt.Errorf("firewall group %q: %w", doc.FirewallGroups[i].Name, err)
+		}
+	}
+
+	snapshot.Profiles = make([]entity.WireGuardProfile, len(doc.Profiles))
+	for i := range doc.Profiles {
+		snapshot.Profiles[i], err = decodeProfile(&doc.Profiles[i])
+		if err != nil {
+			return entity.Snapshot{}, fmt.Errorf("profile %q: %w", doc.Profiles[i].Name, err)
+		}
+	}
+
+	return snapshot, nil
+}
+
+func decodeProfile(profile *Profile) (wgProfile entity.WireGuardProfile, err error) {
+	if profile.Name == "" {
+		return entity.WireGuardProfile{}, fmt.Errorf("name is required")
+	}
+
+	wgProfile.Name = profile.Name
+
+	wgProfile.DNS, err = netutils.ParseIPs(profile.DNS)
+	if err != nil {
+		return entity.WireGuardProfile{}, err
+	}
+
+	wgProfile.AllowedIPs, err = netutils.ParseAddresses(profile.AllowedIPs)
+	if err != nil {
+		return entity.WireGuardProfile{}, err
+	}
+
+	wgProfile.PersistentKeepalive = null.IntFromPtr(profile.PersistentKeepalive)
+
+	return wgProfile, nil
+}
+
+func decodeFirewallGroup(group *FirewallGroup) (fwGroup entity.FirewallGroup, err error) {
+	if group.Name == "" {
+		return entity.FirewallGroup{}, fmt.Errorf("name is required")
+	}
+
+	fwGroup = entity.FirewallGroup{
+		Name:       group.Name,
+		AllowPeers: group.AllowPeers,
+		Rules:      make([]entity.FirewallRule, len(group.Rules)),
+	}
+
+	for i := range group.Rules {
+		fwGroup.Rules[i], err = entity.ParseFirewallRule(group.Rules[i])
+		if err != nil {
+			return entity.FirewallGroup{}, err
+		}
+	}
+
+	return fwGroup, nil
+}
+
+func decodePeer(peer *Peer) (client entity.WireGuardClient, err error) {
+	if peer.Name == "" {
+		return entity.WireGuardClient{}, fmt.Errorf("name is required")
+	}
+
+	client.Name = peer.Name
+
+	client.Address, err = netutils.ParseAddress(peer.Address)
+	if err != nil {
+		return entity.WireGuardClient{}, err
+	}
+
+	client.PrivateKey, err = wgtypes.ParseKey(peer.PrivateKey)
+	if err != nil {
+		return entity.WireGuardClient{}, err
+	}
+
+	client.PublicKey = client.PrivateKey.PublicKey()
+	if peer.PublicKey != "" && peer.PublicKey != client.PublicKey.String() {
+		return entity.WireGuardClient{}, fmt.Errorf("public key does not match private key")
+	}
+
+	client.DNS, err = netutils.ParseIPs(peer.DNS)
+	if err != nil {
+		return entity.WireGuardClient{}, err
+	}
+
+	client.AllowedIPs, err = netutils.ParseAddresses(peer.AllowedIPs)
+	if err != nil {
+		return entity.WireGuardClient{}, err
+	}
+
+	client.PersistentKeepalive = null.IntFromPtr(peer.PersistentKeepalive)
+	client.Disabled = peer.Disabled
+	client.RateLimit = peer.RateLimit
+	client.Group = peer.Group
+	client.Profile = peer.Profile
+	client.ExpiresAt = null.TimeFromPtr(peer.ExpiresAt)
+	client.Labels = peer.Labels
+	client.Description = peer.Description
+	client.Mesh = peer.Mesh
+	client.Endpoint = peer.Endpoint
+
+	if client.Endpoint != "" {
+		if _, _, err := net.SplitHostPort(client.Endpoint); err != nil {
+			return entity.WireGuardClient{}, err
+		}
+	}
+
+	for key := range client.Labels {
+		if !entity.ValidLabelKey(key) {
+			return entity.WireGuardClient{}, fmt.Errorf("invalid label %q", key)
+		}
+	}
+
+	if peer.Quota != nil {
+		period := entity.QuotaPeriod(peer.Quota.Period)
+		if peer.Quota.Limit == 0 || !period.Valid() {
+			return entity.WireGuardClient{}, fmt.Errorf("invalid quota")
+		}
+
+		// Kernel counters are not carried over, so the next sample only sets a baseline.
+		client.Quota = null.ValueFrom(entity.WireGuardClientQuota{
+			Limit:       peer.Quota.Limit,
+			Period:      period,
+			PeriodStart: peer.Quota.PeriodStart.UTC(),
+			Used:        peer.Quota.Used,
+			LastCounter: null.Value[uint64]{},
+		})
+	}
+
+	return client, nil
+}
+
+func trimNewline(b []byte) []byte {
+	if n := len(b); n != 0 && b[n-1] == '\n' {
+		return b[:n-1]
+	}
+	return b
+}
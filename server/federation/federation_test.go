@@ -0,0 +1,424 @@
+package federation_test
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/infastin/wg-wish/pkg/wgtypes"
+	"github.com/infastin/wg-wish/server/entity"
+	"github.com/infastin/wg-wish/server/errors"
+	"github.com/infastin/wg-wish/server/federation"
+	fedrepo "github.com/infastin/wg-wish/server/repo/federation/impl"
+	"github.com/infastin/wg-wish/server/service"
+	federationservice "github.com/infastin/wg-wish/server/service/impl/federation"
+	"github.com/rs/zerolog"
+)
+
+// fakeWireGuard keeps the clients of an interface in memory.
+// Methods the protocol doesn't use panic through the nil embedded service.
+type fakeWireGuard struct {
+	service.WireGuardService
+
+	mu        sync.Mutex
+	subnet    byte
+	publicKey wgtypes.Key
+	clients   map[string]wgtypes.ClientConfig
+	next      byte
+}
+
+func newFakeWireGuard(t *testing.T, subnet byte) *fakeWireGuard {
+	t.Helper()
+
+	privateKey, err := wgtypes.GeneratePrivateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return &fakeWireGuard{
+		subnet:    subnet,
+		publicKey: privateKey.PublicKey(),
+		clients:   make(map[string]wgtypes.ClientConfig),
+		next:      2,
+	}
+}
+
+func (wg *fakeWireGuard) AddClient(ctx context.Context, name string, opts *service.AddClientOptions,
+) (client wgtypes.ClientConfig, err error) {
+	wg.mu.Lock()
+	defer wg.mu.Unlock()
+
+	if _, ok := wg.clients[name]; ok {
+		return wgtypes.ClientConfig{}, errors.ErrWireGuardClientExists
+	}
+
+	privateKey, err := wgtypes.GeneratePrivateKey()
+	if err != nil {
+		return wgtypes.ClientConfig{}, err
+	}
+	if opts != nil && opts.PrivateKey.Valid {
+		privateKey = opts.PrivateKey.V
+	}
+
+	client = wgtypes.ClientConfig{
+		Interface: wgtypes.ClientInterface{ //nolint:exhaustruct
+			Name: name,
+			Address: net.IPNet{
+				IP:   net.IPv4(10, wg.subnet, 0, wg.next).To4(),
+				Mask: net.CIDRMask(32, 32),
+			},
+			PrivateKey: privateKey,
+		},
+		Peers: []wgtypes.ClientPeer{{ //nolint:exhaustruct
+			EndpointHost: "127.0.0.1",
+			EndpointPort: 51820,
+			PublicKey:    wg.publicKey,
+			AllowedIPs:   []net.IPNet{{IP: net.IPv4zero.To4(), Mask: net.CIDRMask(0, 32)}},
+		}},
+	}
+
+	wg.clients[name] = client
+	wg.next++
+
+	return client, nil
+}
+
+func (wg *fakeWireGuard) RemoveClient(ctx context.Context, name string) (err error) {
+	wg.mu.Lock()
+	defer wg.mu.Unlock()
+
+	if _, ok := wg.clients[name]; !ok {
+		return errors.ErrWireGuardClientNotFound
+	}
+	delete(wg.clients, name)
+
+	return nil
+}
+
+func (wg *fakeWireGuard) GetClient(ctx context.Context, name string) (client wgtypes.ClientConfig, err error) {
+	wg.mu.Lock()
+	defer wg.mu.Unlock()
+
+	client, ok := wg.clients[name]
+	if !ok {
+		return wgtypes.ClientConfig{}, errors.ErrWireGuardClientNotFound
+	}
+
+	return client, nil
+}
+
+func (wg *fakeWireGuard) GetClientInfos(ctx context.Context) (clients []entity.WireGuardClientInfo, err error) {
+	wg.mu.Lock()
+	defer wg.mu.Unlock()
+
+	for _, client := range wg.clients {
+		clients = append(clients, entity.WireGuardClientInfo{Config: client}) //nolint:exhaustruct
+	}
+
+	return clients, nil
+}
+
+func (wg *fakeWireGuard) GetServerInfo(ctx context.Context) (info entity.WireGuardServerInfo, err error) {
+	wg.mu.Lock()
+	defer wg.mu.Unlock()
+
+	return entity.WireGuardServerInfo{ //nolint:exhaustruct
+		Interface: "wg0",
+		Address:   net.IPNet{IP: net.IPv4(10, wg.subnet, 0, 1).To4(), Mask: net.CIDRMask(24, 32)},
+		PublicKey: wg.publicKey,
+		Clients:   len(wg.clients),
+	}, nil
+}
+
+func (wg *fakeWireGuard) ReloadServer(ctx context.Context) (err error) {
+	return nil
+}
+
+// instance is a server of the federation serving the protocol over plain HTTP on loopback.
+type instance struct {
+	name string
+	wg   *fakeWireGuard
+	url  string
+	fed  *federationservice.FederationService
+}
+
+// pairSecret returns the secret shared by the two servers.
+func pairSecret(a, b string) string {
+	if a > b {
+		a, b = b, a
+	}
+	return "secret-" + a + "-" + b
+}
+
+// newFederation starts linked instances with the given names.
+func newFederation(t *testing.T, names ...string) map[string]*instance {
+	t.Helper()
+
+	instances := make(map[string]*instance, len(names))
+
+	for i, name := range names {
+		wg := newFakeWireGuard(t, byte(i+1))
+
+		secrets := make(map[string]string, len(names)-1)
+		for _, other := range names {
+			if other != name {
+				secrets[other] = pairSecret(name, other)
+			}
+		}
+
+		srv := httptest.NewServer(federation.NewHandler(&federation.ServerParams{
+			Logger:     zerolog.Nop(),
+			Port:       0,
+			Name:       name,
+			Secrets:    secrets,
+			CertFile:   "",
+			KeyFile:    "",
+			Interfaces: []federation.Interface{{Name: "wg0", WireGuardService: wg}},
+		}))
+		t.Cleanup(srv.Close)
+
+		instances[name] = &instance{
+			name: name,
+			wg:   wg,
+			url:  srv.URL,
+			fed:  nil,
+		}
+	}
+
+	for _, inst := range instances {
+		var servers []federationservice.Server
+
+		for _, name := range names {
+			if name == inst.name {
+				continue
+			}
+
+			repo, err := fedrepo.New(&fedrepo.FederationRepoParams{
+				Logger:  zerolog.Nop(),
+				Name:    inst.name,
+				URL:     instances[name].url,
+				Secret:  pairSecret(inst.name, name),
+				CAFile:  "",
+				Timeout: 5 * time.Second,
+			})
+			if err != nil {
+				t.Fatalf("failed to create federation repo: %v", err)
+			}
+
+			servers = append(servers, federationservice.Server{
+				Name: name,
+				URL:  instances[name].url,
+				Repo: repo,
+			})
+		}
+
+		inst.fed = federationservice.New(&federationservice.FederationServiceParams{
+			Logger:     zerolog.Nop(),
+			Name:       inst.name,
+			Interfaces: []federationservice.Interface{{Name: "wg0", WireGuardService: inst.wg}},
+			Servers:    servers,
+		})
+	}
+
+	return instances
+}
+
+func TestFederationSyncClient(t *testing.T) {
+	ctx := context.Background()
+	instances := newFederation(t, "eu", "us", "ap")
+	eu := instances["eu"]
+
+	servers, err := eu.fed.GetServers(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range servers {
+		if servers[i].Error != "" {
+			t.Fatalf("server %s: %s", servers[i].Name, servers[i].Error)
+		}
+	}
+
+	results, err := eu.fed.SyncClient(ctx, "alice", &service.FederationOptions{}) //nolint:exhaustruct
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != len(instances) {
+		t.Fatalf("expected %d results, got %d", len(instances), len(results))
+	}
+	for i := range results {
+		if results[i].Error != "" || !results[i].Created {
+			t.Fatalf("expected alice to be created on %s, got %+v", results[i].Server, results[i])
+		}
+	}
+
+	local, err := eu.wg.GetClient(ctx, "alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Every server has the client with the same key, but an address of its own.
+	for _, inst := range instances {
+		client, err := inst.wg.GetClient(ctx, "alice")
+		if err != nil {
+			t.Fatalf("%s: %v", inst.name, err)
+		}
+		if client.Interface.PrivateKey != local.Interface.PrivateKey {
+			t.Fatalf("%s: expected the key of the local client", inst.name)
+		}
+		if client.Interface.Address.IP[1] != inst.wg.subnet {
+			t.Fatalf("%s: expected an address from its subnet, got %s", inst.name, &client.Interface.Address)
+		}
+	}
+
+	// Syncing again changes nothing.
+	results, err = eu.fed.SyncClient(ctx, "alice", &service.FederationOptions{}) //nolint:exhaustruct
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range results {
+		if results[i].Error != "" || results[i].Created {
+			t.Fatalf("expected alice to exist on %s, got %+v", results[i].Server, results[i])
+		}
+	}
+
+	clients, err := eu.fed.GetClients(ctx, &service.FederationOptions{}) //nolint:exhaustruct
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(clients) != len(instances) {
+		t.Fatalf("expected %d clients, got %d", len(instances), len(clients))
+	}
+
+	// A server with a different client of the same name refuses the sync.
+	if _, err := instances["us"].wg.AddClient(ctx, "bob", nil); err != nil {
+		t.Fatal(err)
+	}
+
+	results, err = eu.fed.SyncClient(ctx, "bob", &service.FederationOptions{Servers: []string{"us"}}) //nolint:exhaustruct
+	if err != nil {
+		t.Fatal(err)
+	}
+	if results[1].Error == "" {
+		t.Fatalf("expected key mismatch on us, got %+v", results[1])
+	}
+
+	removed, err := eu.fed.RemoveClient(ctx, "alice", &service.FederationOptions{}) //nolint:exhaustruct
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range removed {
+		if removed[i].Error != "" {
+			t.Fatalf("failed to remove alice from %s: %s", removed[i].Server, removed[i].Error)
+		}
+	}
+	for _, inst := range instances {
+		if _, err := inst.wg.GetClient(ctx, "alice"); !errors.Is(err, errors.ErrWireGuardClientNotFound) {
+			t.Fatalf("%s: expected alice to be removed, got %v", inst.name, err)
+		}
+	}
+}
+
+// signedRequest returns a request to the server signed by the named server.
+func signedRequest(t *testing.T, url, server, secret string, timestamp time.Time) *http.Request {
+	t.Helper()
+
+	req, err := http.NewRequest(http.MethodGet, url+"/federation/v1/info", http.NoBody)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ts := strconv.FormatInt(timestamp.Unix(), 10)
+	nonce := federation.NewNonce()
+	req.Header.Set(federation.HeaderServer, server)
+	req.Header.Set(federation.HeaderTimestamp, ts)
+	req.Header.Set(federation.HeaderNonce, nonce)
+	req.Header.Set(federation.HeaderSignature,
+		federation.Sign([]byte(secret), ts, nonce, req.Method, req.URL.RequestURI(), nil))
+
+	return req
+}
+
+func expectStatus(t *testing.T, req *http.Request, status int) {
+	t.Helper()
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != status {
+		t.Fatalf("expected status %d, got %d", status, resp.StatusCode)
+	}
+}
+
+func TestFederationAuthentication(t *testing.T) {
+	instances := newFederation(t, "eu", "us")
+	us := instances["us"]
+	secret := pairSecret("eu", "us")
+
+	req := signedRequest(t, us.url, "eu", secret, time.Now())
+	expectStatus(t, req, http.StatusOK)
+
+	// The same request can't be replayed while its timestamp is valid.
+	replay := req.Clone(context.Background())
+	expectStatus(t, replay, http.StatusUnauthorized)
+
+	tests := []struct {
+		name string
+		req  *http.Request
+	}{
+		{"WrongSecret", signedRequest(t, us.url, "eu", "wrong", time.Now())},
+		{"UnknownServer", signedRequest(t, us.url, "ap", secret, time.Now())},
+		{"Expired", signedRequest(t, us.url, "eu", secret, time.Now().Add(-federation.MaxClockSkew-time.Minute))},
+		{"Future", signedRequest(t, us.url, "eu", secret, time.Now().Add(federation.MaxClockSkew+time.Minute))},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			expectStatus(t, tt.req, http.StatusUnauthorized)
+		})
+	}
+
+	t.Run("MissingNonce", func(t *testing.T) {
+		req := signedRequest(t, us.url, "eu", secret, time.Now())
+		req.Header.Del(federation.HeaderNonce)
+		expectStatus(t, req, http.StatusUnauthorized)
+	})
+}
+
+func TestFederationRequiresTLS(t *testing.T) {
+	tests := []struct {
+		url string
+		ok  bool
+	}{
+		{"https://us.example.com:51824", true},
+		{"http://127.0.0.1:51824", true},
+		{"http://[::1]:51824", true},
+		{"http://localhost:51824", true},
+		{"http://us.example.com:51824", false},
+		{"http://10.0.0.1:51824", false},
+		{"ftp://127.0.0.1:51824", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			_, err := fedrepo.New(&fedrepo.FederationRepoParams{
+				Logger:  zerolog.Nop(),
+				Name:    "eu",
+				URL:     tt.url,
+				Secret:  "secret",
+				CAFile:  "",
+				Timeout: time.Second,
+			})
+			if (err == nil) != tt.ok {
+				t.Fatalf("expected ok=%v, got %v", tt.ok, err)
+			}
+		})
+	}
+}
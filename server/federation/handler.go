@@ -0,0 +1,324 @@
+package federation
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/guregu/null/v5"
+	"github.com/infastin/wg-wish/pkg/wgtypes"
+	"github.com/infastin/wg-wish/server/errors"
+	"github.com/infastin/wg-wish/server/service"
+	"github.com/rs/zerolog"
+)
+
+// maxBodySize limits the size of request bodies, which are read whole to verify their signature.
+const maxBodySize = 1 << 20
+
+type handlerFunc func(w http.ResponseWriter, r *http.Request) error
+
+type handler struct {
+	lg         zerolog.Logger
+	name       string
+	secrets    map[string][]byte
+	interfaces []Interface
+	nonces     nonceCache
+	now        func() time.Time
+}
+
+var (
+	errUnauthorized = &httpError{status: http.StatusUnauthorized, msg: "unauthorized"}
+	errTooLarge     = &httpError{status: http.StatusRequestEntityTooLarge, msg: "request body is too large"}
+)
+
+type httpError struct {
+	status int
+	msg    string
+}
+
+func badRequest(err error) error {
+	return &httpError{
+		status: http.StatusBadRequest,
+		msg:    err.Error(),
+	}
+}
+
+func (e *httpError) Error() string {
+	return e.msg
+}
+
+var domainErrorStatus = map[error]int{
+	errors.ErrWireGuardClientExists:          http.StatusConflict,
+	errors.ErrWireGuardClientNotFound:        http.StatusNotFound,
+	errors.ErrWireGuardClientAddressExists:   http.StatusConflict,
+	errors.ErrWireGuardClientPublicKeyExists: http.StatusConflict,
+	errors.ErrWireGuardInterfaceNotFound:     http.StatusNotFound,
+	errors.ErrFederationKeyMismatch:          http.StatusConflict,
+}
+
+// wrap authenticates the request with the secret of the server named in HeaderServer
+// and logs it along with the error returned by the handler.
+func (h *handler) wrap(fn handlerFunc) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ct := time.Now()
+		server := r.Header.Get(HeaderServer)
+
+		err := h.authenticate(r, server)
+		if err == nil {
+			err = fn(w, r)
+		}
+
+		lg := h.lg.With().
+			Str("method", r.Method).
+			Str("path", r.URL.Path).
+			Str("addr", r.RemoteAddr).
+			Str("server", server).
+			Dur("elapsed", time.Since(ct)).
+			Logger()
+
+		if err != nil {
+			writeError(w, err)
+			if ie, ok := err.(errors.InternalError); ok {
+				lg.Err(ie.Internal()).Msg("request error")
+			} else {
+				lg.Err(err).Msg("request error")
+			}
+			return
+		}
+
+		lg.Info().Msg("request ok")
+	})
+}
+
+func (h *handler) authenticate(r *http.Request, server string) error {
+	secret, ok := h.secrets[server]
+	if !ok {
+		return errUnauthorized
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(nil, r.Body, maxBodySize))
+	if err != nil {
+		return errTooLarge
+	}
+	r.Body = io.NopCloser(bytes.NewReader(body))
+
+	now := h.now()
+	nonce := r.Header.Get(HeaderNonce)
+
+	expiresAt, ok := verify(secret, r.Header.Get(HeaderTimestamp), nonce, r.Method, r.URL.RequestURI(), body,
+		r.Header.Get(HeaderSignature), now)
+	if !ok || !h.nonces.use(server, nonce, expiresAt, now) {
+		return errUnauthorized
+	}
+
+	return nil
+}
+
+func writeError(w http.ResponseWriter, err error) {
+	switch e := err.(type) {
+	case *httpError:
+		writeJSON(w, e.status, &Error{Error: e.msg})
+	case errors.DomainError:
+		status, ok := domainErrorStatus[err]
+		if !ok {
+			status = http.StatusUnprocessableEntity
+		}
+		writeJSON(w, status, &Error{Error: e.Error(), Domain: e.Domain()})
+	default:
+		writeJSON(w, http.StatusInternalServerError, &Error{Error: "internal error"})
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+// wireguardService returns the service of the interface selected
+// with the iface query parameter, or of the default interface.
+func (h *handler) wireguardService(r *http.Request) (service.WireGuardService, error) {
+	name := r.URL.Query().Get("iface")
+	if name == "" {
+		return h.interfaces[0].WireGuardService, nil
+	}
+
+	for i := range h.interfaces {
+		if h.interfaces[i].Name == name {
+			return h.interfaces[i].WireGuardService, nil
+		}
+	}
+
+	return nil, errors.ErrWireGuardInterfaceNotFound
+}
+
+func (h *handler) getInfo(w http.ResponseWriter, r *http.Request) (err error) {
+	info := Info{
+		Name:       h.name,
+		Interfaces: make([]InfoInterface, len(h.interfaces)),
+	}
+
+	for i := range h.interfaces {
+		serverInfo, err := h.interfaces[i].WireGuardService.GetServerInfo(r.Context())
+		if err != nil {
+			return err
+		}
+
+		info.Interfaces[i] = InfoInterface{
+			Name:      h.interfaces[i].Name,
+			Address:   serverInfo.Address.String(),
+			PublicKey: serverInfo.PublicKey.String(),
+			Clients:   serverInfo.Clients,
+		}
+	}
+
+	writeJSON(w, http.StatusOK, &info)
+	return nil
+}
+
+func (h *handler) reload(w http.ResponseWriter, r *http.Request) (err error) {
+	wireguardService, err := h.wireguardService(r)
+	if err != nil {
+		return err
+	}
+
+	if err := wireguardService.ReloadServer(r.Context()); err != nil {
+		return err
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
+
+func (h *handler) listPeers(w http.ResponseWriter, r *http.Request) (err error) {
+	wireguardService, err := h.wireguardService(r)
+	if err != nil {
+		return err
+	}
+
+	infos, err := wireguardService.GetClientInfos(r.Context())
+	if err != nil {
+		return err
+	}
+
+	peers := make([]Peer, len(infos))
+	for i := range infos {
+		peers[i] = newPeer(&infos[i].Config)
+		peers[i].Disabled = infos[i].Disabled
+		if infos[i].Stats.Valid {
+			peers[i].LatestHandshake = infos[i].Stats.V.LatestHandshake.Ptr()
+		}
+	}
+
+	writeJSON(w, http.StatusOK, peers)
+	return nil
+}
+
+// syncPeer adds the client with the given key unless the server already has it.
+func (h *handler) syncPeer(w http.ResponseWriter, r *http.Request) (err error) {
+	wireguardService, err := h.wireguardService(r)
+	if err != nil {
+		return err
+	}
+
+	var req SyncPeerRequest
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&req); err != nil {
+		return badRequest(fmt.Errorf("failed to decode request body: %w", err))
+	}
+
+	key, err := wgtypes.ParseKey(req.PrivateKey)
+	if err != nil {
+		return badRequest(err)
+	}
+
+	name := r.PathValue("name")
+	status := http.StatusOK
+
+	cfg, err := wireguardService.GetClient(r.Context(), name)
+	switch {
+	case err == nil:
+		if cfg.Interface.PrivateKey != key {
+			return errors.ErrFederationKeyMismatch
+		}
+	case errors.Is(err, errors.ErrWireGuardClientNotFound):
+		cfg, err = wireguardService.AddClient(r.Context(), name, &service.AddClientOptions{ //nolint:exhaustruct
+			PrivateKey: null.ValueFrom(key),
+		})
+		if err != nil {
+			return err
+		}
+		status = http.StatusCreated
+	default:
+		return err
+	}
+
+	p, err := newPeerWithConfig(&cfg)
+	if err != nil {
+		return err
+	}
+
+	writeJSON(w, status, &p)
+	return nil
+}
+
+func (h *handler) getPeer(w http.ResponseWriter, r *http.Request) (err error) {
+	wireguardService, err := h.wireguardService(r)
+	if err != nil {
+		return err
+	}
+
+	cfg, err := wireguardService.GetClient(r.Context(), r.PathValue("name"))
+	if err != nil {
+		return err
+	}
+
+	p, err := newPeerWithConfig(&cfg)
+	if err != nil {
+		return err
+	}
+
+	writeJSON(w, http.StatusOK, &p)
+	return nil
+}
+
+func (h *handler) removePeer(w http.ResponseWriter, r *http.Request) (err error) {
+	wireguardService, err := h.wireguardService(r)
+	if err != nil {
+		return err
+	}
+
+	if err := wireguardService.RemoveClient(r.Context(), r.PathValue("name")); err != nil {
+		return err
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
+
+func newPeer(cfg *wgtypes.ClientConfig) Peer {
+	return Peer{
+		Name:            cfg.Interface.Name,
+		Address:         cfg.Interface.Address.String(),
+		PublicKey:       cfg.Interface.PrivateKey.PublicKey().String(),
+		Disabled:        false,
+		LatestHandshake: nil,
+		Config:          "",
+	}
+}
+
+func newPeerWithConfig(cfg *wgtypes.ClientConfig) (p Peer, err error) {
+	var conf bytes.Buffer
+	if err := cfg.Encode(&conf); err != nil {
+		return Peer{}, err
+	}
+
+	p = newPeer(cfg)
+	p.Config = conf.String()
+
+	return p, nil
+}
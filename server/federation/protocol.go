@@ -0,0 +1,142 @@
+package federation
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// HeaderServer contains the name of the server making the request,
+	// which selects the secret the request is signed with.
+	HeaderServer    = "X-Wg-Wish-Server"
+	HeaderTimestamp = "X-Wg-Wish-Timestamp"
+	// HeaderNonce contains a random value unique to the request,
+	// so that a captured request can't be replayed.
+	HeaderNonce = "X-Wg-Wish-Nonce"
+	// HeaderSignature contains "sha256=" followed by hex-encoded HMAC-SHA256
+	// of the timestamp, the nonce, the method, the request URI and the body separated by newlines,
+	// keyed with the secret shared by the two servers.
+	HeaderSignature = "X-Wg-Wish-Signature"
+)
+
+// MaxClockSkew is how far the timestamp of a request may be from the current time.
+const MaxClockSkew = 5 * time.Minute
+
+// maxNonceLen limits the length of nonces, which are kept until their requests expire.
+const maxNonceLen = 64
+
+// NewNonce returns a random value for HeaderNonce.
+func NewNonce() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// Sign returns the value of the signature header for the request.
+func Sign(secret []byte, timestamp, nonce, method, uri string, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("\n"))
+	mac.Write([]byte(nonce))
+	mac.Write([]byte("\n"))
+	mac.Write([]byte(method))
+	mac.Write([]byte("\n"))
+	mac.Write([]byte(uri))
+	mac.Write([]byte("\n"))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// verify reports whether the signature is valid and the timestamp is within MaxClockSkew of now,
+// returning the time after which the request can no longer be replayed.
+func verify(secret []byte, timestamp, nonce, method, uri string, body []byte, signature string, now time.Time,
+) (expiresAt time.Time, ok bool) {
+	unix, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return time.Time{}, false
+	}
+
+	signedAt := time.Unix(unix, 0)
+
+	skew := now.Sub(signedAt)
+	if skew > MaxClockSkew || skew < -MaxClockSkew {
+		return time.Time{}, false
+	}
+
+	if nonce == "" || len(nonce) > maxNonceLen {
+		return time.Time{}, false
+	}
+
+	expected := Sign(secret, timestamp, nonce, method, uri, body)
+	if !hmac.Equal([]byte(expected), []byte(strings.TrimSpace(signature))) {
+		return time.Time{}, false
+	}
+
+	return signedAt.Add(MaxClockSkew), true
+}
+
+// nonceCache remembers the nonces of the requests, whose timestamps are still valid.
+type nonceCache struct {
+	mu     sync.Mutex
+	nonces map[string]time.Time
+}
+
+// use reports whether the nonce hasn't been seen before, remembering it until expiresAt.
+func (c *nonceCache) use(server, nonce string, expiresAt, now time.Time) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for key, exp := range c.nonces {
+		if !now.Before(exp) {
+			delete(c.nonces, key)
+		}
+	}
+
+	key := server + "\n" + nonce
+	if _, ok := c.nonces[key]; ok {
+		return false
+	}
+	c.nonces[key] = expiresAt
+
+	return true
+}
+
+// Info describes a server and its interfaces.
+type Info struct {
+	Name       string          `json:"name"`
+	Interfaces []InfoInterface `json:"interfaces"`
+}
+
+type InfoInterface struct {
+	Name      string `json:"name"`
+	Address   string `json:"address"`
+	PublicKey string `json:"public_key"`
+	Clients   int    `json:"clients"`
+}
+
+// Peer is a client of a server. Config is only set for a single client
+// and contains its wg-quick config, including the private key.
+type Peer struct {
+	Name            string     `json:"name"`
+	Address         string     `json:"address"`
+	PublicKey       string     `json:"public_key"`
+	Disabled        bool       `json:"disabled"`
+	LatestHandshake *time.Time `json:"latest_handshake,omitempty"`
+	Config          string     `json:"config,omitempty"`
+}
+
+// SyncPeerRequest asks the server to make sure it has the client with the given key.
+type SyncPeerRequest struct {
+	PrivateKey string `json:"private_key"`
+}
+
+type Error struct {
+	Error  string `json:"error"`
+	Domain string `json:"domain,omitempty"`
+}
@@ -0,0 +1,124 @@
+package federation
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/infastin/wg-wish/server/service"
+	"github.com/rs/zerolog"
+)
+
+type Server struct {
+	lg     zerolog.Logger
+	server *http.Server
+}
+
+// Interface holds the service bound to a single WireGuard interface.
+type Interface struct {
+	Name             string
+	WireGuardService service.WireGuardService
+}
+
+type ServerParams struct {
+	Logger zerolog.Logger
+	Port   int
+	// Name is the name of the local server.
+	Name string
+	// Secrets maps the names of the linked servers to the secrets their requests are signed with.
+	Secrets map[string]string
+	// Without CertFile the server only listens on loopback,
+	// since the configs it sends contain private keys.
+	CertFile string
+	KeyFile  string
+	// Interfaces must contain at least one interface, the first one is used by default.
+	Interfaces []Interface
+}
+
+// New returns the server of the peer-sync protocol, through which
+// the linked servers list, add and remove clients of the local one.
+func New(params *ServerParams) (srv *Server, err error) {
+	host := "0.0.0.0"
+	if params.CertFile == "" {
+		host = "127.0.0.1"
+		params.Logger.Warn().Msg("federation: TLS is not configured, listening on loopback only")
+	}
+
+	srv = &Server{
+		lg: params.Logger,
+		server: &http.Server{ //nolint:exhaustruct
+			Addr:              net.JoinHostPort(host, strconv.Itoa(params.Port)),
+			Handler:           NewHandler(params),
+			ReadHeaderTimeout: 10 * time.Second,
+		},
+	}
+
+	if params.CertFile != "" {
+		cert, err := tls.LoadX509KeyPair(params.CertFile, params.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("federation: failed to load certificate: %w", err)
+		}
+
+		srv.server.TLSConfig = &tls.Config{ //nolint:exhaustruct
+			MinVersion:   tls.VersionTLS12,
+			Certificates: []tls.Certificate{cert},
+		}
+	}
+
+	return srv, nil
+}
+
+// NewHandler returns the handler of the peer-sync protocol served by Server.
+// Port, CertFile and KeyFile of the params are ignored.
+func NewHandler(params *ServerParams) http.Handler {
+	h := &handler{
+		lg:         params.Logger,
+		name:       params.Name,
+		secrets:    make(map[string][]byte, len(params.Secrets)),
+		interfaces: params.Interfaces,
+		nonces: nonceCache{
+			mu:     sync.Mutex{},
+			nonces: make(map[string]time.Time),
+		},
+		now: time.Now,
+	}
+
+	for name, secret := range params.Secrets {
+		h.secrets[name] = []byte(secret)
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("GET /federation/v1/info", h.wrap(h.getInfo))
+	mux.Handle("POST /federation/v1/reload", h.wrap(h.reload))
+	mux.Handle("GET /federation/v1/peers", h.wrap(h.listPeers))
+	mux.Handle("PUT /federation/v1/peers/{name}", h.wrap(h.syncPeer))
+	mux.Handle("GET /federation/v1/peers/{name}", h.wrap(h.getPeer))
+	mux.Handle("DELETE /federation/v1/peers/{name}", h.wrap(h.removePeer))
+
+	return mux
+}
+
+func (s *Server) Run() error {
+	var err error
+	if s.server.TLSConfig != nil {
+		err = s.server.ListenAndServeTLS("", "")
+	} else {
+		err = s.server.ListenAndServe()
+	}
+	if err != nil {
+		return fmt.Errorf("federation: failed to serve: %w", err)
+	}
+	return nil
+}
+
+func (s *Server) Shutdown(ctx context.Context) error {
+	if err := s.server.Shutdown(ctx); err != nil {
+		return fmt.Errorf("federation: failed to shutdown server: %w", err)
+	}
+	return nil
+}
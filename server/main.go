@@ -4,20 +4,41 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"net/http"
 	"os"
 	"path"
 	"syscall"
 
 	charmssh "github.com/charmbracelet/ssh"
 	"github.com/guregu/null/v5"
+	"github.com/infastin/wg-wish/pkg/envelope"
 	"github.com/infastin/wg-wish/pkg/netutils"
+	"github.com/infastin/wg-wish/server/api"
 	"github.com/infastin/wg-wish/server/app"
+	"github.com/infastin/wg-wish/server/cluster"
 	"github.com/infastin/wg-wish/server/errors"
+	"github.com/infastin/wg-wish/server/event"
+	"github.com/infastin/wg-wish/server/federation"
+	"github.com/infastin/wg-wish/server/metrics"
+	"github.com/infastin/wg-wish/server/repo/db"
 	dbrepo "github.com/infastin/wg-wish/server/repo/db/impl"
+	sqlrepo "github.com/infastin/wg-wish/server/repo/db/sqlimpl"
+	fedrepo "github.com/infastin/wg-wish/server/repo/federation/impl"
+	"github.com/infastin/wg-wish/server/repo/firewall"
+	fwrepo "github.com/infastin/wg-wish/server/repo/firewall/impl"
+	"github.com/infastin/wg-wish/server/repo/stats"
+	statsrepo "github.com/infastin/wg-wish/server/repo/stats/impl"
 	wgrepo "github.com/infastin/wg-wish/server/repo/wg/impl"
+	"github.com/infastin/wg-wish/server/service"
+	adminservice "github.com/infastin/wg-wish/server/service/impl/admin"
+	federationservice "github.com/infastin/wg-wish/server/service/impl/federation"
+	firewallservice "github.com/infastin/wg-wish/server/service/impl/firewall"
+	profileservice "github.com/infastin/wg-wish/server/service/impl/profile"
 	publickeyservice "github.com/infastin/wg-wish/server/service/impl/publickey"
+	statsservice "github.com/infastin/wg-wish/server/service/impl/stats"
 	wgservice "github.com/infastin/wg-wish/server/service/impl/wg"
 	"github.com/infastin/wg-wish/server/ssh"
+	"github.com/infastin/wg-wish/server/webhook"
 	"github.com/oklog/run"
 	"github.com/rs/zerolog"
 	"gopkg.in/natefinch/lumberjack.v2"
@@ -64,12 +85,35 @@ func runApp(args []string) (err error) {
 		return err
 	}
 
-	dbRepo, err := dbrepo.New(
-		&dbrepo.DatabaseRepoParams{
-			Logger:    logger.With().Str("tag", "db_repo").Logger(),
-			Path:      config.Database.Path,
-			AdminKeys: config.SSH.AdminKeys,
-		})
+	if cli.Command == "db migrate" && config.Database.Driver == app.DatabaseDriverBolt {
+		return errors.New("database driver must be sqlite or postgres to migrate data from bbolt")
+	}
+
+	if cli.Command == "run" && config.Cluster.Role == app.ClusterRoleStandby {
+		promoted, err := cluster.IsPromoted(config.Database.Path)
+		if err != nil {
+			return fmt.Errorf("failed to check whether standby was promoted: %w", err)
+		}
+
+		if promoted {
+			logger.Warn().Msg("standby has been promoted, running as the primary")
+		} else if promoted, err = runStandby(&config, logger); err != nil || !promoted {
+			return err
+		}
+	}
+
+	kms, err := app.NewKMS(&config.Database.Encryption)
+	if err != nil {
+		return fmt.Errorf("failed to load master key: %w", err)
+	}
+
+	interfaces := make([]string, 0, len(config.WireGuard.Interfaces)+1)
+	for _, iface := range config.WireGuard.AllInterfaces() {
+		interfaces = append(interfaces, iface.Name)
+	}
+
+	dbRepo, err := newDatabaseRepo(&config.Database, config.SSH.AdminKeys, interfaces, kms,
+		logger.With().Str("tag", "db_repo").Logger())
 	if err != nil {
 		return err
 	}
@@ -79,64 +123,289 @@ func runApp(args []string) (err error) {
 		}
 	}()
 
-	wgRepo := wgrepo.New(
-		&wgrepo.WireGuardRepoParams{
-			Logger: logger.With().Str("tag", "wg_repo").Logger(),
-			Path:   config.WireGuard.Path,
-		})
+	ctx := context.Background()
+
+	switch cli.Command {
+	case "db encrypt":
+		return encryptDatabase(ctx, logger, dbRepo)
+	case "db migrate":
+		return migrateDatabase(ctx, logger, dbRepo, cli.DB.Migrate.From, kms)
+	}
+
+	events := event.NewBus()
+
+	var dispatcher *webhook.Dispatcher
+	if len(config.Webhooks.Endpoints) != 0 {
+		endpoints := make([]webhook.Endpoint, len(config.Webhooks.Endpoints))
+		for i, endpoint := range config.Webhooks.Endpoints {
+			endpoints[i] = webhook.Endpoint{
+				URL:    endpoint.URL,
+				Secret: endpoint.Secret,
+				Events: endpoint.Events,
+			}
+		}
+
+		dispatcher, err = webhook.NewDispatcher(
+			&webhook.DispatcherParams{
+				Logger:      logger.With().Str("tag", "webhook").Logger(),
+				Bus:         events,
+				OutboxPath:  config.Webhooks.OutboxPath,
+				Endpoints:   endpoints,
+				MaxAttempts: config.Webhooks.MaxAttempts,
+				MinBackoff:  config.Webhooks.MinBackoff,
+				MaxBackoff:  config.Webhooks.MaxBackoff,
+				Timeout:     config.Webhooks.Timeout,
+			})
+		if err != nil {
+			return err
+		}
+		defer func() {
+			if err := dispatcher.Close(); err != nil {
+				logger.Err(err).Msg("failed to close webhook dispatcher")
+			}
+		}()
+	}
 
 	pubKeyService := publickeyservice.New(
 		&publickeyservice.PublicKeyServiceParams{
 			Logger: logger.With().Str("tag", "pubkey_service").Logger(),
 			Repo:   dbRepo,
+			Events: events,
 		})
 
-	dns, err := netutils.ParseIPs(config.WireGuard.DNS)
-	if err != nil {
-		return err
+	var metricsCollector *metrics.Metrics
+	if config.Metrics.Enabled {
+		metricsCollector = metrics.New()
 	}
 
-	ips, err := netutils.ParseAddresses(config.WireGuard.AllowedIPs)
-	if err != nil {
-		return err
+	ifaceConfigs := config.WireGuard.AllInterfaces()
+
+	fwIfaces := make([]firewallservice.FirewallInterfaceParams, len(ifaceConfigs))
+	for i := range ifaceConfigs {
+		fwIfaces[i] = firewallservice.FirewallInterfaceParams{
+			Name:    ifaceConfigs[i].Name,
+			Address: ifaceConfigs[i].Address,
+			Port:    ifaceConfigs[i].Port,
+		}
 	}
 
-	wireguardService, err := wgservice.New(
-		&wgservice.WireGuardServiceParams{
-			Logger:              logger.With().Str("tag", "wg_service").Logger(),
-			DatabaseRepo:        dbRepo,
-			WireGuardRepo:       wgRepo,
-			Host:                config.WireGuard.Host,
-			Address:             config.WireGuard.Address,
-			Port:                config.WireGuard.Port,
-			Device:              config.WireGuard.Device,
-			DNS:                 dns,
-			AllowedIPs:          ips,
-			PersistentKeepalive: null.IntFrom(int64(config.WireGuard.PersistentKeepalive)),
+	fwRepo := newFirewallRepo(config.WireGuard.Firewall, logger.With().Str("tag", "fw_repo").Logger())
+
+	firewallService, err := firewallservice.New(
+		&firewallservice.FirewallServiceParams{
+			Logger:       logger.With().Str("tag", "firewall_service").Logger(),
+			DatabaseRepo: dbRepo,
+			FirewallRepo: fwRepo,
+			Device:       config.WireGuard.Device,
+			Interfaces:   fwIfaces,
 		})
 	if err != nil {
 		return err
 	}
 
-	ctx := context.Background()
+	var statsRepo stats.Repo
+	if config.Stats.Enabled {
+		repo, err := statsrepo.New(
+			&statsrepo.StatsRepoParams{
+				Logger: logger.With().Str("tag", "stats_repo").Logger(),
+				Path:   config.Stats.Path,
+			})
+		if err != nil {
+			return err
+		}
+		defer func() {
+			if err := repo.Close(); err != nil {
+				logger.Err(err).Msg("failed to close stats repo")
+			}
+		}()
+		statsRepo = repo
+	}
 
-	err = wireguardService.StartServer(ctx)
-	if err != nil {
-		return err
+	ifaces := make([]ssh.Interface, 0, len(ifaceConfigs))
+	wireguardServices := make([]service.WireGuardService, 0, len(ifaceConfigs))
+	quotaEnforcers := make([]*wgservice.WireGuardService, 0, len(ifaceConfigs))
+	statsSamplers := make([]*statsservice.StatsService, 0, len(ifaceConfigs))
+
+	for i := range ifaceConfigs {
+		ifaceConfig := &ifaceConfigs[i]
+		ifaceLogger := logger.With().Str("interface", ifaceConfig.Name).Logger()
+
+		dns, err := netutils.ParseIPs(ifaceConfig.DNS)
+		if err != nil {
+			return err
+		}
+
+		ips, err := netutils.ParseAddresses(ifaceConfig.AllowedIPs)
+		if err != nil {
+			return err
+		}
+
+		wgRepo := wgrepo.New(
+			&wgrepo.WireGuardRepoParams{
+				Logger:    ifaceLogger.With().Str("tag", "wg_repo").Logger(),
+				Interface: ifaceConfig.Name,
+				Path:      ifaceConfig.Path,
+			})
+
+		ifaceRepo := dbRepo.Interface(ifaceConfig.Name)
+
+		wireguardService, err := wgservice.New(
+			&wgservice.WireGuardServiceParams{
+				Logger:              ifaceLogger.With().Str("tag", "wg_service").Logger(),
+				DatabaseRepo:        ifaceRepo,
+				WireGuardRepo:       wgRepo,
+				Metrics:             metricsCollector,
+				Events:              events,
+				Firewall:            firewallService,
+				Interface:           ifaceConfig.Name,
+				Host:                config.WireGuard.Host,
+				Address:             ifaceConfig.Address,
+				Port:                ifaceConfig.Port,
+				DNS:                 dns,
+				AllowedIPs:          ips,
+				PersistentKeepalive: null.IntFrom(int64(ifaceConfig.PersistentKeepalive)),
+				QuotaInterval:       config.WireGuard.QuotaInterval,
+				MTU:                 null.NewInt(int64(ifaceConfig.MTU), ifaceConfig.MTU != 0),
+				Table:               null.NewString(ifaceConfig.Table, ifaceConfig.Table != ""),
+				FwMark:              null.NewInt(int64(ifaceConfig.FwMark), ifaceConfig.FwMark != 0),
+				SaveConfig:          ifaceConfig.SaveConfig,
+				PreUp:               ifaceConfig.PreUp,
+				PostUp:              ifaceConfig.PostUp,
+				PreDown:             ifaceConfig.PreDown,
+				PostDown:            ifaceConfig.PostDown,
+			})
+		if err != nil {
+			return err
+		}
+
+		err = wireguardService.StartServer(ctx)
+		if err != nil {
+			return err
+		}
+		defer func() {
+			if err := wireguardService.StopServer(ctx); err != nil {
+				ifaceLogger.Err(err).Msg("failed to stop wireguard server")
+			}
+		}()
+
+		statsService := statsservice.New(
+			&statsservice.StatsServiceParams{
+				Logger:           ifaceLogger.With().Str("tag", "stats_service").Logger(),
+				StatsRepo:        statsRepo,
+				WireGuardRepo:    wgRepo,
+				WireGuardService: wireguardService,
+				Interval:         config.Stats.SampleInterval,
+			})
+
+		adminService := adminservice.New(
+			&adminservice.AdminServiceParams{
+				Logger:           ifaceLogger.With().Str("tag", "admin_service").Logger(),
+				DatabaseRepo:     ifaceRepo,
+				WireGuardService: wireguardService,
+				Events:           events,
+				Interface:        ifaceConfig.Name,
+			})
+
+		ifaces = append(ifaces, ssh.Interface{
+			Name:             ifaceConfig.Name,
+			AdminService:     adminService,
+			WireGuardService: wireguardService,
+			StatsService:     statsService,
+		})
+		wireguardServices = append(wireguardServices, wireguardService)
+		quotaEnforcers = append(quotaEnforcers, wireguardService)
+		statsSamplers = append(statsSamplers, statsService)
 	}
-	defer func() {
-		if err := wireguardService.StopServer(ctx); err != nil {
-			logger.Err(err).Msg("failed to stop wireguard server")
+
+	profileService := profileservice.New(
+		&profileservice.ProfileServiceParams{
+			Logger:            logger.With().Str("tag", "profile_service").Logger(),
+			DatabaseRepo:      dbRepo,
+			WireGuardServices: wireguardServices,
+		})
+
+	var federationService service.FederationService
+	if config.Federation.Enabled {
+		fedIfaces := make([]federationservice.Interface, len(ifaces))
+		for i := range ifaces {
+			fedIfaces[i] = federationservice.Interface{
+				Name:             ifaces[i].Name,
+				WireGuardService: ifaces[i].WireGuardService,
+			}
 		}
-	}()
+
+		servers := make([]federationservice.Server, len(config.Federation.Servers))
+		for i := range config.Federation.Servers {
+			serverConfig := &config.Federation.Servers[i]
+
+			repo, err := fedrepo.New(
+				&fedrepo.FederationRepoParams{
+					Logger:  logger.With().Str("tag", "fed_repo").Str("server", serverConfig.Name).Logger(),
+					Name:    config.Federation.Name,
+					URL:     serverConfig.URL,
+					Secret:  serverConfig.Secret,
+					CAFile:  serverConfig.CAFile,
+					Timeout: config.Federation.Timeout,
+				})
+			if err != nil {
+				return err
+			}
+
+			servers[i] = federationservice.Server{
+				Name: serverConfig.Name,
+				URL:  serverConfig.URL,
+				Repo: repo,
+			}
+		}
+
+		federationService = federationservice.New(
+			&federationservice.FederationServiceParams{
+				Logger:     logger.With().Str("tag", "federation_service").Logger(),
+				Name:       config.Federation.Name,
+				Interfaces: fedIfaces,
+				Servers:    servers,
+			})
+	}
+
+	var (
+		clusterPrimary *cluster.Primary
+		clusterService service.ClusterService
+	)
+	if config.Cluster.Role != "" {
+		clusterDB, ok := dbRepo.(cluster.Database)
+		if !ok {
+			return errors.New("replication requires the bbolt database driver")
+		}
+
+		clusterPrimary, err = cluster.NewPrimary(
+			&cluster.PrimaryParams{
+				Logger:   logger.With().Str("tag", "cluster").Logger(),
+				Port:     config.Cluster.Port,
+				Secret:   config.Cluster.Secret,
+				CertFile: config.Cluster.TLS.CertFile,
+				KeyFile:  config.Cluster.TLS.KeyFile,
+				Insecure: config.Cluster.Insecure,
+				Database: clusterDB,
+			})
+		if err != nil {
+			return err
+		}
+		clusterService = clusterPrimary
+	}
 
 	sshSrv, err := ssh.New(
 		&ssh.ServerParams{
-			Logger:           logger.With().Str("tag", "ssh").Logger(),
-			Port:             config.SSH.Port,
-			HostKeyPath:      config.SSH.HostKeyPath,
-			PublicKeyService: pubKeyService,
-			WireGuardService: wireguardService,
+			Logger:            logger.With().Str("tag", "ssh").Logger(),
+			Port:              config.SSH.Port,
+			HostKeyPath:       config.SSH.HostKeyPath,
+			Interfaces:        ifaces,
+			PublicKeyService:  pubKeyService,
+			FirewallService:   firewallService,
+			ProfileService:    profileService,
+			FederationService: federationService,
+			ClusterService:    clusterService,
+			Metrics:           metricsCollector,
+			Events:            events,
 		})
 	if err != nil {
 		return err
@@ -160,6 +429,184 @@ func runApp(args []string) (err error) {
 		}
 	})
 
+	for i := range ifaces {
+		iface := &ifaces[i]
+
+		quotasCtx, cancelQuotas := context.WithCancel(ctx)
+		g.Add(func() error {
+			logger.Info().Str("interface", iface.Name).Msg("starting quota enforcer")
+			return quotaEnforcers[i].RunQuotas(quotasCtx)
+		}, func(err error) {
+			logger.Info().Str("interface", iface.Name).Msg("shutting down quota enforcer")
+			cancelQuotas()
+		})
+
+		if statsRepo != nil {
+			statsCtx, cancelStats := context.WithCancel(ctx)
+			g.Add(func() error {
+				logger.Info().Str("interface", iface.Name).Msg("starting stats sampler")
+				return statsSamplers[i].Run(statsCtx)
+			}, func(err error) {
+				logger.Info().Str("interface", iface.Name).Msg("shutting down stats sampler")
+				cancelStats()
+			})
+		}
+	}
+
+	if dispatcher != nil {
+		for i := range ifaces {
+			watcher := event.NewPeerWatcher(
+				&event.PeerWatcherParams{
+					Logger:           logger.With().Str("tag", "peer_watcher").Str("interface", ifaces[i].Name).Logger(),
+					Bus:              events,
+					WireGuardService: ifaces[i].WireGuardService,
+					Interface:        ifaces[i].Name,
+					Interval:         config.Webhooks.PeerPollInterval,
+					StaleAfter:       config.Webhooks.HandshakeStaleAfter,
+				})
+
+			watcherCtx, cancelWatcher := context.WithCancel(ctx)
+			g.Add(func() error {
+				return watcher.Run(watcherCtx)
+			}, func(err error) {
+				cancelWatcher()
+			})
+		}
+
+		dispatcherCtx, cancelDispatcher := context.WithCancel(ctx)
+		g.Add(func() error {
+			logger.Info().Msg("starting webhook dispatcher")
+			return dispatcher.Run(dispatcherCtx)
+		}, func(err error) {
+			logger.Info().Msg("shutting down webhook dispatcher")
+			cancelDispatcher()
+		})
+	}
+
+	if config.API.Enabled {
+		apiIfaces := make([]api.Interface, len(ifaces))
+		for i := range ifaces {
+			apiIfaces[i] = api.Interface{
+				Name:             ifaces[i].Name,
+				WireGuardService: ifaces[i].WireGuardService,
+			}
+		}
+
+		apiSrv, err := api.New(
+			&api.ServerParams{
+				Logger:           logger.With().Str("tag", "api").Logger(),
+				Port:             config.API.Port,
+				Tokens:           config.API.Tokens,
+				CertFile:         config.API.TLS.CertFile,
+				KeyFile:          config.API.TLS.KeyFile,
+				ClientCAFile:     config.API.TLS.ClientCAFile,
+				PublicKeyService: pubKeyService,
+				Interfaces:       apiIfaces,
+			})
+		if err != nil {
+			return err
+		}
+
+		g.Add(func() error {
+			logger.Info().Msg("starting api server")
+			if err := apiSrv.Run(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				logger.Err(err).Msg("failed to start api server")
+				return err
+			}
+			return nil
+		}, func(err error) {
+			logger.Info().Msg("shutting down api server")
+			if err := apiSrv.Shutdown(ctx); err != nil {
+				logger.Err(err).Msg("failed to shutdown api server")
+			}
+		})
+	}
+
+	if config.Federation.Enabled {
+		fedIfaces := make([]federation.Interface, len(ifaces))
+		for i := range ifaces {
+			fedIfaces[i] = federation.Interface{
+				Name:             ifaces[i].Name,
+				WireGuardService: ifaces[i].WireGuardService,
+			}
+		}
+
+		secrets := make(map[string]string, len(config.Federation.Servers))
+		for i := range config.Federation.Servers {
+			secrets[config.Federation.Servers[i].Name] = config.Federation.Servers[i].Secret
+		}
+
+		fedSrv, err := federation.New(
+			&federation.ServerParams{
+				Logger:     logger.With().Str("tag", "federation").Logger(),
+				Port:       config.Federation.Port,
+				Name:       config.Federation.Name,
+				Secrets:    secrets,
+				CertFile:   config.Federation.TLS.CertFile,
+				KeyFile:    config.Federation.TLS.KeyFile,
+				Interfaces: fedIfaces,
+			})
+		if err != nil {
+			return err
+		}
+
+		g.Add(func() error {
+			logger.Info().Msg("starting federation server")
+			if err := fedSrv.Run(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				logger.Err(err).Msg("failed to start federation server")
+				return err
+			}
+			return nil
+		}, func(err error) {
+			logger.Info().Msg("shutting down federation server")
+			if err := fedSrv.Shutdown(ctx); err != nil {
+				logger.Err(err).Msg("failed to shutdown federation server")
+			}
+		})
+	}
+
+	if clusterPrimary != nil {
+		g.Add(func() error {
+			logger.Info().Msg("starting cluster server")
+			if err := clusterPrimary.Run(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				logger.Err(err).Msg("failed to start cluster server")
+				return err
+			}
+			return nil
+		}, func(err error) {
+			logger.Info().Msg("shutting down cluster server")
+			if err := clusterPrimary.Shutdown(ctx); err != nil {
+				logger.Err(err).Msg("failed to shutdown cluster server")
+			}
+		})
+	}
+
+	if config.Metrics.Enabled {
+		metricsCollector.RegisterWireGuardServices(logger.With().Str("tag", "metrics").Logger(), wireguardServices)
+
+		metricsSrv := metrics.NewServer(
+			&metrics.ServerParams{
+				Logger:  logger.With().Str("tag", "metrics").Logger(),
+				Port:    config.Metrics.Port,
+				Path:    config.Metrics.Path,
+				Metrics: metricsCollector,
+			})
+
+		g.Add(func() error {
+			logger.Info().Msg("starting metrics server")
+			if err := metricsSrv.Run(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				logger.Err(err).Msg("failed to start metrics server")
+				return err
+			}
+			return nil
+		}, func(err error) {
+			logger.Info().Msg("shutting down metrics server")
+			if err := metricsSrv.Shutdown(ctx); err != nil {
+				logger.Err(err).Msg("failed to shutdown metrics server")
+			}
+		})
+	}
+
 	if err := g.Run(); err != nil {
 		if _, ok := err.(run.SignalError); !ok {
 			return err
@@ -169,6 +616,176 @@ func runApp(args []string) (err error) {
 	return nil
 }
 
+// runStandby replicates the database from the primary until the standby is promoted,
+// in which case the server must go on to run as the primary, or a signal is received.
+func runStandby(config *app.Config, logger zerolog.Logger) (promoted bool, err error) {
+	standby, err := cluster.NewStandby(
+		&cluster.StandbyParams{
+			Logger:     logger.With().Str("tag", "cluster").Logger(),
+			Name:       config.Cluster.Name,
+			PrimaryURL: config.Cluster.PrimaryURL,
+			Secret:     config.Cluster.Secret,
+			CAFile:     config.Cluster.TLS.CAFile,
+			Insecure:   config.Cluster.Insecure,
+			Path:       config.Database.Path,
+			Interval:   config.Cluster.Interval,
+			Timeout:    config.Cluster.Timeout,
+		})
+	if err != nil {
+		return false, err
+	}
+
+	sshSrv, err := ssh.NewStandby(
+		&ssh.StandbyServerParams{
+			Logger:         logger.With().Str("tag", "ssh").Logger(),
+			Port:           config.SSH.Port,
+			HostKeyPath:    config.SSH.HostKeyPath,
+			AdminKeys:      config.SSH.AdminKeys,
+			ClusterService: standby,
+		})
+	if err != nil {
+		return false, err
+	}
+
+	ctx := context.Background()
+
+	g := new(run.Group)
+
+	g.Add(run.SignalHandler(ctx, syscall.SIGINT, syscall.SIGQUIT, syscall.SIGTERM))
+
+	g.Add(func() error {
+		logger.Info().Msg("starting ssh server")
+		if err := sshSrv.Run(); err != nil && !errors.Is(err, charmssh.ErrServerClosed) {
+			logger.Err(err).Msg("failed to start ssh server")
+			return err
+		}
+		return nil
+	}, func(err error) {
+		logger.Info().Msg("shutting down ssh server")
+		if err := sshSrv.Shutdown(ctx); err != nil {
+			logger.Err(err).Msg("failed to shutdown ssh server")
+		}
+	})
+
+	replicationCtx, cancelReplication := context.WithCancel(ctx)
+	g.Add(func() error {
+		logger.Info().Str("primary", config.Cluster.PrimaryURL).Msg("starting replication")
+		return standby.Run(replicationCtx)
+	}, func(err error) {
+		logger.Info().Msg("shutting down replication")
+		cancelReplication()
+	})
+
+	if err := g.Run(); err != nil {
+		if _, ok := err.(run.SignalError); !ok {
+			return false, err
+		}
+	}
+
+	select {
+	case <-standby.Promoted():
+		return true, nil
+	default:
+		return false, nil
+	}
+}
+
+type databaseRepo interface {
+	db.Repo
+	SealPrivateKeys(ctx context.Context) (n int, err error)
+	Close() error
+}
+
+func newDatabaseRepo(config *app.DatabaseConfig, adminKeys, interfaces []string, kms envelope.KMS, logger zerolog.Logger,
+) (repo databaseRepo, err error) {
+	switch config.Driver {
+	case app.DatabaseDriverSQLite:
+		return sqlrepo.New(
+			&sqlrepo.DatabaseRepoParams{
+				Logger:    logger,
+				Dialect:   sqlrepo.DialectSQLite,
+				DSN:       config.Path,
+				AdminKeys: adminKeys,
+				KMS:       kms,
+			})
+	case app.DatabaseDriverPostgres:
+		return sqlrepo.New(
+			&sqlrepo.DatabaseRepoParams{
+				Logger:    logger,
+				Dialect:   sqlrepo.DialectPostgres,
+				DSN:       config.DSN,
+				AdminKeys: adminKeys,
+				KMS:       kms,
+			})
+	}
+
+	return dbrepo.New(
+		&dbrepo.DatabaseRepoParams{
+			Logger:     logger,
+			Path:       config.Path,
+			AdminKeys:  adminKeys,
+			KMS:        kms,
+			Interfaces: interfaces,
+			ReadOnly:   false,
+		})
+}
+
+func newFirewallRepo(backend string, logger zerolog.Logger) firewall.Repo {
+	switch backend {
+	case app.FirewallBackendIPTablesNFT:
+		return fwrepo.NewIPTables(
+			&fwrepo.IPTablesRepoParams{
+				Logger: logger,
+				NFT:    true,
+			})
+	case app.FirewallBackendNFTables:
+		return fwrepo.NewNFTables(
+			&fwrepo.NFTablesRepoParams{
+				Logger: logger,
+			})
+	case app.FirewallBackendNone:
+		return fwrepo.NewNone()
+	}
+
+	return fwrepo.NewIPTables(
+		&fwrepo.IPTablesRepoParams{
+			Logger: logger,
+			NFT:    false,
+		})
+}
+
+func migrateDatabase(ctx context.Context, logger zerolog.Logger, dst databaseRepo, from string, kms envelope.KMS) (err error) {
+	src, err := dbrepo.New(
+		&dbrepo.DatabaseRepoParams{
+			Logger:     logger.With().Str("tag", "db_repo").Logger(),
+			Path:       from,
+			AdminKeys:  nil,
+			KMS:        kms,
+			Interfaces: nil,
+			ReadOnly:   true,
+		})
+	if err != nil {
+		return fmt.Errorf("failed to open source database: %w", err)
+	}
+	defer src.Close()
+
+	if err := db.Copy(ctx, dst, src); err != nil {
+		return fmt.Errorf("failed to migrate database: %w", err)
+	}
+
+	logger.Info().Str("from", from).Msg("migrated database")
+	return nil
+}
+
+func encryptDatabase(ctx context.Context, logger zerolog.Logger, dbRepo databaseRepo) (err error) {
+	n, err := dbRepo.SealPrivateKeys(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to encrypt database: %w", err)
+	}
+	logger.Info().Int("records", n).Msg("encrypted private keys")
+	return nil
+}
+
 func main() {
 	if err := runApp(os.Args); err != nil {
 		fmt.Fprintf(os.Stderr, "failed to run: %s\n", err)
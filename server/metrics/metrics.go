@@ -0,0 +1,224 @@
+package metrics
+
+import (
+	"context"
+	"time"
+
+	"github.com/infastin/wg-wish/server/errors"
+	"github.com/infastin/wg-wish/server/service"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/rs/zerolog"
+)
+
+const namespace = "wg_wish"
+
+// Metrics holds collectors updated by the rest of the application.
+// All methods are safe to call on nil Metrics.
+type Metrics struct {
+	registry *prometheus.Registry
+
+	sshCommands        *prometheus.CounterVec
+	sshCommandDuration *prometheus.HistogramVec
+	reloads            *prometheus.CounterVec
+	reloadDuration     prometheus.Histogram
+}
+
+func New() *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		sshCommands: prometheus.NewCounterVec(prometheus.CounterOpts{ //nolint:exhaustruct
+			Namespace: namespace,
+			Subsystem: "ssh",
+			Name:      "commands_total",
+			Help:      "Number of SSH commands handled, by command, result and error domain.",
+		}, []string{"command", "result", "domain"}),
+		sshCommandDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{ //nolint:exhaustruct
+			Namespace: namespace,
+			Subsystem: "ssh",
+			Name:      "command_duration_seconds",
+			Help:      "Duration of SSH commands.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"command"}),
+		reloads: prometheus.NewCounterVec(prometheus.CounterOpts{ //nolint:exhaustruct
+			Namespace: namespace,
+			Subsystem: "wireguard",
+			Name:      "reloads_total",
+			Help:      "Number of WireGuard server reloads, by result.",
+		}, []string{"result"}),
+		reloadDuration: prometheus.NewHistogram(prometheus.HistogramOpts{ //nolint:exhaustruct
+			Namespace: namespace,
+			Subsystem: "wireguard",
+			Name:      "reload_duration_seconds",
+			Help:      "Duration of WireGuard server reloads.",
+			Buckets:   prometheus.DefBuckets,
+		}),
+	}
+
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}), //nolint:exhaustruct
+		m.sshCommands,
+		m.sshCommandDuration,
+		m.reloads,
+		m.reloadDuration,
+	)
+
+	return m
+}
+
+// RegisterWireGuardServices registers the collector of peer and address pool metrics,
+// which are gathered from the services of every interface on every scrape.
+func (m *Metrics) RegisterWireGuardServices(lg zerolog.Logger, services []service.WireGuardService) {
+	if m == nil {
+		return
+	}
+
+	m.registry.MustRegister(&peerCollector{
+		lg:       lg,
+		services: services,
+	})
+}
+
+func (m *Metrics) Registry() *prometheus.Registry {
+	if m == nil {
+		return nil
+	}
+	return m.registry
+}
+
+func (m *Metrics) ObserveCommand(command string, elapsed time.Duration, err error) {
+	if m == nil {
+		return
+	}
+
+	result, domain := "ok", ""
+	if err != nil {
+		result = "error"
+		if de, ok := err.(errors.DomainError); ok {
+			domain = de.Domain()
+		}
+	}
+
+	m.sshCommands.WithLabelValues(command, result, domain).Inc()
+	m.sshCommandDuration.WithLabelValues(command).Observe(elapsed.Seconds())
+}
+
+func (m *Metrics) ObserveReload(elapsed time.Duration, err error) {
+	if m == nil {
+		return
+	}
+
+	result := "ok"
+	if err != nil {
+		result = "error"
+	}
+
+	m.reloads.WithLabelValues(result).Inc()
+	m.reloadDuration.Observe(elapsed.Seconds())
+}
+
+var (
+	peerReceivedDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "peer", "received_bytes_total"),
+		"Bytes received from the peer since the interface has been brought up.",
+		[]string{"interface", "name", "public_key"}, nil)
+	peerSentDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "peer", "sent_bytes_total"),
+		"Bytes sent to the peer since the interface has been brought up.",
+		[]string{"interface", "name", "public_key"}, nil)
+	peerHandshakeAgeDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "peer", "last_handshake_age_seconds"),
+		"Seconds since the latest handshake with the peer.",
+		[]string{"interface", "name", "public_key"}, nil)
+	peersDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "peers"),
+		"Number of peers by state.",
+		[]string{"interface", "state"}, nil)
+	poolSizeDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "address_pool", "size"),
+		"Number of addresses available to peers in the server subnet.",
+		[]string{"interface"}, nil)
+	poolUsedDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "address_pool", "used"),
+		"Number of addresses in the server subnet assigned to peers.",
+		[]string{"interface"}, nil)
+)
+
+// peerCollector collects peer and address pool metrics on every scrape.
+type peerCollector struct {
+	lg       zerolog.Logger
+	services []service.WireGuardService
+}
+
+func (*peerCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- peerReceivedDesc
+	ch <- peerSentDesc
+	ch <- peerHandshakeAgeDesc
+	ch <- peersDesc
+	ch <- poolSizeDesc
+	ch <- poolUsedDesc
+}
+
+func (c *peerCollector) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	for _, svc := range c.services {
+		c.collect(ctx, ch, svc)
+	}
+}
+
+func (c *peerCollector) collect(ctx context.Context, ch chan<- prometheus.Metric, svc service.WireGuardService) {
+	serverInfo, err := svc.GetServerInfo(ctx)
+	if err != nil {
+		c.lg.Err(err).Msg("failed to collect address pool metrics")
+		return
+	}
+
+	iface := serverInfo.Interface
+
+	ch <- prometheus.MustNewConstMetric(poolSizeDesc, prometheus.GaugeValue, float64(serverInfo.AddressPoolSize), iface)
+	ch <- prometheus.MustNewConstMetric(poolUsedDesc, prometheus.GaugeValue, float64(serverInfo.AddressPoolUsed), iface)
+
+	infos, err := svc.GetClientInfos(ctx)
+	if err != nil {
+		c.lg.Err(err).Str("interface", iface).Msg("failed to collect peer metrics")
+		return
+	}
+
+	now := time.Now()
+	online, offline, never := 0, 0, 0
+
+	for i := range infos {
+		info := &infos[i]
+		name := info.Config.Interface.Name
+		publicKey := info.Config.Interface.PrivateKey.PublicKey().String()
+
+		if !info.Stats.Valid || !info.Stats.V.LatestHandshake.Valid {
+			never++
+		} else if info.Stats.V.Online(now) {
+			online++
+		} else {
+			offline++
+		}
+
+		if !info.Stats.Valid {
+			continue
+		}
+
+		ch <- prometheus.MustNewConstMetric(peerReceivedDesc, prometheus.CounterValue,
+			float64(info.Stats.V.Received), iface, name, publicKey)
+		ch <- prometheus.MustNewConstMetric(peerSentDesc, prometheus.CounterValue,
+			float64(info.Stats.V.Sent), iface, name, publicKey)
+
+		if info.Stats.V.LatestHandshake.Valid {
+			ch <- prometheus.MustNewConstMetric(peerHandshakeAgeDesc, prometheus.GaugeValue,
+				now.Sub(info.Stats.V.LatestHandshake.Time).Seconds(), iface, name, publicKey)
+		}
+	}
+
+	ch <- prometheus.MustNewConstMetric(peersDesc, prometheus.GaugeValue, float64(online), iface, "online")
+	ch <- prometheus.MustNewConstMetric(peersDesc, prometheus.GaugeValue, float64(offline), iface, "offline")
+	ch <- prometheus.MustNewConstMetric(peersDesc, prometheus.GaugeValue, float64(never), iface, "never")
+}
@@ -0,0 +1,53 @@
+package metrics
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/rs/zerolog"
+)
+
+type Server struct {
+	lg     zerolog.Logger
+	server *http.Server
+}
+
+type ServerParams struct {
+	Logger  zerolog.Logger
+	Port    int
+	Path    string
+	Metrics *Metrics
+}
+
+func NewServer(params *ServerParams) *Server {
+	mux := http.NewServeMux()
+	mux.Handle(params.Path, promhttp.HandlerFor(params.Metrics.Registry(), promhttp.HandlerOpts{})) //nolint:exhaustruct
+
+	return &Server{
+		lg: params.Logger,
+		server: &http.Server{ //nolint:exhaustruct
+			Addr:              "0.0.0.0:" + strconv.Itoa(params.Port),
+			Handler:           mux,
+			ReadHeaderTimeout: 10 * time.Second,
+		},
+	}
+}
+
+func (s *Server) Run() error {
+	err := s.server.ListenAndServe()
+	if err != nil {
+		return fmt.Errorf("metrics: failed to serve: %w", err)
+	}
+	return nil
+}
+
+func (s *Server) Shutdown(ctx context.Context) error {
+	if err := s.server.Shutdown(ctx); err != nil {
+		return fmt.Errorf("metrics: failed to shutdown server: %w", err)
+	}
+	return nil
+}
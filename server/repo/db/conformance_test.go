@@ -0,0 +1,1320 @@
+package db_test
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"database/sql"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/charmbracelet/ssh"
+	"github.com/guregu/null/v5"
+	"github.com/infastin/wg-wish/pkg/envelope"
+	"github.com/infastin/wg-wish/pkg/netutils"
+	"github.com/infastin/wg-wish/pkg/wgtypes"
+	"github.com/infastin/wg-wish/server/entity"
+	"github.com/infastin/wg-wish/server/errors"
+	database "github.com/infastin/wg-wish/server/repo/db"
+	dbrepo "github.com/infastin/wg-wish/server/repo/db/impl"
+	"github.com/infastin/wg-wish/server/repo/db/impl/queries"
+	sqlrepo "github.com/infastin/wg-wish/server/repo/db/sqlimpl"
+	"github.com/rs/zerolog"
+	"go.etcd.io/bbolt"
+	gossh "golang.org/x/crypto/ssh"
+
+	_ "github.com/jackc/pgx/v5/stdlib" // Registers pgx driver.
+)
+
+// postgresDSNEnv names the environment variable with the DSN of a Postgres database
+// to run the suite against. Postgres is skipped if it's not set.
+const postgresDSNEnv = "WG_WISH_TEST_POSTGRES_DSN"
+
+var errRollback = errors.New("rollback")
+
+type backend struct {
+	name string
+	// open opens a new empty database, returning a function opening it again.
+	open func(t *testing.T, kms envelope.KMS) (repo database.Repo, reopen func() database.Repo)
+	// schemaVersion closes the repo and returns the schema version of its database.
+	schemaVersion func(t *testing.T, repo database.Repo) int
+	// moveClient closes the repo and moves the stored record of the client
+	// to another interface as it is, the way someone with access to the database could.
+	moveClient func(t *testing.T, repo database.Repo, name, from, to string)
+	// expectedVersion is the schema version of migrated databases.
+	expectedVersion int
+}
+
+func backends() []backend {
+	return []backend{
+		bboltBackend(),
+		sqliteBackend(),
+		postgresBackend(),
+	}
+}
+
+func bboltBackend() backend {
+	var path string
+
+	open := func(t *testing.T, kms envelope.KMS) database.Repo {
+		t.Helper()
+		repo, err := dbrepo.New(&dbrepo.DatabaseRepoParams{
+			Logger:     zerolog.Nop(),
+			Path:       path,
+			AdminKeys:  nil,
+			KMS:        kms,
+			Interfaces: []string{"wg0", "wg1"},
+			ReadOnly:   false,
+		})
+		if err != nil {
+			t.Fatalf("failed to open bbolt database: %v", err)
+		}
+		t.Cleanup(func() { _ = repo.Close() })
+		return repo
+	}
+
+	return backend{
+		name: "bbolt",
+		open: func(t *testing.T, kms envelope.KMS) (database.Repo, func() database.Repo) {
+			path = filepath.Join(t.TempDir(), "wg-wish.db")
+			repo := open(t, kms)
+			return repo, func() database.Repo {
+				_ = repo.(*dbrepo.DatabaseRepo).Close()
+				repo = open(t, kms)
+				return repo
+			}
+		},
+		// A read-only database can only be opened at the current schema version.
+		schemaVersion: func(t *testing.T, repo database.Repo) int {
+			_ = repo.(*dbrepo.DatabaseRepo).Close()
+
+			ro, err := dbrepo.New(&dbrepo.DatabaseRepoParams{
+				Logger:     zerolog.Nop(),
+				Path:       path,
+				AdminKeys:  nil,
+				KMS:        nil,
+				Interfaces: nil,
+				ReadOnly:   true,
+			})
+			if err != nil {
+				var versionErr *queries.SchemaVersionError
+				if errors.As(err, &versionErr) {
+					return versionErr.Version
+				}
+				t.Fatalf("failed to open bbolt database: %v", err)
+			}
+			_ = ro.Close()
+
+			return queries.SchemaVersion
+		},
+		moveClient: func(t *testing.T, repo database.Repo, name, from, to string) {
+			_ = repo.(*dbrepo.DatabaseRepo).Close()
+
+			db, err := bbolt.Open(path, 0o600, nil)
+			if err != nil {
+				t.Fatalf("failed to open bbolt database: %v", err)
+			}
+			defer db.Close()
+
+			err = db.Update(func(tx *bbolt.Tx) error {
+				ifaces := tx.Bucket([]byte("interfaces"))
+				src := ifaces.Bucket([]byte(from)).Bucket([]byte("wgclient"))
+				dst := ifaces.Bucket([]byte(to)).Bucket([]byte("wgclient"))
+				if err := dst.Put([]byte(name), src.Get([]byte(name))); err != nil {
+					return err
+				}
+				return src.Delete([]byte(name))
+			})
+			if err != nil {
+				t.Fatalf("failed to move client: %v", err)
+			}
+		},
+		expectedVersion: queries.SchemaVersion,
+	}
+}
+
+func sqliteBackend() backend {
+	var path string
+
+	return sqlBackend("sqlite", sqlrepo.DialectSQLite, "sqlite",
+		func(t *testing.T) string {
+			path = filepath.Join(t.TempDir(), "wg-wish.sqlite")
+			return path
+		},
+		func() string { return "file:" + path })
+}
+
+func postgresBackend() backend {
+	var dsn string
+
+	return sqlBackend("postgres", sqlrepo.DialectPostgres, "pgx",
+		func(t *testing.T) string {
+			base := os.Getenv(postgresDSNEnv)
+			if base == "" {
+				t.Skipf("%s is not set", postgresDSNEnv)
+			}
+
+			// Every test gets its own schema, so that tests don't see each other's data.
+			schema := fmt.Sprintf("wg_wish_test_%d", time.Now().UnixNano())
+
+			conn, err := sql.Open("pgx", base)
+			if err != nil {
+				t.Fatalf("failed to connect to postgres: %v", err)
+			}
+			t.Cleanup(func() { _ = conn.Close() })
+
+			if _, err := conn.Exec(`CREATE SCHEMA ` + schema); err != nil {
+				t.Fatalf("failed to create schema: %v", err)
+			}
+			t.Cleanup(func() { _, _ = conn.Exec(`DROP SCHEMA ` + schema + ` CASCADE`) })
+
+			sep := "?"
+			if strings.Contains(base, "?") {
+				sep = "&"
+			}
+			dsn = base + sep + "search_path=" + schema
+
+			return dsn
+		},
+		func() string { return dsn })
+}
+
+func sqlBackend(name string, dialect sqlrepo.Dialect, driver string,
+	create func(t *testing.T) string, dsn func() string,
+) backend {
+	open := func(t *testing.T, kms envelope.KMS, dsn string) database.Repo {
+		t.Helper()
+		repo, err := sqlrepo.New(&sqlrepo.DatabaseRepoParams{
+			Logger:    zerolog.Nop(),
+			Dialect:   dialect,
+			DSN:       dsn,
+			AdminKeys: nil,
+			KMS:       kms,
+		})
+		if err != nil {
+			t.Fatalf("failed to open %s database: %v", name, err)
+		}
+		t.Cleanup(func() { _ = repo.Close() })
+		return repo
+	}
+
+	return backend{
+		name: name,
+		open: func(t *testing.T, kms envelope.KMS) (database.Repo, func() database.Repo) {
+			dsn := create(t)
+			repo := open(t, kms, dsn)
+			return repo, func() database.Repo {
+				_ = repo.(*sqlrepo.DatabaseRepo).Close()
+				repo = open(t, kms, dsn)
+				return repo
+			}
+		},
+		schemaVersion: func(t *testing.T, repo database.Repo) int {
+			_ = repo.(*sqlrepo.DatabaseRepo).Close()
+
+			conn, err := sql.Open(driver, dsn())
+			if err != nil {
+				t.Fatalf("failed to open %s database: %v", name, err)
+			}
+			defer conn.Close()
+
+			var version int
+			if err := conn.QueryRow(`SELECT MAX(version) FROM schema_migrations`).Scan(&version); err != nil {
+				t.Fatalf("failed to get schema version: %v", err)
+			}
+			return version
+		},
+		moveClient: func(t *testing.T, repo database.Repo, client, from, to string) {
+			_ = repo.(*sqlrepo.DatabaseRepo).Close()
+
+			conn, err := sql.Open(driver, dsn())
+			if err != nil {
+				t.Fatalf("failed to open %s database: %v", name, err)
+			}
+			defer conn.Close()
+
+			query := `UPDATE wg_clients SET interface = ? WHERE interface = ? AND name = ?`
+			if dialect == sqlrepo.DialectPostgres {
+				query = `UPDATE wg_clients SET interface = $1 WHERE interface = $2 AND name = $3`
+			}
+			if _, err := conn.Exec(query, to, from, client); err != nil {
+				t.Fatalf("failed to move client: %v", err)
+			}
+		},
+		expectedVersion: sqlrepo.SchemaVersion,
+	}
+}
+
+func TestConformance(t *testing.T) {
+	tests := []struct {
+		name string
+		run  func(t *testing.T, repo database.Repo)
+	}{
+		{"PublicKeys", testPublicKeys},
+		{"WireGuardClients", testWireGuardClients},
+		{"WireGuardClientErrors", testWireGuardClientErrors},
+		{"WireGuardServer", testWireGuardServer},
+		{"FirewallGroups", testFirewallGroups},
+		{"WireGuardProfiles", testWireGuardProfiles},
+		{"InterfaceIsolation", testInterfaceIsolation},
+		{"Rollback", testRollback},
+	}
+
+	for _, b := range backends() {
+		t.Run(b.name, func(t *testing.T) {
+			for _, sealed := range []bool{false, true} {
+				name := "plain"
+				if sealed {
+					name = "sealed"
+				}
+
+				t.Run(name, func(t *testing.T) {
+					for _, tt := range tests {
+						t.Run(tt.name, func(t *testing.T) {
+							repo, _ := b.open(t, newKMS(t, sealed))
+							tt.run(t, repo)
+						})
+					}
+				})
+			}
+
+			t.Run("Migrate", func(t *testing.T) {
+				testMigrate(t, b)
+			})
+
+			t.Run("MovedPrivateKey", func(t *testing.T) {
+				testMovedPrivateKey(t, b)
+			})
+		})
+	}
+}
+
+func newKMS(t *testing.T, sealed bool) envelope.KMS {
+	t.Helper()
+
+	if !sealed {
+		return nil
+	}
+
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		t.Fatal(err)
+	}
+
+	kms, err := envelope.NewSingleKeyring(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return kms
+}
+
+func update(t *testing.T, repo database.Repo, cb database.AtomicCallback) {
+	t.Helper()
+	if err := repo.Update(context.Background(), cb); err != nil {
+		t.Fatalf("update failed: %v", err)
+	}
+}
+
+func view(t *testing.T, repo database.Repo, cb database.AtomicCallback) {
+	t.Helper()
+	if err := repo.View(context.Background(), cb); err != nil {
+		t.Fatalf("view failed: %v", err)
+	}
+}
+
+func expectError(t *testing.T, err, target error) {
+	t.Helper()
+	if !errors.Is(err, target) {
+		t.Fatalf("expected error %v, got %v", target, err)
+	}
+}
+
+func newPublicKey(t *testing.T) ssh.PublicKey {
+	t.Helper()
+
+	pub, _, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pkey, err := gossh.NewPublicKey(pub)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return pkey
+}
+
+func newPrivateKey(t *testing.T) wgtypes.Key {
+	t.Helper()
+
+	key, err := wgtypes.GeneratePrivateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return key
+}
+
+func mustParseAddress(s string) net.IPNet {
+	addr, err := netutils.ParseAddress(s)
+	if err != nil {
+		panic(err)
+	}
+	return addr
+}
+
+func newClient(t *testing.T, name, address string) entity.WireGuardClient {
+	t.Helper()
+
+	privateKey := newPrivateKey(t)
+
+	return entity.WireGuardClient{
+		Name:                name,
+		Address:             mustParseAddress(address),
+		PrivateKey:          privateKey,
+		PublicKey:           privateKey.PublicKey(),
+		DNS:                 []net.IP{net.ParseIP("1.1.1.1"), net.ParseIP("2606:4700:4700::1111")},
+		AllowedIPs:          []net.IPNet{mustParseAddress("0.0.0.0/0"), mustParseAddress("::/0")},
+		PersistentKeepalive: null.IntFrom(25),
+		Disabled:            false,
+		Quota:               null.Value[entity.WireGuardClientQuota]{},
+		RateLimit:           0,
+		Group:               "",
+		Profile:             "",
+		ExpiresAt:           null.Time{},
+		Labels:              nil,
+		Description:         "",
+		Mesh:                false,
+		Endpoint:            "",
+		LastEndpoint:        "",
+	}
+}
+
+// formatClient formats the client in a form not depending on how the backend
+// stores IPs and times, so that the clients can be compared.
+func formatClient(c *entity.WireGuardClient) string {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "name=%s address=%s private_key=%s public_key=%s dns=%s allowed_ips=%s",
+		c.Name, c.Address.String(), c.PrivateKey, c.PublicKey,
+		netutils.FormatIPs(c.DNS, ","), netutils.FormatAddresses(c.AllowedIPs, ","))
+
+	if c.PersistentKeepalive.Valid {
+		fmt.Fprintf(&b, " keepalive=%d", c.PersistentKeepalive.Int64)
+	}
+
+	fmt.Fprintf(&b, " disabled=%t", c.Disabled)
+
+	if c.Quota.Valid {
+		q := &c.Quota.V
+		fmt.Fprintf(&b, " quota=%d/%s/%d/%d", q.Limit, q.Period, q.PeriodStart.Unix(), q.Used)
+		if q.LastCounter.Valid {
+			fmt.Fprintf(&b, "/%d", q.LastCounter.V)
+		}
+	}
+
+	fmt.Fprintf(&b, " rate_limit=%d group=%s profile=%s", c.RateLimit, c.Group, c.Profile)
+
+	if c.ExpiresAt.Valid {
+		fmt.Fprintf(&b, " expires_at=%d", c.ExpiresAt.Time.Unix())
+	}
+
+	keys := make([]string, 0, len(c.Labels))
+	for k := range c.Labels {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	for _, k := range keys {
+		fmt.Fprintf(&b, " label:%s=%s", k, c.Labels[k])
+	}
+
+	fmt.Fprintf(&b, " description=%q mesh=%t endpoint=%s last_endpoint=%s",
+		c.Description, c.Mesh, c.Endpoint, c.LastEndpoint)
+
+	return b.String()
+}
+
+func expectClient(t *testing.T, got, want *entity.WireGuardClient) {
+	t.Helper()
+	if g, w := formatClient(got), formatClient(want); g != w {
+		t.Fatalf("client mismatch:\n got: %s\nwant: %s", g, w)
+	}
+}
+
+func expectClients(t *testing.T, got, want []entity.WireGuardClient) {
+	t.Helper()
+
+	sortClients := func(clients []entity.WireGuardClient) []string {
+		s := make([]string, len(clients))
+		for i := range clients {
+			s[i] = formatClient(&clients[i])
+		}
+		slices.Sort(s)
+		return s
+	}
+
+	if g, w := sortClients(got), sortClients(want); !slices.Equal(g, w) {
+		t.Fatalf("clients mismatch:\n got: %q\nwant: %q", g, w)
+	}
+}
+
+func testPublicKeys(t *testing.T, repo database.Repo) {
+	ctx := context.Background()
+
+	first := entity.PublicKey{Key: newPublicKey(t), Comment: "first"}
+	second := entity.PublicKey{Key: newPublicKey(t), Comment: "second"}
+
+	update(t, repo, func(repo database.Repo) error {
+		if err := repo.PublicKeyRepo().AddPublicKey(ctx, &first); err != nil {
+			return err
+		}
+		expectError(t, repo.PublicKeyRepo().AddPublicKey(ctx, &first), errors.ErrPublicKeyExists)
+		return repo.PublicKeyRepo().SetPublicKey(ctx, &second)
+	})
+
+	formatKeys := func(pkeys []entity.PublicKey) []string {
+		s := make([]string, len(pkeys))
+		for i := range pkeys {
+			s[i] = string(gossh.MarshalAuthorizedKey(pkeys[i].Key)) + pkeys[i].Comment
+		}
+		slices.Sort(s)
+		return s
+	}
+
+	view(t, repo, func(repo database.Repo) error {
+		exists, err := repo.PublicKeyRepo().PublicKeyExists(ctx, first.Key)
+		if err != nil {
+			return err
+		}
+		if !exists {
+			t.Fatal("expected public key to exist")
+		}
+
+		pkeys, err := repo.PublicKeyRepo().GetPublicKeys(ctx)
+		if err != nil {
+			return err
+		}
+		if g, w := formatKeys(pkeys), formatKeys([]entity.PublicKey{first, second}); !slices.Equal(g, w) {
+			t.Fatalf("public keys mismatch:\n got: %q\nwant: %q", g, w)
+		}
+
+		return nil
+	})
+
+	first.Comment = "renamed"
+	third := entity.PublicKey{Key: newPublicKey(t), Comment: "third"}
+
+	update(t, repo, func(repo database.Repo) error {
+		if err := repo.PublicKeyRepo().SetPublicKey(ctx, &first); err != nil {
+			return err
+		}
+		if err := repo.PublicKeyRepo().RemovePublicKey(ctx, second.Key); err != nil {
+			return err
+		}
+		return repo.PublicKeyRepo().SetPublicKeys(ctx, []entity.PublicKey{first, third})
+	})
+
+	view(t, repo, func(repo database.Repo) error {
+		exists, err := repo.PublicKeyRepo().PublicKeyExists(ctx, second.Key)
+		if err != nil {
+			return err
+		}
+		if exists {
+			t.Fatal("expected public key to be removed")
+		}
+
+		pkeys, err := repo.PublicKeyRepo().GetPublicKeys(ctx)
+		if err != nil {
+			return err
+		}
+		if g, w := formatKeys(pkeys), formatKeys([]entity.PublicKey{first, third}); !slices.Equal(g, w) {
+			t.Fatalf("public keys mismatch:\n got: %q\nwant: %q", g, w)
+		}
+
+		return nil
+	})
+}
+
+func testWireGuardClients(t *testing.T, repo database.Repo) {
+	ctx := context.Background()
+	wg0 := repo.Interface("wg0")
+
+	alice := newClient(t, "alice", "10.0.0.2/32")
+	alice.Disabled = true
+	alice.Quota = null.ValueFrom(entity.WireGuardClientQuota{
+		Limit:       1 << 30,
+		Period:      entity.QuotaPeriodMonthly,
+		PeriodStart: time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC),
+		Used:        12345,
+		LastCounter: null.ValueFrom[uint64](678),
+	})
+	alice.RateLimit = 10_000_000
+	alice.Group = "office"
+	alice.Profile = "laptops"
+	alice.ExpiresAt = null.TimeFrom(time.Date(2030, 1, 2, 3, 4, 5, 0, time.UTC))
+	alice.Labels = map[string]string{"team": "infra", "os": "linux"}
+	alice.Description = "Alice's laptop"
+	alice.Mesh = true
+	alice.Endpoint = "203.0.113.5:51820"
+	alice.LastEndpoint = "198.51.100.7:40000"
+
+	bob := newClient(t, "bob", "fd00::3/128")
+	bob.DNS = nil
+	bob.AllowedIPs = nil
+	bob.PersistentKeepalive = null.Int{}
+
+	update(t, wg0, func(repo database.Repo) error {
+		if err := repo.WireGuardClientRepo().AddWireGuardClient(ctx, &alice); err != nil {
+			return err
+		}
+		return repo.WireGuardClientRepo().AddWireGuardClient(ctx, &bob)
+	})
+
+	view(t, wg0, func(repo database.Repo) error {
+		clients := repo.WireGuardClientRepo()
+
+		exists, err := clients.WireGuardClientExists(ctx, "alice")
+		if err != nil {
+			return err
+		}
+		if !exists {
+			t.Fatal("expected client to exist")
+		}
+
+		got, err := clients.GetWireGuardClient(ctx, "alice")
+		if err != nil {
+			return err
+		}
+		expectClient(t, &got, &alice)
+
+		got, err = clients.GetWireGuardClientByPublicKey(ctx, bob.PublicKey)
+		if err != nil {
+			return err
+		}
+		expectClient(t, &got, &bob)
+
+		got, err = clients.GetWireGuardClientByAddress(ctx, alice.Address.IP)
+		if err != nil {
+			return err
+		}
+		expectClient(t, &got, &alice)
+
+		all, err := clients.GetWireGuardClients(ctx)
+		if err != nil {
+			return err
+		}
+		expectClients(t, all, []entity.WireGuardClient{alice, bob})
+
+		return nil
+	})
+
+	// Changing the address and the key must update the lookups.
+	alice.Address = mustParseAddress("10.0.0.9/32")
+	alice.PrivateKey = newPrivateKey(t)
+	alice.PublicKey = alice.PrivateKey.PublicKey()
+	alice.Quota = null.Value[entity.WireGuardClientQuota]{}
+	alice.Labels = nil
+	oldAlice := newClient(t, "alice", "10.0.0.2/32")
+
+	carol := newClient(t, "carol", "10.0.0.4/32")
+
+	update(t, wg0, func(repo database.Repo) error {
+		clients := repo.WireGuardClientRepo()
+		if err := clients.SetWireGuardClient(ctx, &alice); err != nil {
+			return err
+		}
+		if err := clients.RemoveWireGuardClient(ctx, "bob"); err != nil {
+			return err
+		}
+		return clients.AddWireGuardClient(ctx, &carol)
+	})
+
+	view(t, wg0, func(repo database.Repo) error {
+		clients := repo.WireGuardClientRepo()
+
+		_, err := clients.GetWireGuardClientByAddress(ctx, oldAlice.Address.IP)
+		expectError(t, err, errors.ErrWireGuardClientNotFound)
+
+		got, err := clients.GetWireGuardClientByAddress(ctx, alice.Address.IP)
+		if err != nil {
+			return err
+		}
+		expectClient(t, &got, &alice)
+
+		got, err = clients.GetWireGuardClientByPublicKey(ctx, alice.PublicKey)
+		if err != nil {
+			return err
+		}
+		expectClient(t, &got, &alice)
+
+		exists, err := clients.WireGuardClientExists(ctx, "bob")
+		if err != nil {
+			return err
+		}
+		if exists {
+			t.Fatal("expected client to be removed")
+		}
+
+		_, err = clients.GetWireGuardClientByPublicKey(ctx, bob.PublicKey)
+		expectError(t, err, errors.ErrWireGuardClientNotFound)
+
+		all, err := clients.GetWireGuardClients(ctx)
+		if err != nil {
+			return err
+		}
+		expectClients(t, all, []entity.WireGuardClient{alice, carol})
+
+		return nil
+	})
+
+	dave := newClient(t, "dave", "10.0.0.5/32")
+
+	update(t, wg0, func(repo database.Repo) error {
+		return repo.WireGuardClientRepo().SetWireGuardClients(ctx, []entity.WireGuardClient{carol, dave})
+	})
+
+	view(t, wg0, func(repo database.Repo) error {
+		clients := repo.WireGuardClientRepo()
+
+		all, err := clients.GetWireGuardClients(ctx)
+		if err != nil {
+			return err
+		}
+		expectClients(t, all, []entity.WireGuardClient{carol, dave})
+
+		// Replaced clients must not be found through the indexes.
+		_, err = clients.GetWireGuardClientByPublicKey(ctx, alice.PublicKey)
+		expectError(t, err, errors.ErrWireGuardClientNotFound)
+
+		_, err = clients.GetWireGuardClientByAddress(ctx, alice.Address.IP)
+		expectError(t, err, errors.ErrWireGuardClientNotFound)
+
+		return nil
+	})
+}
+
+func testWireGuardClientErrors(t *testing.T, repo database.Repo) {
+	ctx := context.Background()
+	wg0 := repo.Interface("wg0")
+
+	alice := newClient(t, "alice", "10.0.0.2/32")
+
+	update(t, wg0, func(repo database.Repo) error {
+		return repo.WireGuardClientRepo().AddWireGuardClient(ctx, &alice)
+	})
+
+	clients := []struct {
+		name   string
+		client func() entity.WireGuardClient
+		add    bool
+		err    error
+	}{
+		{
+			name:   "same name",
+			client: func() entity.WireGuardClient { return newClient(t, "alice", "10.0.0.3/32") },
+			add:    true,
+			err:    errors.ErrWireGuardClientExists,
+		},
+		{
+			name: "same public key",
+			client: func() entity.WireGuardClient {
+				c := newClient(t, "bob", "10.0.0.3/32")
+				c.PrivateKey, c.PublicKey = alice.PrivateKey, alice.PublicKey
+				return c
+			},
+			add: true,
+			err: errors.ErrWireGuardClientPublicKeyExists,
+		},
+		{
+			name:   "same address",
+			client: func() entity.WireGuardClient { return newClient(t, "bob", "10.0.0.2/32") },
+			add:    true,
+			err:    errors.ErrWireGuardClientAddressExists,
+		},
+		{
+			name: "set with taken public key",
+			client: func() entity.WireGuardClient {
+				c := newClient(t, "bob", "10.0.0.3/32")
+				c.PrivateKey, c.PublicKey = alice.PrivateKey, alice.PublicKey
+				return c
+			},
+			add: false,
+			err: errors.ErrWireGuardClientPublicKeyExists,
+		},
+		{
+			name:   "set with taken address",
+			client: func() entity.WireGuardClient { return newClient(t, "bob", "10.0.0.2/32") },
+			add:    false,
+			err:    errors.ErrWireGuardClientAddressExists,
+		},
+	}
+
+	for _, tt := range clients {
+		t.Run(tt.name, func(t *testing.T) {
+			client := tt.client()
+			err := wg0.Update(ctx, func(repo database.Repo) error {
+				if tt.add {
+					return repo.WireGuardClientRepo().AddWireGuardClient(ctx, &client)
+				}
+				return repo.WireGuardClientRepo().SetWireGuardClient(ctx, &client)
+			})
+			expectError(t, err, tt.err)
+		})
+	}
+
+	replacements := []struct {
+		name    string
+		clients func() []entity.WireGuardClient
+		err     error
+	}{
+		{
+			name: "replace with shared public key",
+			clients: func() []entity.WireGuardClient {
+				bob := newClient(t, "bob", "10.0.0.3/32")
+				carol := newClient(t, "carol", "10.0.0.4/32")
+				carol.PrivateKey, carol.PublicKey = bob.PrivateKey, bob.PublicKey
+				return []entity.WireGuardClient{bob, carol}
+			},
+			err: errors.ErrWireGuardClientPublicKeyExists,
+		},
+		{
+			name: "replace with shared address",
+			clients: func() []entity.WireGuardClient {
+				return []entity.WireGuardClient{
+					newClient(t, "bob", "10.0.0.3/32"),
+					newClient(t, "carol", "10.0.0.3/32"),
+				}
+			},
+			err: errors.ErrWireGuardClientAddressExists,
+		},
+	}
+
+	for _, tt := range replacements {
+		t.Run(tt.name, func(t *testing.T) {
+			clients := tt.clients()
+			err := wg0.Update(ctx, func(repo database.Repo) error {
+				return repo.WireGuardClientRepo().SetWireGuardClients(ctx, clients)
+			})
+			expectError(t, err, tt.err)
+		})
+	}
+
+	view(t, wg0, func(repo database.Repo) error {
+		clients := repo.WireGuardClientRepo()
+
+		_, err := clients.GetWireGuardClient(ctx, "bob")
+		expectError(t, err, errors.ErrWireGuardClientNotFound)
+
+		_, err = clients.GetWireGuardClientByPublicKey(ctx, newPrivateKey(t).PublicKey())
+		expectError(t, err, errors.ErrWireGuardClientNotFound)
+
+		_, err = clients.GetWireGuardClientByAddress(ctx, net.ParseIP("10.0.0.200"))
+		expectError(t, err, errors.ErrWireGuardClientNotFound)
+
+		all, err := clients.GetWireGuardClients(ctx)
+		if err != nil {
+			return err
+		}
+		expectClients(t, all, []entity.WireGuardClient{alice})
+
+		return nil
+	})
+}
+
+func testWireGuardServer(t *testing.T, repo database.Repo) {
+	wg0 := repo.Interface("wg0")
+
+	view(t, wg0, func(repo database.Repo) error {
+		exists, err := repo.WireGuardServerRepo().WireGuardServerConfigExists()
+		if err != nil {
+			return err
+		}
+		if exists {
+			t.Fatal("expected no server config")
+		}
+
+		_, err = repo.WireGuardServerRepo().GetWireGuardServerConfig()
+		expectError(t, err, errors.ErrWireGuardServerConfigNotFound)
+
+		return nil
+	})
+
+	for range 2 {
+		config := entity.WireGuardServerConfig{PrivateKey: newPrivateKey(t)}
+
+		update(t, wg0, func(repo database.Repo) error {
+			return repo.WireGuardServerRepo().SetWireGuardServerConfig(&config)
+		})
+
+		view(t, wg0, func(repo database.Repo) error {
+			exists, err := repo.WireGuardServerRepo().WireGuardServerConfigExists()
+			if err != nil {
+				return err
+			}
+			if !exists {
+				t.Fatal("expected server config to exist")
+			}
+
+			got, err := repo.WireGuardServerRepo().GetWireGuardServerConfig()
+			if err != nil {
+				return err
+			}
+			if got.PrivateKey != config.PrivateKey {
+				t.Fatalf("expected private key %s, got %s", config.PrivateKey, got.PrivateKey)
+			}
+
+			return nil
+		})
+	}
+}
+
+func formatFirewallGroup(g *entity.FirewallGroup) string {
+	rules := make([]string, len(g.Rules))
+	for i := range g.Rules {
+		rules[i] = g.Rules[i].String()
+	}
+	return fmt.Sprintf("%s allow_peers=%t rules=%s", g.Name, g.AllowPeers, strings.Join(rules, ","))
+}
+
+func expectFirewallGroups(t *testing.T, got, want []entity.FirewallGroup) {
+	t.Helper()
+
+	format := func(groups []entity.FirewallGroup) []string {
+		s := make([]string, len(groups))
+		for i := range groups {
+			s[i] = formatFirewallGroup(&groups[i])
+		}
+		slices.Sort(s)
+		return s
+	}
+
+	if g, w := format(got), format(want); !slices.Equal(g, w) {
+		t.Fatalf("firewall groups mismatch:\n got: %q\nwant: %q", g, w)
+	}
+}
+
+func testFirewallGroups(t *testing.T, repo database.Repo) {
+	ctx := context.Background()
+
+	office := entity.FirewallGroup{
+		Name:       "office",
+		AllowPeers: true,
+		Rules: []entity.FirewallRule{
+			{Destination: mustParseAddress("10.0.5.0/24"), Protocol: entity.FirewallProtocolTCP, PortFrom: 443, PortTo: 443},
+			{Destination: mustParseAddress("10.0.0.53/32"), Protocol: entity.FirewallProtocolUDP, PortFrom: 53, PortTo: 53},
+			{Destination: mustParseAddress("10.0.0.0/8"), Protocol: entity.FirewallProtocolICMP, PortFrom: 0, PortTo: 0},
+		},
+	}
+	guests := entity.FirewallGroup{
+		Name:       "guests",
+		AllowPeers: false,
+		Rules:      nil,
+	}
+
+	update(t, repo, func(repo database.Repo) error {
+		groups := repo.FirewallGroupRepo()
+		if err := groups.AddFirewallGroup(ctx, &office); err != nil {
+			return err
+		}
+		expectError(t, groups.AddFirewallGroup(ctx, &office), errors.ErrFirewallGroupExists)
+		return groups.SetFirewallGroup(ctx, &guests)
+	})
+
+	view(t, repo, func(repo database.Repo) error {
+		groups := repo.FirewallGroupRepo()
+
+		exists, err := groups.FirewallGroupExists(ctx, "office")
+		if err != nil {
+			return err
+		}
+		if !exists {
+			t.Fatal("expected firewall group to exist")
+		}
+
+		got, err := groups.GetFirewallGroup(ctx, "office")
+		if err != nil {
+			return err
+		}
+		if g, w := formatFirewallGroup(&got), formatFirewallGroup(&office); g != w {
+			t.Fatalf("firewall group mismatch:\n got: %s\nwant: %s", g, w)
+		}
+
+		_, err = groups.GetFirewallGroup(ctx, "missing")
+		expectError(t, err, errors.ErrFirewallGroupNotFound)
+
+		all, err := groups.GetFirewallGroups(ctx)
+		if err != nil {
+			return err
+		}
+		expectFirewallGroups(t, all, []entity.FirewallGroup{office, guests})
+
+		return nil
+	})
+
+	admins := entity.FirewallGroup{Name: "admins", AllowPeers: true, Rules: nil}
+
+	update(t, repo, func(repo database.Repo) error {
+		groups := repo.FirewallGroupRepo()
+		if err := groups.RemoveFirewallGroup(ctx, "guests"); err != nil {
+			return err
+		}
+		return groups.SetFirewallGroups(ctx, []entity.FirewallGroup{office, admins})
+	})
+
+	view(t, repo, func(repo database.Repo) error {
+		all, err := repo.FirewallGroupRepo().GetFirewallGroups(ctx)
+		if err != nil {
+			return err
+		}
+		expectFirewallGroups(t, all, []entity.FirewallGroup{office, admins})
+		return nil
+	})
+}
+
+func formatProfile(p *entity.WireGuardProfile) string {
+	return fmt.Sprintf("%s dns=%s allowed_ips=%s keepalive=%d/%t", p.Name,
+		netutils.FormatIPs(p.DNS, ","), netutils.FormatAddresses(p.AllowedIPs, ","),
+		p.PersistentKeepalive.Int64, p.PersistentKeepalive.Valid)
+}
+
+func expectProfiles(t *testing.T, got, want []entity.WireGuardProfile) {
+	t.Helper()
+
+	format := func(profiles []entity.WireGuardProfile) []string {
+		s := make([]string, len(profiles))
+		for i := range profiles {
+			s[i] = formatProfile(&profiles[i])
+		}
+		slices.Sort(s)
+		return s
+	}
+
+	if g, w := format(got), format(want); !slices.Equal(g, w) {
+		t.Fatalf("profiles mismatch:\n got: %q\nwant: %q", g, w)
+	}
+}
+
+func testWireGuardProfiles(t *testing.T, repo database.Repo) {
+	ctx := context.Background()
+
+	laptops := entity.WireGuardProfile{
+		Name:                "laptops",
+		DNS:                 []net.IP{net.ParseIP("10.0.0.1")},
+		AllowedIPs:          []net.IPNet{mustParseAddress("10.0.0.0/8"), mustParseAddress("fd00::/64")},
+		PersistentKeepalive: null.IntFrom(15),
+	}
+	phones := entity.WireGuardProfile{
+		Name:                "phones",
+		DNS:                 nil,
+		AllowedIPs:          nil,
+		PersistentKeepalive: null.Int{},
+	}
+
+	update(t, repo, func(repo database.Repo) error {
+		profiles := repo.WireGuardProfileRepo()
+		if err := profiles.AddWireGuardProfile(ctx, &laptops); err != nil {
+			return err
+		}
+		expectError(t, profiles.AddWireGuardProfile(ctx, &laptops), errors.ErrWireGuardProfileExists)
+		return profiles.SetWireGuardProfile(ctx, &phones)
+	})
+
+	view(t, repo, func(repo database.Repo) error {
+		profiles := repo.WireGuardProfileRepo()
+
+		exists, err := profiles.WireGuardProfileExists(ctx, "laptops")
+		if err != nil {
+			return err
+		}
+		if !exists {
+			t.Fatal("expected profile to exist")
+		}
+
+		got, err := profiles.GetWireGuardProfile(ctx, "laptops")
+		if err != nil {
+			return err
+		}
+		if g, w := formatProfile(&got), formatProfile(&laptops); g != w {
+			t.Fatalf("profile mismatch:\n got: %s\nwant: %s", g, w)
+		}
+
+		_, err = profiles.GetWireGuardProfile(ctx, "missing")
+		expectError(t, err, errors.ErrWireGuardProfileNotFound)
+
+		all, err := profiles.GetWireGuardProfiles(ctx)
+		if err != nil {
+			return err
+		}
+		expectProfiles(t, all, []entity.WireGuardProfile{laptops, phones})
+
+		return nil
+	})
+
+	servers := entity.WireGuardProfile{Name: "servers", DNS: nil, AllowedIPs: nil, PersistentKeepalive: null.IntFrom(0)}
+
+	update(t, repo, func(repo database.Repo) error {
+		profiles := repo.WireGuardProfileRepo()
+		if err := profiles.RemoveWireGuardProfile(ctx, "phones"); err != nil {
+			return err
+		}
+		return profiles.SetWireGuardProfiles(ctx, []entity.WireGuardProfile{laptops, servers})
+	})
+
+	view(t, repo, func(repo database.Repo) error {
+		all, err := repo.WireGuardProfileRepo().GetWireGuardProfiles(ctx)
+		if err != nil {
+			return err
+		}
+		expectProfiles(t, all, []entity.WireGuardProfile{laptops, servers})
+		return nil
+	})
+}
+
+func testInterfaceIsolation(t *testing.T, repo database.Repo) {
+	ctx := context.Background()
+	wg0, wg1 := repo.Interface("wg0"), repo.Interface("wg1")
+
+	// Clients of different interfaces may share names, keys and addresses.
+	alice0 := newClient(t, "alice", "10.0.0.2/32")
+	alice1 := newClient(t, "alice", "10.0.0.2/32")
+	alice1.PrivateKey, alice1.PublicKey = alice0.PrivateKey, alice0.PublicKey
+	alice1.Description = "wg1"
+	bob1 := newClient(t, "bob", "10.0.0.3/32")
+
+	config0 := entity.WireGuardServerConfig{PrivateKey: newPrivateKey(t)}
+	config1 := entity.WireGuardServerConfig{PrivateKey: newPrivateKey(t)}
+
+	update(t, repo, func(repo database.Repo) error {
+		wg0, wg1 := repo.Interface("wg0"), repo.Interface("wg1")
+		if err := wg0.WireGuardServerRepo().SetWireGuardServerConfig(&config0); err != nil {
+			return err
+		}
+		if err := wg1.WireGuardServerRepo().SetWireGuardServerConfig(&config1); err != nil {
+			return err
+		}
+		if err := wg0.WireGuardClientRepo().AddWireGuardClient(ctx, &alice0); err != nil {
+			return err
+		}
+		if err := wg1.WireGuardClientRepo().AddWireGuardClient(ctx, &alice1); err != nil {
+			return err
+		}
+		return wg1.WireGuardClientRepo().AddWireGuardClient(ctx, &bob1)
+	})
+
+	view(t, repo, func(repo database.Repo) error {
+		names, err := repo.GetInterfaces(ctx)
+		if err != nil {
+			return err
+		}
+		if !slices.Contains(names, "wg0") || !slices.Contains(names, "wg1") {
+			t.Fatalf("expected interfaces wg0 and wg1, got %q", names)
+		}
+		return nil
+	})
+
+	expectInterface := func(iface database.Repo, config *entity.WireGuardServerConfig, clients ...entity.WireGuardClient) {
+		t.Helper()
+
+		view(t, iface, func(repo database.Repo) error {
+			got, err := repo.WireGuardServerRepo().GetWireGuardServerConfig()
+			if err != nil {
+				return err
+			}
+			if got.PrivateKey != config.PrivateKey {
+				t.Fatalf("expected server private key %s, got %s", config.PrivateKey, got.PrivateKey)
+			}
+
+			all, err := repo.WireGuardClientRepo().GetWireGuardClients(ctx)
+			if err != nil {
+				return err
+			}
+			expectClients(t, all, clients)
+
+			client, err := repo.WireGuardClientRepo().GetWireGuardClientByPublicKey(ctx, alice0.PublicKey)
+			if len(clients) == 0 {
+				expectError(t, err, errors.ErrWireGuardClientNotFound)
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			expectClient(t, &client, &clients[0])
+
+			return nil
+		})
+	}
+
+	expectInterface(wg0, &config0, alice0)
+	expectInterface(wg1, &config1, alice1, bob1)
+
+	update(t, wg0, func(repo database.Repo) error {
+		return repo.WireGuardClientRepo().RemoveWireGuardClient(ctx, "alice")
+	})
+
+	expectInterface(wg0, &config0)
+	expectInterface(wg1, &config1, alice1, bob1)
+
+	update(t, wg1, func(repo database.Repo) error {
+		return repo.WireGuardClientRepo().SetWireGuardClients(ctx, nil)
+	})
+
+	expectInterface(wg1, &config1)
+}
+
+func testRollback(t *testing.T, repo database.Repo) {
+	ctx := context.Background()
+	wg0 := repo.Interface("wg0")
+
+	alice := newClient(t, "alice", "10.0.0.2/32")
+	profile := entity.WireGuardProfile{Name: "laptops", DNS: nil, AllowedIPs: nil, PersistentKeepalive: null.Int{}}
+	config := entity.WireGuardServerConfig{PrivateKey: newPrivateKey(t)}
+
+	err := repo.Update(ctx, func(repo database.Repo) error {
+		if err := repo.WireGuardProfileRepo().AddWireGuardProfile(ctx, &profile); err != nil {
+			return err
+		}
+		wg0 := repo.Interface("wg0")
+		if err := wg0.WireGuardServerRepo().SetWireGuardServerConfig(&config); err != nil {
+			return err
+		}
+		if err := wg0.WireGuardClientRepo().AddWireGuardClient(ctx, &alice); err != nil {
+			return err
+		}
+		return errRollback
+	})
+	expectError(t, err, errRollback)
+
+	err = wg0.View(ctx, func(repo database.Repo) error {
+		return errRollback
+	})
+	expectError(t, err, errRollback)
+
+	view(t, wg0, func(repo database.Repo) error {
+		exists, err := repo.WireGuardProfileRepo().WireGuardProfileExists(ctx, profile.Name)
+		if err != nil {
+			return err
+		}
+		if exists {
+			t.Fatal("expected profile to be rolled back")
+		}
+
+		exists, err = repo.WireGuardServerRepo().WireGuardServerConfigExists()
+		if err != nil {
+			return err
+		}
+		if exists {
+			t.Fatal("expected server config to be rolled back")
+		}
+
+		exists, err = repo.WireGuardClientRepo().WireGuardClientExists(ctx, alice.Name)
+		if err != nil {
+			return err
+		}
+		if exists {
+			t.Fatal("expected client to be rolled back")
+		}
+
+		_, err = repo.WireGuardClientRepo().GetWireGuardClientByPublicKey(ctx, alice.PublicKey)
+		expectError(t, err, errors.ErrWireGuardClientNotFound)
+
+		_, err = repo.WireGuardClientRepo().GetWireGuardClientByAddress(ctx, alice.Address.IP)
+		expectError(t, err, errors.ErrWireGuardClientNotFound)
+
+		return nil
+	})
+
+	// The rolled back client mustn't block adding it again.
+	update(t, wg0, func(repo database.Repo) error {
+		return repo.WireGuardClientRepo().AddWireGuardClient(ctx, &alice)
+	})
+}
+
+func testMigrate(t *testing.T, b backend) {
+	ctx := context.Background()
+
+	repo, reopen := b.open(t, nil)
+
+	alice := newClient(t, "alice", "10.0.0.2/32")
+
+	update(t, repo.Interface("wg0"), func(repo database.Repo) error {
+		return repo.WireGuardClientRepo().AddWireGuardClient(ctx, &alice)
+	})
+
+	// Migrations must not be applied twice or touch the data.
+	repo = reopen()
+
+	view(t, repo.Interface("wg0"), func(repo database.Repo) error {
+		got, err := repo.WireGuardClientRepo().GetWireGuardClient(ctx, "alice")
+		if err != nil {
+			return err
+		}
+		expectClient(t, &got, &alice)
+		return nil
+	})
+
+	if got := b.schemaVersion(t, repo); got != b.expectedVersion {
+		t.Fatalf("expected schema version %d, got %d", b.expectedVersion, got)
+	}
+}
+
+// testMovedPrivateKey checks that a sealed private key can only be opened
+// by the interface it has been sealed for.
+func testMovedPrivateKey(t *testing.T, b backend) {
+	ctx := context.Background()
+
+	repo, reopen := b.open(t, newKMS(t, true))
+
+	alice := newClient(t, "alice", "10.0.0.2/32")
+
+	update(t, repo.Interface("wg0"), func(repo database.Repo) error {
+		return repo.WireGuardClientRepo().AddWireGuardClient(ctx, &alice)
+	})
+
+	b.moveClient(t, repo, "alice", "wg0", "wg1")
+	repo = reopen()
+
+	err := repo.Interface("wg1").View(ctx, func(repo database.Repo) error {
+		_, err := repo.WireGuardClientRepo().GetWireGuardClient(ctx, "alice")
+		return err
+	})
+	expectError(t, err, envelope.ErrDecryptFailed)
+}
+
+func TestBboltMissingInterface(t *testing.T) {
+	ctx := context.Background()
+
+	repo, _ := bboltBackend().open(t, nil)
+	wg2 := repo.Interface("wg2")
+
+	alice := newClient(t, "alice", "10.0.0.2/32")
+
+	err := wg2.Update(ctx, func(repo database.Repo) error {
+		return repo.WireGuardClientRepo().AddWireGuardClient(ctx, &alice)
+	})
+	expectError(t, err, errors.ErrWireGuardInterfaceNotFound)
+
+	err = wg2.Update(ctx, func(repo database.Repo) error {
+		return repo.WireGuardClientRepo().SetWireGuardClient(ctx, &alice)
+	})
+	expectError(t, err, errors.ErrWireGuardInterfaceNotFound)
+
+	err = wg2.View(ctx, func(repo database.Repo) error {
+		_, err := repo.WireGuardClientRepo().GetWireGuardClients(ctx)
+		return err
+	})
+	expectError(t, err, errors.ErrWireGuardInterfaceNotFound)
+
+	err = wg2.View(ctx, func(repo database.Repo) error {
+		_, err := repo.WireGuardServerRepo().GetWireGuardServerConfig()
+		return err
+	})
+	expectError(t, err, errors.ErrWireGuardInterfaceNotFound)
+}
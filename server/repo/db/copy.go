@@ -0,0 +1,94 @@
+package db
+
+import (
+	"context"
+
+	"github.com/infastin/wg-wish/server/entity"
+	"github.com/infastin/wg-wish/server/errors"
+)
+
+type interfaceData struct {
+	name      string
+	server    entity.WireGuardServerConfig
+	hasServer bool
+	clients   []entity.WireGuardClient
+}
+
+// Copy replaces the contents of dst with the contents of src.
+func Copy(ctx context.Context, dst, src Repo) (err error) {
+	var (
+		ifaces     []interfaceData
+		publicKeys []entity.PublicKey
+		groups     []entity.FirewallGroup
+		profiles   []entity.WireGuardProfile
+	)
+
+	if err := src.View(ctx, func(repo Repo) error {
+		names, err := repo.GetInterfaces(ctx)
+		if err != nil {
+			return err
+		}
+
+		ifaces = make([]interfaceData, len(names))
+		for i, name := range names {
+			iface := &ifaces[i]
+			iface.name = name
+
+			iface.server, err = repo.Interface(name).WireGuardServerRepo().GetWireGuardServerConfig()
+			if err != nil && err != errors.ErrWireGuardServerConfigNotFound {
+				return err
+			}
+			iface.hasServer = err == nil
+
+			iface.clients, err = repo.Interface(name).WireGuardClientRepo().GetWireGuardClients(ctx)
+			if err != nil {
+				return err
+			}
+		}
+
+		publicKeys, err = repo.PublicKeyRepo().GetPublicKeys(ctx)
+		if err != nil {
+			return err
+		}
+
+		groups, err = repo.FirewallGroupRepo().GetFirewallGroups(ctx)
+		if err != nil {
+			return err
+		}
+
+		profiles, err = repo.WireGuardProfileRepo().GetWireGuardProfiles(ctx)
+		if err != nil {
+			return err
+		}
+
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	return dst.Update(ctx, func(repo Repo) error {
+		for i := range ifaces {
+			iface := &ifaces[i]
+
+			if iface.hasServer {
+				if err := repo.Interface(iface.name).WireGuardServerRepo().SetWireGuardServerConfig(&iface.server); err != nil {
+					return err
+				}
+			}
+
+			if err := repo.Interface(iface.name).WireGuardClientRepo().SetWireGuardClients(ctx, iface.clients); err != nil {
+				return err
+			}
+		}
+
+		if err := repo.PublicKeyRepo().SetPublicKeys(ctx, publicKeys); err != nil {
+			return err
+		}
+
+		if err := repo.FirewallGroupRepo().SetFirewallGroups(ctx, groups); err != nil {
+			return err
+		}
+
+		return repo.WireGuardProfileRepo().SetWireGuardProfiles(ctx, profiles)
+	})
+}
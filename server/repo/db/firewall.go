@@ -0,0 +1,17 @@
+package db
+
+import (
+	"context"
+
+	"github.com/infastin/wg-wish/server/entity"
+)
+
+type FirewallGroupRepo interface {
+	AddFirewallGroup(ctx context.Context, group *entity.FirewallGroup) (err error)
+	SetFirewallGroup(ctx context.Context, group *entity.FirewallGroup) (err error)
+	RemoveFirewallGroup(ctx context.Context, name string) (err error)
+	FirewallGroupExists(ctx context.Context, name string) (exists bool, err error)
+	GetFirewallGroup(ctx context.Context, name string) (group entity.FirewallGroup, err error)
+	GetFirewallGroups(ctx context.Context) (groups []entity.FirewallGroup, err error)
+	SetFirewallGroups(ctx context.Context, groups []entity.FirewallGroup) (err error)
+}
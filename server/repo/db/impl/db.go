@@ -2,10 +2,12 @@ package dbrepo
 
 import (
 	"context"
+	"io"
 
 	"github.com/charmbracelet/ssh"
 	"github.com/infastin/gorack/errdefer"
 	"github.com/infastin/gorack/fastconv"
+	"github.com/infastin/wg-wish/pkg/envelope"
 	"github.com/infastin/wg-wish/server/entity"
 	"github.com/infastin/wg-wish/server/errors"
 	database "github.com/infastin/wg-wish/server/repo/db"
@@ -14,41 +16,77 @@ import (
 	"go.etcd.io/bbolt"
 )
 
-var ErrTxNotStarted = errors.New("transaction not started")
+var (
+	ErrTxNotStarted         = errors.New("transaction not started")
+	ErrInterfaceNotSelected = errors.New("interface not selected")
+)
 
 type DatabaseRepoParams struct {
 	Logger zerolog.Logger
 
 	Path      string
 	AdminKeys []string
+	KMS       envelope.KMS
+	// Interfaces are created if they don't exist yet.
+	Interfaces []string
+	// ReadOnly opens the database without modifying it, in which case
+	// it must already be at the current schema version and only View can be used.
+	ReadOnly bool
 }
 
 type DatabaseRepo struct {
 	lg      zerolog.Logger
 	db      *bbolt.DB
+	kms     envelope.KMS
+	iface   string
 	queries *queries.Queries
 }
 
 func New(params *DatabaseRepoParams) (dbrepo *DatabaseRepo, err error) {
-	db, err := bbolt.Open(params.Path, 0600, nil)
+	db, err := bbolt.Open(params.Path, 0600, &bbolt.Options{ //nolint:exhaustruct
+		ReadOnly: params.ReadOnly,
+	})
 	if err != nil {
 		return nil, err
 	}
 	defer errdefer.Close(&err, db.Close)
 
-	err = queries.Prepare(db)
-	if err != nil {
-		return nil, err
-	}
-
 	repo := &DatabaseRepo{
 		lg:      params.Logger,
 		db:      db,
+		kms:     params.KMS,
+		iface:   "",
 		queries: nil,
 	}
 
+	if params.ReadOnly {
+		if err := queries.CheckSchemaVersion(db); err != nil {
+			return nil, err
+		}
+		return repo, nil
+	}
+
+	err = queries.Migrate(db, params.KMS, func(version int, name string) {
+		params.Logger.Info().Int("version", version).Str("name", name).Msg("applying database migration")
+	})
+	if err != nil {
+		return nil, err
+	}
+
 	ctx := context.Background()
 
+	if err := db.Update(func(tx *bbolt.Tx) error {
+		queries := queries.New(tx, params.KMS)
+		for _, name := range params.Interfaces {
+			if err := queries.CreateInterface(name); err != nil {
+				return err
+			}
+		}
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
 	if err := repo.Update(ctx, func(repo database.Repo) error {
 		for _, adminKey := range params.AdminKeys {
 			pkey, comment, _, _, err := ssh.ParseAuthorizedKey(fastconv.Bytes(adminKey))
@@ -71,15 +109,30 @@ func New(params *DatabaseRepoParams) (dbrepo *DatabaseRepo, err error) {
 	return repo, nil
 }
 
+// mapInterfaceError maps the error of queries bound to an interface that doesn't exist.
+func mapInterfaceError(err error) error {
+	if err == queries.ErrInterfaceNotFound {
+		return errors.ErrWireGuardInterfaceNotFound
+	}
+	return err
+}
+
 func (db *DatabaseRepo) Close() error {
 	return db.db.Close()
 }
 
 func (db *DatabaseRepo) atomic(callback database.AtomicCallback, tx *bbolt.Tx) (err error) {
+	queries := queries.New(tx, db.kms)
+	if db.iface != "" {
+		queries = queries.Interface(db.iface)
+	}
+
 	return callback(&DatabaseRepo{
 		lg:      db.lg,
 		db:      db.db,
-		queries: queries.New(tx),
+		kms:     db.kms,
+		iface:   db.iface,
+		queries: queries,
 	})
 }
 
@@ -101,6 +154,59 @@ func (db *DatabaseRepo) Batch(ctx context.Context, callback database.AtomicCallb
 	})
 }
 
+func (db *DatabaseRepo) Interface(name string) database.Repo {
+	repo := &DatabaseRepo{
+		lg:      db.lg,
+		db:      db.db,
+		kms:     db.kms,
+		iface:   name,
+		queries: nil,
+	}
+
+	if db.queries != nil {
+		repo.queries = db.queries.Interface(name)
+	}
+
+	return repo
+}
+
+func (db *DatabaseRepo) GetInterfaces(ctx context.Context) (names []string, err error) {
+	if db.queries == nil {
+		panic(ErrTxNotStarted)
+	}
+	return db.queries.GetInterfaces(), nil
+}
+
+// SealPrivateKeys encrypts all private keys stored in the database
+// with the primary master key, returning the number of rewritten records.
+func (db *DatabaseRepo) SealPrivateKeys(ctx context.Context) (n int, err error) {
+	err = db.db.Update(func(tx *bbolt.Tx) error {
+		n, err = queries.New(tx, db.kms).SealPrivateKeys()
+		return err
+	})
+	return n, err
+}
+
+// Snapshot writes a consistent copy of the database to the writer,
+// returning the ID of the last transaction included in it.
+func (db *DatabaseRepo) Snapshot(ctx context.Context, w io.Writer) (txID uint64, err error) {
+	err = db.db.View(func(tx *bbolt.Tx) error {
+		txID = uint64(tx.ID())
+		_, err := tx.WriteTo(w)
+		return err
+	})
+	return txID, err
+}
+
+// TxID returns the ID of the last committed transaction.
+func (db *DatabaseRepo) TxID(ctx context.Context) (txID uint64, err error) {
+	err = db.db.View(func(tx *bbolt.Tx) error {
+		txID = uint64(tx.ID())
+		return nil
+	})
+	return txID, err
+}
+
 func (db *DatabaseRepo) PublicKeyRepo() database.PublicKeyRepo {
 	if db.queries == nil {
 		panic(ErrTxNotStarted)
@@ -112,10 +218,30 @@ func (db *DatabaseRepo) WireGuardClientRepo() database.WireGuardClientRepo {
 	if db.queries == nil {
 		panic(ErrTxNotStarted)
 	}
+	if db.iface == "" {
+		panic(ErrInterfaceNotSelected)
+	}
 	return db
 }
 
 func (db *DatabaseRepo) WireGuardServerRepo() database.WireGuardServerRepo {
+	if db.queries == nil {
+		panic(ErrTxNotStarted)
+	}
+	if db.iface == "" {
+		panic(ErrInterfaceNotSelected)
+	}
+	return db
+}
+
+func (db *DatabaseRepo) FirewallGroupRepo() database.FirewallGroupRepo {
+	if db.queries == nil {
+		panic(ErrTxNotStarted)
+	}
+	return db
+}
+
+func (db *DatabaseRepo) WireGuardProfileRepo() database.WireGuardProfileRepo {
 	if db.queries == nil {
 		panic(ErrTxNotStarted)
 	}
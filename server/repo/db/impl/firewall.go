@@ -0,0 +1,104 @@
+package dbrepo
+
+import (
+	"context"
+
+	"github.com/infastin/wg-wish/server/entity"
+	"github.com/infastin/wg-wish/server/errors"
+	"github.com/infastin/wg-wish/server/repo/db/impl/queries"
+)
+
+func (db *DatabaseRepo) AddFirewallGroup(ctx context.Context, group *entity.FirewallGroup) (err error) {
+	if db.queries.FirewallGroupExists(group.Name) {
+		return errors.ErrFirewallGroupExists
+	}
+	return db.queries.SetFirewallGroup(mapFromFirewallGroup(group))
+}
+
+func (db *DatabaseRepo) SetFirewallGroup(ctx context.Context, group *entity.FirewallGroup) (err error) {
+	return db.queries.SetFirewallGroup(mapFromFirewallGroup(group))
+}
+
+func (db *DatabaseRepo) RemoveFirewallGroup(ctx context.Context, name string) (err error) {
+	return db.queries.RemoveFirewallGroup(name)
+}
+
+func (db *DatabaseRepo) FirewallGroupExists(ctx context.Context, name string) (exists bool, err error) {
+	return db.queries.FirewallGroupExists(name), nil
+}
+
+func (db *DatabaseRepo) GetFirewallGroup(ctx context.Context, name string) (group entity.FirewallGroup, err error) {
+	dbGroup, err := db.queries.GetFirewallGroup(name)
+	if err != nil {
+		if err == queries.ErrKeyNotFound {
+			err = errors.ErrFirewallGroupNotFound
+		}
+		return entity.FirewallGroup{}, err
+	}
+	return mapToFirewallGroup(&dbGroup), nil
+}
+
+func (db *DatabaseRepo) GetFirewallGroups(ctx context.Context) (groups []entity.FirewallGroup, err error) {
+	dbGroups, err := db.queries.GetFirewallGroups()
+	if err != nil {
+		return nil, err
+	}
+
+	groups = make([]entity.FirewallGroup, 0, len(dbGroups))
+	for i := range dbGroups {
+		groups = append(groups, mapToFirewallGroup(&dbGroups[i]))
+	}
+
+	return groups, nil
+}
+
+func (db *DatabaseRepo) SetFirewallGroups(ctx context.Context, groups []entity.FirewallGroup) (err error) {
+	err = db.queries.ClearFirewallGroups()
+	if err != nil {
+		return err
+	}
+
+	for i := range groups {
+		if err := db.queries.SetFirewallGroup(mapFromFirewallGroup(&groups[i])); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func mapFromFirewallGroup(group *entity.FirewallGroup) *queries.FirewallGroup {
+	rules := make([]queries.FirewallRule, len(group.Rules))
+	for i := range group.Rules {
+		rules[i] = queries.FirewallRule{
+			Destination: group.Rules[i].Destination,
+			Protocol:    string(group.Rules[i].Protocol),
+			PortFrom:    group.Rules[i].PortFrom,
+			PortTo:      group.Rules[i].PortTo,
+		}
+	}
+
+	return &queries.FirewallGroup{
+		Name:       group.Name,
+		AllowPeers: group.AllowPeers,
+		Rules:      rules,
+	}
+}
+
+func mapToFirewallGroup(group *queries.FirewallGroup) entity.FirewallGroup {
+	rules := make([]entity.FirewallRule, len(group.Rules))
+	for i := range group.Rules {
+		rules[i] = entity.FirewallRule{
+			Destination: group.Rules[i].Destination,
+			Protocol:    entity.FirewallProtocol(group.Rules[i].Protocol),
+			PortFrom:    group.Rules[i].PortFrom,
+			PortTo:      group.Rules[i].PortTo,
+		}
+	}
+
+	return entity.FirewallGroup{
+		Name:       group.Name,
+		AllowPeers: group.AllowPeers,
+		Rules:      rules,
+	}
+}
@@ -0,0 +1,86 @@
+package dbrepo
+
+import (
+	"context"
+
+	"github.com/infastin/wg-wish/server/entity"
+	"github.com/infastin/wg-wish/server/errors"
+	"github.com/infastin/wg-wish/server/repo/db/impl/queries"
+)
+
+func (db *DatabaseRepo) AddWireGuardProfile(ctx context.Context, profile *entity.WireGuardProfile) (err error) {
+	if db.queries.WireGuardProfileExists(profile.Name) {
+		return errors.ErrWireGuardProfileExists
+	}
+	return db.queries.SetWireGuardProfile(mapFromWireGuardProfile(profile))
+}
+
+func (db *DatabaseRepo) SetWireGuardProfile(ctx context.Context, profile *entity.WireGuardProfile) (err error) {
+	return db.queries.SetWireGuardProfile(mapFromWireGuardProfile(profile))
+}
+
+func (db *DatabaseRepo) RemoveWireGuardProfile(ctx context.Context, name string) (err error) {
+	return db.queries.RemoveWireGuardProfile(name)
+}
+
+func (db *DatabaseRepo) WireGuardProfileExists(ctx context.Context, name string) (exists bool, err error) {
+	return db.queries.WireGuardProfileExists(name), nil
+}
+
+func (db *DatabaseRepo) GetWireGuardProfile(ctx context.Context, name string) (profile entity.WireGuardProfile, err error) {
+	dbProfile, err := db.queries.GetWireGuardProfile(name)
+	if err != nil {
+		if err == queries.ErrKeyNotFound {
+			err = errors.ErrWireGuardProfileNotFound
+		}
+		return entity.WireGuardProfile{}, err
+	}
+	return mapToWireGuardProfile(&dbProfile), nil
+}
+
+func (db *DatabaseRepo) GetWireGuardProfiles(ctx context.Context) (profiles []entity.WireGuardProfile, err error) {
+	dbProfiles, err := db.queries.GetWireGuardProfiles()
+	if err != nil {
+		return nil, err
+	}
+
+	profiles = make([]entity.WireGuardProfile, 0, len(dbProfiles))
+	for i := range dbProfiles {
+		profiles = append(profiles, mapToWireGuardProfile(&dbProfiles[i]))
+	}
+
+	return profiles, nil
+}
+
+func (db *DatabaseRepo) SetWireGuardProfiles(ctx context.Context, profiles []entity.WireGuardProfile) (err error) {
+	err = db.queries.ClearWireGuardProfiles()
+	if err != nil {
+		return err
+	}
+
+	for i := range profiles {
+		if err := db.queries.SetWireGuardProfile(mapFromWireGuardProfile(&profiles[i])); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func mapFromWireGuardProfile(profile *entity.WireGuardProfile) *queries.WireGuardProfile {
+	return &queries.WireGuardProfile{
+		Name:                profile.Name,
+		DNS:                 profile.DNS,
+		AllowedIPs:          profile.AllowedIPs,
+		PersistentKeepalive: profile.PersistentKeepalive,
+	}
+}
+
+func mapToWireGuardProfile(profile *queries.WireGuardProfile) entity.WireGuardProfile {
+	return entity.WireGuardProfile{
+		Name:                profile.Name,
+		DNS:                 profile.DNS,
+		AllowedIPs:          profile.AllowedIPs,
+		PersistentKeepalive: profile.PersistentKeepalive,
+	}
+}
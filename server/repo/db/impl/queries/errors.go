@@ -1,7 +1,39 @@
 package queries
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+)
 
 var (
-	ErrKeyNotFound = errors.New("key not found")
+	ErrKeyNotFound           = errors.New("key not found")
+	ErrUnsupportedVersion    = errors.New("unsupported value version")
+	ErrEncryptionKeyRequired = errors.New("master key is required to access encrypted values")
+	ErrInterfaceNotFound     = errors.New("interface not found")
 )
+
+type SchemaVersionError struct {
+	Version  int
+	Expected int
+}
+
+func (e *SchemaVersionError) Error() string {
+	if e.Version < e.Expected {
+		return fmt.Sprintf("database schema version %d is older than expected version %d", e.Version, e.Expected)
+	}
+	return fmt.Sprintf("database schema version %d is newer than supported version %d", e.Version, e.Expected)
+}
+
+type MigrationError struct {
+	Version int
+	Name    string
+	Err     error
+}
+
+func (e *MigrationError) Error() string {
+	return fmt.Sprintf("migration %d (%s) failed: %v", e.Version, e.Name, e.Err)
+}
+
+func (e *MigrationError) Unwrap() error {
+	return e.Err
+}
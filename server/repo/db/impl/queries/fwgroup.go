@@ -0,0 +1,176 @@
+package queries
+
+import (
+	"net"
+)
+
+//go:generate msgp -tests=false -unexported
+
+var fwGroupBucketName = []byte("fwgroup")
+
+func fwGroupMarshalKey(b []byte, name string) []byte {
+	return append(b, name...)
+}
+
+func fwGroupUnmarshalKey(b []byte) (name string, err error) {
+	return string(b), nil
+}
+
+//msgp:replace net.IPNet with:msgpIPNet
+
+type fwGroupValueV1 struct {
+	AllowPeers bool            `msg:"allow_peers"`
+	Rules      []fwRuleValueV1 `msg:"rules"`
+}
+
+//msgp:tuple fwRuleValueV1
+
+type fwRuleValueV1 struct {
+	Destination net.IPNet
+	Protocol    string
+	PortFrom    uint16
+	PortTo      uint16
+}
+
+func fwGroupMarshalValueV1(b []byte, value *fwGroupValueV1) []byte {
+	b, _ = value.MarshalMsg(b)
+	return b
+}
+
+func fwGroupUnmarshalValueV1(b []byte) (val fwGroupValueV1, err error) {
+	_, err = val.UnmarshalMsg(b)
+	return val, err
+}
+
+//msgp:ignore FirewallGroup FirewallRule
+
+type FirewallGroup struct {
+	Name       string
+	AllowPeers bool
+	Rules      []FirewallRule
+}
+
+type FirewallRule struct {
+	Destination net.IPNet
+	Protocol    string
+	PortFrom    uint16
+	PortTo      uint16
+}
+
+func fwGroupUnmarshalValue(valb []byte) (group FirewallGroup, err error) {
+	version, valb, err := unmarshalMeta(valb)
+	if err != nil {
+		return FirewallGroup{}, err
+	}
+
+	switch version {
+	case 1:
+		val, err := fwGroupUnmarshalValueV1(valb)
+		if err != nil {
+			return FirewallGroup{}, err
+		}
+
+		group = FirewallGroup{
+			Name:       "",
+			AllowPeers: val.AllowPeers,
+			Rules:      make([]FirewallRule, len(val.Rules)),
+		}
+
+		for i := range val.Rules {
+			group.Rules[i] = FirewallRule(val.Rules[i])
+		}
+
+		return group, nil
+	}
+
+	return FirewallGroup{}, ErrUnsupportedVersion
+}
+
+func (queries *Queries) SetFirewallGroup(group *FirewallGroup) (err error) {
+	b := queries.tx.Bucket(fwGroupBucketName)
+
+	keyb := fwGroupMarshalKey(nil, group.Name)
+
+	value := fwGroupValueV1{
+		AllowPeers: group.AllowPeers,
+		Rules:      make([]fwRuleValueV1, len(group.Rules)),
+	}
+
+	for i := range group.Rules {
+		value.Rules[i] = fwRuleValueV1(group.Rules[i])
+	}
+
+	valb := Meta(0).SetVersion(1).Append(nil)
+	valb = fwGroupMarshalValueV1(valb, &value)
+
+	return b.Put(keyb, valb)
+}
+
+func (queries *Queries) GetFirewallGroup(name string) (group FirewallGroup, err error) {
+	b := queries.tx.Bucket(fwGroupBucketName)
+
+	valb := b.Get(fwGroupMarshalKey(nil, name))
+	if valb == nil {
+		return FirewallGroup{}, ErrKeyNotFound
+	}
+
+	group, err = fwGroupUnmarshalValue(valb)
+	if err != nil {
+		return FirewallGroup{}, err
+	}
+	group.Name = name
+
+	return group, nil
+}
+
+func (queries *Queries) GetFirewallGroups() (groups []FirewallGroup, err error) {
+	b := queries.tx.Bucket(fwGroupBucketName)
+
+	c := b.Cursor()
+	for keyb, valb := c.First(); keyb != nil; keyb, valb = c.Next() {
+		name, err := fwGroupUnmarshalKey(keyb)
+		if err != nil {
+			return nil, err
+		}
+
+		group, err := fwGroupUnmarshalValue(valb)
+		if err != nil {
+			return nil, err
+		}
+		group.Name = name
+
+		groups = append(groups, group)
+	}
+
+	return groups, nil
+}
+
+func (queries *Queries) ClearFirewallGroups() (err error) {
+	b := queries.tx.Bucket(fwGroupBucketName)
+
+	c := b.Cursor()
+	// NOTE: Cursor.Next skips an item after Cursor.Delete, so start over every time.
+	for keyb, _ := c.First(); keyb != nil; keyb, _ = c.First() {
+		err = c.Delete()
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (queries *Queries) RemoveFirewallGroup(name string) (err error) {
+	b := queries.tx.Bucket(fwGroupBucketName)
+	return b.Delete(fwGroupMarshalKey(nil, name))
+}
+
+func (queries *Queries) FirewallGroupExists(name string) (exists bool) {
+	b := queries.tx.Bucket(fwGroupBucketName)
+	return b.Get(fwGroupMarshalKey(nil, name)) != nil
+}
+
+func migrateCreateFirewallGroupBucket(queries *Queries) (err error) {
+	_, err = queries.tx.CreateBucketIfNotExists(fwGroupBucketName)
+	return err
+}
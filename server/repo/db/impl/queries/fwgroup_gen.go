@@ -0,0 +1,301 @@
+package queries
+
+// Code generated by github.com/tinylib/msgp DO NOT EDIT.
+
+import (
+	"github.com/tinylib/msgp/msgp"
+)
+
+// DecodeMsg implements msgp.Decodable
+func (z *fwGroupValueV1) DecodeMsg(dc *msgp.Reader) (err error) {
+	var field []byte
+	_ = field
+	var zb0001 uint32
+	zb0001, err = dc.ReadMapHeader()
+	if err != nil {
+		err = msgp.WrapError(err)
+		return
+	}
+	for zb0001 > 0 {
+		zb0001--
+		field, err = dc.ReadMapKeyPtr()
+		if err != nil {
+			err = msgp.WrapError(err)
+			return
+		}
+		switch msgp.UnsafeString(field) {
+		case "allow_peers":
+			z.AllowPeers, err = dc.ReadBool()
+			if err != nil {
+				err = msgp.WrapError(err, "AllowPeers")
+				return
+			}
+		case "rules":
+			var zb0002 uint32
+			zb0002, err = dc.ReadArrayHeader()
+			if err != nil {
+				err = msgp.WrapError(err, "Rules")
+				return
+			}
+			if cap(z.Rules) >= int(zb0002) {
+				z.Rules = (z.Rules)[:zb0002]
+			} else {
+				z.Rules = make([]fwRuleValueV1, zb0002)
+			}
+			for za0001 := range z.Rules {
+				err = z.Rules[za0001].DecodeMsg(dc)
+				if err != nil {
+					err = msgp.WrapError(err, "Rules", za0001)
+					return
+				}
+			}
+		default:
+			err = dc.Skip()
+			if err != nil {
+				err = msgp.WrapError(err)
+				return
+			}
+		}
+	}
+	return
+}
+
+// EncodeMsg implements msgp.Encodable
+func (z *fwGroupValueV1) EncodeMsg(en *msgp.Writer) (err error) {
+	// map header, size 2
+	// write "allow_peers"
+	err = en.Append(0x82, 0xab, 0x61, 0x6c, 0x6c, 0x6f, 0x77, 0x5f, 0x70, 0x65, 0x65, 0x72, 0x73)
+	if err != nil {
+		return
+	}
+	err = en.WriteBool(z.AllowPeers)
+	if err != nil {
+		err = msgp.WrapError(err, "AllowPeers")
+		return
+	}
+	// write "rules"
+	err = en.Append(0xa5, 0x72, 0x75, 0x6c, 0x65, 0x73)
+	if err != nil {
+		return
+	}
+	err = en.WriteArrayHeader(uint32(len(z.Rules)))
+	if err != nil {
+		err = msgp.WrapError(err, "Rules")
+		return
+	}
+	for za0001 := range z.Rules {
+		err = z.Rules[za0001].EncodeMsg(en)
+		if err != nil {
+			err = msgp.WrapError(err, "Rules", za0001)
+			return
+		}
+	}
+	return
+}
+
+// MarshalMsg implements msgp.Marshaler
+func (z *fwGroupValueV1) MarshalMsg(b []byte) (o []byte, err error) {
+	o = msgp.Require(b, z.Msgsize())
+	// map header, size 2
+	// string "allow_peers"
+	o = append(o, 0x82, 0xab, 0x61, 0x6c, 0x6c, 0x6f, 0x77, 0x5f, 0x70, 0x65, 0x65, 0x72, 0x73)
+	o = msgp.AppendBool(o, z.AllowPeers)
+	// string "rules"
+	o = append(o, 0xa5, 0x72, 0x75, 0x6c, 0x65, 0x73)
+	o = msgp.AppendArrayHeader(o, uint32(len(z.Rules)))
+	for za0001 := range z.Rules {
+		o, err = z.Rules[za0001].MarshalMsg(o)
+		if err != nil {
+			err = msgp.WrapError(err, "Rules", za0001)
+			return
+		}
+	}
+	return
+}
+
+// UnmarshalMsg implements msgp.Unmarshaler
+func (z *fwGroupValueV1) UnmarshalMsg(bts []byte) (o []byte, err error) {
+	var field []byte
+	_ = field
+	var zb0001 uint32
+	zb0001, bts, err = msgp.ReadMapHeaderBytes(bts)
+	if err != nil {
+		err = msgp.WrapError(err)
+		return
+	}
+	for zb0001 > 0 {
+		zb0001--
+		field, bts, err = msgp.ReadMapKeyZC(bts)
+		if err != nil {
+			err = msgp.WrapError(err)
+			return
+		}
+		switch msgp.UnsafeString(field) {
+		case "allow_peers":
+			z.AllowPeers, bts, err = msgp.ReadBoolBytes(bts)
+			if err != nil {
+				err = msgp.WrapError(err, "AllowPeers")
+				return
+			}
+		case "rules":
+			var zb0002 uint32
+			zb0002, bts, err = msgp.ReadArrayHeaderBytes(bts)
+			if err != nil {
+				err = msgp.WrapError(err, "Rules")
+				return
+			}
+			if cap(z.Rules) >= int(zb0002) {
+				z.Rules = (z.Rules)[:zb0002]
+			} else {
+				z.Rules = make([]fwRuleValueV1, zb0002)
+			}
+			for za0001 := range z.Rules {
+				bts, err = z.Rules[za0001].UnmarshalMsg(bts)
+				if err != nil {
+					err = msgp.WrapError(err, "Rules", za0001)
+					return
+				}
+			}
+		default:
+			bts, err = msgp.Skip(bts)
+			if err != nil {
+				err = msgp.WrapError(err)
+				return
+			}
+		}
+	}
+	o = bts
+	return
+}
+
+// Msgsize returns an upper bound estimate of the number of bytes occupied by the serialized message
+func (z *fwGroupValueV1) Msgsize() (s int) {
+	s = 1 + 12 + msgp.BoolSize + 6 + msgp.ArrayHeaderSize
+	for za0001 := range z.Rules {
+		s += z.Rules[za0001].Msgsize()
+	}
+	return
+}
+
+// DecodeMsg implements msgp.Decodable
+func (z *fwRuleValueV1) DecodeMsg(dc *msgp.Reader) (err error) {
+	var zb0001 uint32
+	zb0001, err = dc.ReadArrayHeader()
+	if err != nil {
+		err = msgp.WrapError(err)
+		return
+	}
+	if zb0001 != 4 {
+		err = msgp.ArrayError{Wanted: 4, Got: zb0001}
+		return
+	}
+	err = (*msgpIPNet)(&z.Destination).DecodeMsg(dc)
+	if err != nil {
+		err = msgp.WrapError(err, "Destination")
+		return
+	}
+	z.Protocol, err = dc.ReadString()
+	if err != nil {
+		err = msgp.WrapError(err, "Protocol")
+		return
+	}
+	z.PortFrom, err = dc.ReadUint16()
+	if err != nil {
+		err = msgp.WrapError(err, "PortFrom")
+		return
+	}
+	z.PortTo, err = dc.ReadUint16()
+	if err != nil {
+		err = msgp.WrapError(err, "PortTo")
+		return
+	}
+	return
+}
+
+// EncodeMsg implements msgp.Encodable
+func (z *fwRuleValueV1) EncodeMsg(en *msgp.Writer) (err error) {
+	// array header, size 4
+	err = en.Append(0x94)
+	if err != nil {
+		return
+	}
+	err = (*msgpIPNet)(&z.Destination).EncodeMsg(en)
+	if err != nil {
+		err = msgp.WrapError(err, "Destination")
+		return
+	}
+	err = en.WriteString(z.Protocol)
+	if err != nil {
+		err = msgp.WrapError(err, "Protocol")
+		return
+	}
+	err = en.WriteUint16(z.PortFrom)
+	if err != nil {
+		err = msgp.WrapError(err, "PortFrom")
+		return
+	}
+	err = en.WriteUint16(z.PortTo)
+	if err != nil {
+		err = msgp.WrapError(err, "PortTo")
+		return
+	}
+	return
+}
+
+// MarshalMsg implements msgp.Marshaler
+func (z *fwRuleValueV1) MarshalMsg(b []byte) (o []byte, err error) {
+	o = msgp.Require(b, z.Msgsize())
+	// array header, size 4
+	o = append(o, 0x94)
+	o, err = (*msgpIPNet)(&z.Destination).MarshalMsg(o)
+	if err != nil {
+		err = msgp.WrapError(err, "Destination")
+		return
+	}
+	o = msgp.AppendString(o, z.Protocol)
+	o = msgp.AppendUint16(o, z.PortFrom)
+	o = msgp.AppendUint16(o, z.PortTo)
+	return
+}
+
+// UnmarshalMsg implements msgp.Unmarshaler
+func (z *fwRuleValueV1) UnmarshalMsg(bts []byte) (o []byte, err error) {
+	var zb0001 uint32
+	zb0001, bts, err = msgp.ReadArrayHeaderBytes(bts)
+	if err != nil {
+		err = msgp.WrapError(err)
+		return
+	}
+	if zb0001 != 4 {
+		err = msgp.ArrayError{Wanted: 4, Got: zb0001}
+		return
+	}
+	bts, err = (*msgpIPNet)(&z.Destination).UnmarshalMsg(bts)
+	if err != nil {
+		err = msgp.WrapError(err, "Destination")
+		return
+	}
+	z.Protocol, bts, err = msgp.ReadStringBytes(bts)
+	if err != nil {
+		err = msgp.WrapError(err, "Protocol")
+		return
+	}
+	z.PortFrom, bts, err = msgp.ReadUint16Bytes(bts)
+	if err != nil {
+		err = msgp.WrapError(err, "PortFrom")
+		return
+	}
+	z.PortTo, bts, err = msgp.ReadUint16Bytes(bts)
+	if err != nil {
+		err = msgp.WrapError(err, "PortTo")
+		return
+	}
+	o = bts
+	return
+}
+
+// Msgsize returns an upper bound estimate of the number of bytes occupied by the serialized message
+func (z *fwRuleValueV1) Msgsize() (s int) {
+	s = 1 + (*msgpIPNet)(&z.Destination).Msgsize() + msgp.StringPrefixSize + len(z.Protocol) + msgp.Uint16Size + msgp.Uint16Size
+	return
+}
@@ -0,0 +1,115 @@
+package queries
+
+import (
+	"go.etcd.io/bbolt"
+)
+
+// interfacesBucketName is the bucket holding a nested bucket per WireGuard interface,
+// which in turn holds the interface's server config, clients and their indexes.
+var interfacesBucketName = []byte("interfaces")
+
+// LegacyInterface is the interface receiving the data of databases
+// created before multiple interfaces have been introduced, when wg0 was the only one.
+const LegacyInterface = "wg0"
+
+// interfaceBuckets are the buckets every interface has.
+var interfaceBuckets = [][]byte{
+	wgServerBucketName,
+	wgClientBucketName,
+	wgClientPublicKeyIndexBucketName,
+	wgClientAddressIndexBucketName,
+}
+
+// bucketParent is implemented by both bbolt.Tx and bbolt.Bucket.
+type bucketParent interface {
+	Bucket(name []byte) *bbolt.Bucket
+	CreateBucket(name []byte) (*bbolt.Bucket, error)
+	CreateBucketIfNotExists(name []byte) (*bbolt.Bucket, error)
+	DeleteBucket(name []byte) error
+}
+
+// Interface returns queries accessing the WireGuard data of the interface.
+// If the interface hasn't been created with CreateInterface,
+// the queries fail with ErrInterfaceNotFound.
+func (queries *Queries) Interface(name string) *Queries {
+	var parent bucketParent
+	if ifaces := queries.tx.Bucket(interfacesBucketName); ifaces != nil {
+		if b := ifaces.Bucket([]byte(name)); b != nil {
+			parent = b
+		}
+	}
+
+	return &Queries{
+		tx:     queries.tx,
+		kms:    queries.kms,
+		parent: parent,
+		iface:  name,
+	}
+}
+
+// bucket returns the bucket holding WireGuard data.
+func (queries *Queries) bucket(name []byte) (*bbolt.Bucket, error) {
+	if queries.parent == nil {
+		return nil, ErrInterfaceNotFound
+	}
+	return queries.parent.Bucket(name), nil
+}
+
+// CreateInterface creates the buckets of the interface if they don't exist.
+func (queries *Queries) CreateInterface(name string) (err error) {
+	ifaces, err := queries.tx.CreateBucketIfNotExists(interfacesBucketName)
+	if err != nil {
+		return err
+	}
+
+	b, err := ifaces.CreateBucketIfNotExists([]byte(name))
+	if err != nil {
+		return err
+	}
+
+	for _, name := range interfaceBuckets {
+		if _, err := b.CreateBucketIfNotExists(name); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// GetInterfaces returns the names of all interfaces stored in the database.
+func (queries *Queries) GetInterfaces() (names []string) {
+	ifaces := queries.tx.Bucket(interfacesBucketName)
+	if ifaces == nil {
+		return nil
+	}
+
+	c := ifaces.Cursor()
+	for keyb, valb := c.First(); keyb != nil; keyb, valb = c.Next() {
+		// Nested buckets have nil values.
+		if valb == nil {
+			names = append(names, string(keyb))
+		}
+	}
+
+	return names
+}
+
+func migrateMoveToInterfaceBuckets(queries *Queries) (err error) {
+	if err := queries.CreateInterface(LegacyInterface); err != nil {
+		return err
+	}
+
+	dst := queries.tx.Bucket(interfacesBucketName).Bucket([]byte(LegacyInterface))
+
+	for _, name := range interfaceBuckets {
+		// Buckets have just been created empty, so replace them with the existing ones.
+		if err := dst.DeleteBucket(name); err != nil {
+			return err
+		}
+		if err := queries.tx.MoveBucket(name, nil, dst); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
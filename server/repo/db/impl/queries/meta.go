@@ -35,3 +35,11 @@ func (m Meta) SetCollision(c bool) Meta {
 	}
 	return m & 0xEF
 }
+
+// unmarshalMeta splits the value into its version and the rest.
+func unmarshalMeta(valb []byte) (version int, rest []byte, err error) {
+	if len(valb) == 0 {
+		return 0, nil, ErrUnsupportedVersion
+	}
+	return Meta(valb[0]).Version(), valb[1:], nil
+}
@@ -0,0 +1,126 @@
+package queries
+
+import (
+	"encoding/binary"
+
+	"github.com/infastin/wg-wish/pkg/envelope"
+	"go.etcd.io/bbolt"
+)
+
+var (
+	metaBucketName       = []byte("meta")
+	metaSchemaVersionKey = []byte("schema_version")
+)
+
+// SchemaVersion is the version of the database schema this binary works with.
+// Databases with a greater schema version can not be opened.
+const SchemaVersion = 6
+
+type migration struct {
+	version int
+	name    string
+	up      func(queries *Queries) error
+}
+
+// Migrations must be sorted by version and must not have gaps.
+var migrations = []migration{
+	{version: 1, name: "create buckets", up: migrateCreateBuckets},
+	{version: 2, name: "index wireguard clients", up: migrateIndexWireGuardClients},
+	{version: 3, name: "create firewall groups bucket", up: migrateCreateFirewallGroupBucket},
+	{version: 4, name: "move wireguard data to interface buckets", up: migrateMoveToInterfaceBuckets},
+	{version: 5, name: "create profiles bucket", up: migrateCreateProfileBucket},
+	{version: 6, name: "upgrade wireguard client records", up: migrateUpgradeWireGuardClients},
+}
+
+type MigrationCallback func(version int, name string)
+
+// Migrate brings the database schema to SchemaVersion within a single transaction,
+// calling cb before applying each migration.
+// Databases created before schema versioning have been introduced have version 0.
+func Migrate(db *bbolt.DB, kms envelope.KMS, cb MigrationCallback) (err error) {
+	return db.Update(func(tx *bbolt.Tx) error {
+		meta, err := tx.CreateBucketIfNotExists(metaBucketName)
+		if err != nil {
+			return err
+		}
+
+		version := schemaVersion(meta)
+		if version > SchemaVersion {
+			return &SchemaVersionError{
+				Version:  version,
+				Expected: SchemaVersion,
+			}
+		}
+
+		queries := New(tx, kms)
+		for i := range migrations {
+			if migrations[i].version <= version {
+				continue
+			}
+
+			if cb != nil {
+				cb(migrations[i].version, migrations[i].name)
+			}
+
+			if err := migrations[i].up(queries); err != nil {
+				return &MigrationError{
+					Version: migrations[i].version,
+					Name:    migrations[i].name,
+					Err:     err,
+				}
+			}
+
+			if err := setSchemaVersion(meta, migrations[i].version); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// CheckSchemaVersion checks without modifying the database
+// that it has been migrated to SchemaVersion.
+func CheckSchemaVersion(db *bbolt.DB) (err error) {
+	return db.View(func(tx *bbolt.Tx) error {
+		version := 0
+		if meta := tx.Bucket(metaBucketName); meta != nil {
+			version = schemaVersion(meta)
+		}
+
+		if version != SchemaVersion {
+			return &SchemaVersionError{
+				Version:  version,
+				Expected: SchemaVersion,
+			}
+		}
+
+		return nil
+	})
+}
+
+func (queries *Queries) SchemaVersion() int {
+	return schemaVersion(queries.tx.Bucket(metaBucketName))
+}
+
+func schemaVersion(meta *bbolt.Bucket) int {
+	b := meta.Get(metaSchemaVersionKey)
+	if len(b) != 4 {
+		return 0
+	}
+	return int(binary.BigEndian.Uint32(b))
+}
+
+func setSchemaVersion(meta *bbolt.Bucket, version int) error {
+	return meta.Put(metaSchemaVersionKey, binary.BigEndian.AppendUint32(nil, uint32(version)))
+}
+
+func migrateCreateBuckets(queries *Queries) (err error) {
+	for _, name := range [][]byte{publicKeyBucketName, wgClientBucketName, wgServerBucketName} {
+		_, err = queries.tx.CreateBucketIfNotExists(name)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,157 @@
+package queries
+
+import (
+	"net"
+
+	"github.com/guregu/null/v5"
+)
+
+//go:generate msgp -tests=false -unexported
+
+var profileBucketName = []byte("profile")
+
+func profileMarshalKey(b []byte, name string) []byte {
+	return append(b, name...)
+}
+
+func profileUnmarshalKey(b []byte) (name string, err error) {
+	return string(b), nil
+}
+
+//msgp:replace net.IP with:[]byte
+//msgp:replace net.IPNet with:msgpIPNet
+
+type profileValueV1 struct {
+	DNS                 []net.IP    `msg:"dns"`
+	AllowedIPs          []net.IPNet `msg:"allowed_ips"`
+	PersistentKeepalive *int64      `msg:"persistent_keepalive"`
+}
+
+func profileMarshalValueV1(b []byte, value *profileValueV1) []byte {
+	b, _ = value.MarshalMsg(b)
+	return b
+}
+
+func profileUnmarshalValueV1(b []byte) (val profileValueV1, err error) {
+	_, err = val.UnmarshalMsg(b)
+	return val, err
+}
+
+//msgp:ignore WireGuardProfile
+
+type WireGuardProfile struct {
+	Name                string
+	DNS                 []net.IP
+	AllowedIPs          []net.IPNet
+	PersistentKeepalive null.Int
+}
+
+func profileUnmarshalValue(valb []byte) (profile WireGuardProfile, err error) {
+	version, valb, err := unmarshalMeta(valb)
+	if err != nil {
+		return WireGuardProfile{}, err
+	}
+
+	switch version {
+	case 1:
+		val, err := profileUnmarshalValueV1(valb)
+		if err != nil {
+			return WireGuardProfile{}, err
+		}
+
+		return WireGuardProfile{
+			Name:                "",
+			DNS:                 val.DNS,
+			AllowedIPs:          val.AllowedIPs,
+			PersistentKeepalive: null.IntFromPtr(val.PersistentKeepalive),
+		}, nil
+	}
+
+	return WireGuardProfile{}, ErrUnsupportedVersion
+}
+
+func (queries *Queries) SetWireGuardProfile(profile *WireGuardProfile) (err error) {
+	b := queries.tx.Bucket(profileBucketName)
+
+	keyb := profileMarshalKey(nil, profile.Name)
+
+	value := profileValueV1{
+		DNS:                 profile.DNS,
+		AllowedIPs:          profile.AllowedIPs,
+		PersistentKeepalive: profile.PersistentKeepalive.Ptr(),
+	}
+
+	valb := Meta(0).SetVersion(1).Append(nil)
+	valb = profileMarshalValueV1(valb, &value)
+
+	return b.Put(keyb, valb)
+}
+
+func (queries *Queries) GetWireGuardProfile(name string) (profile WireGuardProfile, err error) {
+	b := queries.tx.Bucket(profileBucketName)
+
+	valb := b.Get(profileMarshalKey(nil, name))
+	if valb == nil {
+		return WireGuardProfile{}, ErrKeyNotFound
+	}
+
+	profile, err = profileUnmarshalValue(valb)
+	if err != nil {
+		return WireGuardProfile{}, err
+	}
+	profile.Name = name
+
+	return profile, nil
+}
+
+func (queries *Queries) GetWireGuardProfiles() (profiles []WireGuardProfile, err error) {
+	b := queries.tx.Bucket(profileBucketName)
+
+	c := b.Cursor()
+	for keyb, valb := c.First(); keyb != nil; keyb, valb = c.Next() {
+		name, err := profileUnmarshalKey(keyb)
+		if err != nil {
+			return nil, err
+		}
+
+		profile, err := profileUnmarshalValue(valb)
+		if err != nil {
+			return nil, err
+		}
+		profile.Name = name
+
+		profiles = append(profiles, profile)
+	}
+
+	return profiles, nil
+}
+
+func (queries *Queries) ClearWireGuardProfiles() (err error) {
+	b := queries.tx.Bucket(profileBucketName)
+
+	c := b.Cursor()
+	// NOTE: Cursor.Next skips an item after Cursor.Delete, so start over every time.
+	for keyb, _ := c.First(); keyb != nil; keyb, _ = c.First() {
+		err = c.Delete()
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (queries *Queries) RemoveWireGuardProfile(name string) (err error) {
+	b := queries.tx.Bucket(profileBucketName)
+	return b.Delete(profileMarshalKey(nil, name))
+}
+
+func (queries *Queries) WireGuardProfileExists(name string) (exists bool) {
+	b := queries.tx.Bucket(profileBucketName)
+	return b.Get(profileMarshalKey(nil, name)) != nil
+}
+
+func migrateCreateProfileBucket(queries *Queries) (err error) {
+	_, err = queries.tx.CreateBucketIfNotExists(profileBucketName)
+	return err
+}
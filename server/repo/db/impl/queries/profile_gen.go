@@ -0,0 +1,293 @@
+package queries
+
+// Code generated by github.com/tinylib/msgp DO NOT EDIT.
+
+import (
+	"net"
+
+	"github.com/tinylib/msgp/msgp"
+)
+
+// DecodeMsg implements msgp.Decodable
+func (z *profileValueV1) DecodeMsg(dc *msgp.Reader) (err error) {
+	var field []byte
+	_ = field
+	var zb0001 uint32
+	zb0001, err = dc.ReadMapHeader()
+	if err != nil {
+		err = msgp.WrapError(err)
+		return
+	}
+	for zb0001 > 0 {
+		zb0001--
+		field, err = dc.ReadMapKeyPtr()
+		if err != nil {
+			err = msgp.WrapError(err)
+			return
+		}
+		switch msgp.UnsafeString(field) {
+		case "dns":
+			var zb0002 uint32
+			zb0002, err = dc.ReadArrayHeader()
+			if err != nil {
+				err = msgp.WrapError(err, "DNS")
+				return
+			}
+			if cap(z.DNS) >= int(zb0002) {
+				z.DNS = (z.DNS)[:zb0002]
+			} else {
+				z.DNS = make([]net.IP, zb0002)
+			}
+			for za0001 := range z.DNS {
+				{
+					var zb0003 []byte
+					zb0003, err = dc.ReadBytes([]byte(z.DNS[za0001]))
+					if err != nil {
+						err = msgp.WrapError(err, "DNS", za0001)
+						return
+					}
+					z.DNS[za0001] = net.IP(zb0003)
+				}
+			}
+		case "allowed_ips":
+			var zb0004 uint32
+			zb0004, err = dc.ReadArrayHeader()
+			if err != nil {
+				err = msgp.WrapError(err, "AllowedIPs")
+				return
+			}
+			if cap(z.AllowedIPs) >= int(zb0004) {
+				z.AllowedIPs = (z.AllowedIPs)[:zb0004]
+			} else {
+				z.AllowedIPs = make([]net.IPNet, zb0004)
+			}
+			for za0002 := range z.AllowedIPs {
+				err = (*msgpIPNet)(&z.AllowedIPs[za0002]).DecodeMsg(dc)
+				if err != nil {
+					err = msgp.WrapError(err, "AllowedIPs", za0002)
+					return
+				}
+			}
+		case "persistent_keepalive":
+			if dc.IsNil() {
+				err = dc.ReadNil()
+				if err != nil {
+					err = msgp.WrapError(err, "PersistentKeepalive")
+					return
+				}
+				z.PersistentKeepalive = nil
+			} else {
+				if z.PersistentKeepalive == nil {
+					z.PersistentKeepalive = new(int64)
+				}
+				*z.PersistentKeepalive, err = dc.ReadInt64()
+				if err != nil {
+					err = msgp.WrapError(err, "PersistentKeepalive")
+					return
+				}
+			}
+		default:
+			err = dc.Skip()
+			if err != nil {
+				err = msgp.WrapError(err)
+				return
+			}
+		}
+	}
+	return
+}
+
+// EncodeMsg implements msgp.Encodable
+func (z *profileValueV1) EncodeMsg(en *msgp.Writer) (err error) {
+	// map header, size 3
+	// write "dns"
+	err = en.Append(0x83, 0xa3, 0x64, 0x6e, 0x73)
+	if err != nil {
+		return
+	}
+	err = en.WriteArrayHeader(uint32(len(z.DNS)))
+	if err != nil {
+		err = msgp.WrapError(err, "DNS")
+		return
+	}
+	for za0001 := range z.DNS {
+		err = en.WriteBytes([]byte(z.DNS[za0001]))
+		if err != nil {
+			err = msgp.WrapError(err, "DNS", za0001)
+			return
+		}
+	}
+	// write "allowed_ips"
+	err = en.Append(0xab, 0x61, 0x6c, 0x6c, 0x6f, 0x77, 0x65, 0x64, 0x5f, 0x69, 0x70, 0x73)
+	if err != nil {
+		return
+	}
+	err = en.WriteArrayHeader(uint32(len(z.AllowedIPs)))
+	if err != nil {
+		err = msgp.WrapError(err, "AllowedIPs")
+		return
+	}
+	for za0002 := range z.AllowedIPs {
+		err = (*msgpIPNet)(&z.AllowedIPs[za0002]).EncodeMsg(en)
+		if err != nil {
+			err = msgp.WrapError(err, "AllowedIPs", za0002)
+			return
+		}
+	}
+	// write "persistent_keepalive"
+	err = en.Append(0xb4, 0x70, 0x65, 0x72, 0x73, 0x69, 0x73, 0x74, 0x65, 0x6e, 0x74, 0x5f, 0x6b, 0x65, 0x65, 0x70, 0x61, 0x6c, 0x69, 0x76, 0x65)
+	if err != nil {
+		return
+	}
+	if z.PersistentKeepalive == nil {
+		err = en.WriteNil()
+		if err != nil {
+			return
+		}
+	} else {
+		err = en.WriteInt64(*z.PersistentKeepalive)
+		if err != nil {
+			err = msgp.WrapError(err, "PersistentKeepalive")
+			return
+		}
+	}
+	return
+}
+
+// MarshalMsg implements msgp.Marshaler
+func (z *profileValueV1) MarshalMsg(b []byte) (o []byte, err error) {
+	o = msgp.Require(b, z.Msgsize())
+	// map header, size 3
+	// string "dns"
+	o = append(o, 0x83, 0xa3, 0x64, 0x6e, 0x73)
+	o = msgp.AppendArrayHeader(o, uint32(len(z.DNS)))
+	for za0001 := range z.DNS {
+		o = msgp.AppendBytes(o, []byte(z.DNS[za0001]))
+	}
+	// string "allowed_ips"
+	o = append(o, 0xab, 0x61, 0x6c, 0x6c, 0x6f, 0x77, 0x65, 0x64, 0x5f, 0x69, 0x70, 0x73)
+	o = msgp.AppendArrayHeader(o, uint32(len(z.AllowedIPs)))
+	for za0002 := range z.AllowedIPs {
+		o, err = (*msgpIPNet)(&z.AllowedIPs[za0002]).MarshalMsg(o)
+		if err != nil {
+			err = msgp.WrapError(err, "AllowedIPs", za0002)
+			return
+		}
+	}
+	// string "persistent_keepalive"
+	o = append(o, 0xb4, 0x70, 0x65, 0x72, 0x73, 0x69, 0x73, 0x74, 0x65, 0x6e, 0x74, 0x5f, 0x6b, 0x65, 0x65, 0x70, 0x61, 0x6c, 0x69, 0x76, 0x65)
+	if z.PersistentKeepalive == nil {
+		o = msgp.AppendNil(o)
+	} else {
+		o = msgp.AppendInt64(o, *z.PersistentKeepalive)
+	}
+	return
+}
+
+// UnmarshalMsg implements msgp.Unmarshaler
+func (z *profileValueV1) UnmarshalMsg(bts []byte) (o []byte, err error) {
+	var field []byte
+	_ = field
+	var zb0001 uint32
+	zb0001, bts, err = msgp.ReadMapHeaderBytes(bts)
+	if err != nil {
+		err = msgp.WrapError(err)
+		return
+	}
+	for zb0001 > 0 {
+		zb0001--
+		field, bts, err = msgp.ReadMapKeyZC(bts)
+		if err != nil {
+			err = msgp.WrapError(err)
+			return
+		}
+		switch msgp.UnsafeString(field) {
+		case "dns":
+			var zb0002 uint32
+			zb0002, bts, err = msgp.ReadArrayHeaderBytes(bts)
+			if err != nil {
+				err = msgp.WrapError(err, "DNS")
+				return
+			}
+			if cap(z.DNS) >= int(zb0002) {
+				z.DNS = (z.DNS)[:zb0002]
+			} else {
+				z.DNS = make([]net.IP, zb0002)
+			}
+			for za0001 := range z.DNS {
+				{
+					var zb0003 []byte
+					zb0003, bts, err = msgp.ReadBytesBytes(bts, []byte(z.DNS[za0001]))
+					if err != nil {
+						err = msgp.WrapError(err, "DNS", za0001)
+						return
+					}
+					z.DNS[za0001] = net.IP(zb0003)
+				}
+			}
+		case "allowed_ips":
+			var zb0004 uint32
+			zb0004, bts, err = msgp.ReadArrayHeaderBytes(bts)
+			if err != nil {
+				err = msgp.WrapError(err, "AllowedIPs")
+				return
+			}
+			if cap(z.AllowedIPs) >= int(zb0004) {
+				z.AllowedIPs = (z.AllowedIPs)[:zb0004]
+			} else {
+				z.AllowedIPs = make([]net.IPNet, zb0004)
+			}
+			for za0002 := range z.AllowedIPs {
+				bts, err = (*msgpIPNet)(&z.AllowedIPs[za0002]).UnmarshalMsg(bts)
+				if err != nil {
+					err = msgp.WrapError(err, "AllowedIPs", za0002)
+					return
+				}
+			}
+		case "persistent_keepalive":
+			if msgp.IsNil(bts) {
+				bts, err = msgp.ReadNilBytes(bts)
+				if err != nil {
+					return
+				}
+				z.PersistentKeepalive = nil
+			} else {
+				if z.PersistentKeepalive == nil {
+					z.PersistentKeepalive = new(int64)
+				}
+				*z.PersistentKeepalive, bts, err = msgp.ReadInt64Bytes(bts)
+				if err != nil {
+					err = msgp.WrapError(err, "PersistentKeepalive")
+					return
+				}
+			}
+		default:
+			bts, err = msgp.Skip(bts)
+			if err != nil {
+				err = msgp.WrapError(err)
+				return
+			}
+		}
+	}
+	o = bts
+	return
+}
+
+// Msgsize returns an upper bound estimate of the number of bytes occupied by the serialized message
+func (z *profileValueV1) Msgsize() (s int) {
+	s = 1 + 4 + msgp.ArrayHeaderSize
+	for za0001 := range z.DNS {
+		s += msgp.BytesPrefixSize + len([]byte(z.DNS[za0001]))
+	}
+	s += 12 + msgp.ArrayHeaderSize
+	for za0002 := range z.AllowedIPs {
+		s += (*msgpIPNet)(&z.AllowedIPs[za0002]).Msgsize()
+	}
+	s += 21
+	if z.PersistentKeepalive == nil {
+		s += msgp.NilSize
+	} else {
+		s += msgp.Int64Size
+	}
+	return
+}
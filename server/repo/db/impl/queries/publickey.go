@@ -32,6 +32,28 @@ func publicKeyUnmarshalValueV1(b []byte) (val publicKeyValueV1, err error) {
 	return val, err
 }
 
+func publicKeyUnmarshalValue(valb []byte) (pkey PublicKey, err error) {
+	version, valb, err := unmarshalMeta(valb)
+	if err != nil {
+		return PublicKey{}, err
+	}
+
+	switch version {
+	case 1:
+		val, err := publicKeyUnmarshalValueV1(valb)
+		if err != nil {
+			return PublicKey{}, err
+		}
+
+		return PublicKey{
+			Key:     nil,
+			Comment: val.Comment,
+		}, nil
+	}
+
+	return PublicKey{}, ErrUnsupportedVersion
+}
+
 //msgp:ignore PublicKey
 
 type PublicKey struct {
@@ -44,7 +66,7 @@ func (queries *Queries) SetPublicKey(pkey *PublicKey) (err error) {
 
 	keyb := publicKeyMarshalKey(nil, pkey.Key)
 
-	valb := Meta(0).Append(nil)
+	valb := Meta(0).SetVersion(1).Append(nil)
 	valb = publicKeyMarshalValueV1(valb, &publicKeyValueV1{Comment: pkey.Comment})
 
 	return b.Put(keyb, valb)
@@ -60,15 +82,13 @@ func (queries *Queries) GetPublicKey(pkey ssh.PublicKey) (key PublicKey, err err
 		return PublicKey{}, ErrKeyNotFound
 	}
 
-	val, err := publicKeyUnmarshalValueV1(valb[1:])
+	key, err = publicKeyUnmarshalValue(valb)
 	if err != nil {
 		return PublicKey{}, err
 	}
+	key.Key = pkey
 
-	return PublicKey{
-		Key:     pkey,
-		Comment: val.Comment,
-	}, nil
+	return key, nil
 }
 
 func (queries *Queries) GetPublicKeys() (keys []PublicKey, err error) {
@@ -81,15 +101,13 @@ func (queries *Queries) GetPublicKeys() (keys []PublicKey, err error) {
 			return nil, err
 		}
 
-		val, err := publicKeyUnmarshalValueV1(valb[1:])
+		pkey, err := publicKeyUnmarshalValue(valb)
 		if err != nil {
 			return nil, err
 		}
+		pkey.Key = key
 
-		keys = append(keys, PublicKey{
-			Key:     key,
-			Comment: val.Comment,
-		})
+		keys = append(keys, pkey)
 	}
 
 	return keys, nil
@@ -99,7 +117,8 @@ func (queries *Queries) ClearPublicKeys() (err error) {
 	b := queries.tx.Bucket(publicKeyBucketName)
 
 	c := b.Cursor()
-	for keyb, _ := c.First(); keyb != nil; keyb, _ = c.Next() {
+	// NOTE: Cursor.Next skips an item after Cursor.Delete, so start over every time.
+	for keyb, _ := c.First(); keyb != nil; keyb, _ = c.First() {
 		err = c.Delete()
 		if err != nil {
 			return err
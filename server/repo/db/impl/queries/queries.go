@@ -1,34 +1,27 @@
 package queries
 
-import "go.etcd.io/bbolt"
+import (
+	"github.com/infastin/wg-wish/pkg/envelope"
+	"go.etcd.io/bbolt"
+)
 
 type Queries struct {
-	tx *bbolt.Tx
+	tx  *bbolt.Tx
+	kms envelope.KMS
+	// parent holds the buckets of WireGuard data.
+	// It's the transaction itself until the queries are bound to an interface,
+	// and nil if the interface doesn't exist.
+	parent bucketParent
+	iface  string
 }
 
-func New(tx *bbolt.Tx) *Queries {
+// New creates queries bound to the transaction.
+// If kms is not nil, private keys are written encrypted.
+func New(tx *bbolt.Tx, kms envelope.KMS) *Queries {
 	return &Queries{
-		tx: tx,
+		tx:     tx,
+		kms:    kms,
+		parent: tx,
+		iface:  "",
 	}
 }
-
-func Prepare(db *bbolt.DB) (err error) {
-	return db.Update(func(tx *bbolt.Tx) error {
-		_, err = tx.CreateBucketIfNotExists(publicKeyBucketName)
-		if err != nil {
-			return err
-		}
-
-		_, err = tx.CreateBucketIfNotExists(wgClientBucketName)
-		if err != nil {
-			return err
-		}
-
-		_, err = tx.CreateBucketIfNotExists(wgServerBucketName)
-		if err != nil {
-			return err
-		}
-
-		return nil
-	})
-}
@@ -0,0 +1,109 @@
+package queries
+
+import (
+	"github.com/infastin/wg-wish/pkg/envelope"
+	"github.com/infastin/wg-wish/pkg/wgtypes"
+)
+
+//go:generate msgp -tests=false -unexported
+
+//msgp:tuple sealedKeyV1
+
+type sealedKeyV1 struct {
+	KeyID      string
+	WrappedKey []byte
+	Ciphertext []byte
+}
+
+func sealAAD(bucket, key []byte) []byte {
+	aad := make([]byte, 0, len(bucket)+1+len(key))
+	aad = append(aad, bucket...)
+	aad = append(aad, 0)
+	aad = append(aad, key...)
+	return aad
+}
+
+// interfaceAAD is sealAAD prefixed with the interface the queries are bound to,
+// so that values sealed for one interface can't be opened as values of another.
+func (queries *Queries) interfaceAAD(bucket, key []byte) []byte {
+	aad := make([]byte, 0, len(queries.iface)+1+len(bucket)+1+len(key))
+	aad = append(aad, queries.iface...)
+	aad = append(aad, 0)
+	return append(aad, sealAAD(bucket, key)...)
+}
+
+func (queries *Queries) sealPrivateKey(key wgtypes.Key, aad []byte) (sealed sealedKeyV1, err error) {
+	if queries.kms == nil {
+		return sealedKeyV1{}, ErrEncryptionKeyRequired
+	}
+
+	env, err := envelope.Seal(queries.kms, key[:], aad)
+	if err != nil {
+		return sealedKeyV1{}, err
+	}
+
+	return sealedKeyV1(env), nil
+}
+
+func (queries *Queries) openPrivateKey(sealed *sealedKeyV1, aad []byte) (key wgtypes.Key, err error) {
+	if queries.kms == nil {
+		return wgtypes.Key{}, ErrEncryptionKeyRequired
+	}
+
+	b, err := envelope.Open(queries.kms, (*envelope.Envelope)(sealed), aad)
+	if err != nil {
+		return wgtypes.Key{}, err
+	}
+
+	if len(b) != wgtypes.KeyLen {
+		return wgtypes.Key{}, envelope.ErrDecryptFailed
+	}
+
+	return wgtypes.Key(b), nil
+}
+
+// SealPrivateKeys rewrites every record holding private key material,
+// so that it's encrypted with the current primary master key.
+func (queries *Queries) SealPrivateKeys() (n int, err error) {
+	if queries.kms == nil {
+		return 0, ErrEncryptionKeyRequired
+	}
+
+	for _, name := range queries.GetInterfaces() {
+		m, err := queries.Interface(name).sealInterfacePrivateKeys()
+		if err != nil {
+			return 0, err
+		}
+		n += m
+	}
+
+	return n, nil
+}
+
+func (queries *Queries) sealInterfacePrivateKeys() (n int, err error) {
+	clients, err := queries.GetWireGuardClients()
+	if err != nil {
+		return 0, err
+	}
+
+	for i := range clients {
+		if err := queries.SetWireGuardClient(&clients[i]); err != nil {
+			return 0, err
+		}
+		n++
+	}
+
+	if queries.WireGuardServerConfigExists() {
+		config, err := queries.GetWireGuardServerConfig()
+		if err != nil {
+			return 0, err
+		}
+
+		if err := queries.SetWireGuardServerConfig(&config); err != nil {
+			return 0, err
+		}
+		n++
+	}
+
+	return n, nil
+}
@@ -0,0 +1,110 @@
+package queries
+
+// Code generated by github.com/tinylib/msgp DO NOT EDIT.
+
+import (
+	"github.com/tinylib/msgp/msgp"
+)
+
+// DecodeMsg implements msgp.Decodable
+func (z *sealedKeyV1) DecodeMsg(dc *msgp.Reader) (err error) {
+	var zb0001 uint32
+	zb0001, err = dc.ReadArrayHeader()
+	if err != nil {
+		err = msgp.WrapError(err)
+		return
+	}
+	if zb0001 != 3 {
+		err = msgp.ArrayError{Wanted: 3, Got: zb0001}
+		return
+	}
+	z.KeyID, err = dc.ReadString()
+	if err != nil {
+		err = msgp.WrapError(err, "KeyID")
+		return
+	}
+	z.WrappedKey, err = dc.ReadBytes(z.WrappedKey)
+	if err != nil {
+		err = msgp.WrapError(err, "WrappedKey")
+		return
+	}
+	z.Ciphertext, err = dc.ReadBytes(z.Ciphertext)
+	if err != nil {
+		err = msgp.WrapError(err, "Ciphertext")
+		return
+	}
+	return
+}
+
+// EncodeMsg implements msgp.Encodable
+func (z *sealedKeyV1) EncodeMsg(en *msgp.Writer) (err error) {
+	// array header, size 3
+	err = en.Append(0x93)
+	if err != nil {
+		return
+	}
+	err = en.WriteString(z.KeyID)
+	if err != nil {
+		err = msgp.WrapError(err, "KeyID")
+		return
+	}
+	err = en.WriteBytes(z.WrappedKey)
+	if err != nil {
+		err = msgp.WrapError(err, "WrappedKey")
+		return
+	}
+	err = en.WriteBytes(z.Ciphertext)
+	if err != nil {
+		err = msgp.WrapError(err, "Ciphertext")
+		return
+	}
+	return
+}
+
+// MarshalMsg implements msgp.Marshaler
+func (z *sealedKeyV1) MarshalMsg(b []byte) (o []byte, err error) {
+	o = msgp.Require(b, z.Msgsize())
+	// array header, size 3
+	o = append(o, 0x93)
+	o = msgp.AppendString(o, z.KeyID)
+	o = msgp.AppendBytes(o, z.WrappedKey)
+	o = msgp.AppendBytes(o, z.Ciphertext)
+	return
+}
+
+// UnmarshalMsg implements msgp.Unmarshaler
+func (z *sealedKeyV1) UnmarshalMsg(bts []byte) (o []byte, err error) {
+	var zb0001 uint32
+	zb0001, bts, err = msgp.ReadArrayHeaderBytes(bts)
+	if err != nil {
+		err = msgp.WrapError(err)
+		return
+	}
+	if zb0001 != 3 {
+		err = msgp.ArrayError{Wanted: 3, Got: zb0001}
+		return
+	}
+	z.KeyID, bts, err = msgp.ReadStringBytes(bts)
+	if err != nil {
+		err = msgp.WrapError(err, "KeyID")
+		return
+	}
+	z.WrappedKey, bts, err = msgp.ReadBytesBytes(bts, z.WrappedKey)
+	if err != nil {
+		err = msgp.WrapError(err, "WrappedKey")
+		return
+	}
+	z.Ciphertext, bts, err = msgp.ReadBytesBytes(bts, z.Ciphertext)
+	if err != nil {
+		err = msgp.WrapError(err, "Ciphertext")
+		return
+	}
+	o = bts
+	return
+}
+
+// Msgsize returns an upper bound estimate of the number of bytes occupied by the serialized message
+func (z *sealedKeyV1) Msgsize() (s int) {
+	s = 1 + msgp.StringPrefixSize + len(z.KeyID) + msgp.BytesPrefixSize + len(z.WrappedKey) + msgp.BytesPrefixSize + len(z.Ciphertext)
+	return
+}
@@ -1,7 +1,9 @@
 package queries
 
 import (
+	"bytes"
 	"net"
+	"time"
 
 	"github.com/guregu/null/v5"
 	"github.com/infastin/wg-wish/pkg/wgtypes"
@@ -24,6 +26,7 @@ func wgClientUnmarshalKey(b []byte) (name string, err error) {
 //msgp:replace net.IP with:[]byte
 //msgp:replace net.IPNet with:msgpIPNet
 
+// Upgraded to wgClientValueV3 by migrations, not read otherwise.
 type wgClientValueV1 struct {
 	Address             net.IPNet
 	PrivateKey          wgtypes.Key
@@ -33,17 +36,74 @@ type wgClientValueV1 struct {
 	PersistentKeepalive *int64
 }
 
-func wgClientMarshalValueV1(b []byte, value *wgClientValueV1) []byte {
+func wgClientUnmarshalValueV1(b []byte) (val wgClientValueV1, err error) {
+	_, err = val.UnmarshalMsg(b)
+	return val, err
+}
+
+//msgp:tuple wgClientValueV2
+
+// Same as wgClientValueV1, but with encrypted private key.
+// Upgraded to wgClientValueV3 by migrations, not read otherwise.
+type wgClientValueV2 struct {
+	Address             net.IPNet
+	PrivateKey          sealedKeyV1
+	PublicKey           wgtypes.Key
+	DNS                 []net.IP
+	AllowedIPs          []net.IPNet
+	PersistentKeepalive *int64
+}
+
+func wgClientUnmarshalValueV2(b []byte) (val wgClientValueV2, err error) {
+	_, err = val.UnmarshalMsg(b)
+	return val, err
+}
+
+// Same as wgClientValueV2, but with private key stored either in plaintext or encrypted,
+// and with peer state. Encoded as a map, so that optional fields can be added
+// without introducing a new version.
+type wgClientValueV3 struct {
+	Address          net.IPNet    `msg:"address"`
+	PrivateKey       *[32]byte    `msg:"private_key"`
+	SealedPrivateKey *sealedKeyV1 `msg:"sealed_private_key"`
+	// InterfaceAAD is set if SealedPrivateKey has been sealed with interfaceAAD rather than sealAAD.
+	InterfaceAAD        bool              `msg:"interface_aad"`
+	PublicKey           wgtypes.Key       `msg:"public_key"`
+	DNS                 []net.IP          `msg:"dns"`
+	AllowedIPs          []net.IPNet       `msg:"allowed_ips"`
+	PersistentKeepalive *int64            `msg:"persistent_keepalive"`
+	Disabled            bool              `msg:"disabled"`
+	Quota               *wgClientQuotaV1  `msg:"quota"`
+	RateLimit           uint64            `msg:"rate_limit"`
+	Group               string            `msg:"group"`
+	Profile             string            `msg:"profile"`
+	ExpiresAt           *int64            `msg:"expires_at"`
+	Labels              map[string]string `msg:"labels"`
+	Description         string            `msg:"description"`
+	Mesh                bool              `msg:"mesh"`
+	Endpoint            string            `msg:"endpoint"`
+	LastEndpoint        string            `msg:"last_endpoint"`
+}
+
+type wgClientQuotaV1 struct {
+	Limit       uint64  `msg:"limit"`
+	Period      string  `msg:"period"`
+	PeriodStart int64   `msg:"period_start"`
+	Used        uint64  `msg:"used"`
+	LastCounter *uint64 `msg:"last_counter"`
+}
+
+func wgClientMarshalValueV3(b []byte, value *wgClientValueV3) []byte {
 	b, _ = value.MarshalMsg(b)
 	return b
 }
 
-func wgClientUnmarshalValueV1(b []byte) (val wgClientValueV1, err error) {
+func wgClientUnmarshalValueV3(b []byte) (val wgClientValueV3, err error) {
 	_, err = val.UnmarshalMsg(b)
 	return val, err
 }
 
-//msgp:ignore WireGuardClient
+//msgp:ignore WireGuardClient WireGuardClientQuota
 
 type WireGuardClient struct {
 	Name                string
@@ -53,28 +113,183 @@ type WireGuardClient struct {
 	DNS                 []net.IP
 	AllowedIPs          []net.IPNet
 	PersistentKeepalive null.Int
+	Disabled            bool
+	Quota               null.Value[WireGuardClientQuota]
+	RateLimit           uint64
+	Group               string
+	Profile             string
+	ExpiresAt           null.Time
+	Labels              map[string]string
+	Description         string
+	Mesh                bool
+	Endpoint            string
+	LastEndpoint        string
+}
+
+type WireGuardClientQuota struct {
+	Limit       uint64
+	Period      string
+	PeriodStart time.Time
+	Used        uint64
+	LastCounter null.Value[uint64]
 }
 
 func (queries *Queries) SetWireGuardClient(client *WireGuardClient) (err error) {
-	b := queries.tx.Bucket(wgClientBucketName)
+	b, err := queries.bucket(wgClientBucketName)
+	if err != nil {
+		return err
+	}
 
 	keyb := wgClientMarshalKey(nil, client.Name)
 
-	valb := Meta(0).Append(nil)
-	valb = wgClientMarshalValueV1(valb, &wgClientValueV1{
+	valb, err := queries.wgClientMarshalValue(keyb, client)
+	if err != nil {
+		return err
+	}
+
+	if old := b.Get(keyb); old != nil {
+		if err := queries.wgClientIndexDelete(old); err != nil {
+			return err
+		}
+	}
+
+	if err := queries.wgClientIndexPut(keyb, client.PublicKey, client.Address); err != nil {
+		return err
+	}
+
+	return b.Put(keyb, valb)
+}
+
+func (queries *Queries) wgClientMarshalValue(keyb []byte, client *WireGuardClient) (valb []byte, err error) {
+	value := wgClientValueV3{
 		Address:             client.Address,
-		PrivateKey:          client.PrivateKey,
+		PrivateKey:          nil,
+		SealedPrivateKey:    nil,
+		InterfaceAAD:        false,
 		PublicKey:           client.PublicKey,
 		DNS:                 client.DNS,
 		AllowedIPs:          client.AllowedIPs,
 		PersistentKeepalive: client.PersistentKeepalive.Ptr(),
-	})
+		Disabled:            client.Disabled,
+		Quota:               nil,
+		RateLimit:           client.RateLimit,
+		Group:               client.Group,
+		Profile:             client.Profile,
+		ExpiresAt:           nil,
+		Labels:              client.Labels,
+		Description:         client.Description,
+		Mesh:                client.Mesh,
+		Endpoint:            client.Endpoint,
+		LastEndpoint:        client.LastEndpoint,
+	}
 
-	return b.Put(keyb, valb)
+	if client.ExpiresAt.Valid {
+		expiresAt := client.ExpiresAt.Time.Unix()
+		value.ExpiresAt = &expiresAt
+	}
+
+	if queries.kms == nil {
+		privateKey := [32]byte(client.PrivateKey)
+		value.PrivateKey = &privateKey
+	} else {
+		privateKey, err := queries.sealPrivateKey(client.PrivateKey, queries.interfaceAAD(wgClientBucketName, keyb))
+		if err != nil {
+			return nil, err
+		}
+		value.SealedPrivateKey = &privateKey
+		value.InterfaceAAD = true
+	}
+
+	if client.Quota.Valid {
+		value.Quota = &wgClientQuotaV1{
+			Limit:       client.Quota.V.Limit,
+			Period:      client.Quota.V.Period,
+			PeriodStart: client.Quota.V.PeriodStart.Unix(),
+			Used:        client.Quota.V.Used,
+			LastCounter: client.Quota.V.LastCounter.Ptr(),
+		}
+	}
+
+	valb = Meta(0).SetVersion(3).Append(nil)
+	valb = wgClientMarshalValueV3(valb, &value)
+
+	return valb, nil
+}
+
+func (queries *Queries) wgClientUnmarshalValue(keyb, valb []byte) (client WireGuardClient, err error) {
+	version, valb, err := unmarshalMeta(valb)
+	if err != nil {
+		return WireGuardClient{}, err
+	}
+
+	switch version {
+	case 3:
+		val, err := wgClientUnmarshalValueV3(valb)
+		if err != nil {
+			return WireGuardClient{}, err
+		}
+
+		client = WireGuardClient{
+			Name:                "",
+			Address:             val.Address,
+			PrivateKey:          wgtypes.Key{},
+			PublicKey:           val.PublicKey,
+			DNS:                 val.DNS,
+			AllowedIPs:          val.AllowedIPs,
+			PersistentKeepalive: null.IntFromPtr(val.PersistentKeepalive),
+			Disabled:            val.Disabled,
+			Quota:               null.Value[WireGuardClientQuota]{},
+			RateLimit:           val.RateLimit,
+			Group:               val.Group,
+			Profile:             val.Profile,
+			ExpiresAt:           null.Time{},
+			Labels:              val.Labels,
+			Description:         val.Description,
+			Mesh:                val.Mesh,
+			Endpoint:            val.Endpoint,
+			LastEndpoint:        val.LastEndpoint,
+		}
+
+		if val.ExpiresAt != nil {
+			client.ExpiresAt = null.TimeFrom(time.Unix(*val.ExpiresAt, 0).UTC())
+		}
+
+		switch {
+		case val.SealedPrivateKey != nil:
+			aad := sealAAD(wgClientBucketName, keyb)
+			if val.InterfaceAAD {
+				aad = queries.interfaceAAD(wgClientBucketName, keyb)
+			}
+
+			client.PrivateKey, err = queries.openPrivateKey(val.SealedPrivateKey, aad)
+			if err != nil {
+				return WireGuardClient{}, err
+			}
+		case val.PrivateKey != nil:
+			client.PrivateKey = wgtypes.Key(*val.PrivateKey)
+		}
+
+		if val.Quota != nil {
+			client.Quota = null.ValueFrom(WireGuardClientQuota{
+				Limit:       val.Quota.Limit,
+				Period:      val.Quota.Period,
+				PeriodStart: time.Unix(val.Quota.PeriodStart, 0).UTC(),
+				Used:        val.Quota.Used,
+				LastCounter: null.ValueFromPtr(val.Quota.LastCounter),
+			})
+		}
+
+		return client, nil
+	}
+
+	return WireGuardClient{}, ErrUnsupportedVersion
 }
 
 func (queries *Queries) GetWireGuardClient(name string) (client WireGuardClient, err error) {
-	b := queries.tx.Bucket(wgClientBucketName)
+	b, err := queries.bucket(wgClientBucketName)
+	if err != nil {
+		return WireGuardClient{}, err
+	}
 
 	keyb := wgClientMarshalKey(nil, name)
 
@@ -83,24 +298,20 @@ func (queries *Queries) GetWireGuardClient(name string) (client WireGuardClient,
 		return WireGuardClient{}, ErrKeyNotFound
 	}
 
-	val, err := wgClientUnmarshalValueV1(valb[1:])
+	client, err = queries.wgClientUnmarshalValue(keyb, valb)
 	if err != nil {
 		return WireGuardClient{}, err
 	}
+	client.Name = name
 
-	return WireGuardClient{
-		Name:                name,
-		Address:             val.Address,
-		PrivateKey:          val.PrivateKey,
-		PublicKey:           val.PublicKey,
-		DNS:                 val.DNS,
-		AllowedIPs:          val.AllowedIPs,
-		PersistentKeepalive: null.IntFromPtr(val.PersistentKeepalive),
-	}, nil
+	return client, nil
 }
 
 func (queries *Queries) GetWireGuardClients() (clients []WireGuardClient, err error) {
-	b := queries.tx.Bucket(wgClientBucketName)
+	b, err := queries.bucket(wgClientBucketName)
+	if err != nil {
+		return nil, err
+	}
 
 	c := b.Cursor()
 	for keyb, valb := c.First(); keyb != nil; keyb, valb = c.Next() {
@@ -109,33 +320,140 @@ func (queries *Queries) GetWireGuardClients() (clients []WireGuardClient, err er
 			return nil, err
 		}
 
-		val, err := wgClientUnmarshalValueV1(valb[1:])
+		client, err := queries.wgClientUnmarshalValue(keyb, valb)
 		if err != nil {
 			return nil, err
 		}
+		client.Name = key
 
-		clients = append(clients, WireGuardClient{
-			Address:             val.Address,
-			Name:                key,
-			PrivateKey:          val.PrivateKey,
-			PublicKey:           val.PublicKey,
-			DNS:                 val.DNS,
-			AllowedIPs:          val.AllowedIPs,
-			PersistentKeepalive: null.IntFromPtr(val.PersistentKeepalive),
-		})
+		clients = append(clients, client)
 	}
 
 	return clients, nil
 }
 
 func (queries *Queries) RemoveWireGuardClient(name string) (err error) {
-	b := queries.tx.Bucket(wgClientBucketName)
+	b, err := queries.bucket(wgClientBucketName)
+	if err != nil {
+		return err
+	}
 	keyb := wgClientMarshalKey(nil, name)
+
+	if old := b.Get(keyb); old != nil {
+		if err := queries.wgClientIndexDelete(old); err != nil {
+			return err
+		}
+	}
+
 	return b.Delete(keyb)
 }
 
+func (queries *Queries) ClearWireGuardClients() (err error) {
+	b, err := queries.bucket(wgClientBucketName)
+	if err != nil {
+		return err
+	}
+
+	c := b.Cursor()
+	// NOTE: Cursor.Next skips an item after Cursor.Delete, so start over every time.
+	for keyb, _ := c.First(); keyb != nil; keyb, _ = c.First() {
+		err = c.Delete()
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, name := range [][]byte{wgClientPublicKeyIndexBucketName, wgClientAddressIndexBucketName} {
+		if err := queries.parent.DeleteBucket(name); err != nil {
+			return err
+		}
+
+		if _, err := queries.parent.CreateBucket(name); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
 func (queries *Queries) WireGuardClientExists(name string) (exists bool) {
-	b := queries.tx.Bucket(wgClientBucketName)
+	b, err := queries.bucket(wgClientBucketName)
+	if err != nil {
+		return false
+	}
 	keyb := wgClientMarshalKey(nil, name)
 	return b.Get(keyb) != nil
 }
+
+// migrateUpgradeWireGuardClients rewrites V1 and V2 client records of every interface as V3.
+// Private keys are carried over as they are, sealed or not, so that no master key is needed.
+func migrateUpgradeWireGuardClients(queries *Queries) (err error) {
+	for _, name := range queries.GetInterfaces() {
+		if err := queries.Interface(name).upgradeWireGuardClients(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (queries *Queries) upgradeWireGuardClients() (err error) {
+	b := queries.parent.Bucket(wgClientBucketName)
+
+	var keys, values [][]byte
+
+	c := b.Cursor()
+	for keyb, valb := c.First(); keyb != nil; keyb, valb = c.Next() {
+		version, valb, err := unmarshalMeta(valb)
+		if err != nil {
+			return err
+		}
+
+		var value wgClientValueV3
+
+		switch version {
+		case 1:
+			val, err := wgClientUnmarshalValueV1(valb)
+			if err != nil {
+				return err
+			}
+
+			privateKey := [32]byte(val.PrivateKey)
+			value = wgClientValueV3{ //nolint:exhaustruct
+				Address:             val.Address,
+				PrivateKey:          &privateKey,
+				PublicKey:           val.PublicKey,
+				DNS:                 val.DNS,
+				AllowedIPs:          val.AllowedIPs,
+				PersistentKeepalive: val.PersistentKeepalive,
+			}
+		case 2:
+			val, err := wgClientUnmarshalValueV2(valb)
+			if err != nil {
+				return err
+			}
+
+			value = wgClientValueV3{ //nolint:exhaustruct
+				Address:             val.Address,
+				SealedPrivateKey:    &val.PrivateKey,
+				PublicKey:           val.PublicKey,
+				DNS:                 val.DNS,
+				AllowedIPs:          val.AllowedIPs,
+				PersistentKeepalive: val.PersistentKeepalive,
+			}
+		default:
+			continue
+		}
+
+		keys = append(keys, bytes.Clone(keyb))
+		values = append(values, wgClientMarshalValueV3(Meta(0).SetVersion(3).Append(nil), &value))
+	}
+
+	// Written after iterating, since the cursor must not be used while the bucket is modified.
+	for i := range keys {
+		if err := b.Put(keys[i], values[i]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -8,6 +8,248 @@ import (
 	"github.com/tinylib/msgp/msgp"
 )
 
+// DecodeMsg implements msgp.Decodable
+func (z *wgClientQuotaV1) DecodeMsg(dc *msgp.Reader) (err error) {
+	var field []byte
+	_ = field
+	var zb0001 uint32
+	zb0001, err = dc.ReadMapHeader()
+	if err != nil {
+		err = msgp.WrapError(err)
+		return
+	}
+	for zb0001 > 0 {
+		zb0001--
+		field, err = dc.ReadMapKeyPtr()
+		if err != nil {
+			err = msgp.WrapError(err)
+			return
+		}
+		switch msgp.UnsafeString(field) {
+		case "limit":
+			z.Limit, err = dc.ReadUint64()
+			if err != nil {
+				err = msgp.WrapError(err, "Limit")
+				return
+			}
+		case "period":
+			z.Period, err = dc.ReadString()
+			if err != nil {
+				err = msgp.WrapError(err, "Period")
+				return
+			}
+		case "period_start":
+			z.PeriodStart, err = dc.ReadInt64()
+			if err != nil {
+				err = msgp.WrapError(err, "PeriodStart")
+				return
+			}
+		case "used":
+			z.Used, err = dc.ReadUint64()
+			if err != nil {
+				err = msgp.WrapError(err, "Used")
+				return
+			}
+		case "last_counter":
+			if dc.IsNil() {
+				err = dc.ReadNil()
+				if err != nil {
+					err = msgp.WrapError(err, "LastCounter")
+					return
+				}
+				z.LastCounter = nil
+			} else {
+				if z.LastCounter == nil {
+					z.LastCounter = new(uint64)
+				}
+				*z.LastCounter, err = dc.ReadUint64()
+				if err != nil {
+					err = msgp.WrapError(err, "LastCounter")
+					return
+				}
+			}
+		default:
+			err = dc.Skip()
+			if err != nil {
+				err = msgp.WrapError(err)
+				return
+			}
+		}
+	}
+	return
+}
+
+// EncodeMsg implements msgp.Encodable
+func (z *wgClientQuotaV1) EncodeMsg(en *msgp.Writer) (err error) {
+	// map header, size 5
+	// write "limit"
+	err = en.Append(0x85, 0xa5, 0x6c, 0x69, 0x6d, 0x69, 0x74)
+	if err != nil {
+		return
+	}
+	err = en.WriteUint64(z.Limit)
+	if err != nil {
+		err = msgp.WrapError(err, "Limit")
+		return
+	}
+	// write "period"
+	err = en.Append(0xa6, 0x70, 0x65, 0x72, 0x69, 0x6f, 0x64)
+	if err != nil {
+		return
+	}
+	err = en.WriteString(z.Period)
+	if err != nil {
+		err = msgp.WrapError(err, "Period")
+		return
+	}
+	// write "period_start"
+	err = en.Append(0xac, 0x70, 0x65, 0x72, 0x69, 0x6f, 0x64, 0x5f, 0x73, 0x74, 0x61, 0x72, 0x74)
+	if err != nil {
+		return
+	}
+	err = en.WriteInt64(z.PeriodStart)
+	if err != nil {
+		err = msgp.WrapError(err, "PeriodStart")
+		return
+	}
+	// write "used"
+	err = en.Append(0xa4, 0x75, 0x73, 0x65, 0x64)
+	if err != nil {
+		return
+	}
+	err = en.WriteUint64(z.Used)
+	if err != nil {
+		err = msgp.WrapError(err, "Used")
+		return
+	}
+	// write "last_counter"
+	err = en.Append(0xac, 0x6c, 0x61, 0x73, 0x74, 0x5f, 0x63, 0x6f, 0x75, 0x6e, 0x74, 0x65, 0x72)
+	if err != nil {
+		return
+	}
+	if z.LastCounter == nil {
+		err = en.WriteNil()
+		if err != nil {
+			return
+		}
+	} else {
+		err = en.WriteUint64(*z.LastCounter)
+		if err != nil {
+			err = msgp.WrapError(err, "LastCounter")
+			return
+		}
+	}
+	return
+}
+
+// MarshalMsg implements msgp.Marshaler
+func (z *wgClientQuotaV1) MarshalMsg(b []byte) (o []byte, err error) {
+	o = msgp.Require(b, z.Msgsize())
+	// map header, size 5
+	// string "limit"
+	o = append(o, 0x85, 0xa5, 0x6c, 0x69, 0x6d, 0x69, 0x74)
+	o = msgp.AppendUint64(o, z.Limit)
+	// string "period"
+	o = append(o, 0xa6, 0x70, 0x65, 0x72, 0x69, 0x6f, 0x64)
+	o = msgp.AppendString(o, z.Period)
+	// string "period_start"
+	o = append(o, 0xac, 0x70, 0x65, 0x72, 0x69, 0x6f, 0x64, 0x5f, 0x73, 0x74, 0x61, 0x72, 0x74)
+	o = msgp.AppendInt64(o, z.PeriodStart)
+	// string "used"
+	o = append(o, 0xa4, 0x75, 0x73, 0x65, 0x64)
+	o = msgp.AppendUint64(o, z.Used)
+	// string "last_counter"
+	o = append(o, 0xac, 0x6c, 0x61, 0x73, 0x74, 0x5f, 0x63, 0x6f, 0x75, 0x6e, 0x74, 0x65, 0x72)
+	if z.LastCounter == nil {
+		o = msgp.AppendNil(o)
+	} else {
+		o = msgp.AppendUint64(o, *z.LastCounter)
+	}
+	return
+}
+
+// UnmarshalMsg implements msgp.Unmarshaler
+func (z *wgClientQuotaV1) UnmarshalMsg(bts []byte) (o []byte, err error) {
+	var field []byte
+	_ = field
+	var zb0001 uint32
+	zb0001, bts, err = msgp.ReadMapHeaderBytes(bts)
+	if err != nil {
+		err = msgp.WrapError(err)
+		return
+	}
+	for zb0001 > 0 {
+		zb0001--
+		field, bts, err = msgp.ReadMapKeyZC(bts)
+		if err != nil {
+			err = msgp.WrapError(err)
+			return
+		}
+		switch msgp.UnsafeString(field) {
+		case "limit":
+			z.Limit, bts, err = msgp.ReadUint64Bytes(bts)
+			if err != nil {
+				err = msgp.WrapError(err, "Limit")
+				return
+			}
+		case "period":
+			z.Period, bts, err = msgp.ReadStringBytes(bts)
+			if err != nil {
+				err = msgp.WrapError(err, "Period")
+				return
+			}
+		case "period_start":
+			z.PeriodStart, bts, err = msgp.ReadInt64Bytes(bts)
+			if err != nil {
+				err = msgp.WrapError(err, "PeriodStart")
+				return
+			}
+		case "used":
+			z.Used, bts, err = msgp.ReadUint64Bytes(bts)
+			if err != nil {
+				err = msgp.WrapError(err, "Used")
+				return
+			}
+		case "last_counter":
+			if msgp.IsNil(bts) {
+				bts, err = msgp.ReadNilBytes(bts)
+				if err != nil {
+					return
+				}
+				z.LastCounter = nil
+			} else {
+				if z.LastCounter == nil {
+					z.LastCounter = new(uint64)
+				}
+				*z.LastCounter, bts, err = msgp.ReadUint64Bytes(bts)
+				if err != nil {
+					err = msgp.WrapError(err, "LastCounter")
+					return
+				}
+			}
+		default:
+			bts, err = msgp.Skip(bts)
+			if err != nil {
+				err = msgp.WrapError(err)
+				return
+			}
+		}
+	}
+	o = bts
+	return
+}
+
+// Msgsize returns an upper bound estimate of the number of bytes occupied by the serialized message
+func (z *wgClientQuotaV1) Msgsize() (s int) {
+	s = 1 + 6 + msgp.Uint64Size + 7 + msgp.StringPrefixSize + len(z.Period) + 13 + msgp.Int64Size + 5 + msgp.Uint64Size + 13
+	if z.LastCounter == nil {
+		s += msgp.NilSize
+	} else {
+		s += msgp.Uint64Size
+	}
+	return
+}
+
 // DecodeMsg implements msgp.Decodable
 func (z *wgClientValueV1) DecodeMsg(dc *msgp.Reader) (err error) {
 	var zb0001 uint32
@@ -46,15 +288,15 @@ func (z *wgClientValueV1) DecodeMsg(dc *msgp.Reader) (err error) {
 	} else {
 		z.DNS = make([]net.IP, zb0002)
 	}
-	for za0007 := range z.DNS {
+	for za0003 := range z.DNS {
 		{
 			var zb0003 []byte
-			zb0003, err = dc.ReadBytes([]byte(z.DNS[za0007]))
+			zb0003, err = dc.ReadBytes([]byte(z.DNS[za0003]))
 			if err != nil {
-				err = msgp.WrapError(err, "DNS", za0007)
+				err = msgp.WrapError(err, "DNS", za0003)
 				return
 			}
-			z.DNS[za0007] = net.IP(zb0003)
+			z.DNS[za0003] = net.IP(zb0003)
 		}
 	}
 	var zb0004 uint32
@@ -68,10 +310,10 @@ func (z *wgClientValueV1) DecodeMsg(dc *msgp.Reader) (err error) {
 	} else {
 		z.AllowedIPs = make([]net.IPNet, zb0004)
 	}
-	for za0008 := range z.AllowedIPs {
-		err = (*msgpIPNet)(&z.AllowedIPs[za0008]).DecodeMsg(dc)
+	for za0004 := range z.AllowedIPs {
+		err = (*msgpIPNet)(&z.AllowedIPs[za0004]).DecodeMsg(dc)
 		if err != nil {
-			err = msgp.WrapError(err, "AllowedIPs", za0008)
+			err = msgp.WrapError(err, "AllowedIPs", za0004)
 			return
 		}
 	}
@@ -122,10 +364,10 @@ func (z *wgClientValueV1) EncodeMsg(en *msgp.Writer) (err error) {
 		err = msgp.WrapError(err, "DNS")
 		return
 	}
-	for za0007 := range z.DNS {
-		err = en.WriteBytes([]byte(z.DNS[za0007]))
+	for za0003 := range z.DNS {
+		err = en.WriteBytes([]byte(z.DNS[za0003]))
 		if err != nil {
-			err = msgp.WrapError(err, "DNS", za0007)
+			err = msgp.WrapError(err, "DNS", za0003)
 			return
 		}
 	}
@@ -134,10 +376,10 @@ func (z *wgClientValueV1) EncodeMsg(en *msgp.Writer) (err error) {
 		err = msgp.WrapError(err, "AllowedIPs")
 		return
 	}
-	for za0008 := range z.AllowedIPs {
-		err = (*msgpIPNet)(&z.AllowedIPs[za0008]).EncodeMsg(en)
+	for za0004 := range z.AllowedIPs {
+		err = (*msgpIPNet)(&z.AllowedIPs[za0004]).EncodeMsg(en)
 		if err != nil {
-			err = msgp.WrapError(err, "AllowedIPs", za0008)
+			err = msgp.WrapError(err, "AllowedIPs", za0004)
 			return
 		}
 	}
@@ -169,14 +411,14 @@ func (z *wgClientValueV1) MarshalMsg(b []byte) (o []byte, err error) {
 	o = msgp.AppendBytes(o, (z.PrivateKey)[:])
 	o = msgp.AppendBytes(o, (z.PublicKey)[:])
 	o = msgp.AppendArrayHeader(o, uint32(len(z.DNS)))
-	for za0007 := range z.DNS {
-		o = msgp.AppendBytes(o, []byte(z.DNS[za0007]))
+	for za0003 := range z.DNS {
+		o = msgp.AppendBytes(o, []byte(z.DNS[za0003]))
 	}
 	o = msgp.AppendArrayHeader(o, uint32(len(z.AllowedIPs)))
-	for za0008 := range z.AllowedIPs {
-		o, err = (*msgpIPNet)(&z.AllowedIPs[za0008]).MarshalMsg(o)
+	for za0004 := range z.AllowedIPs {
+		o, err = (*msgpIPNet)(&z.AllowedIPs[za0004]).MarshalMsg(o)
 		if err != nil {
-			err = msgp.WrapError(err, "AllowedIPs", za0008)
+			err = msgp.WrapError(err, "AllowedIPs", za0004)
 			return
 		}
 	}
@@ -226,15 +468,15 @@ func (z *wgClientValueV1) UnmarshalMsg(bts []byte) (o []byte, err error) {
 	} else {
 		z.DNS = make([]net.IP, zb0002)
 	}
-	for za0007 := range z.DNS {
+	for za0003 := range z.DNS {
 		{
 			var zb0003 []byte
-			zb0003, bts, err = msgp.ReadBytesBytes(bts, []byte(z.DNS[za0007]))
+			zb0003, bts, err = msgp.ReadBytesBytes(bts, []byte(z.DNS[za0003]))
 			if err != nil {
-				err = msgp.WrapError(err, "DNS", za0007)
+				err = msgp.WrapError(err, "DNS", za0003)
 				return
 			}
-			z.DNS[za0007] = net.IP(zb0003)
+			z.DNS[za0003] = net.IP(zb0003)
 		}
 	}
 	var zb0004 uint32
@@ -248,10 +490,10 @@ func (z *wgClientValueV1) UnmarshalMsg(bts []byte) (o []byte, err error) {
 	} else {
 		z.AllowedIPs = make([]net.IPNet, zb0004)
 	}
-	for za0008 := range z.AllowedIPs {
-		bts, err = (*msgpIPNet)(&z.AllowedIPs[za0008]).UnmarshalMsg(bts)
+	for za0004 := range z.AllowedIPs {
+		bts, err = (*msgpIPNet)(&z.AllowedIPs[za0004]).UnmarshalMsg(bts)
 		if err != nil {
-			err = msgp.WrapError(err, "AllowedIPs", za0008)
+			err = msgp.WrapError(err, "AllowedIPs", za0004)
 			return
 		}
 	}
@@ -278,12 +520,12 @@ func (z *wgClientValueV1) UnmarshalMsg(bts []byte) (o []byte, err error) {
 // Msgsize returns an upper bound estimate of the number of bytes occupied by the serialized message
 func (z *wgClientValueV1) Msgsize() (s int) {
 	s = 1 + (*msgpIPNet)(&z.Address).Msgsize() + msgp.ArrayHeaderSize + (32 * (msgp.ByteSize)) + msgp.ArrayHeaderSize + (32 * (msgp.ByteSize)) + msgp.ArrayHeaderSize
-	for za0007 := range z.DNS {
-		s += msgp.BytesPrefixSize + len([]byte(z.DNS[za0007]))
+	for za0003 := range z.DNS {
+		s += msgp.BytesPrefixSize + len([]byte(z.DNS[za0003]))
 	}
 	s += msgp.ArrayHeaderSize
-	for za0008 := range z.AllowedIPs {
-		s += (*msgpIPNet)(&z.AllowedIPs[za0008]).Msgsize()
+	for za0004 := range z.AllowedIPs {
+		s += (*msgpIPNet)(&z.AllowedIPs[za0004]).Msgsize()
 	}
 	if z.PersistentKeepalive == nil {
 		s += msgp.NilSize
@@ -292,3 +534,1220 @@ func (z *wgClientValueV1) Msgsize() (s int) {
 	}
 	return
 }
+
+// DecodeMsg implements msgp.Decodable
+func (z *wgClientValueV2) DecodeMsg(dc *msgp.Reader) (err error) {
+	var zb0001 uint32
+	zb0001, err = dc.ReadArrayHeader()
+	if err != nil {
+		err = msgp.WrapError(err)
+		return
+	}
+	if zb0001 != 6 {
+		err = msgp.ArrayError{Wanted: 6, Got: zb0001}
+		return
+	}
+	err = (*msgpIPNet)(&z.Address).DecodeMsg(dc)
+	if err != nil {
+		err = msgp.WrapError(err, "Address")
+		return
+	}
+	err = z.PrivateKey.DecodeMsg(dc)
+	if err != nil {
+		err = msgp.WrapError(err, "PrivateKey")
+		return
+	}
+	err = dc.ReadExactBytes((z.PublicKey)[:])
+	if err != nil {
+		err = msgp.WrapError(err, "PublicKey")
+		return
+	}
+	var zb0002 uint32
+	zb0002, err = dc.ReadArrayHeader()
+	if err != nil {
+		err = msgp.WrapError(err, "DNS")
+		return
+	}
+	if cap(z.DNS) >= int(zb0002) {
+		z.DNS = (z.DNS)[:zb0002]
+	} else {
+		z.DNS = make([]net.IP, zb0002)
+	}
+	for za0002 := range z.DNS {
+		{
+			var zb0003 []byte
+			zb0003, err = dc.ReadBytes([]byte(z.DNS[za0002]))
+			if err != nil {
+				err = msgp.WrapError(err, "DNS", za0002)
+				return
+			}
+			z.DNS[za0002] = net.IP(zb0003)
+		}
+	}
+	var zb0004 uint32
+	zb0004, err = dc.ReadArrayHeader()
+	if err != nil {
+		err = msgp.WrapError(err, "AllowedIPs")
+		return
+	}
+	if cap(z.AllowedIPs) >= int(zb0004) {
+		z.AllowedIPs = (z.AllowedIPs)[:zb0004]
+	} else {
+		z.AllowedIPs = make([]net.IPNet, zb0004)
+	}
+	for za0003 := range z.AllowedIPs {
+		err = (*msgpIPNet)(&z.AllowedIPs[za0003]).DecodeMsg(dc)
+		if err != nil {
+			err = msgp.WrapError(err, "AllowedIPs", za0003)
+			return
+		}
+	}
+	if dc.IsNil() {
+		err = dc.ReadNil()
+		if err != nil {
+			err = msgp.WrapError(err, "PersistentKeepalive")
+			return
+		}
+		z.PersistentKeepalive = nil
+	} else {
+		if z.PersistentKeepalive == nil {
+			z.PersistentKeepalive = new(int64)
+		}
+		*z.PersistentKeepalive, err = dc.ReadInt64()
+		if err != nil {
+			err = msgp.WrapError(err, "PersistentKeepalive")
+			return
+		}
+	}
+	return
+}
+
+// EncodeMsg implements msgp.Encodable
+func (z *wgClientValueV2) EncodeMsg(en *msgp.Writer) (err error) {
+	// array header, size 6
+	err = en.Append(0x96)
+	if err != nil {
+		return
+	}
+	err = (*msgpIPNet)(&z.Address).EncodeMsg(en)
+	if err != nil {
+		err = msgp.WrapError(err, "Address")
+		return
+	}
+	err = z.PrivateKey.EncodeMsg(en)
+	if err != nil {
+		err = msgp.WrapError(err, "PrivateKey")
+		return
+	}
+	err = en.WriteBytes((z.PublicKey)[:])
+	if err != nil {
+		err = msgp.WrapError(err, "PublicKey")
+		return
+	}
+	err = en.WriteArrayHeader(uint32(len(z.DNS)))
+	if err != nil {
+		err = msgp.WrapError(err, "DNS")
+		return
+	}
+	for za0002 := range z.DNS {
+		err = en.WriteBytes([]byte(z.DNS[za0002]))
+		if err != nil {
+			err = msgp.WrapError(err, "DNS", za0002)
+			return
+		}
+	}
+	err = en.WriteArrayHeader(uint32(len(z.AllowedIPs)))
+	if err != nil {
+		err = msgp.WrapError(err, "AllowedIPs")
+		return
+	}
+	for za0003 := range z.AllowedIPs {
+		err = (*msgpIPNet)(&z.AllowedIPs[za0003]).EncodeMsg(en)
+		if err != nil {
+			err = msgp.WrapError(err, "AllowedIPs", za0003)
+			return
+		}
+	}
+	if z.PersistentKeepalive == nil {
+		err = en.WriteNil()
+		if err != nil {
+			return
+		}
+	} else {
+		err = en.WriteInt64(*z.PersistentKeepalive)
+		if err != nil {
+			err = msgp.WrapError(err, "PersistentKeepalive")
+			return
+		}
+	}
+	return
+}
+
+// MarshalMsg implements msgp.Marshaler
+func (z *wgClientValueV2) MarshalMsg(b []byte) (o []byte, err error) {
+	o = msgp.Require(b, z.Msgsize())
+	// array header, size 6
+	o = append(o, 0x96)
+	o, err = (*msgpIPNet)(&z.Address).MarshalMsg(o)
+	if err != nil {
+		err = msgp.WrapError(err, "Address")
+		return
+	}
+	o, err = z.PrivateKey.MarshalMsg(o)
+	if err != nil {
+		err = msgp.WrapError(err, "PrivateKey")
+		return
+	}
+	o = msgp.AppendBytes(o, (z.PublicKey)[:])
+	o = msgp.AppendArrayHeader(o, uint32(len(z.DNS)))
+	for za0002 := range z.DNS {
+		o = msgp.AppendBytes(o, []byte(z.DNS[za0002]))
+	}
+	o = msgp.AppendArrayHeader(o, uint32(len(z.AllowedIPs)))
+	for za0003 := range z.AllowedIPs {
+		o, err = (*msgpIPNet)(&z.AllowedIPs[za0003]).MarshalMsg(o)
+		if err != nil {
+			err = msgp.WrapError(err, "AllowedIPs", za0003)
+			return
+		}
+	}
+	if z.PersistentKeepalive == nil {
+		o = msgp.AppendNil(o)
+	} else {
+		o = msgp.AppendInt64(o, *z.PersistentKeepalive)
+	}
+	return
+}
+
+// UnmarshalMsg implements msgp.Unmarshaler
+func (z *wgClientValueV2) UnmarshalMsg(bts []byte) (o []byte, err error) {
+	var zb0001 uint32
+	zb0001, bts, err = msgp.ReadArrayHeaderBytes(bts)
+	if err != nil {
+		err = msgp.WrapError(err)
+		return
+	}
+	if zb0001 != 6 {
+		err = msgp.ArrayError{Wanted: 6, Got: zb0001}
+		return
+	}
+	bts, err = (*msgpIPNet)(&z.Address).UnmarshalMsg(bts)
+	if err != nil {
+		err = msgp.WrapError(err, "Address")
+		return
+	}
+	bts, err = z.PrivateKey.UnmarshalMsg(bts)
+	if err != nil {
+		err = msgp.WrapError(err, "PrivateKey")
+		return
+	}
+	bts, err = msgp.ReadExactBytes(bts, (z.PublicKey)[:])
+	if err != nil {
+		err = msgp.WrapError(err, "PublicKey")
+		return
+	}
+	var zb0002 uint32
+	zb0002, bts, err = msgp.ReadArrayHeaderBytes(bts)
+	if err != nil {
+		err = msgp.WrapError(err, "DNS")
+		return
+	}
+	if cap(z.DNS) >= int(zb0002) {
+		z.DNS = (z.DNS)[:zb0002]
+	} else {
+		z.DNS = make([]net.IP, zb0002)
+	}
+	for za0002 := range z.DNS {
+		{
+			var zb0003 []byte
+			zb0003, bts, err = msgp.ReadBytesBytes(bts, []byte(z.DNS[za0002]))
+			if err != nil {
+				err = msgp.WrapError(err, "DNS", za0002)
+				return
+			}
+			z.DNS[za0002] = net.IP(zb0003)
+		}
+	}
+	var zb0004 uint32
+	zb0004, bts, err = msgp.ReadArrayHeaderBytes(bts)
+	if err != nil {
+		err = msgp.WrapError(err, "AllowedIPs")
+		return
+	}
+	if cap(z.AllowedIPs) >= int(zb0004) {
+		z.AllowedIPs = (z.AllowedIPs)[:zb0004]
+	} else {
+		z.AllowedIPs = make([]net.IPNet, zb0004)
+	}
+	for za0003 := range z.AllowedIPs {
+		bts, err = (*msgpIPNet)(&z.AllowedIPs[za0003]).UnmarshalMsg(bts)
+		if err != nil {
+			err = msgp.WrapError(err, "AllowedIPs", za0003)
+			return
+		}
+	}
+	if msgp.IsNil(bts) {
+		bts, err = msgp.ReadNilBytes(bts)
+		if err != nil {
+			return
+		}
+		z.PersistentKeepalive = nil
+	} else {
+		if z.PersistentKeepalive == nil {
+			z.PersistentKeepalive = new(int64)
+		}
+		*z.PersistentKeepalive, bts, err = msgp.ReadInt64Bytes(bts)
+		if err != nil {
+			err = msgp.WrapError(err, "PersistentKeepalive")
+			return
+		}
+	}
+	o = bts
+	return
+}
+
+// Msgsize returns an upper bound estimate of the number of bytes occupied by the serialized message
+func (z *wgClientValueV2) Msgsize() (s int) {
+	s = 1 + (*msgpIPNet)(&z.Address).Msgsize() + z.PrivateKey.Msgsize() + msgp.ArrayHeaderSize + (32 * (msgp.ByteSize)) + msgp.ArrayHeaderSize
+	for za0002 := range z.DNS {
+		s += msgp.BytesPrefixSize + len([]byte(z.DNS[za0002]))
+	}
+	s += msgp.ArrayHeaderSize
+	for za0003 := range z.AllowedIPs {
+		s += (*msgpIPNet)(&z.AllowedIPs[za0003]).Msgsize()
+	}
+	if z.PersistentKeepalive == nil {
+		s += msgp.NilSize
+	} else {
+		s += msgp.Int64Size
+	}
+	return
+}
+
+// DecodeMsg implements msgp.Decodable
+func (z *wgClientValueV3) DecodeMsg(dc *msgp.Reader) (err error) {
+	var field []byte
+	_ = field
+	var zb0001 uint32
+	zb0001, err = dc.ReadMapHeader()
+	if err != nil {
+		err = msgp.WrapError(err)
+		return
+	}
+	for zb0001 > 0 {
+		zb0001--
+		field, err = dc.ReadMapKeyPtr()
+		if err != nil {
+			err = msgp.WrapError(err)
+			return
+		}
+		switch msgp.UnsafeString(field) {
+		case "address":
+			err = (*msgpIPNet)(&z.Address).DecodeMsg(dc)
+			if err != nil {
+				err = msgp.WrapError(err, "Address")
+				return
+			}
+		case "private_key":
+			if dc.IsNil() {
+				err = dc.ReadNil()
+				if err != nil {
+					err = msgp.WrapError(err, "PrivateKey")
+					return
+				}
+				z.PrivateKey = nil
+			} else {
+				if z.PrivateKey == nil {
+					z.PrivateKey = new([32]byte)
+				}
+				err = dc.ReadExactBytes((*z.PrivateKey)[:])
+				if err != nil {
+					err = msgp.WrapError(err, "PrivateKey")
+					return
+				}
+			}
+		case "sealed_private_key":
+			if dc.IsNil() {
+				err = dc.ReadNil()
+				if err != nil {
+					err = msgp.WrapError(err, "SealedPrivateKey")
+					return
+				}
+				z.SealedPrivateKey = nil
+			} else {
+				if z.SealedPrivateKey == nil {
+					z.SealedPrivateKey = new(sealedKeyV1)
+				}
+				err = z.SealedPrivateKey.DecodeMsg(dc)
+				if err != nil {
+					err = msgp.WrapError(err, "SealedPrivateKey")
+					return
+				}
+			}
+		case "interface_aad":
+			z.InterfaceAAD, err = dc.ReadBool()
+			if err != nil {
+				err = msgp.WrapError(err, "InterfaceAAD")
+				return
+			}
+		case "public_key":
+			err = dc.ReadExactBytes((z.PublicKey)[:])
+			if err != nil {
+				err = msgp.WrapError(err, "PublicKey")
+				return
+			}
+		case "dns":
+			var zb0002 uint32
+			zb0002, err = dc.ReadArrayHeader()
+			if err != nil {
+				err = msgp.WrapError(err, "DNS")
+				return
+			}
+			if cap(z.DNS) >= int(zb0002) {
+				z.DNS = (z.DNS)[:zb0002]
+			} else {
+				z.DNS = make([]net.IP, zb0002)
+			}
+			for za0003 := range z.DNS {
+				{
+					var zb0003 []byte
+					zb0003, err = dc.ReadBytes([]byte(z.DNS[za0003]))
+					if err != nil {
+						err = msgp.WrapError(err, "DNS", za0003)
+						return
+					}
+					z.DNS[za0003] = net.IP(zb0003)
+				}
+			}
+		case "allowed_ips":
+			var zb0004 uint32
+			zb0004, err = dc.ReadArrayHeader()
+			if err != nil {
+				err = msgp.WrapError(err, "AllowedIPs")
+				return
+			}
+			if cap(z.AllowedIPs) >= int(zb0004) {
+				z.AllowedIPs = (z.AllowedIPs)[:zb0004]
+			} else {
+				z.AllowedIPs = make([]net.IPNet, zb0004)
+			}
+			for za0004 := range z.AllowedIPs {
+				err = (*msgpIPNet)(&z.AllowedIPs[za0004]).DecodeMsg(dc)
+				if err != nil {
+					err = msgp.WrapError(err, "AllowedIPs", za0004)
+					return
+				}
+			}
+		case "persistent_keepalive":
+			if dc.IsNil() {
+				err = dc.ReadNil()
+				if err != nil {
+					err = msgp.WrapError(err, "PersistentKeepalive")
+					return
+				}
+				z.PersistentKeepalive = nil
+			} else {
+				if z.PersistentKeepalive == nil {
+					z.PersistentKeepalive = new(int64)
+				}
+				*z.PersistentKeepalive, err = dc.ReadInt64()
+				if err != nil {
+					err = msgp.WrapError(err, "PersistentKeepalive")
+					return
+				}
+			}
+		case "disabled":
+			z.Disabled, err = dc.ReadBool()
+			if err != nil {
+				err = msgp.WrapError(err, "Disabled")
+				return
+			}
+		case "quota":
+			if dc.IsNil() {
+				err = dc.ReadNil()
+				if err != nil {
+					err = msgp.WrapError(err, "Quota")
+					return
+				}
+				z.Quota = nil
+			} else {
+				if z.Quota == nil {
+					z.Quota = new(wgClientQuotaV1)
+				}
+				err = z.Quota.DecodeMsg(dc)
+				if err != nil {
+					err = msgp.WrapError(err, "Quota")
+					return
+				}
+			}
+		case "rate_limit":
+			z.RateLimit, err = dc.ReadUint64()
+			if err != nil {
+				err = msgp.WrapError(err, "RateLimit")
+				return
+			}
+		case "group":
+			z.Group, err = dc.ReadString()
+			if err != nil {
+				err = msgp.WrapError(err, "Group")
+				return
+			}
+		case "profile":
+			z.Profile, err = dc.ReadString()
+			if err != nil {
+				err = msgp.WrapError(err, "Profile")
+				return
+			}
+		case "expires_at":
+			if dc.IsNil() {
+				err = dc.ReadNil()
+				if err != nil {
+					err = msgp.WrapError(err, "ExpiresAt")
+					return
+				}
+				z.ExpiresAt = nil
+			} else {
+				if z.ExpiresAt == nil {
+					z.ExpiresAt = new(int64)
+				}
+				*z.ExpiresAt, err = dc.ReadInt64()
+				if err != nil {
+					err = msgp.WrapError(err, "ExpiresAt")
+					return
+				}
+			}
+		case "labels":
+			var zb0005 uint32
+			zb0005, err = dc.ReadMapHeader()
+			if err != nil {
+				err = msgp.WrapError(err, "Labels")
+				return
+			}
+			if z.Labels == nil {
+				z.Labels = make(map[string]string, zb0005)
+			} else if len(z.Labels) > 0 {
+				for key := range z.Labels {
+					delete(z.Labels, key)
+				}
+			}
+			for zb0005 > 0 {
+				zb0005--
+				var za0005 string
+				var za0006 string
+				za0005, err = dc.ReadString()
+				if err != nil {
+					err = msgp.WrapError(err, "Labels")
+					return
+				}
+				za0006, err = dc.ReadString()
+				if err != nil {
+					err = msgp.WrapError(err, "Labels", za0005)
+					return
+				}
+				z.Labels[za0005] = za0006
+			}
+		case "description":
+			z.Description, err = dc.ReadString()
+			if err != nil {
+				err = msgp.WrapError(err, "Description")
+				return
+			}
+		case "mesh":
+			z.Mesh, err = dc.ReadBool()
+			if err != nil {
+				err = msgp.WrapError(err, "Mesh")
+				return
+			}
+		case "endpoint":
+			z.Endpoint, err = dc.ReadString()
+			if err != nil {
+				err = msgp.WrapError(err, "Endpoint")
+				return
+			}
+		case "last_endpoint":
+			z.LastEndpoint, err = dc.ReadString()
+			if err != nil {
+				err = msgp.WrapError(err, "LastEndpoint")
+				return
+			}
+		default:
+			err = dc.Skip()
+			if err != nil {
+				err = msgp.WrapError(err)
+				return
+			}
+		}
+	}
+	return
+}
+
+// EncodeMsg implements msgp.Encodable
+func (z *wgClientValueV3) EncodeMsg(en *msgp.Writer) (err error) {
+	// map header, size 19
+	// write "address"
+	err = en.Append(0xde, 0x0, 0x13, 0xa7, 0x61, 0x64, 0x64, 0x72, 0x65, 0x73, 0x73)
+	if err != nil {
+		return
+	}
+	err = (*msgpIPNet)(&z.Address).EncodeMsg(en)
+	if err != nil {
+		err = msgp.WrapError(err, "Address")
+		return
+	}
+	// write "private_key"
+	err = en.Append(0xab, 0x70, 0x72, 0x69, 0x76, 0x61, 0x74, 0x65, 0x5f, 0x6b, 0x65, 0x79)
+	if err != nil {
+		return
+	}
+	if z.PrivateKey == nil {
+		err = en.WriteNil()
+		if err != nil {
+			return
+		}
+	} else {
+		err = en.WriteBytes((*z.PrivateKey)[:])
+		if err != nil {
+			err = msgp.WrapError(err, "PrivateKey")
+			return
+		}
+	}
+	// write "sealed_private_key"
+	err = en.Append(0xb2, 0x73, 0x65, 0x61, 0x6c, 0x65, 0x64, 0x5f, 0x70, 0x72, 0x69, 0x76, 0x61, 0x74, 0x65, 0x5f, 0x6b, 0x65, 0x79)
+	if err != nil {
+		return
+	}
+	if z.SealedPrivateKey == nil {
+		err = en.WriteNil()
+		if err != nil {
+			return
+		}
+	} else {
+		err = z.SealedPrivateKey.EncodeMsg(en)
+		if err != nil {
+			err = msgp.WrapError(err, "SealedPrivateKey")
+			return
+		}
+	}
+	// write "interface_aad"
+	err = en.Append(0xad, 0x69, 0x6e, 0x74, 0x65, 0x72, 0x66, 0x61, 0x63, 0x65, 0x5f, 0x61, 0x61, 0x64)
+	if err != nil {
+		return
+	}
+	err = en.WriteBool(z.InterfaceAAD)
+	if err != nil {
+		err = msgp.WrapError(err, "InterfaceAAD")
+		return
+	}
+	// write "public_key"
+	err = en.Append(0xaa, 0x70, 0x75, 0x62, 0x6c, 0x69, 0x63, 0x5f, 0x6b, 0x65, 0x79)
+	if err != nil {
+		return
+	}
+	err = en.WriteBytes((z.PublicKey)[:])
+	if err != nil {
+		err = msgp.WrapError(err, "PublicKey")
+		return
+	}
+	// write "dns"
+	err = en.Append(0xa3, 0x64, 0x6e, 0x73)
+	if err != nil {
+		return
+	}
+	err = en.WriteArrayHeader(uint32(len(z.DNS)))
+	if err != nil {
+		err = msgp.WrapError(err, "DNS")
+		return
+	}
+	for za0003 := range z.DNS {
+		err = en.WriteBytes([]byte(z.DNS[za0003]))
+		if err != nil {
+			err = msgp.WrapError(err, "DNS", za0003)
+			return
+		}
+	}
+	// write "allowed_ips"
+	err = en.Append(0xab, 0x61, 0x6c, 0x6c, 0x6f, 0x77, 0x65, 0x64, 0x5f, 0x69, 0x70, 0x73)
+	if err != nil {
+		return
+	}
+	err = en.WriteArrayHeader(uint32(len(z.AllowedIPs)))
+	if err != nil {
+		err = msgp.WrapError(err, "AllowedIPs")
+		return
+	}
+	for za0004 := range z.AllowedIPs {
+		err = (*msgpIPNet)(&z.AllowedIPs[za0004]).EncodeMsg(en)
+		if err != nil {
+			err = msgp.WrapError(err, "AllowedIPs", za0004)
+			return
+		}
+	}
+	// write "persistent_keepalive"
+	err = en.Append(0xb4, 0x70, 0x65, 0x72, 0x73, 0x69, 0x73, 0x74, 0x65, 0x6e, 0x74, 0x5f, 0x6b, 0x65, 0x65, 0x70, 0x61, 0x6c, 0x69, 0x76, 0x65)
+	if err != nil {
+		return
+	}
+	if z.PersistentKeepalive == nil {
+		err = en.WriteNil()
+		if err != nil {
+			return
+		}
+	} else {
+		err = en.WriteInt64(*z.PersistentKeepalive)
+		if err != nil {
+			err = msgp.WrapError(err, "PersistentKeepalive")
+			return
+		}
+	}
+	// write "disabled"
+	err = en.Append(0xa8, 0x64, 0x69, 0x73, 0x61, 0x62, 0x6c, 0x65, 0x64)
+	if err != nil {
+		return
+	}
+	err = en.WriteBool(z.Disabled)
+	if err != nil {
+		err = msgp.WrapError(err, "Disabled")
+		return
+	}
+	// write "quota"
+	err = en.Append(0xa5, 0x71, 0x75, 0x6f, 0x74, 0x61)
+	if err != nil {
+		return
+	}
+	if z.Quota == nil {
+		err = en.WriteNil()
+		if err != nil {
+			return
+		}
+	} else {
+		err = z.Quota.EncodeMsg(en)
+		if err != nil {
+			err = msgp.WrapError(err, "Quota")
+			return
+		}
+	}
+	// write "rate_limit"
+	err = en.Append(0xaa, 0x72, 0x61, 0x74, 0x65, 0x5f, 0x6c, 0x69, 0x6d, 0x69, 0x74)
+	if err != nil {
+		return
+	}
+	err = en.WriteUint64(z.RateLimit)
+	if err != nil {
+		err = msgp.WrapError(err, "RateLimit")
+		return
+	}
+	// write "group"
+	err = en.Append(0xa5, 0x67, 0x72, 0x6f, 0x75, 0x70)
+	if err != nil {
+		return
+	}
+	err = en.WriteString(z.Group)
+	if err != nil {
+		err = msgp.WrapError(err, "Group")
+		return
+	}
+	// write "profile"
+	err = en.Append(0xa7, 0x70, 0x72, 0x6f, 0x66, 0x69, 0x6c, 0x65)
+	if err != nil {
+		return
+	}
+	err = en.WriteString(z.Profile)
+	if err != nil {
+		err = msgp.WrapError(err, "Profile")
+		return
+	}
+	// write "expires_at"
+	err = en.Append(0xaa, 0x65, 0x78, 0x70, 0x69, 0x72, 0x65, 0x73, 0x5f, 0x61, 0x74)
+	if err != nil {
+		return
+	}
+	if z.ExpiresAt == nil {
+		err = en.WriteNil()
+		if err != nil {
+			return
+		}
+	} else {
+		err = en.WriteInt64(*z.ExpiresAt)
+		if err != nil {
+			err = msgp.WrapError(err, "ExpiresAt")
+			return
+		}
+	}
+	// write "labels"
+	err = en.Append(0xa6, 0x6c, 0x61, 0x62, 0x65, 0x6c, 0x73)
+	if err != nil {
+		return
+	}
+	err = en.WriteMapHeader(uint32(len(z.Labels)))
+	if err != nil {
+		err = msgp.WrapError(err, "Labels")
+		return
+	}
+	for za0005, za0006 := range z.Labels {
+		err = en.WriteString(za0005)
+		if err != nil {
+			err = msgp.WrapError(err, "Labels")
+			return
+		}
+		err = en.WriteString(za0006)
+		if err != nil {
+			err = msgp.WrapError(err, "Labels", za0005)
+			return
+		}
+	}
+	// write "description"
+	err = en.Append(0xab, 0x64, 0x65, 0x73, 0x63, 0x72, 0x69, 0x70, 0x74, 0x69, 0x6f, 0x6e)
+	if err != nil {
+		return
+	}
+	err = en.WriteString(z.Description)
+	if err != nil {
+		err = msgp.WrapError(err, "Description")
+		return
+	}
+	// write "mesh"
+	err = en.Append(0xa4, 0x6d, 0x65, 0x73, 0x68)
+	if err != nil {
+		return
+	}
+	err = en.WriteBool(z.Mesh)
+	if err != nil {
+		err = msgp.WrapError(err, "Mesh")
+		return
+	}
+	// write "endpoint"
+	err = en.Append(0xa8, 0x65, 0x6e, 0x64, 0x70, 0x6f, 0x69, 0x6e, 0x74)
+	if err != nil {
+		return
+	}
+	err = en.WriteString(z.Endpoint)
+	if err != nil {
+		err = msgp.WrapError(err, "Endpoint")
+		return
+	}
+	// write "last_endpoint"
+	err = en.Append(0xad, 0x6c, 0x61, 0x73, 0x74, 0x5f, 0x65, 0x6e, 0x64, 0x70, 0x6f, 0x69, 0x6e, 0x74)
+	if err != nil {
+		return
+	}
+	err = en.WriteString(z.LastEndpoint)
+	if err != nil {
+		err = msgp.WrapError(err, "LastEndpoint")
+		return
+	}
+	return
+}
+
+// MarshalMsg implements msgp.Marshaler
+func (z *wgClientValueV3) MarshalMsg(b []byte) (o []byte, err error) {
+	o = msgp.Require(b, z.Msgsize())
+	// map header, size 19
+	// string "address"
+	o = append(o, 0xde, 0x0, 0x13, 0xa7, 0x61, 0x64, 0x64, 0x72, 0x65, 0x73, 0x73)
+	o, err = (*msgpIPNet)(&z.Address).MarshalMsg(o)
+	if err != nil {
+		err = msgp.WrapError(err, "Address")
+		return
+	}
+	// string "private_key"
+	o = append(o, 0xab, 0x70, 0x72, 0x69, 0x76, 0x61, 0x74, 0x65, 0x5f, 0x6b, 0x65, 0x79)
+	if z.PrivateKey == nil {
+		o = msgp.AppendNil(o)
+	} else {
+		o = msgp.AppendBytes(o, (*z.PrivateKey)[:])
+	}
+	// string "sealed_private_key"
+	o = append(o, 0xb2, 0x73, 0x65, 0x61, 0x6c, 0x65, 0x64, 0x5f, 0x70, 0x72, 0x69, 0x76, 0x61, 0x74, 0x65, 0x5f, 0x6b, 0x65, 0x79)
+	if z.SealedPrivateKey == nil {
+		o = msgp.AppendNil(o)
+	} else {
+		o, err = z.SealedPrivateKey.MarshalMsg(o)
+		if err != nil {
+			err = msgp.WrapError(err, "SealedPrivateKey")
+			return
+		}
+	}
+	// string "interface_aad"
+	o = append(o, 0xad, 0x69, 0x6e, 0x74, 0x65, 0x72, 0x66, 0x61, 0x63, 0x65, 0x5f, 0x61, 0x61, 0x64)
+	o = msgp.AppendBool(o, z.InterfaceAAD)
+	// string "public_key"
+	o = append(o, 0xaa, 0x70, 0x75, 0x62, 0x6c, 0x69, 0x63, 0x5f, 0x6b, 0x65, 0x79)
+	o = msgp.AppendBytes(o, (z.PublicKey)[:])
+	// string "dns"
+	o = append(o, 0xa3, 0x64, 0x6e, 0x73)
+	o = msgp.AppendArrayHeader(o, uint32(len(z.DNS)))
+	for za0003 := range z.DNS {
+		o = msgp.AppendBytes(o, []byte(z.DNS[za0003]))
+	}
+	// string "allowed_ips"
+	o = append(o, 0xab, 0x61, 0x6c, 0x6c, 0x6f, 0x77, 0x65, 0x64, 0x5f, 0x69, 0x70, 0x73)
+	o = msgp.AppendArrayHeader(o, uint32(len(z.AllowedIPs)))
+	for za0004 := range z.AllowedIPs {
+		o, err = (*msgpIPNet)(&z.AllowedIPs[za0004]).MarshalMsg(o)
+		if err != nil {
+			err = msgp.WrapError(err, "AllowedIPs", za0004)
+			return
+		}
+	}
+	// string "persistent_keepalive"
+	o = append(o, 0xb4, 0x70, 0x65, 0x72, 0x73, 0x69, 0x73, 0x74, 0x65, 0x6e, 0x74, 0x5f, 0x6b, 0x65, 0x65, 0x70, 0x61, 0x6c, 0x69, 0x76, 0x65)
+	if z.PersistentKeepalive == nil {
+		o = msgp.AppendNil(o)
+	} else {
+		o = msgp.AppendInt64(o, *z.PersistentKeepalive)
+	}
+	// string "disabled"
+	o = append(o, 0xa8, 0x64, 0x69, 0x73, 0x61, 0x62, 0x6c, 0x65, 0x64)
+	o = msgp.AppendBool(o, z.Disabled)
+	// string "quota"
+	o = append(o, 0xa5, 0x71, 0x75, 0x6f, 0x74, 0x61)
+	if z.Quota == nil {
+		o = msgp.AppendNil(o)
+	} else {
+		o, err = z.Quota.MarshalMsg(o)
+		if err != nil {
+			err = msgp.WrapError(err, "Quota")
+			return
+		}
+	}
+	// string "rate_limit"
+	o = append(o, 0xaa, 0x72, 0x61, 0x74, 0x65, 0x5f, 0x6c, 0x69, 0x6d, 0x69, 0x74)
+	o = msgp.AppendUint64(o, z.RateLimit)
+	// string "group"
+	o = append(o, 0xa5, 0x67, 0x72, 0x6f, 0x75, 0x70)
+	o = msgp.AppendString(o, z.Group)
+	// string "profile"
+	o = append(o, 0xa7, 0x70, 0x72, 0x6f, 0x66, 0x69, 0x6c, 0x65)
+	o = msgp.AppendString(o, z.Profile)
+	// string "expires_at"
+	o = append(o, 0xaa, 0x65, 0x78, 0x70, 0x69, 0x72, 0x65, 0x73, 0x5f, 0x61, 0x74)
+	if z.ExpiresAt == nil {
+		o = msgp.AppendNil(o)
+	} else {
+		o = msgp.AppendInt64(o, *z.ExpiresAt)
+	}
+	// string "labels"
+	o = append(o, 0xa6, 0x6c, 0x61, 0x62, 0x65, 0x6c, 0x73)
+	o = msgp.AppendMapHeader(o, uint32(len(z.Labels)))
+	for za0005, za0006 := range z.Labels {
+		o = msgp.AppendString(o, za0005)
+		o = msgp.AppendString(o, za0006)
+	}
+	// string "description"
+	o = append(o, 0xab, 0x64, 0x65, 0x73, 0x63, 0x72, 0x69, 0x70, 0x74, 0x69, 0x6f, 0x6e)
+	o = msgp.AppendString(o, z.Description)
+	// string "mesh"
+	o = append(o, 0xa4, 0x6d, 0x65, 0x73, 0x68)
+	o = msgp.AppendBool(o, z.Mesh)
+	// string "endpoint"
+	o = append(o, 0xa8, 0x65, 0x6e, 0x64, 0x70, 0x6f, 0x69, 0x6e, 0x74)
+	o = msgp.AppendString(o, z.Endpoint)
+	// string "last_endpoint"
+	o = append(o, 0xad, 0x6c, 0x61, 0x73, 0x74, 0x5f, 0x65, 0x6e, 0x64, 0x70, 0x6f, 0x69, 0x6e, 0x74)
+	o = msgp.AppendString(o, z.LastEndpoint)
+	return
+}
+
+// UnmarshalMsg implements msgp.Unmarshaler
+func (z *wgClientValueV3) UnmarshalMsg(bts []byte) (o []byte, err error) {
+	var field []byte
+	_ = field
+	var zb0001 uint32
+	zb0001, bts, err = msgp.ReadMapHeaderBytes(bts)
+	if err != nil {
+		err = msgp.WrapError(err)
+		return
+	}
+	for zb0001 > 0 {
+		zb0001--
+		field, bts, err = msgp.ReadMapKeyZC(bts)
+		if err != nil {
+			err = msgp.WrapError(err)
+			return
+		}
+		switch msgp.UnsafeString(field) {
+		case "address":
+			bts, err = (*msgpIPNet)(&z.Address).UnmarshalMsg(bts)
+			if err != nil {
+				err = msgp.WrapError(err, "Address")
+				return
+			}
+		case "private_key":
+			if msgp.IsNil(bts) {
+				bts, err = msgp.ReadNilBytes(bts)
+				if err != nil {
+					return
+				}
+				z.PrivateKey = nil
+			} else {
+				if z.PrivateKey == nil {
+					z.PrivateKey = new([32]byte)
+				}
+				bts, err = msgp.ReadExactBytes(bts, (*z.PrivateKey)[:])
+				if err != nil {
+					err = msgp.WrapError(err, "PrivateKey")
+					return
+				}
+			}
+		case "sealed_private_key":
+			if msgp.IsNil(bts) {
+				bts, err = msgp.ReadNilBytes(bts)
+				if err != nil {
+					return
+				}
+				z.SealedPrivateKey = nil
+			} else {
+				if z.SealedPrivateKey == nil {
+					z.SealedPrivateKey = new(sealedKeyV1)
+				}
+				bts, err = z.SealedPrivateKey.UnmarshalMsg(bts)
+				if err != nil {
+					err = msgp.WrapError(err, "SealedPrivateKey")
+					return
+				}
+			}
+		case "interface_aad":
+			z.InterfaceAAD, bts, err = msgp.ReadBoolBytes(bts)
+			if err != nil {
+				err = msgp.WrapError(err, "InterfaceAAD")
+				return
+			}
+		case "public_key":
+			bts, err = msgp.ReadExactBytes(bts, (z.PublicKey)[:])
+			if err != nil {
+				err = msgp.WrapError(err, "PublicKey")
+				return
+			}
+		case "dns":
+			var zb0002 uint32
+			zb0002, bts, err = msgp.ReadArrayHeaderBytes(bts)
+			if err != nil {
+				err = msgp.WrapError(err, "DNS")
+				return
+			}
+			if cap(z.DNS) >= int(zb0002) {
+				z.DNS = (z.DNS)[:zb0002]
+			} else {
+				z.DNS = make([]net.IP, zb0002)
+			}
+			for za0003 := range z.DNS {
+				{
+					var zb0003 []byte
+					zb0003, bts, err = msgp.ReadBytesBytes(bts, []byte(z.DNS[za0003]))
+					if err != nil {
+						err = msgp.WrapError(err, "DNS", za0003)
+						return
+					}
+					z.DNS[za0003] = net.IP(zb0003)
+				}
+			}
+		case "allowed_ips":
+			var zb0004 uint32
+			zb0004, bts, err = msgp.ReadArrayHeaderBytes(bts)
+			if err != nil {
+				err = msgp.WrapError(err, "AllowedIPs")
+				return
+			}
+			if cap(z.AllowedIPs) >= int(zb0004) {
+				z.AllowedIPs = (z.AllowedIPs)[:zb0004]
+			} else {
+				z.AllowedIPs = make([]net.IPNet, zb0004)
+			}
+			for za0004 := range z.AllowedIPs {
+				bts, err = (*msgpIPNet)(&z.AllowedIPs[za0004]).UnmarshalMsg(bts)
+				if err != nil {
+					err = msgp.WrapError(err, "AllowedIPs", za0004)
+					return
+				}
+			}
+		case "persistent_keepalive":
+			if msgp.IsNil(bts) {
+				bts, err = msgp.ReadNilBytes(bts)
+				if err != nil {
+					return
+				}
+				z.PersistentKeepalive = nil
+			} else {
+				if z.PersistentKeepalive == nil {
+					z.PersistentKeepalive = new(int64)
+				}
+				*z.PersistentKeepalive, bts, err = msgp.ReadInt64Bytes(bts)
+				if err != nil {
+					err = msgp.WrapError(err, "PersistentKeepalive")
+					return
+				}
+			}
+		case "disabled":
+			z.Disabled, bts, err = msgp.ReadBoolBytes(bts)
+			if err != nil {
+				err = msgp.WrapError(err, "Disabled")
+				return
+			}
+		case "quota":
+			if msgp.IsNil(bts) {
+				bts, err = msgp.ReadNilBytes(bts)
+				if err != nil {
+					return
+				}
+				z.Quota = nil
+			} else {
+				if z.Quota == nil {
+					z.Quota = new(wgClientQuotaV1)
+				}
+				bts, err = z.Quota.UnmarshalMsg(bts)
+				if err != nil {
+					err = msgp.WrapError(err, "Quota")
+					return
+				}
+			}
+		case "rate_limit":
+			z.RateLimit, bts, err = msgp.ReadUint64Bytes(bts)
+			if err != nil {
+				err = msgp.WrapError(err, "RateLimit")
+				return
+			}
+		case "group":
+			z.Group, bts, err = msgp.ReadStringBytes(bts)
+			if err != nil {
+				err = msgp.WrapError(err, "Group")
+				return
+			}
+		case "profile":
+			z.Profile, bts, err = msgp.ReadStringBytes(bts)
+			if err != nil {
+				err = msgp.WrapError(err, "Profile")
+				return
+			}
+		case "expires_at":
+			if msgp.IsNil(bts) {
+				bts, err = msgp.ReadNilBytes(bts)
+				if err != nil {
+					return
+				}
+				z.ExpiresAt = nil
+			} else {
+				if z.ExpiresAt == nil {
+					z.ExpiresAt = new(int64)
+				}
+				*z.ExpiresAt, bts, err = msgp.ReadInt64Bytes(bts)
+				if err != nil {
+					err = msgp.WrapError(err, "ExpiresAt")
+					return
+				}
+			}
+		case "labels":
+			var zb0005 uint32
+			zb0005, bts, err = msgp.ReadMapHeaderBytes(bts)
+			if err != nil {
+				err = msgp.WrapError(err, "Labels")
+				return
+			}
+			if z.Labels == nil {
+				z.Labels = make(map[string]string, zb0005)
+			} else if len(z.Labels) > 0 {
+				for key := range z.Labels {
+					delete(z.Labels, key)
+				}
+			}
+			for zb0005 > 0 {
+				var za0005 string
+				var za0006 string
+				zb0005--
+				za0005, bts, err = msgp.ReadStringBytes(bts)
+				if err != nil {
+					err = msgp.WrapError(err, "Labels")
+					return
+				}
+				za0006, bts, err = msgp.ReadStringBytes(bts)
+				if err != nil {
+					err = msgp.WrapError(err, "Labels", za0005)
+					return
+				}
+				z.Labels[za0005] = za0006
+			}
+		case "description":
+			z.Description, bts, err = msgp.ReadStringBytes(bts)
+			if err != nil {
+				err = msgp.WrapError(err, "Description")
+				return
+			}
+		case "mesh":
+			z.Mesh, bts, err = msgp.ReadBoolBytes(bts)
+			if err != nil {
+				err = msgp.WrapError(err, "Mesh")
+				return
+			}
+		case "endpoint":
+			z.Endpoint, bts, err = msgp.ReadStringBytes(bts)
+			if err != nil {
+				err = msgp.WrapError(err, "Endpoint")
+				return
+			}
+		case "last_endpoint":
+			z.LastEndpoint, bts, err = msgp.ReadStringBytes(bts)
+			if err != nil {
+				err = msgp.WrapError(err, "LastEndpoint")
+				return
+			}
+		default:
+			bts, err = msgp.Skip(bts)
+			if err != nil {
+				err = msgp.WrapError(err)
+				return
+			}
+		}
+	}
+	o = bts
+	return
+}
+
+// Msgsize returns an upper bound estimate of the number of bytes occupied by the serialized message
+func (z *wgClientValueV3) Msgsize() (s int) {
+	s = 3 + 8 + (*msgpIPNet)(&z.Address).Msgsize() + 12
+	if z.PrivateKey == nil {
+		s += msgp.NilSize
+	} else {
+		s += msgp.ArrayHeaderSize + (32 * (msgp.ByteSize))
+	}
+	s += 19
+	if z.SealedPrivateKey == nil {
+		s += msgp.NilSize
+	} else {
+		s += z.SealedPrivateKey.Msgsize()
+	}
+	s += 14 + msgp.BoolSize + 11 + msgp.ArrayHeaderSize + (32 * (msgp.ByteSize)) + 4 + msgp.ArrayHeaderSize
+	for za0003 := range z.DNS {
+		s += msgp.BytesPrefixSize + len([]byte(z.DNS[za0003]))
+	}
+	s += 12 + msgp.ArrayHeaderSize
+	for za0004 := range z.AllowedIPs {
+		s += (*msgpIPNet)(&z.AllowedIPs[za0004]).Msgsize()
+	}
+	s += 21
+	if z.PersistentKeepalive == nil {
+		s += msgp.NilSize
+	} else {
+		s += msgp.Int64Size
+	}
+	s += 9 + msgp.BoolSize + 6
+	if z.Quota == nil {
+		s += msgp.NilSize
+	} else {
+		s += z.Quota.Msgsize()
+	}
+	s += 11 + msgp.Uint64Size + 6 + msgp.StringPrefixSize + len(z.Group) + 8 + msgp.StringPrefixSize + len(z.Profile) + 11
+	if z.ExpiresAt == nil {
+		s += msgp.NilSize
+	} else {
+		s += msgp.Int64Size
+	}
+	s += 7 + msgp.MapHeaderSize
+	if z.Labels != nil {
+		for za0005, za0006 := range z.Labels {
+			_ = za0006
+			s += msgp.StringPrefixSize + len(za0005) + msgp.StringPrefixSize + len(za0006)
+		}
+	}
+	s += 12 + msgp.StringPrefixSize + len(z.Description) + 5 + msgp.BoolSize + 9 + msgp.StringPrefixSize + len(z.Endpoint) + 14 + msgp.StringPrefixSize + len(z.LastEndpoint)
+	return
+}
@@ -0,0 +1,153 @@
+package queries
+
+import (
+	"errors"
+	"fmt"
+	"net"
+
+	"github.com/infastin/wg-wish/pkg/wgtypes"
+)
+
+var (
+	wgClientPublicKeyIndexBucketName = []byte("wgclient_pubkey")
+	wgClientAddressIndexBucketName   = []byte("wgclient_address")
+)
+
+func wgClientAddressIndexKey(b []byte, ip net.IP) []byte {
+	return append(b, ip.To16()...)
+}
+
+type wgClientIndexValue struct {
+	PublicKey wgtypes.Key
+	Address   net.IPNet
+}
+
+// wgClientUnmarshalIndexValue decodes only the indexed fields,
+// so that encrypted values can be indexed without a master key.
+func wgClientUnmarshalIndexValue(valb []byte) (val wgClientIndexValue, err error) {
+	version, valb, err := unmarshalMeta(valb)
+	if err != nil {
+		return wgClientIndexValue{}, err
+	}
+
+	switch version {
+	case 1:
+		v1, err := wgClientUnmarshalValueV1(valb)
+		if err != nil {
+			return wgClientIndexValue{}, err
+		}
+		return wgClientIndexValue{PublicKey: v1.PublicKey, Address: v1.Address}, nil
+	case 2:
+		v2, err := wgClientUnmarshalValueV2(valb)
+		if err != nil {
+			return wgClientIndexValue{}, err
+		}
+		return wgClientIndexValue{PublicKey: v2.PublicKey, Address: v2.Address}, nil
+	case 3:
+		v3, err := wgClientUnmarshalValueV3(valb)
+		if err != nil {
+			return wgClientIndexValue{}, err
+		}
+		return wgClientIndexValue{PublicKey: v3.PublicKey, Address: v3.Address}, nil
+	}
+
+	return wgClientIndexValue{}, ErrUnsupportedVersion
+}
+
+func (queries *Queries) wgClientIndexPut(keyb []byte, publicKey wgtypes.Key, address net.IPNet) (err error) {
+	pkb := queries.parent.Bucket(wgClientPublicKeyIndexBucketName)
+	if err := pkb.Put(publicKey[:], keyb); err != nil {
+		return err
+	}
+
+	addrb := queries.parent.Bucket(wgClientAddressIndexBucketName)
+	return addrb.Put(wgClientAddressIndexKey(nil, address.IP), keyb)
+}
+
+func (queries *Queries) wgClientIndexDelete(valb []byte) (err error) {
+	val, err := wgClientUnmarshalIndexValue(valb)
+	if err != nil {
+		return err
+	}
+
+	pkb := queries.parent.Bucket(wgClientPublicKeyIndexBucketName)
+	if err := pkb.Delete(val.PublicKey[:]); err != nil {
+		return err
+	}
+
+	addrb := queries.parent.Bucket(wgClientAddressIndexBucketName)
+	return addrb.Delete(wgClientAddressIndexKey(nil, val.Address.IP))
+}
+
+// GetWireGuardClientNameByPublicKey returns the name of the client with the given public key.
+func (queries *Queries) GetWireGuardClientNameByPublicKey(publicKey wgtypes.Key) (name string, err error) {
+	b, err := queries.bucket(wgClientPublicKeyIndexBucketName)
+	if err != nil {
+		return "", err
+	}
+
+	keyb := b.Get(publicKey[:])
+	if keyb == nil {
+		return "", ErrKeyNotFound
+	}
+
+	return wgClientUnmarshalKey(keyb)
+}
+
+// GetWireGuardClientNameByAddress returns the name of the client with the given address.
+func (queries *Queries) GetWireGuardClientNameByAddress(ip net.IP) (name string, err error) {
+	b, err := queries.bucket(wgClientAddressIndexBucketName)
+	if err != nil {
+		return "", err
+	}
+
+	keyb := b.Get(wgClientAddressIndexKey(nil, ip))
+	if keyb == nil {
+		return "", ErrKeyNotFound
+	}
+
+	return wgClientUnmarshalKey(keyb)
+}
+
+func migrateIndexWireGuardClients(queries *Queries) (err error) {
+	for _, name := range [][]byte{wgClientPublicKeyIndexBucketName, wgClientAddressIndexBucketName} {
+		_, err = queries.parent.CreateBucketIfNotExists(name)
+		if err != nil {
+			return err
+		}
+	}
+
+	b := queries.parent.Bucket(wgClientBucketName)
+	pkb := queries.parent.Bucket(wgClientPublicKeyIndexBucketName)
+	addrb := queries.parent.Bucket(wgClientAddressIndexBucketName)
+
+	// Clients sharing a public key or an address can't be indexed,
+	// as removing one of them would remove the index entry of the other.
+	var conflicts []error
+
+	c := b.Cursor()
+	for keyb, valb := c.First(); keyb != nil; keyb, valb = c.Next() {
+		val, err := wgClientUnmarshalIndexValue(valb)
+		if err != nil {
+			return err
+		}
+
+		if other := pkb.Get(val.PublicKey[:]); other != nil {
+			conflicts = append(conflicts, fmt.Errorf("clients %q and %q have the same public key %s",
+				other, keyb, val.PublicKey.String()))
+			continue
+		}
+
+		if other := addrb.Get(wgClientAddressIndexKey(nil, val.Address.IP)); other != nil {
+			conflicts = append(conflicts, fmt.Errorf("clients %q and %q have the same address %s",
+				other, keyb, val.Address.IP.String()))
+			continue
+		}
+
+		if err := queries.wgClientIndexPut(keyb, val.PublicKey, val.Address); err != nil {
+			return err
+		}
+	}
+
+	return errors.Join(conflicts...)
+}
@@ -26,6 +26,24 @@ func wgServerConfigUnmarshalValueV1(b []byte) (value wgServerConfigValueV1, err
 	return value, err
 }
 
+//msgp:tuple wgServerConfigValueV2
+
+// Same as wgServerConfigValueV1, but with encrypted private key.
+// Sealed with sealAAD as version 2 and with interfaceAAD as version 3.
+type wgServerConfigValueV2 struct {
+	PrivateKey sealedKeyV1
+}
+
+func wgServerConfigMarshalValueV2(b []byte, value *wgServerConfigValueV2) []byte {
+	b, _ = value.MarshalMsg(b)
+	return b
+}
+
+func wgServerConfigUnmarshalValueV2(b []byte) (value wgServerConfigValueV2, err error) {
+	_, err = value.UnmarshalMsg(b)
+	return value, err
+}
+
 //msgp:ignore WireGuardServerConfig
 
 type WireGuardServerConfig struct {
@@ -33,33 +51,84 @@ type WireGuardServerConfig struct {
 }
 
 func (queries *Queries) SetWireGuardServerConfig(config *WireGuardServerConfig) (err error) {
-	b := queries.tx.Bucket(wgServerBucketName)
+	b, err := queries.bucket(wgServerBucketName)
+	if err != nil {
+		return err
+	}
 
-	valb := Meta(0).Append(nil)
-	valb = wgServerConfigMarshalValueV1(valb, &wgServerConfigValueV1{
-		PrivateKey: config.PrivateKey,
+	if queries.kms == nil {
+		valb := Meta(0).SetVersion(1).Append(nil)
+		valb = wgServerConfigMarshalValueV1(valb, &wgServerConfigValueV1{
+			PrivateKey: config.PrivateKey,
+		})
+		return b.Put(wgServerConfigKey, valb)
+	}
+
+	privateKey, err := queries.sealPrivateKey(config.PrivateKey, queries.interfaceAAD(wgServerBucketName, wgServerConfigKey))
+	if err != nil {
+		return err
+	}
+
+	valb := Meta(0).SetVersion(3).Append(nil)
+	valb = wgServerConfigMarshalValueV2(valb, &wgServerConfigValueV2{
+		PrivateKey: privateKey,
 	})
 
 	return b.Put(wgServerConfigKey, valb)
 }
 
 func (queries *Queries) GetWireGuardServerConfig() (config WireGuardServerConfig, err error) {
-	b := queries.tx.Bucket(wgServerBucketName)
+	b, err := queries.bucket(wgServerBucketName)
+	if err != nil {
+		return WireGuardServerConfig{}, err
+	}
 
 	valb := b.Get(wgServerConfigKey)
 	if valb == nil {
 		return WireGuardServerConfig{}, ErrKeyNotFound
 	}
 
-	val, err := wgServerConfigUnmarshalValueV1(valb[1:])
+	version, valb, err := unmarshalMeta(valb)
 	if err != nil {
 		return WireGuardServerConfig{}, err
 	}
 
-	return WireGuardServerConfig(val), nil
+	switch version {
+	case 1:
+		val, err := wgServerConfigUnmarshalValueV1(valb)
+		if err != nil {
+			return WireGuardServerConfig{}, err
+		}
+
+		return WireGuardServerConfig(val), nil
+	case 2, 3:
+		val, err := wgServerConfigUnmarshalValueV2(valb)
+		if err != nil {
+			return WireGuardServerConfig{}, err
+		}
+
+		aad := sealAAD(wgServerBucketName, wgServerConfigKey)
+		if version == 3 {
+			aad = queries.interfaceAAD(wgServerBucketName, wgServerConfigKey)
+		}
+
+		privateKey, err := queries.openPrivateKey(&val.PrivateKey, aad)
+		if err != nil {
+			return WireGuardServerConfig{}, err
+		}
+
+		return WireGuardServerConfig{
+			PrivateKey: privateKey,
+		}, nil
+	}
+
+	return WireGuardServerConfig{}, ErrUnsupportedVersion
 }
 
 func (queries *Queries) WireGuardServerConfigExists() (exists bool) {
-	b := queries.tx.Bucket(wgServerBucketName)
+	b, err := queries.bucket(wgServerBucketName)
+	if err != nil {
+		return false
+	}
 	return b.Get(wgServerConfigKey) != nil
 }
@@ -2,7 +2,11 @@ package dbrepo
 
 import (
 	"context"
+	"net"
 
+	"github.com/guregu/null/v5"
+
+	"github.com/infastin/wg-wish/pkg/wgtypes"
 	"github.com/infastin/wg-wish/server/entity"
 	"github.com/infastin/wg-wish/server/errors"
 	"github.com/infastin/wg-wish/server/repo/db/impl/queries"
@@ -12,20 +16,31 @@ func (db *DatabaseRepo) AddWireGuardClient(ctx context.Context, client *entity.W
 	if db.queries.WireGuardClientExists(client.Name) {
 		return errors.ErrWireGuardClientExists
 	}
+	return db.SetWireGuardClient(ctx, client)
+}
 
-	return db.queries.SetWireGuardClient(&queries.WireGuardClient{
-		Name:                client.Name,
-		Address:             client.Address,
-		PrivateKey:          client.PrivateKey,
-		PublicKey:           client.PublicKey,
-		DNS:                 client.DNS,
-		AllowedIPs:          client.AllowedIPs,
-		PersistentKeepalive: client.PersistentKeepalive,
-	})
+func (db *DatabaseRepo) SetWireGuardClient(ctx context.Context, client *entity.WireGuardClient) (err error) {
+	name, err := db.queries.GetWireGuardClientNameByPublicKey(client.PublicKey)
+	switch {
+	case err == nil && name != client.Name:
+		return errors.ErrWireGuardClientPublicKeyExists
+	case err != nil && err != queries.ErrKeyNotFound:
+		return mapInterfaceError(err)
+	}
+
+	name, err = db.queries.GetWireGuardClientNameByAddress(client.Address.IP)
+	switch {
+	case err == nil && name != client.Name:
+		return errors.ErrWireGuardClientAddressExists
+	case err != nil && err != queries.ErrKeyNotFound:
+		return mapInterfaceError(err)
+	}
+
+	return mapInterfaceError(db.queries.SetWireGuardClient(mapFromWireGuardClient(client)))
 }
 
 func (db *DatabaseRepo) RemoveWireGuardClient(ctx context.Context, name string) (err error) {
-	return db.queries.RemoveWireGuardClient(name)
+	return mapInterfaceError(db.queries.RemoveWireGuardClient(name))
 }
 
 func (db *DatabaseRepo) WireGuardClientExists(ctx context.Context, name string) (exists bool, err error) {
@@ -35,15 +50,41 @@ func (db *DatabaseRepo) WireGuardClientExists(ctx context.Context, name string)
 func (db *DatabaseRepo) GetWireGuardClient(ctx context.Context, name string) (client entity.WireGuardClient, err error) {
 	dbClient, err := db.queries.GetWireGuardClient(name)
 	if err != nil {
-		return entity.WireGuardClient{}, err
+		if err == queries.ErrKeyNotFound {
+			err = errors.ErrWireGuardClientNotFound
+		}
+		return entity.WireGuardClient{}, mapInterfaceError(err)
 	}
 	return mapToWireGuardClient(&dbClient), nil
 }
 
+func (db *DatabaseRepo) GetWireGuardClientByPublicKey(ctx context.Context, publicKey wgtypes.Key,
+) (client entity.WireGuardClient, err error) {
+	name, err := db.queries.GetWireGuardClientNameByPublicKey(publicKey)
+	if err != nil {
+		if err == queries.ErrKeyNotFound {
+			err = errors.ErrWireGuardClientNotFound
+		}
+		return entity.WireGuardClient{}, mapInterfaceError(err)
+	}
+	return db.GetWireGuardClient(ctx, name)
+}
+
+func (db *DatabaseRepo) GetWireGuardClientByAddress(ctx context.Context, ip net.IP) (client entity.WireGuardClient, err error) {
+	name, err := db.queries.GetWireGuardClientNameByAddress(ip)
+	if err != nil {
+		if err == queries.ErrKeyNotFound {
+			err = errors.ErrWireGuardClientNotFound
+		}
+		return entity.WireGuardClient{}, mapInterfaceError(err)
+	}
+	return db.GetWireGuardClient(ctx, name)
+}
+
 func (db *DatabaseRepo) GetWireGuardClients(ctx context.Context) (clients []entity.WireGuardClient, err error) {
 	dbClients, err := db.queries.GetWireGuardClients()
 	if err != nil {
-		return nil, err
+		return nil, mapInterfaceError(err)
 	}
 
 	clients = make([]entity.WireGuardClient, len(dbClients))
@@ -54,6 +95,57 @@ func (db *DatabaseRepo) GetWireGuardClients(ctx context.Context) (clients []enti
 	return clients, nil
 }
 
+func (db *DatabaseRepo) SetWireGuardClients(ctx context.Context, clients []entity.WireGuardClient) (err error) {
+	err = db.queries.ClearWireGuardClients()
+	if err != nil {
+		return mapInterfaceError(err)
+	}
+
+	for i := range clients {
+		if err := db.SetWireGuardClient(ctx, &clients[i]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func mapFromWireGuardClient(client *entity.WireGuardClient) *queries.WireGuardClient {
+	return &queries.WireGuardClient{
+		Name:                client.Name,
+		Address:             client.Address,
+		PrivateKey:          client.PrivateKey,
+		PublicKey:           client.PublicKey,
+		DNS:                 client.DNS,
+		AllowedIPs:          client.AllowedIPs,
+		PersistentKeepalive: client.PersistentKeepalive,
+		Disabled:            client.Disabled,
+		Quota:               mapFromWireGuardClientQuota(client.Quota),
+		RateLimit:           client.RateLimit,
+		Group:               client.Group,
+		Profile:             client.Profile,
+		ExpiresAt:           client.ExpiresAt,
+		Labels:              client.Labels,
+		Description:         client.Description,
+		Mesh:                client.Mesh,
+		Endpoint:            client.Endpoint,
+		LastEndpoint:        client.LastEndpoint,
+	}
+}
+
+func mapFromWireGuardClientQuota(quota null.Value[entity.WireGuardClientQuota]) null.Value[queries.WireGuardClientQuota] {
+	if !quota.Valid {
+		return null.Value[queries.WireGuardClientQuota]{}
+	}
+	return null.ValueFrom(queries.WireGuardClientQuota{
+		Limit:       quota.V.Limit,
+		Period:      string(quota.V.Period),
+		PeriodStart: quota.V.PeriodStart,
+		Used:        quota.V.Used,
+		LastCounter: quota.V.LastCounter,
+	})
+}
+
 func mapToWireGuardClient(client *queries.WireGuardClient) entity.WireGuardClient {
 	return entity.WireGuardClient{
 		Name:                client.Name,
@@ -63,5 +155,29 @@ func mapToWireGuardClient(client *queries.WireGuardClient) entity.WireGuardClien
 		DNS:                 client.DNS,
 		AllowedIPs:          client.AllowedIPs,
 		PersistentKeepalive: client.PersistentKeepalive,
+		Disabled:            client.Disabled,
+		Quota:               mapToWireGuardClientQuota(client.Quota),
+		RateLimit:           client.RateLimit,
+		Group:               client.Group,
+		Profile:             client.Profile,
+		ExpiresAt:           client.ExpiresAt,
+		Labels:              client.Labels,
+		Description:         client.Description,
+		Mesh:                client.Mesh,
+		Endpoint:            client.Endpoint,
+		LastEndpoint:        client.LastEndpoint,
 	}
 }
+
+func mapToWireGuardClientQuota(quota null.Value[queries.WireGuardClientQuota]) null.Value[entity.WireGuardClientQuota] {
+	if !quota.Valid {
+		return null.Value[entity.WireGuardClientQuota]{}
+	}
+	return null.ValueFrom(entity.WireGuardClientQuota{
+		Limit:       quota.V.Limit,
+		Period:      entity.QuotaPeriod(quota.V.Period),
+		PeriodStart: quota.V.PeriodStart,
+		Used:        quota.V.Used,
+		LastCounter: quota.V.LastCounter,
+	})
+}
@@ -7,9 +7,9 @@ import (
 )
 
 func (db *DatabaseRepo) SetWireGuardServerConfig(config *entity.WireGuardServerConfig) (err error) {
-	return db.queries.SetWireGuardServerConfig(&queries.WireGuardServerConfig{
+	return mapInterfaceError(db.queries.SetWireGuardServerConfig(&queries.WireGuardServerConfig{
 		PrivateKey: config.PrivateKey,
-	})
+	}))
 }
 
 func (db *DatabaseRepo) GetWireGuardServerConfig() (config entity.WireGuardServerConfig, err error) {
@@ -18,7 +18,7 @@ func (db *DatabaseRepo) GetWireGuardServerConfig() (config entity.WireGuardServe
 		if err == queries.ErrKeyNotFound {
 			err = errors.ErrWireGuardServerConfigNotFound
 		}
-		return entity.WireGuardServerConfig{}, err
+		return entity.WireGuardServerConfig{}, mapInterfaceError(err)
 	}
 
 	return entity.WireGuardServerConfig{
@@ -0,0 +1,17 @@
+package db
+
+import (
+	"context"
+
+	"github.com/infastin/wg-wish/server/entity"
+)
+
+type WireGuardProfileRepo interface {
+	AddWireGuardProfile(ctx context.Context, profile *entity.WireGuardProfile) (err error)
+	SetWireGuardProfile(ctx context.Context, profile *entity.WireGuardProfile) (err error)
+	RemoveWireGuardProfile(ctx context.Context, name string) (err error)
+	WireGuardProfileExists(ctx context.Context, name string) (exists bool, err error)
+	GetWireGuardProfile(ctx context.Context, name string) (profile entity.WireGuardProfile, err error)
+	GetWireGuardProfiles(ctx context.Context) (profiles []entity.WireGuardProfile, err error)
+	SetWireGuardProfiles(ctx context.Context, profiles []entity.WireGuardProfile) (err error)
+}
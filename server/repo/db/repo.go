@@ -10,7 +10,14 @@ type Repo interface {
 	Update(ctx context.Context, cb AtomicCallback) (err error)
 	View(ctx context.Context, cb AtomicCallback) (err error)
 	Batch(ctx context.Context, cb AtomicCallback) (err error)
+	// Interface returns the repo whose WireGuard client and server repos
+	// access the data of the interface. Other repos are shared by all interfaces.
+	Interface(name string) Repo
+	// GetInterfaces returns the names of the interfaces having any data.
+	GetInterfaces(ctx context.Context) (names []string, err error)
 	PublicKeyRepo() PublicKeyRepo
 	WireGuardClientRepo() WireGuardClientRepo
 	WireGuardServerRepo() WireGuardServerRepo
+	FirewallGroupRepo() FirewallGroupRepo
+	WireGuardProfileRepo() WireGuardProfileRepo
 }
@@ -0,0 +1,300 @@
+package sqlrepo
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/charmbracelet/ssh"
+	"github.com/infastin/gorack/errdefer"
+	"github.com/infastin/gorack/fastconv"
+	"github.com/infastin/wg-wish/pkg/envelope"
+	"github.com/infastin/wg-wish/server/entity"
+	"github.com/infastin/wg-wish/server/errors"
+	database "github.com/infastin/wg-wish/server/repo/db"
+	"github.com/rs/zerolog"
+
+	_ "github.com/jackc/pgx/v5/stdlib" // Registers pgx driver.
+	_ "modernc.org/sqlite"             // Registers sqlite driver.
+)
+
+var (
+	ErrTxNotStarted         = errors.New("transaction not started")
+	ErrInterfaceNotSelected = errors.New("interface not selected")
+)
+
+type Dialect string
+
+const (
+	DialectSQLite   Dialect = "sqlite"
+	DialectPostgres Dialect = "postgres"
+)
+
+type DatabaseRepoParams struct {
+	Logger zerolog.Logger
+
+	Dialect   Dialect
+	DSN       string
+	AdminKeys []string
+	KMS       envelope.KMS
+}
+
+type DatabaseRepo struct {
+	lg      zerolog.Logger
+	db      *sql.DB
+	dialect Dialect
+	kms     envelope.KMS
+	iface   string
+	tx      *sql.Tx
+}
+
+func New(params *DatabaseRepoParams) (dbrepo *DatabaseRepo, err error) {
+	var db *sql.DB
+
+	switch params.Dialect {
+	case DialectSQLite:
+		db, err = sql.Open("sqlite", sqliteDSN(params.DSN))
+		if err != nil {
+			return nil, err
+		}
+		// SQLite allows only one writer at a time,
+		// so serialize transactions instead of failing with SQLITE_BUSY.
+		db.SetMaxOpenConns(1)
+	case DialectPostgres:
+		db, err = sql.Open("pgx", params.DSN)
+		if err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unsupported sql dialect: %q", params.Dialect)
+	}
+	defer errdefer.Close(&err, db.Close)
+
+	ctx := context.Background()
+
+	if err := db.PingContext(ctx); err != nil {
+		return nil, err
+	}
+
+	repo := &DatabaseRepo{
+		lg:      params.Logger,
+		db:      db,
+		dialect: params.Dialect,
+		kms:     params.KMS,
+		iface:   "",
+		tx:      nil,
+	}
+
+	err = repo.migrate(ctx, func(version int, name string) {
+		params.Logger.Info().Int("version", version).Str("name", name).Msg("applying database migration")
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := repo.Update(ctx, func(repo database.Repo) error {
+		for _, adminKey := range params.AdminKeys {
+			pkey, comment, _, _, err := ssh.ParseAuthorizedKey(fastconv.Bytes(adminKey))
+			if err != nil {
+				return err
+			}
+
+			if err := repo.PublicKeyRepo().SetPublicKey(ctx, &entity.PublicKey{
+				Key:     pkey,
+				Comment: comment,
+			}); err != nil {
+				return err
+			}
+		}
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	return repo, nil
+}
+
+func sqliteDSN(path string) string {
+	sep := "?"
+	if strings.Contains(path, "?") {
+		sep = "&"
+	}
+	return "file:" + path + sep + "_pragma=foreign_keys(1)&_pragma=journal_mode(WAL)&_pragma=busy_timeout(5000)"
+}
+
+func (db *DatabaseRepo) Close() error {
+	return db.db.Close()
+}
+
+func (db *DatabaseRepo) atomic(ctx context.Context, callback database.AtomicCallback, opts *sql.TxOptions) (err error) {
+	tx, err := db.db.BeginTx(ctx, opts)
+	if err != nil {
+		return err
+	}
+	defer errdefer.Close(&err, tx.Rollback)
+	defer func() {
+		if p := recover(); p != nil {
+			_ = tx.Rollback()
+			panic(p)
+		}
+	}()
+
+	err = callback(&DatabaseRepo{
+		lg:      db.lg,
+		db:      db.db,
+		dialect: db.dialect,
+		kms:     db.kms,
+		iface:   db.iface,
+		tx:      tx,
+	})
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (db *DatabaseRepo) Update(ctx context.Context, callback database.AtomicCallback) (err error) {
+	return db.atomic(ctx, callback, nil)
+}
+
+func (db *DatabaseRepo) View(ctx context.Context, callback database.AtomicCallback) (err error) {
+	return db.atomic(ctx, callback, nil)
+}
+
+func (db *DatabaseRepo) Batch(ctx context.Context, callback database.AtomicCallback) (err error) {
+	return db.atomic(ctx, callback, nil)
+}
+
+func (db *DatabaseRepo) Interface(name string) database.Repo {
+	return &DatabaseRepo{
+		lg:      db.lg,
+		db:      db.db,
+		dialect: db.dialect,
+		kms:     db.kms,
+		iface:   name,
+		tx:      db.tx,
+	}
+}
+
+func (db *DatabaseRepo) GetInterfaces(ctx context.Context) (names []string, err error) {
+	if db.tx == nil {
+		panic(ErrTxNotStarted)
+	}
+
+	rows, err := db.query(ctx, `SELECT interface FROM wg_server UNION SELECT interface FROM wg_clients ORDER BY interface`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		names = append(names, name)
+	}
+
+	return names, rows.Err()
+}
+
+// SealPrivateKeys encrypts all private keys stored in the database
+// with the primary master key, returning the number of rewritten records.
+func (db *DatabaseRepo) SealPrivateKeys(ctx context.Context) (n int, err error) {
+	err = db.Update(ctx, func(repo database.Repo) error {
+		n, err = repo.(*DatabaseRepo).sealPrivateKeys(ctx)
+		return err
+	})
+	return n, err
+}
+
+func (db *DatabaseRepo) PublicKeyRepo() database.PublicKeyRepo {
+	if db.tx == nil {
+		panic(ErrTxNotStarted)
+	}
+	return db
+}
+
+func (db *DatabaseRepo) WireGuardClientRepo() database.WireGuardClientRepo {
+	if db.tx == nil {
+		panic(ErrTxNotStarted)
+	}
+	if db.iface == "" {
+		panic(ErrInterfaceNotSelected)
+	}
+	return db
+}
+
+func (db *DatabaseRepo) WireGuardServerRepo() database.WireGuardServerRepo {
+	if db.tx == nil {
+		panic(ErrTxNotStarted)
+	}
+	if db.iface == "" {
+		panic(ErrInterfaceNotSelected)
+	}
+	return db
+}
+
+func (db *DatabaseRepo) FirewallGroupRepo() database.FirewallGroupRepo {
+	if db.tx == nil {
+		panic(ErrTxNotStarted)
+	}
+	return db
+}
+
+func (db *DatabaseRepo) WireGuardProfileRepo() database.WireGuardProfileRepo {
+	if db.tx == nil {
+		panic(ErrTxNotStarted)
+	}
+	return db
+}
+
+// rebind replaces '?' placeholders with the ones supported by the dialect.
+func (db *DatabaseRepo) rebind(query string) string {
+	if db.dialect != DialectPostgres {
+		return query
+	}
+
+	var b strings.Builder
+	b.Grow(len(query) + 8)
+
+	n := 0
+	for i := 0; i < len(query); i++ {
+		if query[i] == '?' {
+			n++
+			b.WriteByte('$')
+			b.WriteString(strconv.Itoa(n))
+		} else {
+			b.WriteByte(query[i])
+		}
+	}
+
+	return b.String()
+}
+
+func (db *DatabaseRepo) exec(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	return db.tx.ExecContext(ctx, db.rebind(query), args...)
+}
+
+func (db *DatabaseRepo) query(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	return db.tx.QueryContext(ctx, db.rebind(query), args...)
+}
+
+func (db *DatabaseRepo) queryRow(ctx context.Context, query string, args ...any) *sql.Row {
+	return db.tx.QueryRowContext(ctx, db.rebind(query), args...)
+}
+
+func (db *DatabaseRepo) exists(ctx context.Context, query string, args ...any) (exists bool, err error) {
+	var one int
+	err = db.queryRow(ctx, query, args...).Scan(&one)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
@@ -0,0 +1,115 @@
+package sqlrepo
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+
+	"github.com/infastin/wg-wish/server/entity"
+	"github.com/infastin/wg-wish/server/errors"
+)
+
+func (db *DatabaseRepo) AddFirewallGroup(ctx context.Context, group *entity.FirewallGroup) (err error) {
+	exists, err := db.FirewallGroupExists(ctx, group.Name)
+	if err != nil {
+		return err
+	}
+
+	if exists {
+		return errors.ErrFirewallGroupExists
+	}
+
+	return db.SetFirewallGroup(ctx, group)
+}
+
+func (db *DatabaseRepo) SetFirewallGroup(ctx context.Context, group *entity.FirewallGroup) (err error) {
+	rules := make([]string, len(group.Rules))
+	for i := range group.Rules {
+		rules[i] = group.Rules[i].String()
+	}
+
+	_, err = db.exec(ctx, `INSERT INTO firewall_groups (name, allow_peers, rules)
+		VALUES (?, ?, ?)
+		ON CONFLICT (name) DO UPDATE SET
+			allow_peers = excluded.allow_peers,
+			rules = excluded.rules`,
+		group.Name,
+		group.AllowPeers,
+		strings.Join(rules, ","),
+	)
+
+	return err
+}
+
+func (db *DatabaseRepo) RemoveFirewallGroup(ctx context.Context, name string) (err error) {
+	_, err = db.exec(ctx, `DELETE FROM firewall_groups WHERE name = ?`, name)
+	return err
+}
+
+func (db *DatabaseRepo) FirewallGroupExists(ctx context.Context, name string) (exists bool, err error) {
+	return db.exists(ctx, `SELECT 1 FROM firewall_groups WHERE name = ?`, name)
+}
+
+func (db *DatabaseRepo) GetFirewallGroup(ctx context.Context, name string) (group entity.FirewallGroup, err error) {
+	group, err = scanFirewallGroup(db.queryRow(ctx,
+		`SELECT name, allow_peers, rules FROM firewall_groups WHERE name = ?`, name))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			err = errors.ErrFirewallGroupNotFound
+		}
+		return entity.FirewallGroup{}, err
+	}
+	return group, nil
+}
+
+func (db *DatabaseRepo) GetFirewallGroups(ctx context.Context) (groups []entity.FirewallGroup, err error) {
+	rows, err := db.query(ctx, `SELECT name, allow_peers, rules FROM firewall_groups ORDER BY name`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		group, err := scanFirewallGroup(rows)
+		if err != nil {
+			return nil, err
+		}
+		groups = append(groups, group)
+	}
+
+	return groups, rows.Err()
+}
+
+func (db *DatabaseRepo) SetFirewallGroups(ctx context.Context, groups []entity.FirewallGroup) (err error) {
+	_, err = db.exec(ctx, `DELETE FROM firewall_groups`)
+	if err != nil {
+		return err
+	}
+
+	for i := range groups {
+		if err := db.SetFirewallGroup(ctx, &groups[i]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func scanFirewallGroup(row scanner) (group entity.FirewallGroup, err error) {
+	var rules string
+
+	err = row.Scan(&group.Name, &group.AllowPeers, &rules)
+	if err != nil {
+		return entity.FirewallGroup{}, err
+	}
+
+	for _, s := range splitList(rules) {
+		rule, err := entity.ParseFirewallRule(s)
+		if err != nil {
+			return entity.FirewallGroup{}, err
+		}
+		group.Rules = append(group.Rules, rule)
+	}
+
+	return group, nil
+}
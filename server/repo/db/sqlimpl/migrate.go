@@ -0,0 +1,255 @@
+package sqlrepo
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// SchemaVersion is the version of the database schema this binary works with.
+// Databases with a greater schema version can not be opened.
+const SchemaVersion = 10
+
+type migration struct {
+	version int
+	name    string
+	up      []string
+}
+
+// Migrations must be sorted by version and must not have gaps.
+// {{blob}} is replaced with the dialect's binary type.
+var migrations = []migration{
+	{
+		version: 1,
+		name:    "create tables",
+		up: []string{
+			`CREATE TABLE public_keys (
+				key     {{blob}} PRIMARY KEY,
+				comment TEXT NOT NULL
+			)`,
+			`CREATE TABLE wg_clients (
+				name                 TEXT PRIMARY KEY,
+				address              TEXT NOT NULL,
+				address_ip           {{blob}} NOT NULL UNIQUE,
+				private_key          {{blob}} NOT NULL,
+				private_key_id       TEXT NOT NULL DEFAULT '',
+				private_key_wrapped  {{blob}},
+				public_key           {{blob}} NOT NULL UNIQUE,
+				dns                  TEXT NOT NULL,
+				allowed_ips          TEXT NOT NULL,
+				persistent_keepalive BIGINT
+			)`,
+			`CREATE TABLE wg_server (
+				id                  INTEGER PRIMARY KEY CHECK (id = 1),
+				private_key         {{blob}} NOT NULL,
+				private_key_id      TEXT NOT NULL DEFAULT '',
+				private_key_wrapped {{blob}}
+			)`,
+		},
+	},
+	{
+		version: 2,
+		name:    "add client quotas",
+		up: []string{
+			`ALTER TABLE wg_clients ADD COLUMN disabled BOOLEAN NOT NULL DEFAULT FALSE`,
+			`ALTER TABLE wg_clients ADD COLUMN quota_limit BIGINT`,
+			`ALTER TABLE wg_clients ADD COLUMN quota_period TEXT`,
+			`ALTER TABLE wg_clients ADD COLUMN quota_period_start BIGINT`,
+			`ALTER TABLE wg_clients ADD COLUMN quota_used BIGINT`,
+			`ALTER TABLE wg_clients ADD COLUMN quota_last_counter BIGINT`,
+		},
+	},
+	{
+		version: 3,
+		name:    "add client rate limits",
+		up: []string{
+			`ALTER TABLE wg_clients ADD COLUMN rate_limit BIGINT NOT NULL DEFAULT 0`,
+		},
+	},
+	{
+		version: 4,
+		name:    "add firewall groups",
+		up: []string{
+			`CREATE TABLE firewall_groups (
+				name        TEXT PRIMARY KEY,
+				allow_peers BOOLEAN NOT NULL,
+				rules       TEXT NOT NULL
+			)`,
+			`ALTER TABLE wg_clients ADD COLUMN firewall_group TEXT NOT NULL DEFAULT ''`,
+		},
+	},
+	{
+		version: 5,
+		name:    "add interfaces",
+		// Tables are rebuilt, since SQLite can't alter primary keys and constraints.
+		// Existing data belongs to wg0, the only interface there has been.
+		up: []string{
+			`CREATE TABLE wg_clients_v5 (
+				interface            TEXT NOT NULL,
+				name                 TEXT NOT NULL,
+				address              TEXT NOT NULL,
+				address_ip           {{blob}} NOT NULL,
+				private_key          {{blob}} NOT NULL,
+				private_key_id       TEXT NOT NULL DEFAULT '',
+				private_key_wrapped  {{blob}},
+				public_key           {{blob}} NOT NULL,
+				dns                  TEXT NOT NULL,
+				allowed_ips          TEXT NOT NULL,
+				persistent_keepalive BIGINT,
+				disabled             BOOLEAN NOT NULL DEFAULT FALSE,
+				quota_limit          BIGINT,
+				quota_period         TEXT,
+				quota_period_start   BIGINT,
+				quota_used           BIGINT,
+				quota_last_counter   BIGINT,
+				rate_limit           BIGINT NOT NULL DEFAULT 0,
+				firewall_group       TEXT NOT NULL DEFAULT '',
+				PRIMARY KEY (interface, name),
+				UNIQUE (interface, address_ip),
+				UNIQUE (interface, public_key)
+			)`,
+			`INSERT INTO wg_clients_v5 (interface, name, address, address_ip, private_key, private_key_id,
+				private_key_wrapped, public_key, dns, allowed_ips, persistent_keepalive, disabled,
+				quota_limit, quota_period, quota_period_start, quota_used, quota_last_counter, rate_limit,
+				firewall_group)
+			SELECT 'wg0', name, address, address_ip, private_key, private_key_id,
+				private_key_wrapped, public_key, dns, allowed_ips, persistent_keepalive, disabled,
+				quota_limit, quota_period, quota_period_start, quota_used, quota_last_counter, rate_limit,
+				firewall_group
+			FROM wg_clients`,
+			`DROP TABLE wg_clients`,
+			`ALTER TABLE wg_clients_v5 RENAME TO wg_clients`,
+			`CREATE TABLE wg_server_v5 (
+				interface           TEXT PRIMARY KEY,
+				private_key         {{blob}} NOT NULL,
+				private_key_id      TEXT NOT NULL DEFAULT '',
+				private_key_wrapped {{blob}}
+			)`,
+			`INSERT INTO wg_server_v5 (interface, private_key, private_key_id, private_key_wrapped)
+			SELECT 'wg0', private_key, private_key_id, private_key_wrapped FROM wg_server`,
+			`DROP TABLE wg_server`,
+			`ALTER TABLE wg_server_v5 RENAME TO wg_server`,
+		},
+	},
+	{
+		version: 6,
+		name:    "add profiles",
+		up: []string{
+			`CREATE TABLE profiles (
+				name                 TEXT PRIMARY KEY,
+				dns                  TEXT NOT NULL,
+				allowed_ips          TEXT NOT NULL,
+				persistent_keepalive BIGINT
+			)`,
+			`ALTER TABLE wg_clients ADD COLUMN profile TEXT NOT NULL DEFAULT ''`,
+		},
+	},
+	{
+		version: 7,
+		name:    "add client expiry and labels",
+		up: []string{
+			`ALTER TABLE wg_clients ADD COLUMN expires_at BIGINT`,
+			`ALTER TABLE wg_clients ADD COLUMN labels TEXT NOT NULL DEFAULT ''`,
+		},
+	},
+	{
+		version: 8,
+		name:    "add client description",
+		up: []string{
+			`ALTER TABLE wg_clients ADD COLUMN description TEXT NOT NULL DEFAULT ''`,
+		},
+	},
+	{
+		version: 9,
+		name:    "add client mesh settings",
+		up: []string{
+			`ALTER TABLE wg_clients ADD COLUMN mesh BOOLEAN NOT NULL DEFAULT FALSE`,
+			`ALTER TABLE wg_clients ADD COLUMN endpoint TEXT NOT NULL DEFAULT ''`,
+			`ALTER TABLE wg_clients ADD COLUMN last_endpoint TEXT NOT NULL DEFAULT ''`,
+		},
+	},
+	{
+		version: 10,
+		name:    "bind sealed private keys to interfaces",
+		// Existing keys keep the AAD they have been sealed with until they are rewritten.
+		up: []string{
+			`ALTER TABLE wg_clients ADD COLUMN private_key_aad INTEGER NOT NULL DEFAULT 1`,
+			`ALTER TABLE wg_server ADD COLUMN private_key_aad INTEGER NOT NULL DEFAULT 1`,
+		},
+	},
+}
+
+type MigrationCallback func(version int, name string)
+
+type SchemaVersionError struct {
+	Version  int
+	Expected int
+}
+
+func (e *SchemaVersionError) Error() string {
+	return fmt.Sprintf("database schema version %d is newer than supported version %d", e.Version, e.Expected)
+}
+
+func (db *DatabaseRepo) migrate(ctx context.Context, cb MigrationCallback) (err error) {
+	tx, err := db.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	if _, err := tx.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (
+		version    INTEGER PRIMARY KEY,
+		name       TEXT NOT NULL,
+		applied_at TEXT NOT NULL
+	)`); err != nil {
+		return err
+	}
+
+	var version sql.NullInt64
+	if err := tx.QueryRowContext(ctx, `SELECT MAX(version) FROM schema_migrations`).Scan(&version); err != nil {
+		return err
+	}
+
+	if int(version.Int64) > SchemaVersion {
+		return &SchemaVersionError{
+			Version:  int(version.Int64),
+			Expected: SchemaVersion,
+		}
+	}
+
+	blob := "BLOB"
+	if db.dialect == DialectPostgres {
+		blob = "BYTEA"
+	}
+
+	for i := range migrations {
+		if migrations[i].version <= int(version.Int64) {
+			continue
+		}
+
+		if cb != nil {
+			cb(migrations[i].version, migrations[i].name)
+		}
+
+		for _, stmt := range migrations[i].up {
+			stmt = strings.ReplaceAll(stmt, "{{blob}}", blob)
+			if _, err := tx.ExecContext(ctx, stmt); err != nil {
+				return fmt.Errorf("migration %d (%s) failed: %w", migrations[i].version, migrations[i].name, err)
+			}
+		}
+
+		if _, err := tx.ExecContext(ctx, db.rebind(`INSERT INTO schema_migrations (version, name, applied_at) VALUES (?, ?, ?)`),
+			migrations[i].version, migrations[i].name, time.Now().UTC().Format(time.RFC3339),
+		); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
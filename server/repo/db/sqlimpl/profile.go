@@ -0,0 +1,121 @@
+package sqlrepo
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/guregu/null/v5"
+	"github.com/infastin/wg-wish/pkg/netutils"
+	"github.com/infastin/wg-wish/server/entity"
+	"github.com/infastin/wg-wish/server/errors"
+)
+
+func (db *DatabaseRepo) AddWireGuardProfile(ctx context.Context, profile *entity.WireGuardProfile) (err error) {
+	exists, err := db.WireGuardProfileExists(ctx, profile.Name)
+	if err != nil {
+		return err
+	}
+
+	if exists {
+		return errors.ErrWireGuardProfileExists
+	}
+
+	return db.SetWireGuardProfile(ctx, profile)
+}
+
+func (db *DatabaseRepo) SetWireGuardProfile(ctx context.Context, profile *entity.WireGuardProfile) (err error) {
+	_, err = db.exec(ctx, `INSERT INTO profiles (name, dns, allowed_ips, persistent_keepalive)
+		VALUES (?, ?, ?, ?)
+		ON CONFLICT (name) DO UPDATE SET
+			dns = excluded.dns,
+			allowed_ips = excluded.allowed_ips,
+			persistent_keepalive = excluded.persistent_keepalive`,
+		profile.Name,
+		netutils.FormatIPs(profile.DNS, ","),
+		netutils.FormatAddresses(profile.AllowedIPs, ","),
+		profile.PersistentKeepalive.Ptr(),
+	)
+
+	return err
+}
+
+func (db *DatabaseRepo) RemoveWireGuardProfile(ctx context.Context, name string) (err error) {
+	_, err = db.exec(ctx, `DELETE FROM profiles WHERE name = ?`, name)
+	return err
+}
+
+func (db *DatabaseRepo) WireGuardProfileExists(ctx context.Context, name string) (exists bool, err error) {
+	return db.exists(ctx, `SELECT 1 FROM profiles WHERE name = ?`, name)
+}
+
+func (db *DatabaseRepo) GetWireGuardProfile(ctx context.Context, name string) (profile entity.WireGuardProfile, err error) {
+	profile, err = scanWireGuardProfile(db.queryRow(ctx,
+		`SELECT name, dns, allowed_ips, persistent_keepalive FROM profiles WHERE name = ?`, name))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			err = errors.ErrWireGuardProfileNotFound
+		}
+		return entity.WireGuardProfile{}, err
+	}
+	return profile, nil
+}
+
+func (db *DatabaseRepo) GetWireGuardProfiles(ctx context.Context) (profiles []entity.WireGuardProfile, err error) {
+	rows, err := db.query(ctx, `SELECT name, dns, allowed_ips, persistent_keepalive FROM profiles ORDER BY name`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		profile, err := scanWireGuardProfile(rows)
+		if err != nil {
+			return nil, err
+		}
+		profiles = append(profiles, profile)
+	}
+
+	return profiles, rows.Err()
+}
+
+func (db *DatabaseRepo) SetWireGuardProfiles(ctx context.Context, profiles []entity.WireGuardProfile) (err error) {
+	_, err = db.exec(ctx, `DELETE FROM profiles`)
+	if err != nil {
+		return err
+	}
+
+	for i := range profiles {
+		if err := db.SetWireGuardProfile(ctx, &profiles[i]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func scanWireGuardProfile(row scanner) (profile entity.WireGuardProfile, err error) {
+	var (
+		dns                 string
+		allowedIPs          string
+		persistentKeepalive sql.NullInt64
+	)
+
+	err = row.Scan(&profile.Name, &dns, &allowedIPs, &persistentKeepalive)
+	if err != nil {
+		return entity.WireGuardProfile{}, err
+	}
+
+	profile.DNS, err = netutils.ParseIPs(splitList(dns))
+	if err != nil {
+		return entity.WireGuardProfile{}, err
+	}
+
+	profile.AllowedIPs, err = netutils.ParseAddresses(splitList(allowedIPs))
+	if err != nil {
+		return entity.WireGuardProfile{}, err
+	}
+
+	profile.PersistentKeepalive = null.Int{NullInt64: persistentKeepalive}
+
+	return profile, nil
+}
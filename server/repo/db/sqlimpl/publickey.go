@@ -0,0 +1,82 @@
+package sqlrepo
+
+import (
+	"context"
+
+	"github.com/charmbracelet/ssh"
+	"github.com/infastin/wg-wish/server/entity"
+	"github.com/infastin/wg-wish/server/errors"
+)
+
+func (db *DatabaseRepo) AddPublicKey(ctx context.Context, pkey *entity.PublicKey) (err error) {
+	exists, err := db.PublicKeyExists(ctx, pkey.Key)
+	if err != nil {
+		return err
+	}
+
+	if exists {
+		return errors.ErrPublicKeyExists
+	}
+
+	return db.SetPublicKey(ctx, pkey)
+}
+
+func (db *DatabaseRepo) SetPublicKey(ctx context.Context, pkey *entity.PublicKey) (err error) {
+	_, err = db.exec(ctx, `INSERT INTO public_keys (key, comment) VALUES (?, ?)
+		ON CONFLICT (key) DO UPDATE SET comment = excluded.comment`,
+		pkey.Key.Marshal(), pkey.Comment)
+	return err
+}
+
+func (db *DatabaseRepo) PublicKeyExists(ctx context.Context, pkey ssh.PublicKey) (exists bool, err error) {
+	return db.exists(ctx, `SELECT 1 FROM public_keys WHERE key = ?`, pkey.Marshal())
+}
+
+func (db *DatabaseRepo) RemovePublicKey(ctx context.Context, pkey ssh.PublicKey) (err error) {
+	_, err = db.exec(ctx, `DELETE FROM public_keys WHERE key = ?`, pkey.Marshal())
+	return err
+}
+
+func (db *DatabaseRepo) GetPublicKeys(ctx context.Context) (pkeys []entity.PublicKey, err error) {
+	rows, err := db.query(ctx, `SELECT key, comment FROM public_keys ORDER BY key`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var keyb []byte
+		var comment string
+
+		if err := rows.Scan(&keyb, &comment); err != nil {
+			return nil, err
+		}
+
+		pkey, err := ssh.ParsePublicKey(keyb)
+		if err != nil {
+			return nil, err
+		}
+
+		pkeys = append(pkeys, entity.PublicKey{
+			Key:     pkey,
+			Comment: comment,
+		})
+	}
+
+	return pkeys, rows.Err()
+}
+
+func (db *DatabaseRepo) SetPublicKeys(ctx context.Context, pkeys []entity.PublicKey) (err error) {
+	_, err = db.exec(ctx, `DELETE FROM public_keys`)
+	if err != nil {
+		return err
+	}
+
+	for i := range pkeys {
+		if err := db.SetPublicKey(ctx, &pkeys[i]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,134 @@
+package sqlrepo
+
+import (
+	"context"
+
+	"github.com/infastin/wg-wish/pkg/envelope"
+	"github.com/infastin/wg-wish/pkg/wgtypes"
+	"github.com/infastin/wg-wish/server/errors"
+)
+
+var ErrEncryptionKeyRequired = errors.New("master key is required to access encrypted values")
+
+// Versions of the additional data private keys are sealed with.
+// Keys sealed with aadV1 aren't bound to their interface.
+const (
+	aadV1 = 1
+	aadV2 = 2
+)
+
+// sealedKey is how private key is stored in the database.
+// Empty KeyID means that Data holds the key itself,
+// otherwise Data holds the key encrypted with the data key from WrappedKey
+// and AAD is the version of the additional data it's been sealed with.
+type sealedKey struct {
+	KeyID      string
+	WrappedKey []byte
+	Data       []byte
+	AAD        int
+}
+
+// sealPrivateKey seals the key with the additional data returned by aad for aadV2.
+func (db *DatabaseRepo) sealPrivateKey(key wgtypes.Key, aad func(version int) string) (sealed sealedKey, err error) {
+	if db.kms == nil {
+		return sealedKey{
+			KeyID:      "",
+			WrappedKey: nil,
+			Data:       key[:],
+			AAD:        aadV2,
+		}, nil
+	}
+
+	env, err := envelope.Seal(db.kms, key[:], []byte(aad(aadV2)))
+	if err != nil {
+		return sealedKey{}, err
+	}
+
+	return sealedKey{
+		KeyID:      env.KeyID,
+		WrappedKey: env.WrappedKey,
+		Data:       env.Ciphertext,
+		AAD:        aadV2,
+	}, nil
+}
+
+// openPrivateKey opens the key with the additional data returned by aad
+// for the version the key has been sealed with.
+func (db *DatabaseRepo) openPrivateKey(sealed *sealedKey, aad func(version int) string) (key wgtypes.Key, err error) {
+	b := sealed.Data
+
+	if sealed.KeyID != "" {
+		if db.kms == nil {
+			return wgtypes.Key{}, ErrEncryptionKeyRequired
+		}
+
+		b, err = envelope.Open(db.kms, &envelope.Envelope{
+			KeyID:      sealed.KeyID,
+			WrappedKey: sealed.WrappedKey,
+			Ciphertext: sealed.Data,
+		}, []byte(aad(sealed.AAD)))
+		if err != nil {
+			return wgtypes.Key{}, err
+		}
+	}
+
+	if len(b) != wgtypes.KeyLen {
+		return wgtypes.Key{}, envelope.ErrDecryptFailed
+	}
+
+	return wgtypes.Key(b), nil
+}
+
+func (db *DatabaseRepo) sealPrivateKeys(ctx context.Context) (n int, err error) {
+	if db.kms == nil {
+		return 0, ErrEncryptionKeyRequired
+	}
+
+	names, err := db.GetInterfaces(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, name := range names {
+		m, err := db.Interface(name).(*DatabaseRepo).sealInterfacePrivateKeys(ctx)
+		if err != nil {
+			return 0, err
+		}
+		n += m
+	}
+
+	return n, nil
+}
+
+func (db *DatabaseRepo) sealInterfacePrivateKeys(ctx context.Context) (n int, err error) {
+	clients, err := db.GetWireGuardClients(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	for i := range clients {
+		if err := db.SetWireGuardClient(ctx, &clients[i]); err != nil {
+			return 0, err
+		}
+		n++
+	}
+
+	exists, err := db.WireGuardServerConfigExists()
+	if err != nil {
+		return 0, err
+	}
+
+	if exists {
+		config, err := db.GetWireGuardServerConfig()
+		if err != nil {
+			return 0, err
+		}
+
+		if err := db.SetWireGuardServerConfig(&config); err != nil {
+			return 0, err
+		}
+		n++
+	}
+
+	return n, nil
+}
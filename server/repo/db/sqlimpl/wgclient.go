@@ -0,0 +1,338 @@
+package sqlrepo
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/guregu/null/v5"
+	"github.com/infastin/wg-wish/pkg/netutils"
+	"github.com/infastin/wg-wish/pkg/wgtypes"
+	"github.com/infastin/wg-wish/server/entity"
+	"github.com/infastin/wg-wish/server/errors"
+)
+
+const wgClientColumns = `name, address, private_key, private_key_id, private_key_wrapped, private_key_aad,
+	public_key, dns, allowed_ips, persistent_keepalive, disabled,
+	quota_limit, quota_period, quota_period_start, quota_used, quota_last_counter, rate_limit,
+	firewall_group, profile, expires_at, labels, description, mesh, endpoint, last_endpoint`
+
+func (db *DatabaseRepo) wgClientAAD(name string) func(version int) string {
+	return func(version int) string {
+		if version == aadV1 {
+			return "wg_clients\x00" + name
+		}
+		return "wg_clients\x00" + db.iface + "\x00" + name
+	}
+}
+
+func (db *DatabaseRepo) AddWireGuardClient(ctx context.Context, client *entity.WireGuardClient) (err error) {
+	exists, err := db.WireGuardClientExists(ctx, client.Name)
+	if err != nil {
+		return err
+	}
+
+	if exists {
+		return errors.ErrWireGuardClientExists
+	}
+
+	return db.SetWireGuardClient(ctx, client)
+}
+
+func (db *DatabaseRepo) SetWireGuardClient(ctx context.Context, client *entity.WireGuardClient) (err error) {
+	exists, err := db.exists(ctx, `SELECT 1 FROM wg_clients WHERE interface = ? AND public_key = ? AND name <> ?`,
+		db.iface, client.PublicKey[:], client.Name)
+	if err != nil {
+		return err
+	}
+
+	if exists {
+		return errors.ErrWireGuardClientPublicKeyExists
+	}
+
+	exists, err = db.exists(ctx, `SELECT 1 FROM wg_clients WHERE interface = ? AND address_ip = ? AND name <> ?`,
+		db.iface, []byte(client.Address.IP.To16()), client.Name)
+	if err != nil {
+		return err
+	}
+
+	if exists {
+		return errors.ErrWireGuardClientAddressExists
+	}
+
+	sealed, err := db.sealPrivateKey(client.PrivateKey, db.wgClientAAD(client.Name))
+	if err != nil {
+		return err
+	}
+
+	var (
+		quotaLimit       *int64
+		quotaPeriod      *string
+		quotaPeriodStart *int64
+		quotaUsed        *int64
+		quotaLastCounter *int64
+	)
+
+	if client.Quota.Valid {
+		quota := &client.Quota.V
+		quotaLimit = ptr(int64(quota.Limit))
+		quotaPeriod = ptr(string(quota.Period))
+		quotaPeriodStart = ptr(quota.PeriodStart.Unix())
+		quotaUsed = ptr(int64(quota.Used))
+		if quota.LastCounter.Valid {
+			quotaLastCounter = ptr(int64(quota.LastCounter.V))
+		}
+	}
+
+	var expiresAt *int64
+	if client.ExpiresAt.Valid {
+		expiresAt = ptr(client.ExpiresAt.Time.Unix())
+	}
+
+	labels, err := formatLabels(client.Labels)
+	if err != nil {
+		return err
+	}
+
+	_, err = db.exec(ctx, `INSERT INTO wg_clients (`+wgClientColumns+`, address_ip, interface)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (interface, name) DO UPDATE SET
+			address = excluded.address,
+			address_ip = excluded.address_ip,
+			private_key = excluded.private_key,
+			private_key_id = excluded.private_key_id,
+			private_key_wrapped = excluded.private_key_wrapped,
+			private_key_aad = excluded.private_key_aad,
+			public_key = excluded.public_key,
+			dns = excluded.dns,
+			allowed_ips = excluded.allowed_ips,
+			persistent_keepalive = excluded.persistent_keepalive,
+			disabled = excluded.disabled,
+			quota_limit = excluded.quota_limit,
+			quota_period = excluded.quota_period,
+			quota_period_start = excluded.quota_period_start,
+			quota_used = excluded.quota_used,
+			quota_last_counter = excluded.quota_last_counter,
+			rate_limit = excluded.rate_limit,
+			firewall_group = excluded.firewall_group,
+			profile = excluded.profile,
+			expires_at = excluded.expires_at,
+			labels = excluded.labels,
+			description = excluded.description,
+			mesh = excluded.mesh,
+			endpoint = excluded.endpoint,
+			last_endpoint = excluded.last_endpoint`,
+		client.Name,
+		client.Address.String(),
+		sealed.Data,
+		sealed.KeyID,
+		sealed.WrappedKey,
+		sealed.AAD,
+		client.PublicKey[:],
+		netutils.FormatIPs(client.DNS, ","),
+		netutils.FormatAddresses(client.AllowedIPs, ","),
+		client.PersistentKeepalive.Ptr(),
+		client.Disabled,
+		quotaLimit,
+		quotaPeriod,
+		quotaPeriodStart,
+		quotaUsed,
+		quotaLastCounter,
+		int64(client.RateLimit),
+		client.Group,
+		client.Profile,
+		expiresAt,
+		labels,
+		client.Description,
+		client.Mesh,
+		client.Endpoint,
+		client.LastEndpoint,
+		[]byte(client.Address.IP.To16()),
+		db.iface,
+	)
+
+	return err
+}
+
+func (db *DatabaseRepo) RemoveWireGuardClient(ctx context.Context, name string) (err error) {
+	_, err = db.exec(ctx, `DELETE FROM wg_clients WHERE interface = ? AND name = ?`, db.iface, name)
+	return err
+}
+
+func (db *DatabaseRepo) WireGuardClientExists(ctx context.Context, name string) (exists bool, err error) {
+	return db.exists(ctx, `SELECT 1 FROM wg_clients WHERE interface = ? AND name = ?`, db.iface, name)
+}
+
+func (db *DatabaseRepo) GetWireGuardClient(ctx context.Context, name string) (client entity.WireGuardClient, err error) {
+	return db.getWireGuardClient(ctx, `name = ?`, name)
+}
+
+func (db *DatabaseRepo) GetWireGuardClientByPublicKey(ctx context.Context, publicKey wgtypes.Key,
+) (client entity.WireGuardClient, err error) {
+	return db.getWireGuardClient(ctx, `public_key = ?`, publicKey[:])
+}
+
+func (db *DatabaseRepo) GetWireGuardClientByAddress(ctx context.Context, ip net.IP) (client entity.WireGuardClient, err error) {
+	return db.getWireGuardClient(ctx, `address_ip = ?`, []byte(ip.To16()))
+}
+
+func (db *DatabaseRepo) getWireGuardClient(ctx context.Context, where string, args ...any,
+) (client entity.WireGuardClient, err error) {
+	args = append([]any{db.iface}, args...)
+	client, err = db.scanWireGuardClient(db.queryRow(ctx,
+		`SELECT `+wgClientColumns+` FROM wg_clients WHERE interface = ? AND `+where, args...))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			err = errors.ErrWireGuardClientNotFound
+		}
+		return entity.WireGuardClient{}, err
+	}
+	return client, nil
+}
+
+func (db *DatabaseRepo) GetWireGuardClients(ctx context.Context) (clients []entity.WireGuardClient, err error) {
+	rows, err := db.query(ctx, `SELECT `+wgClientColumns+` FROM wg_clients WHERE interface = ? ORDER BY name`, db.iface)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		client, err := db.scanWireGuardClient(rows)
+		if err != nil {
+			return nil, err
+		}
+		clients = append(clients, client)
+	}
+
+	return clients, rows.Err()
+}
+
+func (db *DatabaseRepo) SetWireGuardClients(ctx context.Context, clients []entity.WireGuardClient) (err error) {
+	_, err = db.exec(ctx, `DELETE FROM wg_clients WHERE interface = ?`, db.iface)
+	if err != nil {
+		return err
+	}
+
+	for i := range clients {
+		if err := db.SetWireGuardClient(ctx, &clients[i]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+func (db *DatabaseRepo) scanWireGuardClient(row scanner) (client entity.WireGuardClient, err error) {
+	var (
+		address             string
+		sealed              sealedKey
+		publicKey           []byte
+		dns                 string
+		allowedIPs          string
+		persistentKeepalive sql.NullInt64
+		quotaLimit          sql.NullInt64
+		quotaPeriod         sql.NullString
+		quotaPeriodStart    sql.NullInt64
+		quotaUsed           sql.NullInt64
+		quotaLastCounter    sql.NullInt64
+		rateLimit           int64
+		expiresAt           sql.NullInt64
+		labels              string
+	)
+
+	err = row.Scan(&client.Name, &address, &sealed.Data, &sealed.KeyID, &sealed.WrappedKey, &sealed.AAD,
+		&publicKey, &dns, &allowedIPs, &persistentKeepalive, &client.Disabled,
+		&quotaLimit, &quotaPeriod, &quotaPeriodStart, &quotaUsed, &quotaLastCounter, &rateLimit,
+		&client.Group, &client.Profile, &expiresAt, &labels, &client.Description,
+		&client.Mesh, &client.Endpoint, &client.LastEndpoint)
+	if err != nil {
+		return entity.WireGuardClient{}, err
+	}
+
+	client.Address, err = netutils.ParseAddress(address)
+	if err != nil {
+		return entity.WireGuardClient{}, err
+	}
+
+	client.PrivateKey, err = db.openPrivateKey(&sealed, db.wgClientAAD(client.Name))
+	if err != nil {
+		return entity.WireGuardClient{}, err
+	}
+
+	copy(client.PublicKey[:], publicKey)
+
+	client.DNS, err = netutils.ParseIPs(splitList(dns))
+	if err != nil {
+		return entity.WireGuardClient{}, err
+	}
+
+	client.AllowedIPs, err = netutils.ParseAddresses(splitList(allowedIPs))
+	if err != nil {
+		return entity.WireGuardClient{}, err
+	}
+
+	client.PersistentKeepalive = null.Int{NullInt64: persistentKeepalive}
+	client.RateLimit = uint64(rateLimit)
+
+	if expiresAt.Valid {
+		client.ExpiresAt = null.TimeFrom(time.Unix(expiresAt.Int64, 0).UTC())
+	}
+
+	client.Labels, err = parseLabels(labels)
+	if err != nil {
+		return entity.WireGuardClient{}, err
+	}
+
+	if quotaLimit.Valid {
+		client.Quota = null.ValueFrom(entity.WireGuardClientQuota{
+			Limit:       uint64(quotaLimit.Int64),
+			Period:      entity.QuotaPeriod(quotaPeriod.String),
+			PeriodStart: time.Unix(quotaPeriodStart.Int64, 0).UTC(),
+			Used:        uint64(quotaUsed.Int64),
+			LastCounter: null.Value[uint64]{},
+		})
+		if quotaLastCounter.Valid {
+			client.Quota.V.LastCounter = null.ValueFrom(uint64(quotaLastCounter.Int64))
+		}
+	}
+
+	return client, nil
+}
+
+func splitList(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(s, ",")
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
+
+// formatLabels encodes the labels as a JSON object, no labels are stored as an empty string.
+func formatLabels(labels map[string]string) (string, error) {
+	if len(labels) == 0 {
+		return "", nil
+	}
+	b, err := json.Marshal(labels)
+	return string(b), err
+}
+
+func parseLabels(s string) (labels map[string]string, err error) {
+	if s == "" {
+		return nil, nil
+	}
+	if err := json.Unmarshal([]byte(s), &labels); err != nil {
+		return nil, err
+	}
+	return labels, nil
+}
@@ -0,0 +1,58 @@
+package sqlrepo
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/infastin/wg-wish/server/entity"
+	"github.com/infastin/wg-wish/server/errors"
+)
+
+func (db *DatabaseRepo) wgServerAAD(version int) string {
+	if version == aadV1 {
+		return "wg_server"
+	}
+	return "wg_server\x00" + db.iface
+}
+
+func (db *DatabaseRepo) SetWireGuardServerConfig(config *entity.WireGuardServerConfig) (err error) {
+	sealed, err := db.sealPrivateKey(config.PrivateKey, db.wgServerAAD)
+	if err != nil {
+		return err
+	}
+
+	_, err = db.exec(context.Background(), `INSERT INTO wg_server (interface, private_key, private_key_id, private_key_wrapped, private_key_aad)
+		VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT (interface) DO UPDATE SET
+			private_key = excluded.private_key,
+			private_key_id = excluded.private_key_id,
+			private_key_wrapped = excluded.private_key_wrapped,
+			private_key_aad = excluded.private_key_aad`,
+		db.iface, sealed.Data, sealed.KeyID, sealed.WrappedKey, sealed.AAD)
+	return err
+}
+
+func (db *DatabaseRepo) GetWireGuardServerConfig() (config entity.WireGuardServerConfig, err error) {
+	var sealed sealedKey
+
+	err = db.queryRow(context.Background(), `SELECT private_key, private_key_id, private_key_wrapped, private_key_aad
+		FROM wg_server WHERE interface = ?`, db.iface).
+		Scan(&sealed.Data, &sealed.KeyID, &sealed.WrappedKey, &sealed.AAD)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			err = errors.ErrWireGuardServerConfigNotFound
+		}
+		return entity.WireGuardServerConfig{}, err
+	}
+
+	config.PrivateKey, err = db.openPrivateKey(&sealed, db.wgServerAAD)
+	if err != nil {
+		return entity.WireGuardServerConfig{}, err
+	}
+
+	return config, nil
+}
+
+func (db *DatabaseRepo) WireGuardServerConfigExists() (exists bool, err error) {
+	return db.exists(context.Background(), `SELECT 1 FROM wg_server WHERE interface = ?`, db.iface)
+}
@@ -2,14 +2,20 @@ package db
 
 import (
 	"context"
+	"net"
 
+	"github.com/infastin/wg-wish/pkg/wgtypes"
 	"github.com/infastin/wg-wish/server/entity"
 )
 
 type WireGuardClientRepo interface {
 	AddWireGuardClient(ctx context.Context, client *entity.WireGuardClient) (err error)
+	SetWireGuardClient(ctx context.Context, client *entity.WireGuardClient) (err error)
 	RemoveWireGuardClient(ctx context.Context, name string) (err error)
 	WireGuardClientExists(ctx context.Context, name string) (exists bool, err error)
 	GetWireGuardClient(ctx context.Context, name string) (client entity.WireGuardClient, err error)
+	GetWireGuardClientByPublicKey(ctx context.Context, publicKey wgtypes.Key) (client entity.WireGuardClient, err error)
+	GetWireGuardClientByAddress(ctx context.Context, ip net.IP) (client entity.WireGuardClient, err error)
 	GetWireGuardClients(ctx context.Context) (clients []entity.WireGuardClient, err error)
+	SetWireGuardClients(ctx context.Context, clients []entity.WireGuardClient) (err error)
 }
@@ -0,0 +1,22 @@
+package federation
+
+import (
+	"context"
+
+	"github.com/infastin/wg-wish/pkg/wgtypes"
+	"github.com/infastin/wg-wish/server/entity"
+)
+
+// Repo manages clients of a linked wg-wish server.
+// Empty iface selects the primary interface of the server.
+type Repo interface {
+	// GetServer returns the name the server reports and its interfaces.
+	GetServer(ctx context.Context) (server entity.FederationServer, err error)
+	GetClients(ctx context.Context, iface string) (clients []entity.FederatedClient, err error)
+	// SyncClient adds the client with the given key unless the server already has it.
+	SyncClient(ctx context.Context, iface, name string, privateKey wgtypes.Key,
+	) (client wgtypes.ClientConfig, created bool, err error)
+	GetClient(ctx context.Context, iface, name string) (client wgtypes.ClientConfig, err error)
+	RemoveClient(ctx context.Context, iface, name string) (err error)
+	ReloadServer(ctx context.Context, iface string) (err error)
+}
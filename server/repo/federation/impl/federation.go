@@ -0,0 +1,283 @@
+package fedrepo
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/guregu/null/v5"
+	"github.com/infastin/wg-wish/pkg/netutils"
+	"github.com/infastin/wg-wish/pkg/wgtypes"
+	"github.com/infastin/wg-wish/server/entity"
+	"github.com/infastin/wg-wish/server/errors"
+	"github.com/infastin/wg-wish/server/federation"
+	"github.com/rs/zerolog"
+)
+
+// knownErrors are turned back into the sentinel errors
+// when reported by the server, so that callers can check for them.
+var knownErrors = []error{
+	errors.ErrWireGuardClientExists,
+	errors.ErrWireGuardClientNotFound,
+	errors.ErrWireGuardClientAddressExists,
+	errors.ErrWireGuardClientPublicKeyExists,
+	errors.ErrWireGuardInterfaceNotFound,
+	errors.ErrFederationKeyMismatch,
+}
+
+type FederationRepoParams struct {
+	Logger zerolog.Logger
+	// Name is the name of the local server, under which the requests are signed.
+	Name string
+	// URL is the base URL of the remote server's federation endpoint.
+	// It must use https, unless the server is on loopback.
+	URL    string
+	Secret string
+	// CAFile is the certificate authority the remote server's certificate is verified with,
+	// the system pool is used if empty.
+	CAFile  string
+	Timeout time.Duration
+}
+
+// FederationRepo is a client of the peer-sync protocol of a linked server.
+type FederationRepo struct {
+	lg     zerolog.Logger
+	name   string
+	url    string
+	secret []byte
+	client *http.Client
+}
+
+func New(params *FederationRepoParams) (repo *FederationRepo, err error) {
+	u, err := url.Parse(params.URL)
+	if err != nil {
+		return nil, fmt.Errorf("federation: invalid url: %w", err)
+	}
+
+	// Configs sent by the protocol contain private keys.
+	if u.Scheme != "https" && (u.Scheme != "http" || !netutils.IsLoopbackHost(u.Hostname())) {
+		return nil, fmt.Errorf("federation: url %q must use https unless the server is on loopback", params.URL)
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+
+	if params.CAFile != "" {
+		b, err := os.ReadFile(params.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("federation: failed to read CA: %w", err)
+		}
+
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(b) {
+			return nil, fmt.Errorf("federation: no certificates found in %q", params.CAFile)
+		}
+
+		transport.TLSClientConfig = &tls.Config{ //nolint:exhaustruct
+			MinVersion: tls.VersionTLS12,
+			RootCAs:    pool,
+		}
+	}
+
+	return &FederationRepo{
+		lg:     params.Logger,
+		name:   params.Name,
+		url:    strings.TrimSuffix(params.URL, "/"),
+		secret: []byte(params.Secret),
+		client: &http.Client{ //nolint:exhaustruct
+			Transport: transport,
+			Timeout:   params.Timeout,
+		},
+	}, nil
+}
+
+func (repo *FederationRepo) GetServer(ctx context.Context) (server entity.FederationServer, err error) {
+	var info federation.Info
+	if err := repo.do(ctx, http.MethodGet, "/federation/v1/info", "", nil, &info); err != nil {
+		return entity.FederationServer{}, err
+	}
+
+	server = entity.FederationServer{
+		Name:       info.Name,
+		URL:        repo.url,
+		Local:      false,
+		Interfaces: make([]string, len(info.Interfaces)),
+		Clients:    0,
+		Error:      "",
+	}
+
+	for i := range info.Interfaces {
+		server.Interfaces[i] = info.Interfaces[i].Name
+	}
+
+	if len(info.Interfaces) != 0 {
+		server.Clients = info.Interfaces[0].Clients
+	}
+
+	return server, nil
+}
+
+func (repo *FederationRepo) GetClients(ctx context.Context, iface string) (clients []entity.FederatedClient, err error) {
+	var peers []federation.Peer
+	if err := repo.do(ctx, http.MethodGet, "/federation/v1/peers", iface, nil, &peers); err != nil {
+		return nil, err
+	}
+
+	clients = make([]entity.FederatedClient, len(peers))
+	for i := range peers {
+		clients[i], err = mapToFederatedClient(&peers[i])
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return clients, nil
+}
+
+func (repo *FederationRepo) SyncClient(ctx context.Context, iface, name string, privateKey wgtypes.Key,
+) (client wgtypes.ClientConfig, created bool, err error) {
+	req := federation.SyncPeerRequest{
+		PrivateKey: privateKey.String(),
+	}
+
+	var p federation.Peer
+	status, err := repo.doStatus(ctx, http.MethodPut, "/federation/v1/peers/"+url.PathEscape(name), iface, &req, &p)
+	if err != nil {
+		return wgtypes.ClientConfig{}, false, err
+	}
+
+	client, err = decodeClientConfig(&p)
+	if err != nil {
+		return wgtypes.ClientConfig{}, false, err
+	}
+
+	return client, status == http.StatusCreated, nil
+}
+
+func (repo *FederationRepo) GetClient(ctx context.Context, iface, name string) (client wgtypes.ClientConfig, err error) {
+	var p federation.Peer
+	if err := repo.do(ctx, http.MethodGet, "/federation/v1/peers/"+url.PathEscape(name), iface, nil, &p); err != nil {
+		return wgtypes.ClientConfig{}, err
+	}
+	return decodeClientConfig(&p)
+}
+
+func (repo *FederationRepo) RemoveClient(ctx context.Context, iface, name string) (err error) {
+	return repo.do(ctx, http.MethodDelete, "/federation/v1/peers/"+url.PathEscape(name), iface, nil, nil)
+}
+
+func (repo *FederationRepo) ReloadServer(ctx context.Context, iface string) (err error) {
+	return repo.do(ctx, http.MethodPost, "/federation/v1/reload", iface, nil, nil)
+}
+
+func (repo *FederationRepo) do(ctx context.Context, method, path, iface string, in, out any) (err error) {
+	_, err = repo.doStatus(ctx, method, path, iface, in, out)
+	return err
+}
+
+// doStatus sends the signed request and decodes the response into out,
+// returning the status code of the successful response.
+func (repo *FederationRepo) doStatus(ctx context.Context, method, path, iface string, in, out any,
+) (status int, err error) {
+	var body []byte
+	if in != nil {
+		body, err = json.Marshal(in)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	if iface != "" {
+		path += "?iface=" + url.QueryEscape(iface)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, repo.url+path, bytes.NewReader(body))
+	if err != nil {
+		return 0, errors.NewInternalError(err)
+	}
+
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	nonce := federation.NewNonce()
+	req.Header.Set(federation.HeaderServer, repo.name)
+	req.Header.Set(federation.HeaderTimestamp, timestamp)
+	req.Header.Set(federation.HeaderNonce, nonce)
+	req.Header.Set(federation.HeaderSignature,
+		federation.Sign(repo.secret, timestamp, nonce, method, req.URL.RequestURI(), body))
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := repo.client.Do(req)
+	if err != nil {
+		return 0, errors.NewDomainError("federation", fmt.Sprintf("server is unreachable: %v", err))
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return 0, decodeError(resp)
+	}
+
+	if out != nil {
+		if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+			return 0, errors.NewDomainError("federation", fmt.Sprintf("failed to decode server response: %v", err))
+		}
+	}
+
+	return resp.StatusCode, nil
+}
+
+func decodeError(resp *http.Response) error {
+	var e federation.Error
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<16)).Decode(&e); err != nil || e.Error == "" {
+		return errors.NewDomainError("federation", fmt.Sprintf("server responded with %s", resp.Status))
+	}
+
+	if e.Domain == "" {
+		return errors.NewDomainError("federation", fmt.Sprintf("server responded with %s: %s", resp.Status, e.Error))
+	}
+
+	for _, known := range knownErrors {
+		if de := known.(errors.DomainError); de.Domain() == e.Domain && de.Error() == e.Error {
+			return known
+		}
+	}
+
+	return errors.NewDomainError(e.Domain, e.Error)
+}
+
+func decodeClientConfig(p *federation.Peer) (client wgtypes.ClientConfig, err error) {
+	if err := client.Decode(strings.NewReader(p.Config)); err != nil {
+		return wgtypes.ClientConfig{}, errors.NewDomainError("federation", fmt.Sprintf("server sent invalid config: %v", err))
+	}
+	return client, nil
+}
+
+func mapToFederatedClient(p *federation.Peer) (client entity.FederatedClient, err error) {
+	addr, err := netutils.ParseAddress(p.Address)
+	if err != nil {
+		return entity.FederatedClient{}, errors.NewDomainError("federation", fmt.Sprintf("server sent invalid address: %v", err))
+	}
+
+	key, err := wgtypes.ParseKey(p.PublicKey)
+	if err != nil {
+		return entity.FederatedClient{}, errors.NewDomainError("federation", fmt.Sprintf("server sent invalid key: %v", err))
+	}
+
+	return entity.FederatedClient{
+		Server:          "",
+		Name:            p.Name,
+		Address:         addr,
+		PublicKey:       key,
+		Disabled:        p.Disabled,
+		LatestHandshake: null.TimeFromPtr(p.LatestHandshake),
+	}, nil
+}
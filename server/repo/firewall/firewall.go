@@ -0,0 +1,16 @@
+package firewall
+
+import (
+	"context"
+
+	"github.com/infastin/wg-wish/server/entity"
+)
+
+type Repo interface {
+	// Apply atomically replaces the rules with the given ruleset.
+	Apply(ctx context.Context, ruleset *entity.FirewallRuleset) (err error)
+	// Cleanup removes everything created by Apply.
+	Cleanup(ctx context.Context) (err error)
+	// Show lists the rules programmed by Apply.
+	Show(ctx context.Context) (state entity.FirewallState, err error)
+}
@@ -0,0 +1,272 @@
+package fwrepo
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+
+	"github.com/infastin/wg-wish/server/entity"
+	"github.com/infastin/wg-wish/server/errors"
+	"github.com/rs/zerolog"
+)
+
+// Names of the chains programmed by the iptables backends.
+const (
+	InputChain       = "WG_WISH_INPUT"
+	ForwardChain     = "WG_WISH_FORWARD"
+	ACLChain         = "WG_WISH_ACL"
+	PostroutingChain = "WG_WISH_POSTROUTING"
+)
+
+type IPTablesRepoParams struct {
+	Logger zerolog.Logger
+	// NFT selects iptables-nft binaries instead of the default ones.
+	NFT bool
+}
+
+type IPTablesRepo struct {
+	lg zerolog.Logger
+
+	backend  string
+	iptables string
+	restore  string
+	save     string
+
+	applied string
+}
+
+func NewIPTables(params *IPTablesRepoParams) *IPTablesRepo {
+	fw := &IPTablesRepo{
+		lg:       params.Logger,
+		backend:  "iptables",
+		iptables: "iptables",
+		restore:  "iptables-restore",
+		save:     "iptables-save",
+		applied:  "",
+	}
+
+	if params.NFT {
+		fw.backend = "iptables-nft"
+		fw.iptables = "iptables-nft"
+		fw.restore = "iptables-nft-restore"
+		fw.save = "iptables-nft-save"
+	}
+
+	return fw
+}
+
+// jumps are the rules hooking our chains into the built-in ones.
+var jumps = []struct {
+	table string
+	chain string
+	jump  string
+}{
+	{table: "filter", chain: "INPUT", jump: InputChain},
+	{table: "filter", chain: "FORWARD", jump: ForwardChain},
+	{table: "nat", chain: "POSTROUTING", jump: PostroutingChain},
+}
+
+func (fw *IPTablesRepo) Apply(ctx context.Context, ruleset *entity.FirewallRuleset) (err error) {
+	var stderr bytes.Buffer
+
+	// Declaring the chains creates them or flushes the existing ones,
+	// so the rules are replaced within a single iptables-restore transaction.
+	cmd := exec.CommandContext(ctx, fw.restore, "--noflush") //nolint:gosec
+	cmd.Stdin = renderIPTables(ruleset)
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		return errors.NewCommandError(cmd, err, stderr.String())
+	}
+
+	for _, j := range jumps {
+		if exec.CommandContext(ctx, fw.iptables, "-t", j.table, "-C", j.chain, "-j", j.jump).Run() == nil { //nolint:gosec
+			continue
+		}
+
+		stderr.Reset()
+
+		cmd = exec.CommandContext(ctx, fw.iptables, "-t", j.table, "-I", j.chain, "1", "-j", j.jump) //nolint:gosec
+		cmd.Stderr = &stderr
+
+		if err := cmd.Run(); err != nil {
+			return errors.NewCommandError(cmd, err, stderr.String())
+		}
+	}
+
+	fw.applied, err = fw.list(ctx)
+	if err != nil {
+		fw.lg.Warn().Err(err).Msg("failed to list applied rules")
+	}
+
+	return nil
+}
+
+func (fw *IPTablesRepo) Cleanup(ctx context.Context) (err error) {
+	// Rules and chains might not exist, so errors are ignored.
+	for _, j := range jumps {
+		_ = exec.CommandContext(ctx, fw.iptables, "-t", j.table, "-D", j.chain, "-j", j.jump).Run() //nolint:gosec
+	}
+
+	// Chains must be flushed before deleting, as they reference each other.
+	for _, op := range []string{"-F", "-X"} {
+		for _, chain := range []string{InputChain, ForwardChain, ACLChain} {
+			_ = exec.CommandContext(ctx, fw.iptables, "-t", "filter", op, chain).Run() //nolint:gosec
+		}
+		_ = exec.CommandContext(ctx, fw.iptables, "-t", "nat", op, PostroutingChain).Run() //nolint:gosec
+	}
+
+	fw.applied = ""
+
+	return nil
+}
+
+func (fw *IPTablesRepo) Show(ctx context.Context) (state entity.FirewallState, err error) {
+	current, err := fw.list(ctx)
+	if err != nil {
+		return entity.FirewallState{}, err
+	}
+
+	return entity.FirewallState{
+		Backend: fw.backend,
+		Applied: fw.applied,
+		Current: current,
+	}, nil
+}
+
+// list returns our chains and rules in iptables-save format.
+func (fw *IPTablesRepo) list(ctx context.Context) (rules string, err error) {
+	var stdout, stderr bytes.Buffer
+
+	cmd := exec.CommandContext(ctx, fw.save) //nolint:gosec
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		return "", errors.NewCommandError(cmd, err, stderr.String())
+	}
+
+	var (
+		b     strings.Builder
+		table string
+	)
+
+	scanner := bufio.NewScanner(&stdout)
+	for scanner.Scan() {
+		line := scanner.Text()
+
+		switch {
+		case strings.HasPrefix(line, "*"):
+			table = line
+		case strings.Contains(line, "WG_WISH_"):
+			if table != "" {
+				b.WriteString(table)
+				b.WriteByte('\n')
+				table = ""
+			}
+			b.WriteString(line)
+			b.WriteByte('\n')
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return "", err
+	}
+
+	return b.String(), nil
+}
+
+func renderIPTables(ruleset *entity.FirewallRuleset) *bytes.Buffer {
+	var b bytes.Buffer
+
+	fmt.Fprintln(&b, "*filter")
+	fmt.Fprintf(&b, ":%s - [0:0]\n", InputChain)
+	fmt.Fprintf(&b, ":%s - [0:0]\n", ForwardChain)
+	fmt.Fprintf(&b, ":%s - [0:0]\n", ACLChain)
+
+	for i := range ruleset.Interfaces {
+		fmt.Fprintf(&b, "-A %s -i %s -p udp -m udp --dport %d -j ACCEPT\n", InputChain, ruleset.Device, ruleset.Interfaces[i].Port)
+	}
+
+	for i := range ruleset.Interfaces {
+		for j := range ruleset.Interfaces {
+			if i != j {
+				fmt.Fprintf(&b, "-A %s -i %s -o %s -j DROP\n", ForwardChain, ruleset.Interfaces[i].Name, ruleset.Interfaces[j].Name)
+			}
+		}
+	}
+
+	hasPolicies := false
+
+	for i := range ruleset.Interfaces {
+		iface := &ruleset.Interfaces[i]
+		fmt.Fprintf(&b, "-A %s -i %s -j %s\n", ForwardChain, iface.Name, ACLChain)
+		fmt.Fprintf(&b, "-A %s -i %s -o %s -j ACCEPT\n", ForwardChain, iface.Name, ruleset.Device)
+		fmt.Fprintf(&b, "-A %s -i %s -o %s -j ACCEPT\n", ForwardChain, ruleset.Device, iface.Name)
+		hasPolicies = hasPolicies || len(iface.Policies) != 0
+	}
+
+	if hasPolicies {
+		fmt.Fprintf(&b, "-A %s -m conntrack --ctstate RELATED,ESTABLISHED -j ACCEPT\n", ACLChain)
+	}
+
+	for i := range ruleset.Interfaces {
+		renderIPTablesPolicies(&b, &ruleset.Interfaces[i])
+	}
+
+	fmt.Fprintln(&b, "COMMIT")
+
+	fmt.Fprintln(&b, "*nat")
+	fmt.Fprintf(&b, ":%s - [0:0]\n", PostroutingChain)
+	for i := range ruleset.Interfaces {
+		fmt.Fprintf(&b, "-A %s -s %s -o %s -j MASQUERADE\n", PostroutingChain, ruleset.Interfaces[i].Subnet.String(), ruleset.Device)
+	}
+	fmt.Fprintln(&b, "COMMIT")
+
+	return &b
+}
+
+func renderIPTablesPolicies(b *bytes.Buffer, iface *entity.FirewallInterface) {
+	subnet := iface.Subnet.String()
+
+	for i := range iface.Policies {
+		policy := &iface.Policies[i]
+
+		source := policy.Address.IP.String() + "/32"
+		comment := strconv.Quote(policy.Name)
+
+		peersTarget := "DROP"
+		if policy.AllowPeers {
+			peersTarget = "ACCEPT"
+		}
+
+		fmt.Fprintf(b, "-A %s -s %s -d %s -m comment --comment %s -j %s\n",
+			ACLChain, source, subnet, comment, peersTarget)
+
+		for j := range policy.Rules {
+			rule := &policy.Rules[j]
+
+			fmt.Fprintf(b, "-A %s -s %s -d %s", ACLChain, source, rule.Destination.String())
+
+			if rule.Protocol != entity.FirewallProtocolAny {
+				fmt.Fprintf(b, " -p %s", rule.Protocol)
+			}
+
+			if rule.PortFrom != 0 {
+				if rule.PortFrom == rule.PortTo {
+					fmt.Fprintf(b, " --dport %d", rule.PortFrom)
+				} else {
+					fmt.Fprintf(b, " --dport %d:%d", rule.PortFrom, rule.PortTo)
+				}
+			}
+
+			fmt.Fprintf(b, " -m comment --comment %s -j ACCEPT\n", comment)
+		}
+
+		fmt.Fprintf(b, "-A %s -s %s -m comment --comment %s -j DROP\n", ACLChain, source, comment)
+	}
+}
@@ -0,0 +1,193 @@
+package fwrepo
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+
+	"github.com/infastin/wg-wish/server/entity"
+	"github.com/infastin/wg-wish/server/errors"
+	"github.com/rs/zerolog"
+)
+
+// Table is the nftables table programmed by the nftables backend.
+const Table = "wg_wish"
+
+type NFTablesRepoParams struct {
+	Logger zerolog.Logger
+}
+
+type NFTablesRepo struct {
+	lg zerolog.Logger
+
+	applied string
+}
+
+func NewNFTables(params *NFTablesRepoParams) *NFTablesRepo {
+	return &NFTablesRepo{
+		lg:      params.Logger,
+		applied: "",
+	}
+}
+
+func (fw *NFTablesRepo) Apply(ctx context.Context, ruleset *entity.FirewallRuleset) (err error) {
+	var stderr bytes.Buffer
+
+	// The whole script is a single nft transaction, so the table is replaced atomically.
+	cmd := exec.CommandContext(ctx, "nft", "-f", "-")
+	cmd.Stdin = renderNFTables(ruleset)
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		return errors.NewCommandError(cmd, err, stderr.String())
+	}
+
+	fw.applied, err = fw.list(ctx)
+	if err != nil {
+		fw.lg.Warn().Err(err).Msg("failed to list applied rules")
+	}
+
+	return nil
+}
+
+func (fw *NFTablesRepo) Cleanup(ctx context.Context) (err error) {
+	// Table might not exist, so errors are ignored.
+	_ = exec.CommandContext(ctx, "nft", "delete", "table", "inet", Table).Run()
+	fw.applied = ""
+	return nil
+}
+
+func (fw *NFTablesRepo) Show(ctx context.Context) (state entity.FirewallState, err error) {
+	current, err := fw.list(ctx)
+	if err != nil {
+		return entity.FirewallState{}, err
+	}
+
+	return entity.FirewallState{
+		Backend: "nftables",
+		Applied: fw.applied,
+		Current: current,
+	}, nil
+}
+
+func (*NFTablesRepo) list(ctx context.Context) (rules string, err error) {
+	var stdout, stderr bytes.Buffer
+
+	cmd := exec.CommandContext(ctx, "nft", "list", "table", "inet", Table)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		return "", errors.NewCommandError(cmd, err, stderr.String())
+	}
+
+	return stdout.String(), nil
+}
+
+func renderNFTables(ruleset *entity.FirewallRuleset) *bytes.Buffer {
+	var b bytes.Buffer
+
+	// Declaring the table first makes the deletion succeed when it doesn't exist yet.
+	fmt.Fprintf(&b, "table inet %s\n", Table)
+	fmt.Fprintf(&b, "delete table inet %s\n", Table)
+	fmt.Fprintf(&b, "table inet %s {\n", Table)
+
+	fmt.Fprintf(&b, "\tchain input {\n")
+	fmt.Fprintf(&b, "\t\ttype filter hook input priority 0; policy accept;\n")
+	for i := range ruleset.Interfaces {
+		fmt.Fprintf(&b, "\t\tiifname %q udp dport %d accept\n", ruleset.Device, ruleset.Interfaces[i].Port)
+	}
+	fmt.Fprintf(&b, "\t}\n")
+
+	fmt.Fprintf(&b, "\tchain forward {\n")
+	fmt.Fprintf(&b, "\t\ttype filter hook forward priority 0; policy accept;\n")
+
+	for i := range ruleset.Interfaces {
+		for j := range ruleset.Interfaces {
+			if i != j {
+				fmt.Fprintf(&b, "\t\tiifname %q oifname %q drop\n", ruleset.Interfaces[i].Name, ruleset.Interfaces[j].Name)
+			}
+		}
+	}
+
+	hasPolicies := false
+
+	for i := range ruleset.Interfaces {
+		iface := &ruleset.Interfaces[i]
+		fmt.Fprintf(&b, "\t\tiifname %q jump acl\n", iface.Name)
+		fmt.Fprintf(&b, "\t\tiifname %q oifname %q accept\n", iface.Name, ruleset.Device)
+		fmt.Fprintf(&b, "\t\tiifname %q oifname %q accept\n", ruleset.Device, iface.Name)
+		hasPolicies = hasPolicies || len(iface.Policies) != 0
+	}
+
+	fmt.Fprintf(&b, "\t}\n")
+
+	fmt.Fprintf(&b, "\tchain acl {\n")
+
+	if hasPolicies {
+		fmt.Fprintf(&b, "\t\tct state established,related accept\n")
+	}
+
+	for i := range ruleset.Interfaces {
+		renderNFTablesPolicies(&b, &ruleset.Interfaces[i])
+	}
+
+	fmt.Fprintf(&b, "\t}\n")
+
+	fmt.Fprintf(&b, "\tchain postrouting {\n")
+	fmt.Fprintf(&b, "\t\ttype nat hook postrouting priority 100; policy accept;\n")
+	for i := range ruleset.Interfaces {
+		fmt.Fprintf(&b, "\t\tip saddr %s oifname %q masquerade\n", ruleset.Interfaces[i].Subnet.String(), ruleset.Device)
+	}
+	fmt.Fprintf(&b, "\t}\n")
+
+	fmt.Fprintf(&b, "}\n")
+
+	return &b
+}
+
+func renderNFTablesPolicies(b *bytes.Buffer, iface *entity.FirewallInterface) {
+	subnet := iface.Subnet.String()
+
+	for i := range iface.Policies {
+		policy := &iface.Policies[i]
+
+		source := policy.Address.IP.String()
+		comment := nftComment(policy.Name)
+
+		peersVerdict := "drop"
+		if policy.AllowPeers {
+			peersVerdict = "accept"
+		}
+
+		fmt.Fprintf(b, "\t\tip saddr %s ip daddr %s %s comment %s\n", source, subnet, peersVerdict, comment)
+
+		for j := range policy.Rules {
+			rule := &policy.Rules[j]
+
+			fmt.Fprintf(b, "\t\tip saddr %s ip daddr %s", source, rule.Destination.String())
+
+			switch {
+			case rule.PortFrom == 0:
+				if rule.Protocol != entity.FirewallProtocolAny {
+					fmt.Fprintf(b, " ip protocol %s", rule.Protocol)
+				}
+			case rule.PortFrom == rule.PortTo:
+				fmt.Fprintf(b, " %s dport %d", rule.Protocol, rule.PortFrom)
+			case rule.PortFrom != 0:
+				fmt.Fprintf(b, " %s dport %d-%d", rule.Protocol, rule.PortFrom, rule.PortTo)
+			}
+
+			fmt.Fprintf(b, " accept comment %s\n", comment)
+		}
+
+		fmt.Fprintf(b, "\t\tip saddr %s drop comment %s\n", source, comment)
+	}
+}
+
+// nftComment quotes the comment, nft doesn't support escaping quotes inside strings.
+func nftComment(s string) string {
+	return `"` + strings.ReplaceAll(s, `"`, `'`) + `"`
+}
@@ -0,0 +1,30 @@
+package fwrepo
+
+import (
+	"context"
+
+	"github.com/infastin/wg-wish/server/entity"
+)
+
+// NoneRepo doesn't program any rules, leaving the firewall to the administrator.
+type NoneRepo struct{}
+
+func NewNone() *NoneRepo {
+	return &NoneRepo{}
+}
+
+func (*NoneRepo) Apply(context.Context, *entity.FirewallRuleset) (err error) {
+	return nil
+}
+
+func (*NoneRepo) Cleanup(context.Context) (err error) {
+	return nil
+}
+
+func (*NoneRepo) Show(context.Context) (state entity.FirewallState, err error) {
+	return entity.FirewallState{
+		Backend: "none",
+		Applied: "",
+		Current: "",
+	}, nil
+}
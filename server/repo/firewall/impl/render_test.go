@@ -0,0 +1,152 @@
+package fwrepo
+
+import (
+	"bytes"
+	"flag"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/infastin/wg-wish/server/entity"
+)
+
+var update = flag.Bool("update", false, "update golden files")
+
+func mustParseCIDR(t *testing.T, s string) net.IPNet {
+	t.Helper()
+
+	ip, ipNet, err := net.ParseCIDR(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ipNet.IP = ip.To4()
+
+	return *ipNet
+}
+
+func mustParseRule(t *testing.T, s string) entity.FirewallRule {
+	t.Helper()
+
+	rule, err := entity.ParseFirewallRule(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return rule
+}
+
+func testRuleset(t *testing.T) *entity.FirewallRuleset {
+	t.Helper()
+
+	return &entity.FirewallRuleset{
+		Device: "eth0",
+		Interfaces: []entity.FirewallInterface{
+			{
+				Name:   "wg0",
+				Port:   51820,
+				Subnet: mustParseCIDR(t, "10.0.0.0/24"),
+				Policies: []entity.FirewallPolicy{
+					{
+						Name:       "alice",
+						Address:    mustParseCIDR(t, "10.0.0.2/32"),
+						AllowPeers: true,
+						Rules: []entity.FirewallRule{
+							mustParseRule(t, "10.0.5.0/24:8000-8080"),
+							mustParseRule(t, "udp:10.0.0.53:53"),
+							mustParseRule(t, "icmp:10.0.0.0/8"),
+						},
+					},
+					{
+						Name:       "bob",
+						Address:    mustParseCIDR(t, "10.0.0.3/32"),
+						AllowPeers: false,
+						Rules:      nil,
+					},
+				},
+			},
+			{
+				Name:     "wg1",
+				Port:     51821,
+				Subnet:   mustParseCIDR(t, "10.1.0.0/24"),
+				Policies: nil,
+			},
+		},
+	}
+}
+
+func checkGolden(t *testing.T, name string, output []byte) {
+	t.Helper()
+
+	goldenPath := filepath.Join("testdata", name+".golden")
+	if *update {
+		if err := os.WriteFile(goldenPath, output, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	golden, err := os.ReadFile(goldenPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(output, golden) {
+		t.Fatalf("rendered rules differ from %s:\n%s", goldenPath, output)
+	}
+}
+
+func expectLines(t *testing.T, output []byte, lines ...string) {
+	t.Helper()
+
+	rendered := strings.Split(string(output), "\n")
+
+	for _, line := range lines {
+		found := false
+		for i := range rendered {
+			if strings.TrimSpace(rendered[i]) == line {
+				found = true
+				break
+			}
+		}
+		if !found {
+			t.Errorf("expected line %q in:\n%s", line, output)
+		}
+	}
+}
+
+func TestRenderIPTables(t *testing.T) {
+	output := renderIPTables(testRuleset(t)).Bytes()
+
+	expectLines(t, output,
+		// Peers are allowed or denied as a whole.
+		`-A WG_WISH_ACL -s 10.0.0.2/32 -d 10.0.0.0/24 -m comment --comment "alice" -j ACCEPT`,
+		`-A WG_WISH_ACL -s 10.0.0.3/32 -d 10.0.0.0/24 -m comment --comment "bob" -j DROP`,
+		// Port ranges and single ports.
+		`-A WG_WISH_ACL -s 10.0.0.2/32 -d 10.0.5.0/24 -p tcp --dport 8000:8080 -m comment --comment "alice" -j ACCEPT`,
+		`-A WG_WISH_ACL -s 10.0.0.2/32 -d 10.0.0.53/32 -p udp --dport 53 -m comment --comment "alice" -j ACCEPT`,
+		// Everything else is dropped.
+		`-A WG_WISH_ACL -s 10.0.0.2/32 -m comment --comment "alice" -j DROP`,
+		`-A WG_WISH_ACL -s 10.0.0.3/32 -m comment --comment "bob" -j DROP`,
+	)
+
+	checkGolden(t, "iptables", output)
+}
+
+func TestRenderNFTables(t *testing.T) {
+	output := renderNFTables(testRuleset(t)).Bytes()
+
+	expectLines(t, output,
+		// Peers are allowed or denied as a whole.
+		`ip saddr 10.0.0.2 ip daddr 10.0.0.0/24 accept comment "alice"`,
+		`ip saddr 10.0.0.3 ip daddr 10.0.0.0/24 drop comment "bob"`,
+		// Port ranges and single ports.
+		`ip saddr 10.0.0.2 ip daddr 10.0.5.0/24 tcp dport 8000-8080 accept comment "alice"`,
+		`ip saddr 10.0.0.2 ip daddr 10.0.0.53/32 udp dport 53 accept comment "alice"`,
+		// Everything else is dropped.
+		`ip saddr 10.0.0.2 drop comment "alice"`,
+		`ip saddr 10.0.0.3 drop comment "bob"`,
+	)
+
+	checkGolden(t, "nftables", output)
+}
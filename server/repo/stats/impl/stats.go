@@ -0,0 +1,261 @@
+package statsrepo
+
+import (
+	"context"
+	"encoding/binary"
+	"time"
+
+	"github.com/guregu/null/v5"
+	"github.com/infastin/gorack/errdefer"
+	"github.com/infastin/wg-wish/pkg/wgtypes"
+	"github.com/infastin/wg-wish/server/entity"
+	"github.com/rs/zerolog"
+	"go.etcd.io/bbolt"
+)
+
+//go:generate msgp -tests=false -unexported
+
+var (
+	countersBucketName = []byte("counters")
+	trafficBucketName  = []byte("traffic")
+)
+
+// tier is a series of traffic buckets of the same size.
+// Every recorded delta is added to the current bucket of each tier,
+// so that coarser tiers can be kept for longer.
+type tier struct {
+	name      []byte
+	step      time.Duration
+	retention time.Duration
+}
+
+var tiers = []tier{
+	{name: []byte("5m"), step: 5 * time.Minute, retention: 48 * time.Hour},
+	{name: []byte("1h"), step: time.Hour, retention: 90 * 24 * time.Hour},
+	{name: []byte("1d"), step: 24 * time.Hour, retention: 5 * 365 * 24 * time.Hour},
+}
+
+//msgp:ignore tier StatsRepo StatsRepoParams
+
+//msgp:tuple countersValue
+
+// countersValue holds the last seen values of the kernel counters.
+type countersValue struct {
+	Received uint64
+	Sent     uint64
+}
+
+//msgp:tuple trafficValue
+
+type trafficValue struct {
+	Received        uint64
+	Sent            uint64
+	LatestHandshake int64
+}
+
+type StatsRepoParams struct {
+	Logger zerolog.Logger
+
+	Path string
+}
+
+type StatsRepo struct {
+	lg zerolog.Logger
+	db *bbolt.DB
+}
+
+func New(params *StatsRepoParams) (repo *StatsRepo, err error) {
+	db, err := bbolt.Open(params.Path, 0600, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer errdefer.Close(&err, db.Close)
+
+	if err := db.Update(func(tx *bbolt.Tx) error {
+		if _, err := tx.CreateBucketIfNotExists(countersBucketName); err != nil {
+			return err
+		}
+		_, err := tx.CreateBucketIfNotExists(trafficBucketName)
+		return err
+	}); err != nil {
+		return nil, err
+	}
+
+	return &StatsRepo{
+		lg: params.Logger,
+		db: db,
+	}, nil
+}
+
+func (repo *StatsRepo) Close() error {
+	return repo.db.Close()
+}
+
+func (repo *StatsRepo) RecordPeerStats(ctx context.Context, stats map[wgtypes.Key]entity.WireGuardPeerStats, now time.Time,
+) (err error) {
+	return repo.db.Update(func(tx *bbolt.Tx) error {
+		counters := tx.Bucket(countersBucketName)
+		traffic := tx.Bucket(trafficBucketName)
+
+		for key, peerStats := range stats {
+			value := countersValue{
+				Received: peerStats.Received,
+				Sent:     peerStats.Sent,
+			}
+
+			var delta trafficValue
+			if peerStats.LatestHandshake.Valid {
+				delta.LatestHandshake = peerStats.LatestHandshake.Time.Unix()
+			}
+
+			if valb := counters.Get(key[:]); valb != nil {
+				var prev countersValue
+				if _, err := prev.UnmarshalMsg(valb); err != nil {
+					return err
+				}
+				delta.Received = entity.CounterDelta(prev.Received, value.Received)
+				delta.Sent = entity.CounterDelta(prev.Sent, value.Sent)
+			} else {
+				// Traffic before the first sample is unknown, since counters
+				// might have been accumulated before the peer was (re)added.
+				delta.Received, delta.Sent = 0, 0
+			}
+
+			valb, err := value.MarshalMsg(nil)
+			if err != nil {
+				return err
+			}
+
+			if err := counters.Put(key[:], valb); err != nil {
+				return err
+			}
+
+			peer, err := traffic.CreateBucketIfNotExists(key[:])
+			if err != nil {
+				return err
+			}
+
+			if err := recordTraffic(peer, &delta, now); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+func recordTraffic(peer *bbolt.Bucket, delta *trafficValue, now time.Time) (err error) {
+	for i := range tiers {
+		t := &tiers[i]
+
+		b, err := peer.CreateBucketIfNotExists(t.name)
+		if err != nil {
+			return err
+		}
+
+		key := bucketKey(now.Truncate(t.step))
+
+		var value trafficValue
+		if valb := b.Get(key); valb != nil {
+			if _, err := value.UnmarshalMsg(valb); err != nil {
+				return err
+			}
+		}
+
+		value.Received += delta.Received
+		value.Sent += delta.Sent
+		value.LatestHandshake = max(value.LatestHandshake, delta.LatestHandshake)
+
+		valb, err := value.MarshalMsg(nil)
+		if err != nil {
+			return err
+		}
+
+		if err := b.Put(key, valb); err != nil {
+			return err
+		}
+
+		if err := pruneTraffic(b, bucketKey(now.Add(-t.retention))); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func pruneTraffic(b *bbolt.Bucket, cutoff []byte) (err error) {
+	c := b.Cursor()
+	// NOTE: Cursor.Next skips the element after the deleted one,
+	// so the cursor is rewound to the first element instead.
+	for key, _ := c.First(); key != nil && string(key) < string(cutoff); key, _ = c.First() {
+		if err := c.Delete(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (repo *StatsRepo) GetTraffic(ctx context.Context, publicKey wgtypes.Key, since time.Time, resolution time.Duration,
+) (samples []entity.TrafficSample, err error) {
+	t := selectTier(time.Since(since), resolution)
+
+	err = repo.db.View(func(tx *bbolt.Tx) error {
+		peer := tx.Bucket(trafficBucketName).Bucket(publicKey[:])
+		if peer == nil {
+			return nil
+		}
+
+		b := peer.Bucket(t.name)
+		if b == nil {
+			return nil
+		}
+
+		c := b.Cursor()
+		for key, valb := c.Seek(bucketKey(since.Truncate(t.step))); key != nil; key, valb = c.Next() {
+			var value trafficValue
+			if _, err := value.UnmarshalMsg(valb); err != nil {
+				return err
+			}
+
+			sample := entity.TrafficSample{
+				Start:           time.Unix(int64(binary.BigEndian.Uint64(key)), 0),
+				Received:        value.Received,
+				Sent:            value.Sent,
+				LatestHandshake: null.Time{},
+			}
+			if value.LatestHandshake != 0 {
+				sample.LatestHandshake = null.TimeFrom(time.Unix(value.LatestHandshake, 0))
+			}
+
+			samples = append(samples, sample)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return samples, nil
+}
+
+// selectTier returns the coarsest tier, which covers the given period
+// and whose step is not greater than the resolution.
+// If no such tier exists, the tier with the longest retention covering the period is returned.
+func selectTier(period, resolution time.Duration) *tier {
+	var selected *tier
+	for i := range tiers {
+		t := &tiers[i]
+		if t.retention < period && i != len(tiers)-1 {
+			continue
+		}
+		if selected == nil || t.step <= resolution {
+			selected = t
+		}
+	}
+	return selected
+}
+
+func bucketKey(t time.Time) []byte {
+	return binary.BigEndian.AppendUint64(nil, uint64(max(t.Unix(), 0)))
+}
@@ -0,0 +1,197 @@
+package statsrepo
+
+// Code generated by github.com/tinylib/msgp DO NOT EDIT.
+
+import (
+	"github.com/tinylib/msgp/msgp"
+)
+
+// DecodeMsg implements msgp.Decodable
+func (z *countersValue) DecodeMsg(dc *msgp.Reader) (err error) {
+	var zb0001 uint32
+	zb0001, err = dc.ReadArrayHeader()
+	if err != nil {
+		err = msgp.WrapError(err)
+		return
+	}
+	if zb0001 != 2 {
+		err = msgp.ArrayError{Wanted: 2, Got: zb0001}
+		return
+	}
+	z.Received, err = dc.ReadUint64()
+	if err != nil {
+		err = msgp.WrapError(err, "Received")
+		return
+	}
+	z.Sent, err = dc.ReadUint64()
+	if err != nil {
+		err = msgp.WrapError(err, "Sent")
+		return
+	}
+	return
+}
+
+// EncodeMsg implements msgp.Encodable
+func (z countersValue) EncodeMsg(en *msgp.Writer) (err error) {
+	// array header, size 2
+	err = en.Append(0x92)
+	if err != nil {
+		return
+	}
+	err = en.WriteUint64(z.Received)
+	if err != nil {
+		err = msgp.WrapError(err, "Received")
+		return
+	}
+	err = en.WriteUint64(z.Sent)
+	if err != nil {
+		err = msgp.WrapError(err, "Sent")
+		return
+	}
+	return
+}
+
+// MarshalMsg implements msgp.Marshaler
+func (z countersValue) MarshalMsg(b []byte) (o []byte, err error) {
+	o = msgp.Require(b, z.Msgsize())
+	// array header, size 2
+	o = append(o, 0x92)
+	o = msgp.AppendUint64(o, z.Received)
+	o = msgp.AppendUint64(o, z.Sent)
+	return
+}
+
+// UnmarshalMsg implements msgp.Unmarshaler
+func (z *countersValue) UnmarshalMsg(bts []byte) (o []byte, err error) {
+	var zb0001 uint32
+	zb0001, bts, err = msgp.ReadArrayHeaderBytes(bts)
+	if err != nil {
+		err = msgp.WrapError(err)
+		return
+	}
+	if zb0001 != 2 {
+		err = msgp.ArrayError{Wanted: 2, Got: zb0001}
+		return
+	}
+	z.Received, bts, err = msgp.ReadUint64Bytes(bts)
+	if err != nil {
+		err = msgp.WrapError(err, "Received")
+		return
+	}
+	z.Sent, bts, err = msgp.ReadUint64Bytes(bts)
+	if err != nil {
+		err = msgp.WrapError(err, "Sent")
+		return
+	}
+	o = bts
+	return
+}
+
+// Msgsize returns an upper bound estimate of the number of bytes occupied by the serialized message
+func (z countersValue) Msgsize() (s int) {
+	s = 1 + msgp.Uint64Size + msgp.Uint64Size
+	return
+}
+
+// DecodeMsg implements msgp.Decodable
+func (z *trafficValue) DecodeMsg(dc *msgp.Reader) (err error) {
+	var zb0001 uint32
+	zb0001, err = dc.ReadArrayHeader()
+	if err != nil {
+		err = msgp.WrapError(err)
+		return
+	}
+	if zb0001 != 3 {
+		err = msgp.ArrayError{Wanted: 3, Got: zb0001}
+		return
+	}
+	z.Received, err = dc.ReadUint64()
+	if err != nil {
+		err = msgp.WrapError(err, "Received")
+		return
+	}
+	z.Sent, err = dc.ReadUint64()
+	if err != nil {
+		err = msgp.WrapError(err, "Sent")
+		return
+	}
+	z.LatestHandshake, err = dc.ReadInt64()
+	if err != nil {
+		err = msgp.WrapError(err, "LatestHandshake")
+		return
+	}
+	return
+}
+
+// EncodeMsg implements msgp.Encodable
+func (z trafficValue) EncodeMsg(en *msgp.Writer) (err error) {
+	// array header, size 3
+	err = en.Append(0x93)
+	if err != nil {
+		return
+	}
+	err = en.WriteUint64(z.Received)
+	if err != nil {
+		err = msgp.WrapError(err, "Received")
+		return
+	}
+	err = en.WriteUint64(z.Sent)
+	if err != nil {
+		err = msgp.WrapError(err, "Sent")
+		return
+	}
+	err = en.WriteInt64(z.LatestHandshake)
+	if err != nil {
+		err = msgp.WrapError(err, "LatestHandshake")
+		return
+	}
+	return
+}
+
+// MarshalMsg implements msgp.Marshaler
+func (z trafficValue) MarshalMsg(b []byte) (o []byte, err error) {
+	o = msgp.Require(b, z.Msgsize())
+	// array header, size 3
+	o = append(o, 0x93)
+	o = msgp.AppendUint64(o, z.Received)
+	o = msgp.AppendUint64(o, z.Sent)
+	o = msgp.AppendInt64(o, z.LatestHandshake)
+	return
+}
+
+// UnmarshalMsg implements msgp.Unmarshaler
+func (z *trafficValue) UnmarshalMsg(bts []byte) (o []byte, err error) {
+	var zb0001 uint32
+	zb0001, bts, err = msgp.ReadArrayHeaderBytes(bts)
+	if err != nil {
+		err = msgp.WrapError(err)
+		return
+	}
+	if zb0001 != 3 {
+		err = msgp.ArrayError{Wanted: 3, Got: zb0001}
+		return
+	}
+	z.Received, bts, err = msgp.ReadUint64Bytes(bts)
+	if err != nil {
+		err = msgp.WrapError(err, "Received")
+		return
+	}
+	z.Sent, bts, err = msgp.ReadUint64Bytes(bts)
+	if err != nil {
+		err = msgp.WrapError(err, "Sent")
+		return
+	}
+	z.LatestHandshake, bts, err = msgp.ReadInt64Bytes(bts)
+	if err != nil {
+		err = msgp.WrapError(err, "LatestHandshake")
+		return
+	}
+	o = bts
+	return
+}
+
+// Msgsize returns an upper bound estimate of the number of bytes occupied by the serialized message
+func (z trafficValue) Msgsize() (s int) {
+	s = 1 + msgp.Uint64Size + msgp.Uint64Size + msgp.Int64Size
+	return
+}
@@ -0,0 +1,19 @@
+package stats
+
+import (
+	"context"
+	"time"
+
+	"github.com/infastin/wg-wish/pkg/wgtypes"
+	"github.com/infastin/wg-wish/server/entity"
+)
+
+type Repo interface {
+	// RecordPeerStats records traffic of every peer since the previous call,
+	// which is computed from the current values of the kernel counters.
+	RecordPeerStats(ctx context.Context, stats map[wgtypes.Key]entity.WireGuardPeerStats, now time.Time) (err error)
+	// GetTraffic returns traffic samples of the peer since the given time
+	// with the coarsest available resolution not greater than the given one.
+	GetTraffic(ctx context.Context, publicKey wgtypes.Key, since time.Time, resolution time.Duration,
+	) (samples []entity.TrafficSample, err error)
+}
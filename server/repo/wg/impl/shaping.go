@@ -0,0 +1,71 @@
+package wgrepo
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"strconv"
+
+	"github.com/infastin/wg-wish/server/entity"
+	"github.com/infastin/wg-wish/server/errors"
+)
+
+// Minimal burst size for policing, in bytes.
+const minBurst = 16 * 1024
+
+// SetRateLimits replaces traffic control rules of the interface with the given limits.
+// Traffic sent to a peer is shaped with an HTB class,
+// while traffic received from a peer is policed on ingress.
+func (wg *WireGuardRepo) SetRateLimits(ctx context.Context, limits []entity.WireGuardRateLimit) (err error) {
+	// Qdiscs might not exist, so errors are ignored.
+	_ = exec.CommandContext(ctx, "tc", "qdisc", "del", "dev", wg.iface, "root").Run()    //nolint:gosec
+	_ = exec.CommandContext(ctx, "tc", "qdisc", "del", "dev", wg.iface, "ingress").Run() //nolint:gosec
+
+	if len(limits) == 0 {
+		return nil
+	}
+
+	batch := rateLimitBatch(wg.iface, limits)
+
+	var stderr bytes.Buffer
+
+	cmd := exec.CommandContext(ctx, "tc", "-batch", "-")
+	cmd.Stdin = bytes.NewReader(batch)
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		return errors.NewCommandError(cmd, err, stderr.String())
+	}
+
+	return nil
+}
+
+// rateLimitBatch returns the tc batch setting up the limits on the interface.
+func rateLimitBatch(iface string, limits []entity.WireGuardRateLimit) []byte {
+	var batch bytes.Buffer
+
+	fmt.Fprintf(&batch, "qdisc add dev %s root handle 1: htb\n", iface)
+	fmt.Fprintf(&batch, "qdisc add dev %s handle ffff: ingress\n", iface)
+
+	for i := range limits {
+		proto, match := "ip", "ip"
+		if limits[i].Address.IP.To4() == nil {
+			proto, match = "ipv6", "ip6"
+		}
+
+		classID := "1:" + strconv.FormatUint(uint64(i+1), 16)
+		address := limits[i].Address.String()
+		rate := strconv.FormatUint(limits[i].Rate, 10) + "bit"
+		burst := strconv.FormatUint(max(limits[i].Rate/80, minBurst), 10) + "b"
+
+		fmt.Fprintf(&batch, "class add dev %s parent 1: classid %s htb rate %s ceil %s\n",
+			iface, classID, rate, rate)
+		fmt.Fprintf(&batch, "filter add dev %s parent 1: protocol %s prio 1 u32 match %s dst %s flowid %s\n",
+			iface, proto, match, address, classID)
+		fmt.Fprintf(&batch, "filter add dev %s parent ffff: protocol %s prio 1 u32 match %s src %s police rate %s burst %s drop flowid :1\n",
+			iface, proto, match, address, rate, burst)
+	}
+
+	return batch.Bytes()
+}
@@ -0,0 +1,87 @@
+package wgrepo
+
+import (
+	"bytes"
+	"flag"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/infastin/wg-wish/server/entity"
+)
+
+var update = flag.Bool("update", false, "update golden files")
+
+func mustParseCIDR(t *testing.T, s string) net.IPNet {
+	t.Helper()
+
+	ip, ipNet, err := net.ParseCIDR(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ip4 := ip.To4(); ip4 != nil {
+		ip = ip4
+	}
+	ipNet.IP = ip
+
+	return *ipNet
+}
+
+// TestRateLimitBatchGolden checks the tc batch against testdata/shaping_*.golden.
+func TestRateLimitBatchGolden(t *testing.T) {
+	tests := []struct {
+		name   string
+		limits []entity.WireGuardRateLimit
+	}{
+		{
+			name: "ipv4",
+			limits: []entity.WireGuardRateLimit{
+				{Address: mustParseCIDR(t, "10.0.0.2/32"), Rate: 10_000_000},
+				{Address: mustParseCIDR(t, "10.0.0.3/32"), Rate: 1_000_000},
+			},
+		},
+		{
+			name: "ipv6",
+			limits: []entity.WireGuardRateLimit{
+				{Address: mustParseCIDR(t, "fd00::2/128"), Rate: 50_000_000},
+			},
+		},
+		{
+			// Class IDs are hexadecimal.
+			name: "many",
+			limits: func() []entity.WireGuardRateLimit {
+				limits := make([]entity.WireGuardRateLimit, 11)
+				for i := range limits {
+					limits[i] = entity.WireGuardRateLimit{
+						Address: net.IPNet{IP: net.IPv4(10, 0, 0, byte(i+2)).To4(), Mask: net.CIDRMask(32, 32)},
+						Rate:    uint64(i+1) * 1_000_000,
+					}
+				}
+				return limits
+			}(),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			output := rateLimitBatch("wg0", tt.limits)
+
+			goldenPath := filepath.Join("testdata", "shaping_"+tt.name+".golden")
+			if *update {
+				if err := os.WriteFile(goldenPath, output, 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			golden, err := os.ReadFile(goldenPath)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !bytes.Equal(output, golden) {
+				t.Fatalf("tc batch differs from %s:\n%s", goldenPath, output)
+			}
+		})
+	}
+}
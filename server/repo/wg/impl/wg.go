@@ -7,6 +7,7 @@ import (
 	"os/exec"
 	"slices"
 	"strconv"
+	"strings"
 	"sync"
 	"time"
 
@@ -21,12 +22,15 @@ import (
 type WireGuardRepoParams struct {
 	Logger zerolog.Logger
 
-	Path string
+	// Interface is the name of the interface, which must match the config file name.
+	Interface string
+	Path      string
 }
 
 type WireGuardRepo struct {
 	lg zerolog.Logger
 
+	iface  string
 	path   string
 	config wgtypes.ServerConfig
 	mu     *sync.RWMutex
@@ -35,6 +39,7 @@ type WireGuardRepo struct {
 func New(params *WireGuardRepoParams) *WireGuardRepo {
 	return &WireGuardRepo{
 		lg:     params.Logger,
+		iface:  params.Interface,
 		path:   params.Path,
 		config: wgtypes.ServerConfig{},
 		mu:     &sync.RWMutex{},
@@ -84,8 +89,8 @@ func (wg *WireGuardRepo) RemoveServerPeer(ctx context.Context, name string) (err
 	return errors.ErrWireGuardServerPeerNotFound
 }
 
-func (*WireGuardRepo) GetPeerStats(ctx context.Context) (stats map[wgtypes.Key]entity.WireGuardPeerStats, err error) {
-	cmd := exec.CommandContext(ctx, "wg", "show", "wg0", "dump")
+func (wg *WireGuardRepo) GetPeerStats(ctx context.Context) (stats map[wgtypes.Key]entity.WireGuardPeerStats, err error) {
+	cmd := exec.CommandContext(ctx, "wg", "show", wg.iface, "dump") //nolint:gosec
 
 	out, err := cmd.Output()
 	if err != nil {
@@ -124,6 +129,10 @@ func (*WireGuardRepo) GetPeerStats(ctx context.Context) (stats map[wgtypes.Key]e
 			if err != nil {
 				return nil, err
 			}
+		case 2:
+			if field != "(none)" {
+				stat.Endpoint = strings.Clone(field)
+			}
 		case 4:
 			timestamp, err := strconv.ParseInt(field, 10, 64)
 			if err != nil {
@@ -175,7 +184,7 @@ func (wg *WireGuardRepo) StopServer(ctx context.Context) (err error) {
 }
 
 func (wg *WireGuardRepo) ReloadServer(ctx context.Context) (err error) {
-	f, err := os.CreateTemp("", "wg0-*.conf")
+	f, err := os.CreateTemp("", wg.iface+"-*.conf")
 	if err != nil {
 		return err
 	}
@@ -191,7 +200,7 @@ func (wg *WireGuardRepo) ReloadServer(ctx context.Context) (err error) {
 		return errors.NewCommandError(stripCmd, err, stderr.String())
 	}
 
-	wgCmd := exec.CommandContext(ctx, "wg", "syncconf", "wg0", f.Name()) //nolint:gosec
+	wgCmd := exec.CommandContext(ctx, "wg", "syncconf", wg.iface, f.Name()) //nolint:gosec
 	wgCmd.Stderr = &stderr
 
 	if err := wgCmd.Run(); err != nil {
@@ -18,4 +18,5 @@ type Repo interface {
 	StartServer(ctx context.Context) (err error)
 	StopServer(ctx context.Context) (err error)
 	ReloadServer(ctx context.Context) (err error)
+	SetRateLimits(ctx context.Context, limits []entity.WireGuardRateLimit) (err error)
 }
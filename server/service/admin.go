@@ -0,0 +1,22 @@
+package service
+
+import (
+	"context"
+
+	"github.com/infastin/wg-wish/server/entity"
+)
+
+type ImportMode int
+
+const (
+	// ImportMerge adds or overwrites peers and public keys from the snapshot,
+	// leaving everything else untouched.
+	ImportMerge ImportMode = iota
+	// ImportReplace replaces the whole state with the snapshot.
+	ImportReplace
+)
+
+type AdminService interface {
+	ExportState(ctx context.Context) (snapshot entity.Snapshot, err error)
+	ImportState(ctx context.Context, snapshot *entity.Snapshot, mode ImportMode) (err error)
+}
@@ -0,0 +1,13 @@
+package service
+
+import (
+	"context"
+
+	"github.com/infastin/wg-wish/server/entity"
+)
+
+type ClusterService interface {
+	GetStatus(ctx context.Context) (status entity.ClusterStatus, err error)
+	// Promote stops replication and makes the standby take over as the primary.
+	Promote(ctx context.Context) (err error)
+}
@@ -0,0 +1,29 @@
+package service
+
+import (
+	"context"
+
+	"github.com/infastin/wg-wish/pkg/wgtypes"
+	"github.com/infastin/wg-wish/server/entity"
+)
+
+// FederationOptions select where an operation is performed.
+type FederationOptions struct {
+	// Servers are the names of the servers, every server if empty.
+	Servers []string
+	// Interface is the name of the interface on each server, the primary one if empty.
+	Interface string
+}
+
+type FederationService interface {
+	// GetServers returns the local server followed by the linked ones,
+	// each of which is contacted to check that it can be reached.
+	GetServers(ctx context.Context) (servers []entity.FederationServer, err error)
+	GetClients(ctx context.Context, opts *FederationOptions) (clients []entity.FederatedClient, err error)
+	// SyncClient adds the client with the key of the local one to the servers that don't have it,
+	// adding the local client first if it doesn't exist.
+	SyncClient(ctx context.Context, name string, opts *FederationOptions) (results []entity.FederatedClientSync, err error)
+	GetClient(ctx context.Context, server, name, iface string) (client wgtypes.ClientConfig, err error)
+	RemoveClient(ctx context.Context, name string, opts *FederationOptions) (results []entity.FederationResult, err error)
+	ReloadServers(ctx context.Context, opts *FederationOptions) (results []entity.FederationResult, err error)
+}
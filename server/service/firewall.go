@@ -0,0 +1,17 @@
+package service
+
+import (
+	"context"
+
+	"github.com/infastin/wg-wish/server/entity"
+)
+
+type FirewallService interface {
+	AddGroup(ctx context.Context, group *entity.FirewallGroup) (err error)
+	SetGroup(ctx context.Context, group *entity.FirewallGroup) (err error)
+	RemoveGroup(ctx context.Context, name string) (err error)
+	GetGroups(ctx context.Context) (groups []entity.FirewallGroup, err error)
+	Apply(ctx context.Context) (err error)
+	Cleanup(ctx context.Context) (err error)
+	Show(ctx context.Context) (state entity.FirewallState, err error)
+}
@@ -0,0 +1,241 @@
+package adminservice
+
+import (
+	"context"
+
+	"github.com/guregu/null/v5"
+	"github.com/infastin/wg-wish/pkg/wgtypes"
+	"github.com/infastin/wg-wish/server/entity"
+	"github.com/infastin/wg-wish/server/errors"
+	"github.com/infastin/wg-wish/server/event"
+	"github.com/infastin/wg-wish/server/repo/db"
+	"github.com/infastin/wg-wish/server/service"
+	"github.com/rs/zerolog"
+)
+
+type AdminServiceParams struct {
+	Logger zerolog.Logger
+	// DatabaseRepo must be bound to the interface.
+	DatabaseRepo     db.Repo
+	WireGuardService service.WireGuardService
+	Events           *event.Bus
+	Interface        string
+}
+
+// AdminService exports and imports the state of a single interface
+// along with the state shared by all interfaces.
+type AdminService struct {
+	lg               zerolog.Logger
+	dbRepo           db.Repo
+	wireguardService service.WireGuardService
+	events           *event.Bus
+	iface            string
+}
+
+func New(params *AdminServiceParams) *AdminService {
+	return &AdminService{
+		lg:               params.Logger,
+		dbRepo:           params.DatabaseRepo,
+		wireguardService: params.WireGuardService,
+		events:           params.Events,
+		iface:            params.Interface,
+	}
+}
+
+func (s *AdminService) ExportState(ctx context.Context) (snapshot entity.Snapshot, err error) {
+	if err := s.dbRepo.View(ctx, func(repo db.Repo) error {
+		config, err := repo.WireGuardServerRepo().GetWireGuardServerConfig()
+		if err != nil && err != errors.ErrWireGuardServerConfigNotFound {
+			return err
+		}
+		if err == nil {
+			snapshot.Server = null.ValueFrom(config)
+		}
+
+		snapshot.Clients, err = repo.WireGuardClientRepo().GetWireGuardClients(ctx)
+		if err != nil {
+			return err
+		}
+
+		snapshot.PublicKeys, err = repo.PublicKeyRepo().GetPublicKeys(ctx)
+		if err != nil {
+			return err
+		}
+
+		snapshot.FirewallGroups, err = repo.FirewallGroupRepo().GetFirewallGroups(ctx)
+		if err != nil {
+			return err
+		}
+
+		snapshot.Profiles, err = repo.WireGuardProfileRepo().GetWireGuardProfiles(ctx)
+		if err != nil {
+			return err
+		}
+
+		return nil
+	}); err != nil {
+		return entity.Snapshot{}, err
+	}
+
+	return snapshot, nil
+}
+
+func (s *AdminService) ImportState(ctx context.Context, snapshot *entity.Snapshot, mode service.ImportMode) (err error) {
+	if err := validateSnapshot(snapshot); err != nil {
+		return err
+	}
+
+	var events []event.Event
+
+	if err := s.dbRepo.Update(ctx, func(repo db.Repo) error {
+		existing, err := repo.WireGuardClientRepo().GetWireGuardClients(ctx)
+		if err != nil {
+			return err
+		}
+		events = importEvents(existing, snapshot, mode)
+
+		switch mode {
+		case service.ImportReplace:
+			return importReplace(ctx, repo, snapshot)
+		case service.ImportMerge:
+			return importMerge(ctx, repo, snapshot)
+		}
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	for i := range events {
+		events[i].Interface = s.iface
+		s.events.Publish(events[i])
+	}
+
+	return s.wireguardService.SyncServer(ctx)
+}
+
+// importEvents returns peer events describing the changes made by the import.
+func importEvents(existing []entity.WireGuardClient, snapshot *entity.Snapshot, mode service.ImportMode) (events []event.Event) {
+	imported := make(map[string]struct{}, len(snapshot.Clients))
+	for i := range snapshot.Clients {
+		imported[snapshot.Clients[i].Name] = struct{}{}
+	}
+
+	existingNames := make(map[string]struct{}, len(existing))
+	for i := range existing {
+		existingNames[existing[i].Name] = struct{}{}
+
+		if _, ok := imported[existing[i].Name]; !ok && mode == service.ImportReplace {
+			events = append(events, event.NewClientEvent(event.PeerRemoved, &existing[i]))
+		}
+	}
+
+	for i := range snapshot.Clients {
+		typ := event.PeerAdded
+		if _, ok := existingNames[snapshot.Clients[i].Name]; ok {
+			typ = event.PeerEdited
+		}
+		events = append(events, event.NewClientEvent(typ, &snapshot.Clients[i]))
+	}
+
+	return events
+}
+
+func importReplace(ctx context.Context, repo db.Repo, snapshot *entity.Snapshot) (err error) {
+	if snapshot.Server.Valid {
+		err = repo.WireGuardServerRepo().SetWireGuardServerConfig(&snapshot.Server.V)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = repo.WireGuardClientRepo().SetWireGuardClients(ctx, snapshot.Clients)
+	if err != nil {
+		return err
+	}
+
+	err = repo.PublicKeyRepo().SetPublicKeys(ctx, snapshot.PublicKeys)
+	if err != nil {
+		return err
+	}
+
+	err = repo.FirewallGroupRepo().SetFirewallGroups(ctx, snapshot.FirewallGroups)
+	if err != nil {
+		return err
+	}
+
+	return repo.WireGuardProfileRepo().SetWireGuardProfiles(ctx, snapshot.Profiles)
+}
+
+func importMerge(ctx context.Context, repo db.Repo, snapshot *entity.Snapshot) (err error) {
+	if snapshot.Server.Valid {
+		exists, err := repo.WireGuardServerRepo().WireGuardServerConfigExists()
+		if err != nil {
+			return err
+		}
+
+		if !exists {
+			err = repo.WireGuardServerRepo().SetWireGuardServerConfig(&snapshot.Server.V)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	for i := range snapshot.Clients {
+		err = repo.WireGuardClientRepo().SetWireGuardClient(ctx, &snapshot.Clients[i])
+		if err != nil {
+			return err
+		}
+	}
+
+	for i := range snapshot.PublicKeys {
+		err = repo.PublicKeyRepo().SetPublicKey(ctx, &snapshot.PublicKeys[i])
+		if err != nil {
+			return err
+		}
+	}
+
+	for i := range snapshot.FirewallGroups {
+		err = repo.FirewallGroupRepo().SetFirewallGroup(ctx, &snapshot.FirewallGroups[i])
+		if err != nil {
+			return err
+		}
+	}
+
+	for i := range snapshot.Profiles {
+		err = repo.WireGuardProfileRepo().SetWireGuardProfile(ctx, &snapshot.Profiles[i])
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func validateSnapshot(snapshot *entity.Snapshot) (err error) {
+	names := make(map[string]struct{}, len(snapshot.Clients))
+	addresses := make(map[string]struct{}, len(snapshot.Clients))
+	publicKeys := make(map[wgtypes.Key]struct{}, len(snapshot.Clients))
+
+	for i := range snapshot.Clients {
+		client := &snapshot.Clients[i]
+
+		if _, ok := names[client.Name]; ok {
+			return errors.ErrImportDuplicateName
+		}
+		names[client.Name] = struct{}{}
+
+		key := string(client.Address.IP.To16())
+		if _, ok := addresses[key]; ok {
+			return errors.ErrImportAddressConflict
+		}
+		addresses[key] = struct{}{}
+
+		if _, ok := publicKeys[client.PublicKey]; ok {
+			return errors.ErrImportPublicKeyConflict
+		}
+		publicKeys[client.PublicKey] = struct{}{}
+	}
+
+	return nil
+}
@@ -0,0 +1,348 @@
+package federationservice
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+
+	"github.com/infastin/wg-wish/pkg/wgtypes"
+	"github.com/infastin/wg-wish/server/entity"
+	"github.com/infastin/wg-wish/server/errors"
+	"github.com/infastin/wg-wish/server/repo/federation"
+	"github.com/infastin/wg-wish/server/service"
+	"github.com/rs/zerolog"
+)
+
+// Interface holds the service bound to a single local WireGuard interface.
+type Interface struct {
+	Name             string
+	WireGuardService service.WireGuardService
+}
+
+// Server is a linked server.
+type Server struct {
+	Name string
+	URL  string
+	Repo federation.Repo
+}
+
+type FederationServiceParams struct {
+	Logger zerolog.Logger
+	// Name is the name of the local server.
+	Name string
+	// Interfaces must contain at least one interface, the first one is used by default.
+	Interfaces []Interface
+	Servers    []Server
+}
+
+// FederationService manages clients of the local server and of the linked ones,
+// which share the identity of the local clients.
+type FederationService struct {
+	lg         zerolog.Logger
+	name       string
+	interfaces []Interface
+	servers    []Server
+}
+
+func New(params *FederationServiceParams) *FederationService {
+	return &FederationService{
+		lg:         params.Logger,
+		name:       params.Name,
+		interfaces: params.Interfaces,
+		servers:    params.Servers,
+	}
+}
+
+func (s *FederationService) GetServers(ctx context.Context) (servers []entity.FederationServer, err error) {
+	local := entity.FederationServer{
+		Name:       s.name,
+		URL:        "",
+		Local:      true,
+		Interfaces: make([]string, len(s.interfaces)),
+		Clients:    0,
+		Error:      "",
+	}
+
+	for i := range s.interfaces {
+		local.Interfaces[i] = s.interfaces[i].Name
+	}
+
+	info, err := s.interfaces[0].WireGuardService.GetServerInfo(ctx)
+	if err != nil {
+		return nil, err
+	}
+	local.Clients = info.Clients
+
+	servers = make([]entity.FederationServer, len(s.servers)+1)
+	servers[0] = local
+
+	s.forEach(s.servers, func(i int, server *Server) {
+		remote, err := server.Repo.GetServer(ctx)
+		switch {
+		case err != nil:
+			remote = entity.FederationServer{Error: s.errorMessage(server.Name, err)} //nolint:exhaustruct
+		case remote.Name != server.Name:
+			remote = entity.FederationServer{Error: fmt.Sprintf("server reports name %q", remote.Name)} //nolint:exhaustruct
+		}
+
+		remote.Name = server.Name
+		remote.URL = server.URL
+		servers[i+1] = remote
+	})
+
+	return servers, nil
+}
+
+func (s *FederationService) GetClients(ctx context.Context, opts *service.FederationOptions,
+) (clients []entity.FederatedClient, err error) {
+	local, remotes, err := s.selectServers(opts.Servers)
+	if err != nil {
+		return nil, err
+	}
+
+	if local {
+		wireguardService, err := s.wireguardService(opts.Interface)
+		if err != nil {
+			return nil, err
+		}
+
+		infos, err := wireguardService.GetClientInfos(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		for i := range infos {
+			info := &infos[i]
+			client := entity.FederatedClient{
+				Server:          s.name,
+				Name:            info.Config.Interface.Name,
+				Address:         info.Config.Interface.Address,
+				PublicKey:       info.Config.Interface.PrivateKey.PublicKey(),
+				Disabled:        info.Disabled,
+				LatestHandshake: info.Stats.V.LatestHandshake,
+			}
+			clients = append(clients, client)
+		}
+	}
+
+	remoteClients := make([][]entity.FederatedClient, len(remotes))
+	remoteErrs := make([]error, len(remotes))
+
+	s.forEach(remotes, func(i int, server *Server) {
+		remoteClients[i], remoteErrs[i] = server.Repo.GetClients(ctx, opts.Interface)
+	})
+
+	for i := range remotes {
+		if remoteErrs[i] != nil {
+			return nil, serverError(remotes[i].Name, remoteErrs[i])
+		}
+		for j := range remoteClients[i] {
+			remoteClients[i][j].Server = remotes[i].Name
+		}
+		clients = append(clients, remoteClients[i]...)
+	}
+
+	return clients, nil
+}
+
+func (s *FederationService) SyncClient(ctx context.Context, name string, opts *service.FederationOptions,
+) (results []entity.FederatedClientSync, err error) {
+	_, remotes, err := s.selectServers(opts.Servers)
+	if err != nil {
+		return nil, err
+	}
+
+	wireguardService, err := s.wireguardService(opts.Interface)
+	if err != nil {
+		return nil, err
+	}
+
+	local := entity.FederatedClientSync{
+		FederationResult: entity.FederationResult{Server: s.name, Error: ""},
+		Created:          false,
+	}
+
+	client, err := wireguardService.GetClient(ctx, name)
+	if errors.Is(err, errors.ErrWireGuardClientNotFound) {
+		client, err = wireguardService.AddClient(ctx, name, nil)
+		local.Created = true
+	}
+	if err != nil {
+		return nil, err
+	}
+	local.Address = client.Interface.Address
+
+	results = make([]entity.FederatedClientSync, len(remotes)+1)
+	results[0] = local
+
+	s.forEach(remotes, func(i int, server *Server) {
+		result := entity.FederatedClientSync{
+			FederationResult: entity.FederationResult{Server: server.Name, Error: ""},
+			Created:          false,
+		}
+
+		remote, created, err := server.Repo.SyncClient(ctx, opts.Interface, name, client.Interface.PrivateKey)
+		if err != nil {
+			result.Error = s.errorMessage(server.Name, err)
+		} else {
+			result.Address = remote.Interface.Address
+			result.Created = created
+		}
+
+		results[i+1] = result
+	})
+
+	return results, nil
+}
+
+func (s *FederationService) GetClient(ctx context.Context, server, name, iface string,
+) (client wgtypes.ClientConfig, err error) {
+	if server == s.name {
+		wireguardService, err := s.wireguardService(iface)
+		if err != nil {
+			return wgtypes.ClientConfig{}, err
+		}
+		return wireguardService.GetClient(ctx, name)
+	}
+
+	_, remotes, err := s.selectServers([]string{server})
+	if err != nil {
+		return wgtypes.ClientConfig{}, err
+	}
+
+	client, err = remotes[0].Repo.GetClient(ctx, iface, name)
+	if err != nil {
+		return wgtypes.ClientConfig{}, serverError(server, err)
+	}
+
+	return client, nil
+}
+
+func (s *FederationService) RemoveClient(ctx context.Context, name string, opts *service.FederationOptions,
+) (results []entity.FederationResult, err error) {
+	return s.forEachServer(opts, func(wireguardService service.WireGuardService) error {
+		return wireguardService.RemoveClient(ctx, name)
+	}, func(server *Server) error {
+		return server.Repo.RemoveClient(ctx, opts.Interface, name)
+	})
+}
+
+func (s *FederationService) ReloadServers(ctx context.Context, opts *service.FederationOptions,
+) (results []entity.FederationResult, err error) {
+	return s.forEachServer(opts, func(wireguardService service.WireGuardService) error {
+		return wireguardService.ReloadServer(ctx)
+	}, func(server *Server) error {
+		return server.Repo.ReloadServer(ctx, opts.Interface)
+	})
+}
+
+// forEachServer performs the operation on the local server if selected
+// and on the selected linked servers, recording the outcome on each.
+func (s *FederationService) forEachServer(opts *service.FederationOptions,
+	localFn func(wireguardService service.WireGuardService) error, remoteFn func(server *Server) error,
+) (results []entity.FederationResult, err error) {
+	local, remotes, err := s.selectServers(opts.Servers)
+	if err != nil {
+		return nil, err
+	}
+
+	if local {
+		wireguardService, err := s.wireguardService(opts.Interface)
+		if err != nil {
+			return nil, err
+		}
+
+		result := entity.FederationResult{Server: s.name, Error: ""}
+		if err := localFn(wireguardService); err != nil {
+			result.Error = s.errorMessage(s.name, err)
+		}
+		results = append(results, result)
+	}
+
+	remoteResults := make([]entity.FederationResult, len(remotes))
+	s.forEach(remotes, func(i int, server *Server) {
+		remoteResults[i] = entity.FederationResult{Server: server.Name, Error: ""}
+		if err := remoteFn(server); err != nil {
+			remoteResults[i].Error = s.errorMessage(server.Name, err)
+		}
+	})
+
+	return append(results, remoteResults...), nil
+}
+
+// selectServers reports whether the local server is selected and returns the selected linked servers.
+// Every server is selected if names are empty.
+func (s *FederationService) selectServers(names []string) (local bool, remotes []Server, err error) {
+	if len(names) == 0 {
+		return true, s.servers, nil
+	}
+
+	for _, name := range names {
+		if name == s.name {
+			local = true
+			continue
+		}
+
+		i := slices.IndexFunc(s.servers, func(server Server) bool {
+			return server.Name == name
+		})
+		if i == -1 {
+			return false, nil, errors.NewDomainError("federation",
+				fmt.Sprintf("%s: %q", errors.ErrFederationServerNotFound.Error(), name))
+		}
+
+		if !slices.ContainsFunc(remotes, func(server Server) bool {
+			return server.Name == name
+		}) {
+			remotes = append(remotes, s.servers[i])
+		}
+	}
+
+	return local, remotes, nil
+}
+
+// wireguardService returns the service of the local interface, or of the default one if the name is empty.
+func (s *FederationService) wireguardService(name string) (service.WireGuardService, error) {
+	if name == "" {
+		return s.interfaces[0].WireGuardService, nil
+	}
+
+	for i := range s.interfaces {
+		if s.interfaces[i].Name == name {
+			return s.interfaces[i].WireGuardService, nil
+		}
+	}
+
+	return nil, errors.ErrWireGuardInterfaceNotFound
+}
+
+// forEach calls fn for every server concurrently, so that slow servers don't hold up the others.
+func (*FederationService) forEach(servers []Server, fn func(i int, server *Server)) {
+	var wg sync.WaitGroup
+	for i := range servers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			fn(i, &servers[i])
+		}()
+	}
+	wg.Wait()
+}
+
+// errorMessage returns the message of the domain error, internal errors are only logged.
+func (s *FederationService) errorMessage(server string, err error) string {
+	if de, ok := err.(errors.DomainError); ok {
+		return de.Error()
+	}
+	s.lg.Err(err).Str("server", server).Msg("federation request failed")
+	return "internal error"
+}
+
+// serverError prefixes the domain error with the name of the server it was reported by.
+func serverError(name string, err error) error {
+	if de, ok := err.(errors.DomainError); ok {
+		return errors.NewDomainError(de.Domain(), fmt.Sprintf("server %q: %s", name, de.Error()))
+	}
+	return err
+}
@@ -0,0 +1,240 @@
+package firewallservice
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sync"
+
+	"github.com/infastin/wg-wish/pkg/netutils"
+	"github.com/infastin/wg-wish/server/entity"
+	"github.com/infastin/wg-wish/server/errors"
+	"github.com/infastin/wg-wish/server/repo/db"
+	"github.com/infastin/wg-wish/server/repo/firewall"
+	"github.com/rs/zerolog"
+)
+
+type FirewallServiceParams struct {
+	Logger       zerolog.Logger
+	DatabaseRepo db.Repo
+	FirewallRepo firewall.Repo
+
+	Device     string
+	Interfaces []FirewallInterfaceParams
+}
+
+type FirewallInterfaceParams struct {
+	Name    string
+	Address string
+	Port    int
+}
+
+type firewallInterface struct {
+	name   string
+	port   int
+	subnet net.IPNet
+}
+
+// FirewallService programs the rules of all interfaces at once,
+// firewall groups are shared by the interfaces.
+type FirewallService struct {
+	lg     zerolog.Logger
+	dbRepo db.Repo
+	fwRepo firewall.Repo
+	device string
+	ifaces []firewallInterface
+	mu     *sync.Mutex
+}
+
+func New(params *FirewallServiceParams) (fwservice *FirewallService, err error) {
+	ifaces := make([]firewallInterface, len(params.Interfaces))
+	for i := range params.Interfaces {
+		address, err := netutils.ParseAddress(params.Interfaces[i].Address)
+		if err != nil {
+			return nil, err
+		}
+
+		ifaces[i] = firewallInterface{
+			name: params.Interfaces[i].Name,
+			port: params.Interfaces[i].Port,
+			subnet: net.IPNet{
+				IP:   address.IP.Mask(address.Mask),
+				Mask: address.Mask,
+			},
+		}
+	}
+
+	return &FirewallService{
+		lg:     params.Logger,
+		dbRepo: params.DatabaseRepo,
+		fwRepo: params.FirewallRepo,
+		device: params.Device,
+		ifaces: ifaces,
+		mu:     &sync.Mutex{},
+	}, nil
+}
+
+func (s *FirewallService) AddGroup(ctx context.Context, group *entity.FirewallGroup) (err error) {
+	if err := s.dbRepo.Update(ctx, func(repo db.Repo) error {
+		return repo.FirewallGroupRepo().AddFirewallGroup(ctx, group)
+	}); err != nil {
+		return err
+	}
+	return s.Apply(ctx)
+}
+
+func (s *FirewallService) SetGroup(ctx context.Context, group *entity.FirewallGroup) (err error) {
+	if err := s.dbRepo.Update(ctx, func(repo db.Repo) error {
+		exists, err := repo.FirewallGroupRepo().FirewallGroupExists(ctx, group.Name)
+		if err != nil {
+			return err
+		}
+
+		if !exists {
+			return errors.ErrFirewallGroupNotFound
+		}
+
+		return repo.FirewallGroupRepo().SetFirewallGroup(ctx, group)
+	}); err != nil {
+		return err
+	}
+	return s.Apply(ctx)
+}
+
+func (s *FirewallService) RemoveGroup(ctx context.Context, name string) (err error) {
+	return s.dbRepo.Update(ctx, func(repo db.Repo) error {
+		exists, err := repo.FirewallGroupRepo().FirewallGroupExists(ctx, name)
+		if err != nil {
+			return err
+		}
+
+		if !exists {
+			return errors.ErrFirewallGroupNotFound
+		}
+
+		for i := range s.ifaces {
+			clients, err := repo.Interface(s.ifaces[i].name).WireGuardClientRepo().GetWireGuardClients(ctx)
+			if err != nil {
+				return err
+			}
+
+			for j := range clients {
+				if clients[j].Group == name {
+					return errors.ErrFirewallGroupInUse
+				}
+			}
+		}
+
+		return repo.FirewallGroupRepo().RemoveFirewallGroup(ctx, name)
+	})
+}
+
+func (s *FirewallService) GetGroups(ctx context.Context) (groups []entity.FirewallGroup, err error) {
+	if err := s.dbRepo.View(ctx, func(repo db.Repo) error {
+		groups, err = repo.FirewallGroupRepo().GetFirewallGroups(ctx)
+		return err
+	}); err != nil {
+		return nil, err
+	}
+	return groups, nil
+}
+
+// Apply renders the forwarding rules of every interface
+// and the rules of all enabled clients assigned to a group.
+// Clients assigned to a group that doesn't exist are denied everything.
+func (s *FirewallService) Apply(ctx context.Context) (err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var (
+		clients = make([][]entity.WireGuardClient, len(s.ifaces))
+		groups  []entity.FirewallGroup
+	)
+
+	if err := s.dbRepo.View(ctx, func(repo db.Repo) error {
+		for i := range s.ifaces {
+			clients[i], err = repo.Interface(s.ifaces[i].name).WireGuardClientRepo().GetWireGuardClients(ctx)
+			if err != nil {
+				return err
+			}
+		}
+
+		groups, err = repo.FirewallGroupRepo().GetFirewallGroups(ctx)
+		return err
+	}); err != nil {
+		return err
+	}
+
+	groupsByName := make(map[string]*entity.FirewallGroup, len(groups))
+	for i := range groups {
+		groupsByName[groups[i].Name] = &groups[i]
+	}
+
+	ruleset := entity.FirewallRuleset{
+		Device:     s.device,
+		Interfaces: make([]entity.FirewallInterface, len(s.ifaces)),
+	}
+
+	for i := range s.ifaces {
+		policies, err := mapToFirewallPolicies(clients[i], groupsByName)
+		if err != nil {
+			return err
+		}
+
+		ruleset.Interfaces[i] = entity.FirewallInterface{
+			Name:     s.ifaces[i].name,
+			Port:     s.ifaces[i].port,
+			Subnet:   s.ifaces[i].subnet,
+			Policies: policies,
+		}
+	}
+
+	return s.fwRepo.Apply(ctx, &ruleset)
+}
+
+// mapToFirewallPolicies returns the policies of the enabled clients assigned to a group.
+// A member without an IPv4 address is an error rather than being left out,
+// as it would otherwise reach everything.
+func mapToFirewallPolicies(clients []entity.WireGuardClient, groupsByName map[string]*entity.FirewallGroup,
+) (policies []entity.FirewallPolicy, err error) {
+	for i := range clients {
+		if clients[i].Group == "" || clients[i].Disabled {
+			continue
+		}
+
+		if clients[i].Address.IP.To4() == nil {
+			return nil, errors.NewDomainError("firewall",
+				fmt.Sprintf("client %q: %s", clients[i].Name, errors.ErrFirewallGroupNotIPv4.Error()))
+		}
+
+		policy := entity.FirewallPolicy{
+			Name:       clients[i].Name,
+			Address:    clients[i].Address,
+			AllowPeers: false,
+			Rules:      nil,
+		}
+
+		if group, ok := groupsByName[clients[i].Group]; ok {
+			policy.AllowPeers = group.AllowPeers
+			policy.Rules = group.Rules
+		}
+
+		policies = append(policies, policy)
+	}
+
+	return policies, nil
+}
+
+func (s *FirewallService) Cleanup(ctx context.Context) (err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.fwRepo.Cleanup(ctx)
+}
+
+func (s *FirewallService) Show(ctx context.Context) (state entity.FirewallState, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.fwRepo.Show(ctx)
+}
@@ -0,0 +1,119 @@
+package profileservice
+
+import (
+	"context"
+
+	"github.com/infastin/wg-wish/server/entity"
+	"github.com/infastin/wg-wish/server/errors"
+	"github.com/infastin/wg-wish/server/repo/db"
+	"github.com/infastin/wg-wish/server/service"
+	"github.com/rs/zerolog"
+)
+
+type ProfileServiceParams struct {
+	Logger       zerolog.Logger
+	DatabaseRepo db.Repo
+	// WireGuardServices are the services of every interface,
+	// through which profile changes are propagated to the clients.
+	WireGuardServices []service.WireGuardService
+}
+
+// ProfileService manages profiles shared by all interfaces.
+type ProfileService struct {
+	lg                zerolog.Logger
+	dbRepo            db.Repo
+	wireguardServices []service.WireGuardService
+}
+
+func New(params *ProfileServiceParams) *ProfileService {
+	return &ProfileService{
+		lg:                params.Logger,
+		dbRepo:            params.DatabaseRepo,
+		wireguardServices: params.WireGuardServices,
+	}
+}
+
+func (s *ProfileService) AddProfile(ctx context.Context, profile *entity.WireGuardProfile) (err error) {
+	return s.dbRepo.Update(ctx, func(repo db.Repo) error {
+		return repo.WireGuardProfileRepo().AddWireGuardProfile(ctx, profile)
+	})
+}
+
+// SetProfile replaces the profile and propagates the change to the clients
+// of every interface in the same transaction, so that either all of them follow it or none.
+func (s *ProfileService) SetProfile(ctx context.Context, profile *entity.WireGuardProfile) (clients int, err error) {
+	var publish []func()
+
+	if err := s.dbRepo.Update(ctx, func(repo db.Repo) error {
+		prev, err := repo.WireGuardProfileRepo().GetWireGuardProfile(ctx, profile.Name)
+		if err != nil {
+			return err
+		}
+
+		if err := repo.WireGuardProfileRepo().SetWireGuardProfile(ctx, profile); err != nil {
+			return err
+		}
+
+		for _, wireguardService := range s.wireguardServices {
+			n, p, err := wireguardService.ApplyProfile(ctx, repo, &prev, profile)
+			if err != nil {
+				return err
+			}
+			clients += n
+			publish = append(publish, p)
+		}
+
+		return nil
+	}); err != nil {
+		return 0, err
+	}
+
+	for _, p := range publish {
+		p()
+	}
+
+	return clients, nil
+}
+
+func (s *ProfileService) RemoveProfile(ctx context.Context, name string) (err error) {
+	return s.dbRepo.Update(ctx, func(repo db.Repo) error {
+		exists, err := repo.WireGuardProfileRepo().WireGuardProfileExists(ctx, name)
+		if err != nil {
+			return err
+		}
+
+		if !exists {
+			return errors.ErrWireGuardProfileNotFound
+		}
+
+		ifaces, err := repo.GetInterfaces(ctx)
+		if err != nil {
+			return err
+		}
+
+		for _, iface := range ifaces {
+			clients, err := repo.Interface(iface).WireGuardClientRepo().GetWireGuardClients(ctx)
+			if err != nil {
+				return err
+			}
+
+			for i := range clients {
+				if clients[i].Profile == name {
+					return errors.ErrWireGuardProfileInUse
+				}
+			}
+		}
+
+		return repo.WireGuardProfileRepo().RemoveWireGuardProfile(ctx, name)
+	})
+}
+
+func (s *ProfileService) GetProfiles(ctx context.Context) (profiles []entity.WireGuardProfile, err error) {
+	if err := s.dbRepo.View(ctx, func(repo db.Repo) error {
+		profiles, err = repo.WireGuardProfileRepo().GetWireGuardProfiles(ctx)
+		return err
+	}); err != nil {
+		return nil, err
+	}
+	return profiles, nil
+}
@@ -5,6 +5,7 @@ import (
 
 	"github.com/charmbracelet/ssh"
 	"github.com/infastin/wg-wish/server/entity"
+	"github.com/infastin/wg-wish/server/event"
 	"github.com/infastin/wg-wish/server/repo/db"
 	"github.com/rs/zerolog"
 )
@@ -12,24 +13,32 @@ import (
 type PublicKeyServiceParams struct {
 	Logger zerolog.Logger
 	Repo   db.Repo
+	Events *event.Bus
 }
 
 type PublicKeyService struct {
-	lg   zerolog.Logger
-	repo db.Repo
+	lg     zerolog.Logger
+	repo   db.Repo
+	events *event.Bus
 }
 
 func New(params *PublicKeyServiceParams) *PublicKeyService {
 	return &PublicKeyService{
-		lg:   params.Logger,
-		repo: params.Repo,
+		lg:     params.Logger,
+		repo:   params.Repo,
+		events: params.Events,
 	}
 }
 
 func (s *PublicKeyService) AddPublicKey(ctx context.Context, pkey *entity.PublicKey) (err error) {
-	return s.repo.Update(ctx, func(repo db.Repo) error {
+	if err := s.repo.Update(ctx, func(repo db.Repo) error {
 		return repo.PublicKeyRepo().AddPublicKey(ctx, pkey)
-	})
+	}); err != nil {
+		return err
+	}
+
+	s.events.Publish(event.NewPublicKeyEvent(event.PublicKeyAdded, pkey))
+	return nil
 }
 
 func (s *PublicKeyService) PublicKeyExists(ctx context.Context, pkey ssh.PublicKey) (exists bool, err error) {
@@ -43,9 +52,17 @@ func (s *PublicKeyService) PublicKeyExists(ctx context.Context, pkey ssh.PublicK
 }
 
 func (s *PublicKeyService) RemovePublicKey(ctx context.Context, pkey ssh.PublicKey) (err error) {
-	return s.repo.Update(ctx, func(repo db.Repo) error {
+	if err := s.repo.Update(ctx, func(repo db.Repo) error {
 		return repo.PublicKeyRepo().RemovePublicKey(ctx, pkey)
-	})
+	}); err != nil {
+		return err
+	}
+
+	s.events.Publish(event.NewPublicKeyEvent(event.PublicKeyRemoved, &entity.PublicKey{
+		Key:     pkey,
+		Comment: "",
+	}))
+	return nil
 }
 
 func (s *PublicKeyService) GetPublicKeys(ctx context.Context) (pkeys []entity.PublicKey, err error) {
@@ -0,0 +1,141 @@
+package statsservice
+
+import (
+	"context"
+	"time"
+
+	"github.com/guregu/null/v5"
+	"github.com/infastin/wg-wish/server/entity"
+	"github.com/infastin/wg-wish/server/errors"
+	"github.com/infastin/wg-wish/server/repo/stats"
+	wireguard "github.com/infastin/wg-wish/server/repo/wg"
+	"github.com/infastin/wg-wish/server/service"
+	"github.com/rs/zerolog"
+)
+
+type StatsServiceParams struct {
+	Logger           zerolog.Logger
+	StatsRepo        stats.Repo
+	WireGuardRepo    wireguard.Repo
+	WireGuardService service.WireGuardService
+	Interval         time.Duration
+}
+
+// StatsService records peer traffic and serves its history.
+// If StatsRepo is nil, the statistics are disabled.
+type StatsService struct {
+	lg               zerolog.Logger
+	statsRepo        stats.Repo
+	wgRepo           wireguard.Repo
+	wireguardService service.WireGuardService
+	interval         time.Duration
+}
+
+func New(params *StatsServiceParams) *StatsService {
+	return &StatsService{
+		lg:               params.Logger,
+		statsRepo:        params.StatsRepo,
+		wgRepo:           params.WireGuardRepo,
+		wireguardService: params.WireGuardService,
+		interval:         params.Interval,
+	}
+}
+
+// Run periodically samples peer stats until the context is canceled.
+func (s *StatsService) Run(ctx context.Context) error {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		if err := s.sample(ctx); err != nil {
+			if ie, ok := err.(errors.InternalError); ok {
+				err = ie.Internal()
+			}
+			s.lg.Err(err).Msg("failed to record peer stats")
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *StatsService) sample(ctx context.Context) (err error) {
+	peerStats, err := s.wgRepo.GetPeerStats(ctx)
+	if err != nil {
+		return err
+	}
+	return s.statsRepo.RecordPeerStats(ctx, peerStats, time.Now())
+}
+
+func (s *StatsService) GetTrafficStats(ctx context.Context, name string, opts *service.GetTrafficStatsOptions,
+) (trafficStats entity.TrafficStats, err error) {
+	if s.statsRepo == nil {
+		return entity.TrafficStats{}, errors.ErrTrafficStatsDisabled
+	}
+
+	if opts.Since <= 0 || opts.Interval <= 0 {
+		return entity.TrafficStats{}, errors.ErrTrafficStatsInvalidRange
+	}
+
+	client, err := s.wireguardService.GetClient(ctx, name)
+	if err != nil {
+		return entity.TrafficStats{}, err
+	}
+
+	now := time.Now()
+	since := now.Add(-opts.Since)
+
+	samples, err := s.statsRepo.GetTraffic(ctx, client.Interface.PrivateKey.PublicKey(), since, opts.Interval)
+	if err != nil {
+		return entity.TrafficStats{}, err
+	}
+
+	trafficStats = entity.TrafficStats{
+		Since:           since,
+		Until:           now,
+		Interval:        opts.Interval,
+		Received:        0,
+		Sent:            0,
+		LatestHandshake: null.Time{},
+		Samples:         nil,
+	}
+
+	// Samples are regrouped into the requested intervals,
+	// since they might have a finer resolution.
+	for i := range samples {
+		sample := &samples[i]
+		start := sample.Start.Truncate(opts.Interval)
+
+		n := len(trafficStats.Samples)
+		if n == 0 || !trafficStats.Samples[n-1].Start.Equal(start) {
+			trafficStats.Samples = append(trafficStats.Samples, entity.TrafficSample{
+				Start:           start,
+				Received:        0,
+				Sent:            0,
+				LatestHandshake: null.Time{},
+			})
+			n++
+		}
+
+		group := &trafficStats.Samples[n-1]
+		group.Received += sample.Received
+		group.Sent += sample.Sent
+		group.LatestHandshake = latestTime(group.LatestHandshake, sample.LatestHandshake)
+
+		trafficStats.Received += sample.Received
+		trafficStats.Sent += sample.Sent
+		trafficStats.LatestHandshake = latestTime(trafficStats.LatestHandshake, sample.LatestHandshake)
+	}
+
+	return trafficStats, nil
+}
+
+func latestTime(a, b null.Time) null.Time {
+	if !a.Valid || (b.Valid && b.Time.After(a.Time)) {
+		return b
+	}
+	return a
+}
@@ -0,0 +1,117 @@
+package wgservice
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"path"
+	"slices"
+	"time"
+
+	"github.com/guregu/null/v5"
+	"github.com/infastin/wg-wish/server/entity"
+	"github.com/infastin/wg-wish/server/errors"
+	"github.com/infastin/wg-wish/server/service"
+)
+
+func (wg *WireGuardService) ListClients(ctx context.Context, opts *service.ListClientsOptions,
+) (clients []entity.WireGuardClientInfo, err error) {
+	if _, err := path.Match(opts.Name, ""); err != nil {
+		return nil, errors.ErrWireGuardClientInvalidPattern
+	}
+
+	compare, err := clientComparator(opts.Sort)
+	if err != nil {
+		return nil, err
+	}
+
+	infos, err := wg.GetClientInfos(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+
+	clients = infos[:0]
+	for i := range infos {
+		if matchClient(&infos[i], opts, now) {
+			clients = append(clients, infos[i])
+		}
+	}
+
+	slices.SortStableFunc(clients, compare)
+
+	if opts.Limit > 0 && len(clients) > opts.Limit {
+		clients = clients[:opts.Limit]
+	}
+
+	return clients, nil
+}
+
+func matchClient(info *entity.WireGuardClientInfo, opts *service.ListClientsOptions, now time.Time) bool {
+	if opts.Name != "" {
+		if ok, _ := path.Match(opts.Name, info.Config.Interface.Name); !ok {
+			return false
+		}
+	}
+
+	if opts.Online.Valid && (info.Stats.Valid && info.Stats.V.Online(now)) != opts.Online.Bool {
+		return false
+	}
+
+	if opts.Stale != 0 {
+		// Clients that have never connected are stale too.
+		handshake := latestHandshake(info)
+		if handshake.Valid && now.Sub(handshake.Time) < opts.Stale {
+			return false
+		}
+	}
+
+	return matchLabels(info.Labels, opts.Labels)
+}
+
+// matchLabels reports whether the labels contain every one of the selector,
+// a null value of which matches any value of the label.
+func matchLabels(labels map[string]string, selector map[string]null.String) bool {
+	for key, want := range selector {
+		value, ok := labels[key]
+		if !ok || (want.Valid && want.String != value) {
+			return false
+		}
+	}
+	return true
+}
+
+func clientComparator(sort service.ClientSort) (compare func(a, b entity.WireGuardClientInfo) int, err error) {
+	switch sort {
+	case "", service.ClientSortName:
+		return func(a, b entity.WireGuardClientInfo) int {
+			return cmp.Compare(a.Config.Interface.Name, b.Config.Interface.Name)
+		}, nil
+	case service.ClientSortAddress:
+		return func(a, b entity.WireGuardClientInfo) int {
+			return bytes.Compare(a.Config.Interface.Address.IP.To16(), b.Config.Interface.Address.IP.To16())
+		}, nil
+	case service.ClientSortTraffic:
+		return func(a, b entity.WireGuardClientInfo) int {
+			return cmp.Compare(traffic(&b), traffic(&a))
+		}, nil
+	case service.ClientSortHandshake:
+		return func(a, b entity.WireGuardClientInfo) int {
+			return latestHandshake(&b).Time.Compare(latestHandshake(&a).Time)
+		}, nil
+	default:
+		return nil, errors.ErrWireGuardClientInvalidSort
+	}
+}
+
+func traffic(info *entity.WireGuardClientInfo) uint64 {
+	return info.Stats.V.Received + info.Stats.V.Sent
+}
+
+func latestHandshake(info *entity.WireGuardClientInfo) null.Time {
+	if !info.Stats.Valid {
+		return null.Time{}
+	}
+	return info.Stats.V.LatestHandshake
+}
@@ -0,0 +1,127 @@
+package wgservice
+
+import (
+	"context"
+	"net"
+	"slices"
+
+	"github.com/guregu/null/v5"
+	"github.com/infastin/wg-wish/server/entity"
+	"github.com/infastin/wg-wish/server/event"
+	"github.com/infastin/wg-wish/server/repo/db"
+)
+
+// clientSettings are the client settings a profile controls.
+type clientSettings struct {
+	DNS                 []net.IP
+	AllowedIPs          []net.IPNet
+	PersistentKeepalive null.Int
+}
+
+// profileSettings returns the settings of the profile with unset ones
+// replaced by the server defaults. Nil profile results in the server defaults.
+func (wg *WireGuardService) profileSettings(profile *entity.WireGuardProfile) clientSettings {
+	settings := clientSettings{
+		DNS:                 wg.dns,
+		AllowedIPs:          wg.allowedIPs,
+		PersistentKeepalive: wg.persistentKeepalive,
+	}
+
+	if profile == nil {
+		return settings
+	}
+
+	if len(profile.DNS) != 0 {
+		settings.DNS = profile.DNS
+	}
+	if len(profile.AllowedIPs) != 0 {
+		settings.AllowedIPs = profile.AllowedIPs
+	}
+	if profile.PersistentKeepalive.Valid {
+		settings.PersistentKeepalive = profile.PersistentKeepalive
+	}
+
+	return settings
+}
+
+// update replaces the client's settings matching the prev ones with the new ones
+// and reports whether any of them has changed.
+func (settings *clientSettings) update(client *entity.WireGuardClient, prev *clientSettings) (changed bool) {
+	if slices.EqualFunc(client.DNS, prev.DNS, net.IP.Equal) && !slices.EqualFunc(client.DNS, settings.DNS, net.IP.Equal) {
+		client.DNS = settings.DNS
+		changed = true
+	}
+
+	if slices.EqualFunc(client.AllowedIPs, prev.AllowedIPs, equalIPNet) &&
+		!slices.EqualFunc(client.AllowedIPs, settings.AllowedIPs, equalIPNet) {
+		client.AllowedIPs = settings.AllowedIPs
+		changed = true
+	}
+
+	if client.PersistentKeepalive.Equal(prev.PersistentKeepalive) &&
+		!client.PersistentKeepalive.Equal(settings.PersistentKeepalive) {
+		client.PersistentKeepalive = settings.PersistentKeepalive
+		changed = true
+	}
+
+	return changed
+}
+
+func (settings *clientSettings) apply(client *entity.WireGuardClient) {
+	client.DNS = settings.DNS
+	client.AllowedIPs = settings.AllowedIPs
+	client.PersistentKeepalive = settings.PersistentKeepalive
+}
+
+func equalIPNet(a, b net.IPNet) bool {
+	return a.IP.Equal(b.IP) && slices.Equal(a.Mask, b.Mask)
+}
+
+func (wg *WireGuardService) ApplyProfile(ctx context.Context, repo db.Repo, prev, profile *entity.WireGuardProfile,
+) (clients int, publish func(), err error) {
+	prevSettings := wg.profileSettings(prev)
+	settings := wg.profileSettings(profile)
+
+	clientRepo := repo.Interface(wg.iface).WireGuardClientRepo()
+
+	dbClients, err := clientRepo.GetWireGuardClients(ctx)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	var updated []entity.WireGuardClient
+
+	for i := range dbClients {
+		client := &dbClients[i]
+		if client.Profile != profile.Name || !settings.update(client, &prevSettings) {
+			continue
+		}
+
+		if err := clientRepo.SetWireGuardClient(ctx, client); err != nil {
+			return 0, nil, err
+		}
+
+		updated = append(updated, *client)
+	}
+
+	publish = func() {
+		for i := range updated {
+			wg.publish(event.NewClientEvent(event.PeerEdited, &updated[i]))
+		}
+	}
+
+	return len(updated), publish, nil
+}
+
+func getProfile(ctx context.Context, repo db.Repo, name string) (profile null.Value[entity.WireGuardProfile], err error) {
+	if name == "" {
+		return null.Value[entity.WireGuardProfile]{}, nil
+	}
+
+	p, err := repo.WireGuardProfileRepo().GetWireGuardProfile(ctx, name)
+	if err != nil {
+		return null.Value[entity.WireGuardProfile]{}, err
+	}
+
+	return null.ValueFrom(p), nil
+}
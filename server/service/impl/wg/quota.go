@@ -0,0 +1,137 @@
+package wgservice
+
+import (
+	"context"
+	"time"
+
+	"github.com/guregu/null/v5"
+	"github.com/infastin/wg-wish/server/entity"
+	"github.com/infastin/wg-wish/server/errors"
+	"github.com/infastin/wg-wish/server/event"
+	"github.com/infastin/wg-wish/server/repo/db"
+)
+
+// RunQuotas periodically enforces peer quotas until the context is canceled.
+func (wg *WireGuardService) RunQuotas(ctx context.Context) error {
+	ticker := time.NewTicker(wg.quotaInterval)
+	defer ticker.Stop()
+
+	for {
+		if err := wg.EnforceQuotas(ctx); err != nil {
+			if ie, ok := err.(errors.InternalError); ok {
+				err = ie.Internal()
+			}
+			wg.lg.Err(err).Msg("failed to enforce quotas")
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// EnforceQuotas accounts the traffic of the peers with a quota,
+// disables the peers that have run out of it or have expired and enables them back
+// once a new period starts. It also records the endpoints the mesh peers were last seen at.
+// The server is reloaded if any peer has changed its state.
+func (wg *WireGuardService) EnforceQuotas(ctx context.Context) (err error) {
+	peerStats, err := wg.wgRepo.GetPeerStats(ctx)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+
+	// Server peers of the clients that have changed their state are added or removed
+	// once the transaction has been committed, so that a failed one leaves them as they were.
+	var expired, toggled []entity.WireGuardClient
+
+	if err := wg.dbRepo.Update(ctx, func(repo db.Repo) error {
+		clients, err := repo.WireGuardClientRepo().GetWireGuardClients(ctx)
+		if err != nil {
+			return err
+		}
+
+		for i := range clients {
+			client := &clients[i]
+			if !client.Quota.Valid && !client.ExpiresAt.Valid && !client.Mesh {
+				continue
+			}
+
+			modified := false
+
+			if stats, ok := peerStats[client.PublicKey]; ok && client.Mesh &&
+				stats.Endpoint != "" && stats.Endpoint != client.LastEndpoint {
+				client.LastEndpoint = stats.Endpoint
+				modified = true
+			}
+
+			if client.Quota.Valid {
+				quota := client.Quota.V
+
+				if periodStart := quota.Period.Start(now); !periodStart.Equal(quota.PeriodStart) {
+					quota.PeriodStart = periodStart
+					quota.Used = 0
+				}
+
+				if stats, ok := peerStats[client.PublicKey]; ok {
+					counter := stats.Received + stats.Sent
+					if quota.LastCounter.Valid {
+						quota.Used += entity.CounterDelta(quota.LastCounter.V, counter)
+					}
+					quota.LastCounter = null.ValueFrom(counter)
+				}
+
+				modified = modified || quota != client.Quota.V
+				client.Quota.V = quota
+			}
+
+			changed := updateClientState(client, now)
+
+			if changed && client.Disabled {
+				if client.Quota.Valid {
+					// The peer's counters start from zero once it's added back.
+					client.Quota.V.LastCounter = null.ValueFrom[uint64](0)
+				}
+				expired = append(expired, *client)
+			}
+
+			if !modified && !changed {
+				continue
+			}
+
+			if err := repo.WireGuardClientRepo().SetWireGuardClient(ctx, client); err != nil {
+				return err
+			}
+
+			if changed {
+				toggled = append(toggled, *client)
+			}
+		}
+
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	if err := wg.applyClientStates(ctx, toggled); err != nil {
+		return err
+	}
+
+	for i := range expired {
+		if expired[i].Expired(now) {
+			wg.lg.Info().Str("name", expired[i].Name).Msg("peer has expired")
+		} else {
+			wg.lg.Info().Str("name", expired[i].Name).Msg("peer has run out of its quota")
+		}
+		wg.publish(event.NewClientEvent(event.PeerExpired, &expired[i]))
+	}
+
+	if len(toggled) == 0 {
+		return nil
+	}
+
+	return wg.ReloadServer(ctx)
+}
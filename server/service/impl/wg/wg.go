@@ -3,13 +3,18 @@ package wgservice
 import (
 	"bytes"
 	"context"
+	"fmt"
 	"net"
+	"strconv"
+	"time"
 
 	"github.com/guregu/null/v5"
 	"github.com/infastin/wg-wish/pkg/netutils"
 	"github.com/infastin/wg-wish/pkg/wgtypes"
 	"github.com/infastin/wg-wish/server/entity"
 	"github.com/infastin/wg-wish/server/errors"
+	"github.com/infastin/wg-wish/server/event"
+	"github.com/infastin/wg-wish/server/metrics"
 	"github.com/infastin/wg-wish/server/repo/db"
 	wireguard "github.com/infastin/wg-wish/server/repo/wg"
 	"github.com/infastin/wg-wish/server/service"
@@ -20,21 +25,39 @@ type WireGuardServiceParams struct {
 	Logger        zerolog.Logger
 	DatabaseRepo  db.Repo
 	WireGuardRepo wireguard.Repo
+	Metrics       *metrics.Metrics
+	Events        *event.Bus
+	Firewall      service.FirewallService
 
+	Interface           string
 	Host                string
 	Address             string
 	Port                int
-	Device              string
 	DNS                 []net.IP
 	AllowedIPs          []net.IPNet
 	PersistentKeepalive null.Int
+	QuotaInterval       time.Duration
+
+	// Server interface options written to the wg-quick config.
+	MTU        null.Int
+	Table      null.String
+	FwMark     null.Int
+	SaveConfig bool
+	PreUp      []string
+	PostUp     []string
+	PreDown    []string
+	PostDown   []string
 }
 
 type WireGuardService struct {
-	lg     zerolog.Logger
-	dbRepo db.Repo
-	wgRepo wireguard.Repo
+	lg       zerolog.Logger
+	dbRepo   db.Repo
+	wgRepo   wireguard.Repo
+	metrics  *metrics.Metrics
+	events   *event.Bus
+	firewall service.FirewallService
 
+	iface               string
 	publicKey           wgtypes.Key
 	address             net.IPNet
 	port                int
@@ -42,143 +65,319 @@ type WireGuardService struct {
 	dns                 []net.IP
 	allowedIPs          []net.IPNet
 	persistentKeepalive null.Int
+	quotaInterval       time.Duration
+	serverParams        wgtypes.ServerConfigParams
 	lastAddress         net.IPNet
 }
 
 func New(params *WireGuardServiceParams) (wgservice *WireGuardService, err error) {
-	var address net.IPNet
-	var lastAddress net.IPNet
-	var publicKey wgtypes.Key
+	wgservice = &WireGuardService{
+		lg:                  params.Logger,
+		dbRepo:              params.DatabaseRepo,
+		wgRepo:              params.WireGuardRepo,
+		metrics:             params.Metrics,
+		events:              params.Events,
+		firewall:            params.Firewall,
+		iface:               params.Interface,
+		publicKey:           wgtypes.Key{},
+		address:             net.IPNet{},
+		port:                params.Port,
+		host:                params.Host,
+		dns:                 params.DNS,
+		allowedIPs:          params.AllowedIPs,
+		persistentKeepalive: params.PersistentKeepalive,
+		quotaInterval:       params.QuotaInterval,
+		serverParams: wgtypes.ServerConfigParams{
+			PrivateKey: wgtypes.Key{},
+			Address:    params.Address,
+			ListenPort: null.IntFrom(int64(params.Port)),
+			MTU:        params.MTU,
+			Table:      params.Table,
+			FwMark:     params.FwMark,
+			SaveConfig: params.SaveConfig,
+			PreUp:      params.PreUp,
+			PostUp:     params.PostUp,
+			PreDown:    params.PreDown,
+			PostDown:   params.PostDown,
+		},
+		lastAddress: net.IPNet{},
+	}
 
-	ctx := context.Background()
+	if err := wgservice.SyncServer(context.Background()); err != nil {
+		return nil, err
+	}
 
-	if err := params.DatabaseRepo.Update(ctx, func(repo db.Repo) error {
-		config, err := repo.WireGuardServerRepo().GetWireGuardServerConfig()
-		if err != nil && err != errors.ErrWireGuardServerConfigNotFound {
-			return err
-		}
+	return wgservice, nil
+}
 
-		if err == errors.ErrWireGuardServerConfigNotFound {
-			config.PrivateKey, err = wgtypes.GeneratePrivateKey()
-			if err != nil {
-				return err
-			}
+// publish publishes the event on behalf of the interface.
+func (wg *WireGuardService) publish(e event.Event) {
+	e.Interface = wg.iface
+	wg.events.Publish(e)
+}
 
-			err = repo.WireGuardServerRepo().SetWireGuardServerConfig(&config)
-			if err != nil {
-				return err
-			}
-		}
+func (wg *WireGuardService) SyncServer(ctx context.Context) (err error) {
+	return wg.dbRepo.Update(ctx, func(repo db.Repo) error {
+		return wg.loadServerConfig(ctx, repo)
+	})
+}
+
+func (wg *WireGuardService) loadServerConfig(ctx context.Context, repo db.Repo) (err error) {
+	config, err := repo.WireGuardServerRepo().GetWireGuardServerConfig()
+	if err != nil && err != errors.ErrWireGuardServerConfigNotFound {
+		return err
+	}
 
-		cfg, err := wgtypes.NewServerConfig(
-			&wgtypes.ServerConfigParams{
-				PrivateKey: config.PrivateKey,
-				Address:    params.Address,
-				Device:     params.Device,
-				ListenPort: null.IntFrom(int64(params.Port)),
-			})
+	if err == errors.ErrWireGuardServerConfigNotFound {
+		config.PrivateKey, err = wgtypes.GeneratePrivateKey()
 		if err != nil {
 			return err
 		}
 
-		clients, err := repo.WireGuardClientRepo().GetWireGuardClients(ctx)
+		err = repo.WireGuardServerRepo().SetWireGuardServerConfig(&config)
 		if err != nil {
 			return err
 		}
+	}
 
-		address = cfg.Interface.Address
-		publicKey = config.PrivateKey.PublicKey()
+	serverParams := wg.serverParams
+	serverParams.PrivateKey = config.PrivateKey
 
-		lastAddress = address
-		lastAddress.Mask = net.CIDRMask(32, 32)
-		lastIP := lastAddress.IP
+	cfg, err := wgtypes.NewServerConfig(&serverParams)
+	if err != nil {
+		return err
+	}
 
-		cfg.Peers = make([]wgtypes.ServerPeer, len(clients))
-		for i := range clients {
-			clientAddress := clients[i].Address
-			if clientLastIP := netutils.LastIP(clientAddress); bytes.Compare(lastIP, clientLastIP) < 0 {
-				lastAddress, lastIP = clientAddress, clientLastIP
-			}
+	clients, err := repo.WireGuardClientRepo().GetWireGuardClients(ctx)
+	if err != nil {
+		return err
+	}
 
-			cfg.Peers[i] = wgtypes.ServerPeer{
-				Name:       clients[i].Name,
-				PublicKey:  clients[i].PublicKey,
-				AllowedIPs: []net.IPNet{clients[i].Address},
-			}
+	lastAddress := cfg.Interface.Address
+	lastAddress.Mask = net.CIDRMask(32, 32)
+	lastIP := lastAddress.IP
+
+	cfg.Peers = make([]wgtypes.ServerPeer, 0, len(clients))
+	for i := range clients {
+		clientAddress := clients[i].Address
+		if clientLastIP := netutils.LastIP(clientAddress); bytes.Compare(lastIP, clientLastIP) < 0 {
+			lastAddress, lastIP = clientAddress, clientLastIP
 		}
 
-		return params.WireGuardRepo.LoadServerConfig(ctx, &cfg)
-	}); err != nil {
-		return nil, err
+		if clients[i].Disabled {
+			continue
+		}
+
+		cfg.Peers = append(cfg.Peers, mapToServerPeer(&clients[i]))
 	}
 
-	return &WireGuardService{
-		lg:                  params.Logger,
-		dbRepo:              params.DatabaseRepo,
-		wgRepo:              params.WireGuardRepo,
-		publicKey:           publicKey,
-		address:             address,
-		port:                params.Port,
-		host:                params.Host,
-		dns:                 params.DNS,
-		allowedIPs:          params.AllowedIPs,
-		persistentKeepalive: params.PersistentKeepalive,
-		lastAddress:         lastAddress,
-	}, nil
+	err = wg.wgRepo.LoadServerConfig(ctx, &cfg)
+	if err != nil {
+		return err
+	}
+
+	wg.address = cfg.Interface.Address
+	wg.publicKey = config.PrivateKey.PublicKey()
+	wg.lastAddress = lastAddress
+
+	return nil
 }
 
 func (wg *WireGuardService) AddClient(ctx context.Context, name string, opts *service.AddClientOptions,
 ) (clientConfig wgtypes.ClientConfig, err error) {
-	var client entity.WireGuardClient
+	var (
+		client  entity.WireGuardClient
+		clients []entity.WireGuardClient
+		added   []string
+	)
 
 	if err := wg.dbRepo.Update(ctx, func(repo db.Repo) error {
-		exists, err := repo.WireGuardClientRepo().WireGuardClientExists(ctx, name)
+		client, err = wg.addClient(ctx, repo, name, opts, wg.lastAddress)
 		if err != nil {
 			return err
 		}
 
-		if exists {
-			return errors.ErrWireGuardClientExists
+		serverPeer := mapToServerPeer(&client)
+
+		err = wg.wgRepo.AddServerPeer(ctx, &serverPeer)
+		if err != nil {
+			return err
 		}
+		added = append(added, client.Name)
 
-		clientParams, err := wg.mapToAddClientParams(opts)
+		clients, err = repo.WireGuardClientRepo().GetWireGuardClients(ctx)
 		if err != nil {
 			return err
 		}
 
-		client = entity.WireGuardClient{
-			Name:                name,
-			Address:             clientParams.Address,
-			PrivateKey:          clientParams.PrivateKey,
-			PublicKey:           clientParams.PublicKey,
-			DNS:                 clientParams.DNS,
-			AllowedIPs:          clientParams.AllowedIPs,
-			PersistentKeepalive: clientParams.PersistentKeepalive,
+		wg.lastAddress = client.Address
+		return nil
+	}); err != nil {
+		wg.removeServerPeers(ctx, added)
+		return wgtypes.ClientConfig{}, err
+	}
+
+	wg.publish(event.NewClientEvent(event.PeerAdded, &client))
+
+	if client.RateLimit != 0 {
+		if err := wg.applyRateLimits(ctx); err != nil {
+			return wgtypes.ClientConfig{}, err
 		}
+	}
 
-		err = repo.WireGuardClientRepo().AddWireGuardClient(ctx, &client)
-		if err != nil {
-			return err
+	return wg.mapToClientConfig(&client, clients), nil
+}
+
+func (wg *WireGuardService) AddClients(ctx context.Context, requests []service.AddClientRequest,
+) (clientConfigs []wgtypes.ClientConfig, err error) {
+	var (
+		clients, allClients []entity.WireGuardClient
+		added               []string
+	)
+
+	if err := wg.dbRepo.Update(ctx, func(repo db.Repo) error {
+		clients = make([]entity.WireGuardClient, 0, len(requests))
+		lastAddress := wg.lastAddress
+
+		for i := range requests {
+			client, err := wg.addClient(ctx, repo, requests[i].Name, &requests[i].Options, lastAddress)
+			if err != nil {
+				return clientError(requests[i].Name, err)
+			}
+			clients = append(clients, client)
+			lastAddress = client.Address
 		}
 
-		serverPeer := wgtypes.ServerPeer{
-			Name:       name,
-			PublicKey:  client.PublicKey,
-			AllowedIPs: []net.IPNet{client.Address},
+		for i := range clients {
+			serverPeer := mapToServerPeer(&clients[i])
+			if err := wg.wgRepo.AddServerPeer(ctx, &serverPeer); err != nil {
+				return clientError(clients[i].Name, err)
+			}
+			added = append(added, clients[i].Name)
 		}
 
-		err = wg.wgRepo.AddServerPeer(ctx, &serverPeer)
+		allClients, err = repo.WireGuardClientRepo().GetWireGuardClients(ctx)
 		if err != nil {
 			return err
 		}
 
-		wg.lastAddress = client.Address
+		wg.lastAddress = lastAddress
 		return nil
 	}); err != nil {
-		return wgtypes.ClientConfig{}, err
+		wg.removeServerPeers(ctx, added)
+		return nil, err
+	}
+
+	limited := false
+
+	clientConfigs = make([]wgtypes.ClientConfig, len(clients))
+	for i := range clients {
+		wg.publish(event.NewClientEvent(event.PeerAdded, &clients[i]))
+		clientConfigs[i] = wg.mapToClientConfig(&clients[i], allClients)
+		limited = limited || clients[i].RateLimit != 0
+	}
+
+	if limited {
+		if err := wg.applyRateLimits(ctx); err != nil {
+			return nil, err
+		}
 	}
 
-	return wg.mapToClientConfig(&client), nil
+	return clientConfigs, nil
+}
+
+// removeServerPeers removes the server peers added in a transaction that has failed.
+// The database changes are rolled back along with the transaction,
+// but the peers of the interface have to be removed by hand.
+func (wg *WireGuardService) removeServerPeers(ctx context.Context, names []string) {
+	for _, name := range names {
+		if err := wg.wgRepo.RemoveServerPeer(ctx, name); err != nil {
+			wg.lg.Err(err).Str("name", name).Msg("failed to remove server peer")
+		}
+	}
+}
+
+// addClient stores a new client, whose address follows the last one unless given explicitly.
+// The server peer is left for the caller to add.
+func (wg *WireGuardService) addClient(ctx context.Context, repo db.Repo, name string, opts *service.AddClientOptions,
+	lastAddress net.IPNet,
+) (client entity.WireGuardClient, err error) {
+	exists, err := repo.WireGuardClientRepo().WireGuardClientExists(ctx, name)
+	if err != nil {
+		return entity.WireGuardClient{}, err
+	}
+
+	if exists {
+		return entity.WireGuardClient{}, errors.ErrWireGuardClientExists
+	}
+
+	if opts == nil {
+		opts = &service.AddClientOptions{}
+	}
+
+	if opts.ExpiresAt.Valid && !time.Now().Before(opts.ExpiresAt.Time) {
+		return entity.WireGuardClient{}, errors.ErrWireGuardClientExpired
+	}
+
+	for key := range opts.Labels {
+		if !entity.ValidLabelKey(key) {
+			return entity.WireGuardClient{}, errors.ErrWireGuardClientInvalidLabel
+		}
+	}
+
+	if err := checkEndpoint(opts.Endpoint); err != nil {
+		return entity.WireGuardClient{}, err
+	}
+
+	profile, err := getProfile(ctx, repo, opts.Profile)
+	if err != nil {
+		return entity.WireGuardClient{}, err
+	}
+
+	clientParams, err := wg.mapToAddClientParams(opts, profile.Ptr(), lastAddress)
+	if err != nil {
+		return entity.WireGuardClient{}, err
+	}
+
+	client = entity.WireGuardClient{
+		Name:                name,
+		Address:             clientParams.Address,
+		PrivateKey:          clientParams.PrivateKey,
+		PublicKey:           clientParams.PublicKey,
+		DNS:                 clientParams.DNS,
+		AllowedIPs:          clientParams.AllowedIPs,
+		PersistentKeepalive: clientParams.PersistentKeepalive,
+		Disabled:            false,
+		Quota:               null.Value[entity.WireGuardClientQuota]{},
+		RateLimit:           opts.RateLimit,
+		Group:               opts.Group,
+		Profile:             opts.Profile,
+		ExpiresAt:           opts.ExpiresAt,
+		Labels:              opts.Labels,
+		Description:         opts.Description,
+		Mesh:                opts.Mesh,
+		Endpoint:            opts.Endpoint,
+		LastEndpoint:        "",
+	}
+
+	if err := checkGroup(ctx, repo, client.Group, client.Address); err != nil {
+		return entity.WireGuardClient{}, err
+	}
+
+	err = repo.WireGuardClientRepo().AddWireGuardClient(ctx, &client)
+	if err != nil {
+		return entity.WireGuardClient{}, err
+	}
+
+	return client, nil
+}
+
+// clientError prefixes the domain error with the name of the client it concerns.
+func clientError(name string, err error) error {
+	if de, ok := err.(errors.DomainError); ok {
+		return errors.NewDomainError(de.Domain(), fmt.Sprintf("client %q: %s", name, de.Error()))
+	}
+	return err
 }
 
 type addClientParams struct {
@@ -190,10 +389,18 @@ type addClientParams struct {
 	PersistentKeepalive null.Int
 }
 
-func (wg *WireGuardService) mapToAddClientParams(opts *service.AddClientOptions) (params addClientParams, err error) {
-	params.PrivateKey, err = wgtypes.GeneratePrivateKey()
-	if err != nil {
-		return addClientParams{}, err
+// mapToAddClientParams fills the settings not given in the options
+// from the profile, if any, and from the server defaults.
+func (wg *WireGuardService) mapToAddClientParams(opts *service.AddClientOptions, profile *entity.WireGuardProfile,
+	lastAddress net.IPNet,
+) (params addClientParams, err error) {
+	if opts != nil && opts.PrivateKey.Valid {
+		params.PrivateKey = opts.PrivateKey.V
+	} else {
+		params.PrivateKey, err = wgtypes.GeneratePrivateKey()
+		if err != nil {
+			return addClientParams{}, err
+		}
 	}
 
 	params.PublicKey = params.PrivateKey.PublicKey()
@@ -210,64 +417,307 @@ func (wg *WireGuardService) mapToAddClientParams(opts *service.AddClientOptions)
 			return addClientParams{}, errors.ErrWireGuardClientAddressOverlaps
 		}
 	} else {
-		params.Address, err = netutils.NextAddress(wg.lastAddress)
+		params.Address, err = netutils.NextAddress(lastAddress)
 		if err != nil {
 			return addClientParams{}, err
 		}
 	}
 
+	defaults := wg.profileSettings(profile)
+
 	if opts != nil && opts.DNS != nil {
 		params.DNS = opts.DNS
 	} else {
-		params.DNS = wg.dns
+		params.DNS = defaults.DNS
 	}
 
 	if opts != nil && len(opts.AllowedIPs) != 0 {
 		params.AllowedIPs = opts.AllowedIPs
 	} else {
-		params.AllowedIPs = wg.allowedIPs
+		params.AllowedIPs = defaults.AllowedIPs
 	}
 
 	if opts != nil && opts.PersistentKeepalive.Valid {
 		params.PersistentKeepalive = opts.PersistentKeepalive
 	} else {
-		params.PersistentKeepalive = wg.persistentKeepalive
+		params.PersistentKeepalive = defaults.PersistentKeepalive
 	}
 
 	return params, nil
 }
 
 func (wg *WireGuardService) RemoveClient(ctx context.Context, name string) (err error) {
-	return wg.dbRepo.Update(ctx, func(repo db.Repo) error {
-		exists, err := repo.WireGuardClientRepo().WireGuardClientExists(ctx, name)
+	var client entity.WireGuardClient
+
+	if err := wg.dbRepo.Update(ctx, func(repo db.Repo) error {
+		client, err = repo.WireGuardClientRepo().GetWireGuardClient(ctx, name)
 		if err != nil {
 			return err
 		}
 
-		if !exists {
-			return errors.ErrWireGuardClientNotFound
-		}
-
 		err = repo.WireGuardClientRepo().RemoveWireGuardClient(ctx, name)
 		if err != nil {
 			return err
 		}
 
+		if client.Disabled {
+			return nil
+		}
+
 		return wg.wgRepo.RemoveServerPeer(ctx, name)
-	})
+	}); err != nil {
+		return err
+	}
+
+	wg.publish(event.NewClientEvent(event.PeerRemoved, &client))
+
+	if activeRateLimit(&client) != 0 {
+		return wg.applyRateLimits(ctx)
+	}
+
+	return nil
+}
+
+func (wg *WireGuardService) EditClient(ctx context.Context, name string, opts *service.EditClientOptions) (err error) {
+	if opts.Quota.Valid && opts.Quota.V.Period != "" && !opts.Quota.V.Period.Valid() {
+		return errors.ErrWireGuardClientInvalidQuota
+	}
+
+	var (
+		client           entity.WireGuardClient
+		stateChanged     bool
+		groupChanged     bool
+		rateLimitChanged bool
+	)
+
+	if err := wg.dbRepo.Update(ctx, func(repo db.Repo) error {
+		client, err = repo.WireGuardClientRepo().GetWireGuardClient(ctx, name)
+		if err != nil {
+			return err
+		}
+
+		rateLimit := activeRateLimit(&client)
+
+		switch {
+		case opts.RemoveQuota:
+			client.Quota = null.Value[entity.WireGuardClientQuota]{}
+		case opts.Quota.Valid:
+			if opts.Quota.V.Limit == 0 && !client.Quota.Valid {
+				return errors.ErrWireGuardClientNoQuota
+			}
+			client.Quota = null.ValueFrom(updateQuota(client.Quota, &opts.Quota.V, time.Now()))
+		}
+
+		if opts.RateLimit.Valid {
+			client.RateLimit = opts.RateLimit.V
+		}
+
+		if opts.Group.Valid && opts.Group.String != client.Group {
+			if err := checkGroup(ctx, repo, opts.Group.String, client.Address); err != nil {
+				return err
+			}
+			client.Group = opts.Group.String
+			groupChanged = true
+		}
+
+		if opts.Profile.Valid {
+			profile, err := getProfile(ctx, repo, opts.Profile.String)
+			if err != nil {
+				return err
+			}
+			if profile.Valid {
+				settings := wg.profileSettings(&profile.V)
+				settings.apply(&client)
+			}
+			client.Profile = opts.Profile.String
+		}
+
+		for _, key := range opts.RemoveLabels {
+			delete(client.Labels, key)
+		}
+
+		for key, value := range opts.Labels {
+			if !entity.ValidLabelKey(key) {
+				return errors.ErrWireGuardClientInvalidLabel
+			}
+			if client.Labels == nil {
+				client.Labels = make(map[string]string, len(opts.Labels))
+			}
+			client.Labels[key] = value
+		}
+
+		if opts.Description.Valid {
+			client.Description = opts.Description.String
+		}
+
+		if opts.Mesh.Valid {
+			client.Mesh = opts.Mesh.Bool
+		}
+
+		if opts.Endpoint.Valid {
+			if err := checkEndpoint(opts.Endpoint.String); err != nil {
+				return err
+			}
+			client.Endpoint = opts.Endpoint.String
+		}
+
+		stateChanged = updateClientState(&client, time.Now())
+		rateLimitChanged = activeRateLimit(&client) != rateLimit
+
+		return repo.WireGuardClientRepo().SetWireGuardClient(ctx, &client)
+	}); err != nil {
+		return err
+	}
+
+	if stateChanged {
+		if err := wg.applyClientStates(ctx, []entity.WireGuardClient{client}); err != nil {
+			return err
+		}
+	}
+
+	wg.publish(event.NewClientEvent(event.PeerEdited, &client))
+
+	if groupChanged {
+		if err := wg.firewall.Apply(ctx); err != nil {
+			return err
+		}
+	}
+
+	if rateLimitChanged {
+		return wg.applyRateLimits(ctx)
+	}
+
+	return nil
+}
+
+// checkGroup checks that the group exists and can be assigned to the client with the address.
+// Firewall rules only match IPv4 traffic, so the members of a group must have IPv4 addresses.
+func checkGroup(ctx context.Context, repo db.Repo, group string, address net.IPNet) (err error) {
+	if group == "" {
+		return nil
+	}
+
+	if address.IP.To4() == nil {
+		return errors.ErrFirewallGroupNotIPv4
+	}
+
+	exists, err := repo.FirewallGroupRepo().FirewallGroupExists(ctx, group)
+	if err != nil {
+		return err
+	}
+
+	if !exists {
+		return errors.ErrFirewallGroupNotFound
+	}
+
+	return nil
+}
+
+// checkEndpoint checks that the mesh endpoint, if any, is a host and a valid port.
+func checkEndpoint(endpoint string) error {
+	if endpoint == "" {
+		return nil
+	}
+
+	host, port, err := net.SplitHostPort(endpoint)
+	if err != nil || host == "" {
+		return errors.ErrWireGuardClientInvalidEndpoint
+	}
+
+	if n, err := strconv.Atoi(port); err != nil || n <= 0 || n > 65535 {
+		return errors.ErrWireGuardClientInvalidEndpoint
+	}
+
+	return nil
+}
+
+// updateQuota applies the new limit and period to the quota.
+// The traffic used so far is kept as long as the current period doesn't change.
+func updateQuota(quota null.Value[entity.WireGuardClientQuota], opts *service.ClientQuotaOptions, now time.Time,
+) entity.WireGuardClientQuota {
+	limit, period := opts.Limit, opts.Period
+	if limit == 0 {
+		limit = quota.V.Limit
+	}
+	if period == "" {
+		period = entity.QuotaPeriodMonthly
+		if quota.Valid {
+			period = quota.V.Period
+		}
+	}
+
+	periodStart := period.Start(now)
+
+	if quota.Valid && quota.V.Period == period && quota.V.PeriodStart.Equal(periodStart) {
+		quota.V.Limit = limit
+		return quota.V
+	}
+
+	return entity.WireGuardClientQuota{
+		Limit:       limit,
+		Period:      period,
+		PeriodStart: periodStart,
+		Used:        0,
+		LastCounter: quota.V.LastCounter,
+	}
+}
+
+// updateClientState disables the client if it has run out of its quota or has expired
+// and enables it back otherwise. It reports whether the state has changed.
+// The server peer is left for the caller to add or remove with applyClientStates
+// once the change has been stored.
+func updateClientState(client *entity.WireGuardClient, now time.Time) (changed bool) {
+	disabled := (client.Quota.Valid && client.Quota.V.Exceeded()) || client.Expired(now)
+	if disabled == client.Disabled {
+		return false
+	}
+
+	client.Disabled = disabled
+	return true
+}
+
+// applyClientStates adds the server peers of the enabled clients and removes those of the disabled ones.
+func (wg *WireGuardService) applyClientStates(ctx context.Context, clients []entity.WireGuardClient) (err error) {
+	for i := range clients {
+		if clients[i].Disabled {
+			err = wg.wgRepo.RemoveServerPeer(ctx, clients[i].Name)
+		} else {
+			serverPeer := mapToServerPeer(&clients[i])
+			err = wg.wgRepo.AddServerPeer(ctx, &serverPeer)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func mapToServerPeer(client *entity.WireGuardClient) wgtypes.ServerPeer {
+	return wgtypes.ServerPeer{
+		Name:       client.Name,
+		PublicKey:  client.PublicKey,
+		AllowedIPs: []net.IPNet{client.Address},
+	}
 }
 
 func (wg *WireGuardService) GetClient(ctx context.Context, name string) (client wgtypes.ClientConfig, err error) {
-	var dbClient entity.WireGuardClient
+	var (
+		dbClient  entity.WireGuardClient
+		dbClients []entity.WireGuardClient
+	)
 
 	if err := wg.dbRepo.View(ctx, func(repo db.Repo) error {
 		dbClient, err = repo.WireGuardClientRepo().GetWireGuardClient(ctx, name)
+		if err != nil || !dbClient.Mesh {
+			return err
+		}
+		dbClients, err = repo.WireGuardClientRepo().GetWireGuardClients(ctx)
 		return err
 	}); err != nil {
 		return wgtypes.ClientConfig{}, err
 	}
 
-	return wg.mapToClientConfig(&dbClient), nil
+	return wg.mapToClientConfig(&dbClient, dbClients), nil
 }
 
 func (wg *WireGuardService) GetClientInfos(ctx context.Context) (clients []entity.WireGuardClientInfo, err error) {
@@ -290,7 +740,18 @@ func (wg *WireGuardService) GetClientInfos(ctx context.Context) (clients []entit
 
 	clients = make([]entity.WireGuardClientInfo, len(dbClients))
 	for i := range dbClients {
-		clients[i].Config = wg.mapToClientConfig(&dbClients[i])
+		clients[i].Config = wg.mapToClientConfig(&dbClients[i], dbClients)
+		clients[i].Disabled = dbClients[i].Disabled
+		clients[i].Quota = dbClients[i].Quota
+		clients[i].RateLimit = dbClients[i].RateLimit
+		clients[i].Group = dbClients[i].Group
+		clients[i].Profile = dbClients[i].Profile
+		clients[i].ExpiresAt = dbClients[i].ExpiresAt
+		clients[i].Labels = dbClients[i].Labels
+		clients[i].Description = dbClients[i].Description
+		clients[i].Mesh = dbClients[i].Mesh
+		clients[i].Endpoint = dbClients[i].Endpoint
+		clients[i].LastEndpoint = dbClients[i].LastEndpoint
 		if stats, ok := peerStats[dbClients[i].PublicKey]; ok {
 			clients[i].Stats = null.ValueFrom(stats)
 		}
@@ -299,39 +760,282 @@ func (wg *WireGuardService) GetClientInfos(ctx context.Context) (clients []entit
 	return clients, nil
 }
 
-func (wg *WireGuardService) mapToClientConfig(client *entity.WireGuardClient) wgtypes.ClientConfig {
-	return wgtypes.ClientConfig{
+func (wg *WireGuardService) FindClient(ctx context.Context, opts *service.FindClientOptions,
+) (client entity.WireGuardClientInfo, err error) {
+	var (
+		dbClient  entity.WireGuardClient
+		dbClients []entity.WireGuardClient
+	)
+
+	if err := wg.dbRepo.View(ctx, func(repo db.Repo) error {
+		switch {
+		case opts.PublicKey.Valid:
+			dbClient, err = repo.WireGuardClientRepo().GetWireGuardClientByPublicKey(ctx, opts.PublicKey.V)
+		case opts.Address.Valid:
+			dbClient, err = repo.WireGuardClientRepo().GetWireGuardClientByAddress(ctx, opts.Address.V)
+		default:
+			err = errors.ErrWireGuardClientNotFound
+		}
+		if err != nil || !dbClient.Mesh {
+			return err
+		}
+		dbClients, err = repo.WireGuardClientRepo().GetWireGuardClients(ctx)
+		return err
+	}); err != nil {
+		return entity.WireGuardClientInfo{}, err
+	}
+
+	client.Config = wg.mapToClientConfig(&dbClient, dbClients)
+	client.Disabled = dbClient.Disabled
+	client.Quota = dbClient.Quota
+	client.RateLimit = dbClient.RateLimit
+	client.Group = dbClient.Group
+	client.Profile = dbClient.Profile
+	client.ExpiresAt = dbClient.ExpiresAt
+	client.Labels = dbClient.Labels
+	client.Description = dbClient.Description
+	client.Mesh = dbClient.Mesh
+	client.Endpoint = dbClient.Endpoint
+	client.LastEndpoint = dbClient.LastEndpoint
+
+	peerStats, err := wg.wgRepo.GetPeerStats(ctx)
+	if err != nil {
+		if ie, ok := err.(errors.InternalError); ok {
+			err = ie.Internal()
+		}
+		wg.lg.Err(err).Msg("failed to get peer stats")
+	}
+
+	if stats, ok := peerStats[dbClient.PublicKey]; ok {
+		client.Stats = null.ValueFrom(stats)
+	}
+
+	return client, nil
+}
+
+// mapToClientConfig makes the config of the client, which peers with the server
+// and, if it's a mesh client, with the other enabled mesh clients among the given ones.
+func (wg *WireGuardService) mapToClientConfig(client *entity.WireGuardClient, clients []entity.WireGuardClient,
+) wgtypes.ClientConfig {
+	config := wgtypes.ClientConfig{
 		Interface: wgtypes.ClientInterface{
 			Name:       client.Name,
 			Address:    client.Address,
 			PrivateKey: client.PrivateKey,
 			DNS:        client.DNS,
 		},
-		Peer: wgtypes.ClientPeer{
+		Peers: []wgtypes.ClientPeer{{
 			Name:                "Server",
 			EndpointHost:        wg.host,
 			EndpointPort:        wg.port,
 			PublicKey:           wg.publicKey,
 			AllowedIPs:          client.AllowedIPs,
 			PersistentKeepalive: client.PersistentKeepalive,
-		},
+		}},
+	}
+
+	if !client.Mesh {
+		return config
+	}
+
+	// Listen on the announced port, so that the other mesh clients can reach the client there.
+	if _, port, err := net.SplitHostPort(client.Endpoint); err == nil {
+		if port, err := strconv.Atoi(port); err == nil {
+			config.Interface.ListenPort = null.IntFrom(int64(port))
+		}
+	}
+
+	for i := range clients {
+		other := &clients[i]
+		if !other.Mesh || other.Disabled || other.Name == client.Name {
+			continue
+		}
+
+		// Either side has to know where to find the other one to make the first handshake.
+		endpoint := other.MeshEndpoint()
+		if endpoint == "" && client.MeshEndpoint() == "" {
+			continue
+		}
+
+		peer := wgtypes.ClientPeer{
+			Name:                other.Name,
+			PublicKey:           other.PublicKey,
+			AllowedIPs:          []net.IPNet{other.Address},
+			PersistentKeepalive: client.PersistentKeepalive,
+		}
+
+		if host, port, err := net.SplitHostPort(endpoint); err == nil {
+			peer.EndpointHost = host
+			peer.EndpointPort, _ = strconv.Atoi(port)
+		}
+
+		config.Peers = append(config.Peers, peer)
+	}
+
+	return config
+}
+
+func (wg *WireGuardService) GetServerInfo(ctx context.Context) (info entity.WireGuardServerInfo, err error) {
+	var clients []entity.WireGuardClient
+
+	if err := wg.dbRepo.View(ctx, func(repo db.Repo) error {
+		clients, err = repo.WireGuardClientRepo().GetWireGuardClients(ctx)
+		return err
+	}); err != nil {
+		return entity.WireGuardServerInfo{}, err
+	}
+
+	subnet := net.IPNet{
+		IP:   wg.address.IP.Mask(wg.address.Mask),
+		Mask: wg.address.Mask,
+	}
+
+	info = entity.WireGuardServerInfo{
+		Interface:       wg.iface,
+		Host:            wg.host,
+		Port:            wg.port,
+		Address:         wg.address,
+		PublicKey:       wg.publicKey,
+		Clients:         len(clients),
+		AddressPoolSize: netutils.HostCount(subnet) - 1,
+		AddressPoolUsed: 0,
+	}
+
+	for i := range clients {
+		if subnet.Contains(clients[i].Address.IP) {
+			info.AddressPoolUsed++
+		}
+	}
+
+	return info, nil
+}
+
+// LintConfigs validates the server config as it would be generated on reload and every client config.
+func (wg *WireGuardService) LintConfigs(ctx context.Context) (issues []entity.WireGuardConfigIssue, err error) {
+	var (
+		config  entity.WireGuardServerConfig
+		clients []entity.WireGuardClient
+	)
+
+	if err := wg.dbRepo.View(ctx, func(repo db.Repo) error {
+		config, err = repo.WireGuardServerRepo().GetWireGuardServerConfig()
+		if err != nil {
+			return err
+		}
+		clients, err = repo.WireGuardClientRepo().GetWireGuardClients(ctx)
+		return err
+	}); err != nil {
+		return nil, err
 	}
+
+	serverParams := wg.serverParams
+	serverParams.PrivateKey = config.PrivateKey
+
+	serverConfig, err := wgtypes.NewServerConfig(&serverParams)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range clients {
+		if !clients[i].Disabled {
+			serverConfig.Peers = append(serverConfig.Peers, mapToServerPeer(&clients[i]))
+		}
+	}
+
+	for _, issue := range serverConfig.Validate() {
+		issues = append(issues, entity.WireGuardConfigIssue{Config: "", Issue: issue})
+	}
+
+	for i := range clients {
+		clientConfig := wg.mapToClientConfig(&clients[i], clients)
+		for _, issue := range clientConfig.Validate() {
+			issues = append(issues, entity.WireGuardConfigIssue{Config: clients[i].Name, Issue: issue})
+		}
+	}
+
+	return issues, nil
 }
 
 func (wg *WireGuardService) StartServer(ctx context.Context) (err error) {
 	if err := wg.wgRepo.WriteServerConfig(ctx); err != nil {
 		return err
 	}
-	return wg.wgRepo.StartServer(ctx)
+	if err := wg.wgRepo.StartServer(ctx); err != nil {
+		return err
+	}
+	if err := wg.firewall.Apply(ctx); err != nil {
+		// Don't leave the server running without its firewall rules.
+		if err := wg.StopServer(ctx); err != nil {
+			wg.lg.Err(err).Msg("failed to stop wireguard server")
+		}
+		return err
+	}
+	return wg.applyRateLimits(ctx)
 }
 
 func (wg *WireGuardService) StopServer(ctx context.Context) (err error) {
+	if err := wg.firewall.Cleanup(ctx); err != nil {
+		wg.lg.Err(err).Msg("failed to clean up firewall rules")
+	}
 	return wg.wgRepo.StopServer(ctx)
 }
 
 func (wg *WireGuardService) ReloadServer(ctx context.Context) (err error) {
+	start := time.Now()
+	defer func() {
+		wg.metrics.ObserveReload(time.Since(start), err)
+	}()
+
 	if err := wg.wgRepo.WriteServerConfig(ctx); err != nil {
 		return err
 	}
-	return wg.wgRepo.ReloadServer(ctx)
+
+	if err := wg.wgRepo.ReloadServer(ctx); err != nil {
+		return err
+	}
+
+	if err := wg.firewall.Apply(ctx); err != nil {
+		return err
+	}
+
+	if err := wg.applyRateLimits(ctx); err != nil {
+		return err
+	}
+
+	wg.publish(event.New(event.ServerReloaded))
+	return nil
+}
+
+// activeRateLimit returns the bandwidth limit the client is shaped with,
+// zero if it has none or is disabled.
+func activeRateLimit(client *entity.WireGuardClient) uint64 {
+	if client.Disabled {
+		return 0
+	}
+	return client.RateLimit
+}
+
+// applyRateLimits sets up bandwidth limits of the enabled clients.
+func (wg *WireGuardService) applyRateLimits(ctx context.Context) (err error) {
+	var clients []entity.WireGuardClient
+
+	if err := wg.dbRepo.View(ctx, func(repo db.Repo) error {
+		clients, err = repo.WireGuardClientRepo().GetWireGuardClients(ctx)
+		return err
+	}); err != nil {
+		return err
+	}
+
+	var limits []entity.WireGuardRateLimit
+	for i := range clients {
+		if activeRateLimit(&clients[i]) == 0 {
+			continue
+		}
+		limits = append(limits, entity.WireGuardRateLimit{
+			Address: clients[i].Address,
+			Rate:    clients[i].RateLimit,
+		})
+	}
+
+	return wg.wgRepo.SetRateLimits(ctx, limits)
 }
@@ -0,0 +1,415 @@
+package wgservice_test
+
+import (
+	"context"
+	"net"
+	"path/filepath"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/guregu/null/v5"
+	"github.com/infastin/wg-wish/pkg/wgtypes"
+	"github.com/infastin/wg-wish/server/entity"
+	"github.com/infastin/wg-wish/server/errors"
+	"github.com/infastin/wg-wish/server/repo/db"
+	dbrepo "github.com/infastin/wg-wish/server/repo/db/impl"
+	"github.com/infastin/wg-wish/server/service"
+	wgservice "github.com/infastin/wg-wish/server/service/impl/wg"
+	"github.com/rs/zerolog"
+)
+
+var errWriteFailed = errors.NewDomainError("test", "write failed")
+
+// fakeWireGuardRepo keeps the server config in memory instead of touching the interface.
+type fakeWireGuardRepo struct {
+	mu     sync.Mutex
+	config wgtypes.ServerConfig
+	stats  map[wgtypes.Key]entity.WireGuardPeerStats
+	limits []entity.WireGuardRateLimit
+}
+
+func (wg *fakeWireGuardRepo) LoadServerConfig(ctx context.Context, config *wgtypes.ServerConfig) (err error) {
+	wg.mu.Lock()
+	defer wg.mu.Unlock()
+	wg.config = *config
+	wg.config.Peers = slices.Clone(config.Peers)
+	return nil
+}
+
+func (wg *fakeWireGuardRepo) WriteServerConfig(ctx context.Context) (err error) {
+	return nil
+}
+
+func (wg *fakeWireGuardRepo) AddServerPeer(ctx context.Context, peer *wgtypes.ServerPeer) (err error) {
+	wg.mu.Lock()
+	defer wg.mu.Unlock()
+
+	for i := range wg.config.Peers {
+		if wg.config.Peers[i].Name == peer.Name {
+			return errors.ErrWireGuardServerPeerExists
+		}
+	}
+
+	wg.config.Peers = append(wg.config.Peers, *peer)
+	return nil
+}
+
+func (wg *fakeWireGuardRepo) RemoveServerPeer(ctx context.Context, name string) (err error) {
+	wg.mu.Lock()
+	defer wg.mu.Unlock()
+
+	for i := range wg.config.Peers {
+		if wg.config.Peers[i].Name == name {
+			wg.config.Peers = slices.Delete(wg.config.Peers, i, i+1)
+			return nil
+		}
+	}
+
+	return errors.ErrWireGuardServerPeerNotFound
+}
+
+func (wg *fakeWireGuardRepo) GetPeerStats(ctx context.Context) (stats map[wgtypes.Key]entity.WireGuardPeerStats, err error) {
+	wg.mu.Lock()
+	defer wg.mu.Unlock()
+	return wg.stats, nil
+}
+
+func (wg *fakeWireGuardRepo) StartServer(ctx context.Context) (err error)  { return nil }
+func (wg *fakeWireGuardRepo) StopServer(ctx context.Context) (err error)   { return nil }
+func (wg *fakeWireGuardRepo) ReloadServer(ctx context.Context) (err error) { return nil }
+
+func (wg *fakeWireGuardRepo) SetRateLimits(ctx context.Context, limits []entity.WireGuardRateLimit) (err error) {
+	wg.mu.Lock()
+	defer wg.mu.Unlock()
+	wg.limits = limits
+	return nil
+}
+
+// peers returns the names of the server peers.
+func (wg *fakeWireGuardRepo) peers() []string {
+	wg.mu.Lock()
+	defer wg.mu.Unlock()
+
+	names := make([]string, len(wg.config.Peers))
+	for i := range wg.config.Peers {
+		names[i] = wg.config.Peers[i].Name
+	}
+	slices.Sort(names)
+
+	return names
+}
+
+// failingRepo wraps the database, so that client writes or commits can be made to fail.
+type failingRepo struct {
+	db.Repo
+	failures *failures
+}
+
+type failures struct {
+	setClient bool
+	commit    bool
+}
+
+func (r *failingRepo) Update(ctx context.Context, cb db.AtomicCallback) (err error) {
+	return r.Repo.Update(ctx, func(repo db.Repo) error {
+		if err := cb(&failingRepo{Repo: repo, failures: r.failures}); err != nil {
+			return err
+		}
+		if r.failures.commit {
+			return errWriteFailed
+		}
+		return nil
+	})
+}
+
+func (r *failingRepo) View(ctx context.Context, cb db.AtomicCallback) (err error) {
+	return r.Repo.View(ctx, func(repo db.Repo) error {
+		return cb(&failingRepo{Repo: repo, failures: r.failures})
+	})
+}
+
+func (r *failingRepo) Interface(name string) db.Repo {
+	return &failingRepo{Repo: r.Repo.Interface(name), failures: r.failures}
+}
+
+func (r *failingRepo) WireGuardClientRepo() db.WireGuardClientRepo {
+	return &failingClientRepo{WireGuardClientRepo: r.Repo.WireGuardClientRepo(), failures: r.failures}
+}
+
+type failingClientRepo struct {
+	db.WireGuardClientRepo
+	failures *failures
+}
+
+func (r *failingClientRepo) SetWireGuardClient(ctx context.Context, client *entity.WireGuardClient) (err error) {
+	if r.failures.setClient {
+		return errWriteFailed
+	}
+	return r.WireGuardClientRepo.SetWireGuardClient(ctx, client)
+}
+
+type fakeFirewall struct {
+	service.FirewallService
+}
+
+func (fakeFirewall) Apply(ctx context.Context) (err error) {
+	return nil
+}
+
+type testService struct {
+	*wgservice.WireGuardService
+	db       db.Repo
+	wg       *fakeWireGuardRepo
+	failures *failures
+}
+
+// newService starts the service of wg0 with the given clients already stored.
+func newService(t *testing.T, clients ...entity.WireGuardClient) *testService {
+	t.Helper()
+
+	repo, err := dbrepo.New(&dbrepo.DatabaseRepoParams{
+		Logger:     zerolog.Nop(),
+		Path:       filepath.Join(t.TempDir(), "wg-wish.db"),
+		AdminKeys:  nil,
+		KMS:        nil,
+		Interfaces: []string{"wg0"},
+		ReadOnly:   false,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = repo.Close() })
+
+	wg0 := repo.Interface("wg0")
+
+	if err := wg0.Update(context.Background(), func(repo db.Repo) error {
+		for i := range clients {
+			if err := repo.WireGuardClientRepo().AddWireGuardClient(context.Background(), &clients[i]); err != nil {
+				return err
+			}
+		}
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	ts := &testService{
+		WireGuardService: nil,
+		db:               wg0,
+		wg:               &fakeWireGuardRepo{}, //nolint:exhaustruct
+		failures:         &failures{},          //nolint:exhaustruct
+	}
+
+	ts.WireGuardService, err = wgservice.New(&wgservice.WireGuardServiceParams{ //nolint:exhaustruct
+		Logger:        zerolog.Nop(),
+		DatabaseRepo:  &failingRepo{Repo: wg0, failures: ts.failures},
+		WireGuardRepo: ts.wg,
+		Metrics:       nil,
+		Events:        nil,
+		Firewall:      fakeFirewall{}, //nolint:exhaustruct
+		Interface:     "wg0",
+		Host:          "vpn.example.com",
+		Address:       "10.0.0.1/24",
+		Port:          51820,
+		AllowedIPs:    []net.IPNet{{IP: net.IPv4zero.To4(), Mask: net.CIDRMask(0, 32)}},
+		QuotaInterval: time.Minute,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return ts
+}
+
+// client returns the stored client.
+func (ts *testService) client(t *testing.T, name string) (client entity.WireGuardClient) {
+	t.Helper()
+
+	if err := ts.db.View(context.Background(), func(repo db.Repo) (err error) {
+		client, err = repo.WireGuardClientRepo().GetWireGuardClient(context.Background(), name)
+		return err
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	return client
+}
+
+func expectPeers(t *testing.T, ts *testService, want ...string) {
+	t.Helper()
+
+	if got := ts.wg.peers(); !slices.Equal(got, want) {
+		t.Fatalf("expected server peers %v, got %v", want, got)
+	}
+}
+
+func newClient(t *testing.T, name, address string) entity.WireGuardClient {
+	t.Helper()
+
+	privateKey, err := wgtypes.GeneratePrivateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ip, ipNet, err := net.ParseCIDR(address)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ip4 := ip.To4(); ip4 != nil {
+		ip = ip4
+	}
+	ipNet.IP = ip
+
+	return entity.WireGuardClient{ //nolint:exhaustruct
+		Name:       name,
+		Address:    *ipNet,
+		PrivateKey: privateKey,
+		PublicKey:  privateKey.PublicKey(),
+		AllowedIPs: []net.IPNet{{IP: net.IPv4zero.To4(), Mask: net.CIDRMask(0, 32)}},
+	}
+}
+
+// exhaustedClient returns a disabled client that has run out of its quota.
+func exhaustedClient(t *testing.T, name, address string) entity.WireGuardClient {
+	t.Helper()
+
+	client := newClient(t, name, address)
+	client.Disabled = true
+	client.Quota = null.ValueFrom(entity.WireGuardClientQuota{
+		Limit:       100,
+		Period:      entity.QuotaPeriodMonthly,
+		PeriodStart: entity.QuotaPeriodMonthly.Start(time.Now()),
+		Used:        100,
+		LastCounter: null.ValueFrom[uint64](0),
+	})
+
+	return client
+}
+
+func TestEditClientStateRollback(t *testing.T) {
+	ctx := context.Background()
+	ts := newService(t, exhaustedClient(t, "alice", "10.0.0.2/32"))
+
+	expectPeers(t, ts)
+
+	// Removing the quota enables the client, but storing it fails.
+	ts.failures.setClient = true
+
+	err := ts.EditClient(ctx, "alice", &service.EditClientOptions{RemoveQuota: true}) //nolint:exhaustruct
+	if err != errWriteFailed {
+		t.Fatalf("expected %v, got %v", errWriteFailed, err)
+	}
+
+	expectPeers(t, ts)
+	if client := ts.client(t, "alice"); !client.Disabled || !client.Quota.Valid {
+		t.Fatalf("expected the client to stay disabled, got %+v", client)
+	}
+
+	ts.failures.setClient = false
+
+	err = ts.EditClient(ctx, "alice", &service.EditClientOptions{RemoveQuota: true}) //nolint:exhaustruct
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expectPeers(t, ts, "alice")
+	if client := ts.client(t, "alice"); client.Disabled || client.Quota.Valid {
+		t.Fatalf("expected the client to be enabled without a quota, got %+v", client)
+	}
+}
+
+func TestEnforceQuotasStateRollback(t *testing.T) {
+	ctx := context.Background()
+
+	alice := exhaustedClient(t, "alice", "10.0.0.2/32")
+	alice.Disabled = false
+	alice.Quota.V.Used = 0
+
+	ts := newService(t, alice)
+	expectPeers(t, ts, "alice")
+
+	// Traffic above the limit disables the client, but storing it fails.
+	ts.wg.stats = map[wgtypes.Key]entity.WireGuardPeerStats{
+		alice.PublicKey: {Received: 150, Sent: 50, LatestHandshake: null.TimeFrom(time.Now()), Endpoint: ""},
+	}
+	ts.failures.setClient = true
+
+	if err := ts.EnforceQuotas(ctx); err != errWriteFailed {
+		t.Fatalf("expected %v, got %v", errWriteFailed, err)
+	}
+
+	expectPeers(t, ts, "alice")
+	if client := ts.client(t, "alice"); client.Disabled || client.Quota.V.Used != 0 {
+		t.Fatalf("expected the client to stay enabled, got %+v", client)
+	}
+
+	ts.failures.setClient = false
+
+	if err := ts.EnforceQuotas(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	expectPeers(t, ts)
+	if client := ts.client(t, "alice"); !client.Disabled || client.Quota.V.Used != 200 {
+		t.Fatalf("expected the client to be disabled after using 200 bytes, got %+v", client)
+	}
+}
+
+func TestGroupRequiresIPv4(t *testing.T) {
+	ctx := context.Background()
+	ts := newService(t, newClient(t, "alice", "10.0.0.2/32"), newClient(t, "bob", "fd00::2/128"))
+
+	if err := ts.db.Update(ctx, func(repo db.Repo) error {
+		return repo.FirewallGroupRepo().AddFirewallGroup(ctx, &entity.FirewallGroup{
+			Name:       "office",
+			AllowPeers: false,
+			Rules:      nil,
+		})
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	err := ts.EditClient(ctx, "bob", &service.EditClientOptions{Group: null.StringFrom("office")}) //nolint:exhaustruct
+	if err != errors.ErrFirewallGroupNotIPv4 {
+		t.Fatalf("expected %v, got %v", errors.ErrFirewallGroupNotIPv4, err)
+	}
+
+	err = ts.EditClient(ctx, "alice", &service.EditClientOptions{Group: null.StringFrom("office")}) //nolint:exhaustruct
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestAddClientPeersRollback(t *testing.T) {
+	ctx := context.Background()
+	ts := newService(t)
+
+	// The peers are added inside the transaction, but committing it fails.
+	ts.failures.commit = true
+
+	if _, err := ts.AddClient(ctx, "alice", nil); err != errWriteFailed {
+		t.Fatalf("expected %v, got %v", errWriteFailed, err)
+	}
+
+	expectPeers(t, ts)
+
+	_, err := ts.AddClients(ctx, []service.AddClientRequest{
+		{Name: "bob", Options: service.AddClientOptions{}},   //nolint:exhaustruct
+		{Name: "carol", Options: service.AddClientOptions{}}, //nolint:exhaustruct
+	})
+	if err != errWriteFailed {
+		t.Fatalf("expected %v, got %v", errWriteFailed, err)
+	}
+
+	expectPeers(t, ts)
+
+	ts.failures.commit = false
+
+	if _, err := ts.AddClient(ctx, "alice", nil); err != nil {
+		t.Fatal(err)
+	}
+
+	expectPeers(t, ts, "alice")
+}
@@ -0,0 +1,16 @@
+package service
+
+import (
+	"context"
+
+	"github.com/infastin/wg-wish/server/entity"
+)
+
+type ProfileService interface {
+	AddProfile(ctx context.Context, profile *entity.WireGuardProfile) (err error)
+	// SetProfile replaces the profile's settings and propagates them to the clients following it
+	// on every interface, returning the number of updated clients.
+	SetProfile(ctx context.Context, profile *entity.WireGuardProfile) (clients int, err error)
+	RemoveProfile(ctx context.Context, name string) (err error)
+	GetProfiles(ctx context.Context) (profiles []entity.WireGuardProfile, err error)
+}
@@ -0,0 +1,17 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/infastin/wg-wish/server/entity"
+)
+
+type GetTrafficStatsOptions struct {
+	Since    time.Duration
+	Interval time.Duration
+}
+
+type StatsService interface {
+	GetTrafficStats(ctx context.Context, name string, opts *GetTrafficStatsOptions) (stats entity.TrafficStats, err error)
+}
@@ -3,10 +3,12 @@ package service
 import (
 	"context"
 	"net"
+	"time"
 
 	"github.com/guregu/null/v5"
 	"github.com/infastin/wg-wish/pkg/wgtypes"
 	"github.com/infastin/wg-wish/server/entity"
+	"github.com/infastin/wg-wish/server/repo/db"
 )
 
 type AddClientOptions struct {
@@ -14,12 +16,108 @@ type AddClientOptions struct {
 	DNS                 []net.IP
 	AllowedIPs          []net.IPNet
 	PersistentKeepalive null.Int
+	RateLimit           uint64
+	Group               string
+	// Profile is the name of the profile to follow.
+	// Its settings are used in place of the server defaults.
+	Profile string
+	// ExpiresAt is the time the client gets disabled at.
+	ExpiresAt   null.Time
+	Labels      map[string]string
+	Description string
+	// Mesh makes the client peer directly with the other mesh clients.
+	Mesh bool
+	// Endpoint is the host:port the other mesh clients reach the client at.
+	Endpoint string
+	// PrivateKey is the client's key, a new one is generated if not given.
+	// Lets the same identity be used on several servers.
+	PrivateKey null.Value[wgtypes.Key]
+}
+
+type AddClientRequest struct {
+	Name    string
+	Options AddClientOptions
+}
+
+// ClientQuotaOptions change the client's quota.
+// Zero limit keeps the current one and requires the client to have a quota.
+// Empty period keeps the current one, new quotas are monthly by default.
+type ClientQuotaOptions struct {
+	Limit  uint64
+	Period entity.QuotaPeriod
+}
+
+type EditClientOptions struct {
+	Quota       null.Value[ClientQuotaOptions]
+	RemoveQuota bool
+	// RateLimit is the new bandwidth limit in bits per second, zero removes the limit.
+	RateLimit null.Value[uint64]
+	// Group is the new firewall group, empty string removes the client from its group.
+	Group null.String
+	// Profile is the new profile to follow, whose settings are applied to the client.
+	// Empty string stops following the profile, keeping the settings.
+	Profile null.String
+	// Labels are added to the client, replacing the values of the existing ones.
+	Labels map[string]string
+	// RemoveLabels are the keys of the labels to remove from the client.
+	RemoveLabels []string
+	// Description is the new description, empty string removes it.
+	Description null.String
+	Mesh        null.Bool
+	// Endpoint is the new mesh endpoint, empty string removes it.
+	Endpoint null.String
+}
+
+type ClientSort string
+
+const (
+	ClientSortName    ClientSort = "name"
+	ClientSortAddress ClientSort = "address"
+	// ClientSortTraffic puts the clients with the most traffic first.
+	ClientSortTraffic ClientSort = "traffic"
+	// ClientSortHandshake puts the clients with the latest handshake first.
+	ClientSortHandshake ClientSort = "handshake"
+)
+
+type ListClientsOptions struct {
+	// Labels the clients must have. A null value matches any value of the label.
+	Labels map[string]null.String
+	// Online lists either only online or only offline clients.
+	Online null.Bool
+	// Stale lists only the clients without a handshake for at least the duration, zero disables the filter.
+	Stale time.Duration
+	// Name is a glob pattern the names of the clients must match, in path.Match syntax.
+	Name string
+	// Sort is the order of the clients, by name if empty.
+	Sort ClientSort
+	// Limit is the maximum number of clients to list, zero means no limit.
+	Limit int
+}
+
+type FindClientOptions struct {
+	PublicKey null.Value[wgtypes.Key]
+	Address   null.Value[net.IP]
 }
 
 type WireGuardService interface {
 	AddClient(ctx context.Context, name string, opts *AddClientOptions) (client wgtypes.ClientConfig, err error)
+	// AddClients adds all of the clients at once. If any of them can't be added, none are.
+	AddClients(ctx context.Context, requests []AddClientRequest) (clients []wgtypes.ClientConfig, err error)
 	RemoveClient(ctx context.Context, name string) (err error)
+	EditClient(ctx context.Context, name string, opts *EditClientOptions) (err error)
 	GetClient(ctx context.Context, name string) (client wgtypes.ClientConfig, err error)
 	GetClientInfos(ctx context.Context) (clients []entity.WireGuardClientInfo, err error)
+	// ListClients returns the infos of the clients matching the options.
+	ListClients(ctx context.Context, opts *ListClientsOptions) (clients []entity.WireGuardClientInfo, err error)
+	FindClient(ctx context.Context, opts *FindClientOptions) (client entity.WireGuardClientInfo, err error)
+	GetServerInfo(ctx context.Context) (info entity.WireGuardServerInfo, err error)
 	ReloadServer(ctx context.Context) (err error)
+	SyncServer(ctx context.Context) (err error)
+	LintConfigs(ctx context.Context) (issues []entity.WireGuardConfigIssue, err error)
+	// ApplyProfile updates within the transaction of the repo the settings of the clients
+	// following the profile, which still match the previous version of it,
+	// returning the number of updated clients. The returned function publishes the changes
+	// and must be called once the transaction has been committed.
+	ApplyProfile(ctx context.Context, repo db.Repo, prev, profile *entity.WireGuardProfile,
+	) (clients int, publish func(), err error)
 }
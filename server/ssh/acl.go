@@ -0,0 +1,103 @@
+package ssh
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/infastin/wg-wish/server/entity"
+	"github.com/infastin/wg-wish/server/errors"
+)
+
+type ACLCmd struct {
+	Add struct {
+		Name       string   `arg:"" help:"Group's name."`
+		Allow      []string `optional:"" short:"a" placeholder:"RULE" help:"Allowed IPv4 destination in form [PROTO:]CIDR[:PORT[-PORT]], e.g. tcp:10.0.5.0/24:443."`
+		AllowPeers bool     `optional:"" name:"peers" help:"Allow traffic to other peers inside the tunnel."`
+	} `cmd:"" help:"Add firewall group."`
+
+	Set struct {
+		Name       string   `arg:"" help:"Group's name."`
+		Allow      []string `optional:"" short:"a" placeholder:"RULE" help:"Allowed IPv4 destination in form [PROTO:]CIDR[:PORT[-PORT]], e.g. tcp:10.0.5.0/24:443."`
+		AllowPeers bool     `optional:"" name:"peers" help:"Allow traffic to other peers inside the tunnel."`
+	} `cmd:"" help:"Replace firewall group's rules."`
+
+	Rm struct {
+		Name string `arg:"" help:"Group's name."`
+	} `cmd:"" help:"Remove firewall group."`
+
+	Ls struct{} `cmd:"" help:"List firewall groups."`
+}
+
+func (cmd *ACLCmd) Run(ctx *Context) (err error) {
+	switch ctx.kctx.Command() {
+	case "acl add <name>":
+		err = cmd.HandleAdd(ctx)
+	case "acl set <name>":
+		err = cmd.HandleSet(ctx)
+	case "acl rm <name>":
+		err = cmd.HandleRm(ctx)
+	case "acl ls":
+		err = cmd.HandleLs(ctx)
+	}
+	return err
+}
+
+func (cmd *ACLCmd) HandleAdd(ctx *Context) (err error) {
+	group, err := parseFirewallGroup(cmd.Add.Name, cmd.Add.Allow, cmd.Add.AllowPeers)
+	if err != nil {
+		return err
+	}
+	return ctx.firewallService.AddGroup(ctx, &group)
+}
+
+func (cmd *ACLCmd) HandleSet(ctx *Context) (err error) {
+	group, err := parseFirewallGroup(cmd.Set.Name, cmd.Set.Allow, cmd.Set.AllowPeers)
+	if err != nil {
+		return err
+	}
+	return ctx.firewallService.SetGroup(ctx, &group)
+}
+
+func (cmd *ACLCmd) HandleRm(ctx *Context) (err error) {
+	return ctx.firewallService.RemoveGroup(ctx, cmd.Rm.Name)
+}
+
+func (*ACLCmd) HandleLs(ctx *Context) (err error) {
+	groups, err := ctx.firewallService.GetGroups(ctx)
+	if err != nil {
+		return err
+	}
+
+	var b bytes.Buffer
+	for i := range groups {
+		fmt.Fprintf(&b, "%d. %s\n", i+1, groups[i].Name)
+		if groups[i].AllowPeers {
+			fmt.Fprintf(&b, "Peers: allowed\n")
+		} else {
+			fmt.Fprintf(&b, "Peers: denied\n")
+		}
+		for j := range groups[i].Rules {
+			fmt.Fprintf(&b, "Allow: %s\n", groups[i].Rules[j].String())
+		}
+	}
+	_, _ = ctx.session.Write(b.Bytes())
+
+	return nil
+}
+
+func parseFirewallGroup(name string, allow []string, allowPeers bool) (group entity.FirewallGroup, err error) {
+	group = entity.FirewallGroup{
+		Name:       name,
+		AllowPeers: allowPeers,
+		Rules:      make([]entity.FirewallRule, len(allow)),
+	}
+
+	for i := range allow {
+		group.Rules[i], err = entity.ParseFirewallRule(allow[i])
+		if err != nil {
+			return entity.FirewallGroup{}, errors.NewDomainError("firewall", err.Error())
+		}
+	}
+
+	return group, nil
+}
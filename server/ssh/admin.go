@@ -0,0 +1,54 @@
+package ssh
+
+import (
+	"github.com/infastin/wg-wish/server/errors"
+	"github.com/infastin/wg-wish/server/export"
+	"github.com/infastin/wg-wish/server/service"
+)
+
+type AdminCmd struct {
+	Interface string `optional:"" name:"iface" placeholder:"NAME" help:"WireGuard interface, the primary one by default."`
+
+	Export struct{} `cmd:"" help:"Export server key, peers and public keys as JSON."`
+
+	Import struct {
+		Merge   bool `xor:"mode" required:"" help:"Add or overwrite peers and public keys from the document."`
+		Replace bool `xor:"mode" required:"" help:"Replace the whole state with the document."`
+	} `cmd:"" help:"Import server key, peers and public keys from JSON read on stdin."`
+}
+
+func (cmd *AdminCmd) Run(ctx *Context) (err error) {
+	if err := ctx.selectInterface(cmd.Interface); err != nil {
+		return err
+	}
+
+	switch ctx.kctx.Command() {
+	case "admin export":
+		err = cmd.HandleExport(ctx)
+	case "admin import":
+		err = cmd.HandleImport(ctx)
+	}
+	return err
+}
+
+func (*AdminCmd) HandleExport(ctx *Context) (err error) {
+	snapshot, err := ctx.adminService.ExportState(ctx)
+	if err != nil {
+		return err
+	}
+	return export.Encode(ctx.session, &snapshot)
+}
+
+func (cmd *AdminCmd) HandleImport(ctx *Context) (err error) {
+	snapshot, err := export.Decode(ctx.session)
+	if err != nil {
+		return errors.NewDomainError("admin", err.Error())
+	}
+
+	mode := service.ImportMerge
+	if cmd.Import.Replace {
+		mode = service.ImportReplace
+	}
+
+	return ctx.adminService.ImportState(ctx, &snapshot, mode)
+}
@@ -0,0 +1,263 @@
+package ssh
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/guregu/null/v5"
+	"github.com/infastin/wg-wish/pkg/netutils"
+	"github.com/infastin/wg-wish/pkg/wgtypes"
+	"github.com/infastin/wg-wish/server/errors"
+	"github.com/infastin/wg-wish/server/service"
+	"rsc.io/qr"
+)
+
+var (
+	errBatchEmpty   = errors.NewDomainError("wg", "batch contains no clients")
+	errBatchName    = errors.NewDomainError("wg", "specify either client's name or --batch")
+	errBatchAddress = errors.NewDomainError("wg", "--address can't be used with --batch, specify addresses in the batch")
+)
+
+// batchEntry is a client to add, described by a line of the batch.
+type batchEntry struct {
+	Name        string   `json:"name"`
+	Address     string   `json:"address"`
+	Profile     string   `json:"profile"`
+	Expiry      string   `json:"expiry"`
+	Tags        []string `json:"tags"`
+	Description string   `json:"description"`
+}
+
+// readBatch reads the batch either as JSON lines or as CSV with a header row,
+// depending on the first non-blank character of the input.
+func readBatch(r io.Reader) (entries []batchEntry, err error) {
+	br := bufio.NewReader(r)
+
+	for {
+		c, _, err := br.ReadRune()
+		if err == io.EOF {
+			return nil, errBatchEmpty
+		}
+		if err != nil {
+			return nil, err
+		}
+		if unicode.IsSpace(c) {
+			continue
+		}
+		_ = br.UnreadRune()
+		if c == '{' {
+			return readBatchJSON(br)
+		}
+		return readBatchCSV(br)
+	}
+}
+
+func readBatchJSON(r io.Reader) (entries []batchEntry, err error) {
+	dec := json.NewDecoder(r)
+	dec.DisallowUnknownFields()
+
+	for {
+		var entry batchEntry
+		if err := dec.Decode(&entry); err != nil {
+			if err == io.EOF {
+				return entries, nil
+			}
+			return nil, batchError(len(entries)+1, err)
+		}
+		entries = append(entries, entry)
+	}
+}
+
+func readBatchCSV(r io.Reader) (entries []batchEntry, err error) {
+	cr := csv.NewReader(r)
+	cr.TrimLeadingSpace = true
+
+	header, err := cr.Read()
+	if err != nil {
+		return nil, errors.NewDomainError("wg", fmt.Sprintf("batch header: %v", err))
+	}
+
+	hasName := false
+	for i := range header {
+		header[i] = strings.ToLower(strings.TrimSpace(header[i]))
+		switch header[i] {
+		case "name":
+			hasName = true
+		case "address", "profile", "expiry", "tags", "description":
+		default:
+			return nil, errors.NewDomainError("wg", fmt.Sprintf("batch header: unknown column %q", header[i]))
+		}
+	}
+
+	if !hasName {
+		return nil, errors.NewDomainError("wg", "batch header: missing column \"name\"")
+	}
+
+	for {
+		record, err := cr.Read()
+		if err == io.EOF {
+			return entries, nil
+		}
+		if err != nil {
+			return nil, batchError(len(entries)+1, err)
+		}
+
+		var entry batchEntry
+		for i, value := range record {
+			value = strings.TrimSpace(value)
+			switch header[i] {
+			case "name":
+				entry.Name = value
+			case "address":
+				entry.Address = value
+			case "profile":
+				entry.Profile = value
+			case "expiry":
+				entry.Expiry = value
+			case "description":
+				entry.Description = value
+			case "tags":
+				// Tags are separated by semicolons within the column.
+				for _, tag := range strings.Split(value, ";") {
+					if tag = strings.TrimSpace(tag); tag != "" {
+						entry.Tags = append(entry.Tags, tag)
+					}
+				}
+			}
+		}
+
+		entries = append(entries, entry)
+	}
+}
+
+func batchError(n int, err error) error {
+	return errors.NewDomainError("wg", fmt.Sprintf("batch entry %d: %v", n, err))
+}
+
+// request makes a request to add the client with the given options
+// extended by the ones of the entry.
+func (entry *batchEntry) request(opts service.AddClientOptions, now time.Time) (req service.AddClientRequest, err error) {
+	if entry.Name == "" {
+		return service.AddClientRequest{}, fmt.Errorf("missing name")
+	}
+
+	if entry.Address != "" {
+		addr, err := netutils.ParseAddress(entry.Address)
+		if err != nil {
+			return service.AddClientRequest{}, err
+		}
+		opts.Address = null.ValueFrom(addr)
+	}
+
+	if entry.Profile != "" {
+		opts.Profile = entry.Profile
+	}
+
+	if entry.Expiry != "" {
+		expiresAt, err := parseExpiry(entry.Expiry, now)
+		if err != nil {
+			return service.AddClientRequest{}, err
+		}
+		opts.ExpiresAt = null.TimeFrom(expiresAt)
+	}
+
+	if entry.Description != "" {
+		opts.Description = entry.Description
+	}
+
+	if len(entry.Tags) != 0 {
+		// A tag is either a key=value pair or a bare key with an empty value.
+		opts.Labels = make(map[string]string, len(entry.Tags))
+		for _, tag := range entry.Tags {
+			key, value, _ := strings.Cut(tag, "=")
+			if _, ok := opts.Labels[key]; ok {
+				return service.AddClientRequest{}, fmt.Errorf("duplicate tag %q", key)
+			}
+			opts.Labels[key] = value
+		}
+	}
+
+	return service.AddClientRequest{
+		Name:    entry.Name,
+		Options: opts,
+	}, nil
+}
+
+// writeBatchArchive writes the config and its QR code of every client
+// to a tar or zip archive.
+func writeBatchArchive(w io.Writer, format string, configs []wgtypes.ClientConfig) (err error) {
+	now := time.Now()
+
+	var (
+		writeFile func(name string, data []byte) error
+		closer    io.Closer
+	)
+
+	switch format {
+	case "zip":
+		zw := zip.NewWriter(w)
+		writeFile = func(name string, data []byte) error {
+			hdr := &zip.FileHeader{
+				Name:     name,
+				Method:   zip.Deflate,
+				Modified: now,
+			}
+			hdr.SetMode(0o600)
+			fw, err := zw.CreateHeader(hdr)
+			if err != nil {
+				return err
+			}
+			_, err = fw.Write(data)
+			return err
+		}
+		closer = zw
+	default:
+		tw := tar.NewWriter(w)
+		writeFile = func(name string, data []byte) error {
+			if err := tw.WriteHeader(&tar.Header{
+				Typeflag: tar.TypeReg,
+				Name:     name,
+				Mode:     0o600,
+				Size:     int64(len(data)),
+				ModTime:  now,
+			}); err != nil {
+				return err
+			}
+			_, err := tw.Write(data)
+			return err
+		}
+		closer = tw
+	}
+
+	for i := range configs {
+		var conf bytes.Buffer
+		if err := configs[i].Encode(&conf); err != nil {
+			return err
+		}
+
+		code, err := qr.Encode(conf.String(), qr.L)
+		if err != nil {
+			return err
+		}
+
+		name := strings.ReplaceAll(configs[i].Interface.Name, "/", "_")
+
+		if err := writeFile(name+".conf", conf.Bytes()); err != nil {
+			return err
+		}
+		if err := writeFile(name+".png", code.PNG()); err != nil {
+			return err
+		}
+	}
+
+	return closer.Close()
+}
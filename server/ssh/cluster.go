@@ -0,0 +1,104 @@
+package ssh
+
+import (
+	"bytes"
+	"fmt"
+	"text/tabwriter"
+	"time"
+
+	"github.com/infastin/wg-wish/server/entity"
+	"github.com/infastin/wg-wish/server/errors"
+)
+
+type ClusterCmd struct {
+	Status struct{} `cmd:"" help:"Show replication status and lag."`
+
+	Promote struct{} `cmd:"" help:"Stop replicating and make the standby the primary. The old primary must be stopped first."`
+}
+
+func (cmd *ClusterCmd) Run(ctx *Context) (err error) {
+	if ctx.clusterService == nil {
+		return errors.ErrClusterDisabled
+	}
+
+	switch ctx.kctx.Command() {
+	case "cluster status":
+		err = cmd.HandleStatus(ctx)
+	case "cluster promote":
+		err = cmd.HandlePromote(ctx)
+	}
+	return err
+}
+
+func (*ClusterCmd) HandleStatus(ctx *Context) (err error) {
+	status, err := ctx.clusterService.GetStatus(ctx)
+	if err != nil {
+		return err
+	}
+
+	const timeLayout = "_2 Jan 2006 15:04:05 MST"
+
+	now := time.Now()
+
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "Role: %s\n", status.Role)
+	fmt.Fprintf(&b, "Transaction: %d\n", status.TxID)
+
+	if status.Replication.Valid {
+		replication := &status.Replication.V
+
+		fmt.Fprintf(&b, "Primary: %s\n", replication.PrimaryURL)
+		fmt.Fprintf(&b, "Primary transaction: %d\n", replication.PrimaryTxID)
+
+		synced := "never"
+		if replication.SyncedAt.Valid {
+			synced = replication.SyncedAt.Time.Format(timeLayout)
+		}
+		fmt.Fprintf(&b, "Last synced: %s\n", synced)
+		fmt.Fprintf(&b, "Lag: %s\n", formatLag(replication.Lag(now).Ptr()))
+
+		if replication.Error != "" {
+			fmt.Fprintf(&b, "Error: %s\n", replication.Error)
+		}
+	}
+
+	if status.Role == entity.ClusterRolePrimary {
+		b.WriteByte('\n')
+
+		tw := tabwriter.NewWriter(&b, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "STANDBY\tADDRESS\tTRANSACTION\tLAST SEEN\tLAG")
+		for i := range status.Standbys {
+			standby := &status.Standbys[i]
+
+			fmt.Fprintf(tw, "%s\t%s\t%d\t%s\t%s\n",
+				standby.Name,
+				standby.Address,
+				standby.TxID,
+				standby.LastSeen.Format(timeLayout),
+				formatLag(standby.Lag(now).Ptr()))
+		}
+		_ = tw.Flush()
+	}
+
+	_, _ = ctx.session.Write(b.Bytes())
+
+	return nil
+}
+
+func (*ClusterCmd) HandlePromote(ctx *Context) (err error) {
+	if err := ctx.clusterService.Promote(ctx); err != nil {
+		return err
+	}
+
+	_, _ = ctx.session.Write([]byte("Promoted, the server is restarting as the primary.\n"))
+
+	return nil
+}
+
+// formatLag formats the lag rounded to seconds, since replication runs at intervals of seconds.
+func formatLag(lag *time.Duration) string {
+	if lag == nil {
+		return "unknown"
+	}
+	return lag.Round(time.Second).String()
+}
@@ -2,10 +2,13 @@ package ssh
 
 import (
 	"context"
+	"strings"
 
 	"github.com/alecthomas/kong"
 	"github.com/charmbracelet/ssh"
 	"github.com/charmbracelet/wish"
+	"github.com/infastin/wg-wish/server/errors"
+	"github.com/infastin/wg-wish/server/event"
 	"github.com/infastin/wg-wish/server/service"
 	"github.com/rs/zerolog"
 )
@@ -18,55 +21,117 @@ type Context struct {
 	lg      zerolog.Logger
 	session ssh.Session
 
-	publicKeyService service.PublicKeyService
-	wireguardService service.WireGuardService
+	interfaces        []Interface
+	iface             string
+	adminService      service.AdminService
+	publicKeyService  service.PublicKeyService
+	wireguardService  service.WireGuardService
+	statsService      service.StatsService
+	firewallService   service.FirewallService
+	profileService    service.ProfileService
+	federationService service.FederationService
+	clusterService    service.ClusterService
+	events            *event.Bus
+}
+
+// Interface holds the services bound to a single WireGuard interface.
+type Interface struct {
+	Name             string
+	AdminService     service.AdminService
+	WireGuardService service.WireGuardService
+	StatsService     service.StatsService
 }
 
 type CommandsHandlerParams struct {
-	Logger           zerolog.Logger
+	Logger zerolog.Logger
+	// Interfaces must contain at least one interface, the first one is selected by default.
+	Interfaces       []Interface
 	PublicKeyService service.PublicKeyService
-	WireGuardService service.WireGuardService
+	FirewallService  service.FirewallService
+	ProfileService   service.ProfileService
+	// FederationService is nil if federation is not configured.
+	FederationService service.FederationService
+	// ClusterService is nil if replication is not configured.
+	ClusterService service.ClusterService
+	Events         *event.Bus
 }
 
 func NewCommandsHandler(params *CommandsHandlerParams) wish.Middleware {
 	return func(handler ssh.Handler) ssh.Handler {
 		return func(session ssh.Session) {
 			var cli struct {
-				PublicKey PublicKeyCmd `cmd:"" name:"publickey" help:"Manage public keys."`
-				WireGuard WireGuardCmd `cmd:"" name:"wireguard" help:"Manage WireGuard."`
+				Admin      AdminCmd      `cmd:"" name:"admin" help:"Manage server state."`
+				PublicKey  PublicKeyCmd  `cmd:"" name:"publickey" help:"Manage public keys."`
+				WireGuard  WireGuardCmd  `cmd:"" name:"wireguard" help:"Manage WireGuard."`
+				ACL        ACLCmd        `cmd:"" name:"acl" help:"Manage firewall groups."`
+				Profile    ProfileCmd    `cmd:"" name:"profile" help:"Manage client profiles."`
+				Server     ServerCmd     `cmd:"" name:"server" help:"Inspect server."`
+				Federation FederationCmd `cmd:"" name:"federation" help:"Manage clients across linked servers."`
+				Cluster    ClusterCmd    `cmd:"" name:"cluster" help:"Manage replication."`
 			}
 
-			k, err := kong.New(&cli,
-				kong.Writers(session, session.Stderr()),
-				kong.Name("wg-wish"),
-				kong.Description("Manage WireGuard."),
-				kong.ConfigureHelp(kong.HelpOptions{ //nolint:exhaustruct
-					NoExpandSubcommands: true,
-				}),
-				kong.Exit(func(i int) {}),
-			)
-
-			if err != nil {
-				Abortf(handler, session, "could not initialize session: %v", err)
+			kctx, ok := parseCommand(handler, session, &cli)
+			if !ok {
 				return
 			}
 
-			kctx, err := k.Parse(session.Command())
-			if err != nil {
+			ctx := &Context{
+				Context:           session.Context(),
+				kctx:              kctx,
+				lg:                params.Logger,
+				session:           session,
+				interfaces:        params.Interfaces,
+				iface:             "",
+				adminService:      nil,
+				publicKeyService:  params.PublicKeyService,
+				wireguardService:  nil,
+				statsService:      nil,
+				firewallService:   params.FirewallService,
+				profileService:    params.ProfileService,
+				federationService: params.FederationService,
+				clusterService:    params.ClusterService,
+				events:            params.Events,
+			}
+			_ = ctx.selectInterface("")
+
+			if err := kctx.Run(ctx); err != nil {
 				AbortError(handler, session, err)
 				return
 			}
 
-			err = kctx.Run(&Context{
-				Context:          context.Background(),
-				kctx:             kctx,
-				lg:               params.Logger,
-				session:          session,
-				publicKeyService: params.PublicKeyService,
-				wireguardService: params.WireGuardService,
-			})
+			handler(session)
+		}
+	}
+}
+
+type StandbyCommandsHandlerParams struct {
+	Logger         zerolog.Logger
+	ClusterService service.ClusterService
+}
 
-			if err != nil {
+// NewStandbyCommandsHandler only serves the cluster commands,
+// as the rest of the state is owned by the primary until the standby is promoted.
+func NewStandbyCommandsHandler(params *StandbyCommandsHandlerParams) wish.Middleware {
+	return func(handler ssh.Handler) ssh.Handler {
+		return func(session ssh.Session) {
+			var cli struct {
+				Cluster ClusterCmd `cmd:"" name:"cluster" help:"Manage replication."`
+			}
+
+			kctx, ok := parseCommand(handler, session, &cli)
+			if !ok {
+				return
+			}
+
+			ctx := &Context{ //nolint:exhaustruct
+				Context:        session.Context(),
+				kctx:           kctx,
+				lg:             params.Logger,
+				session:        session,
+				clusterService: params.ClusterService,
+			}
+
+			if err := kctx.Run(ctx); err != nil {
 				AbortError(handler, session, err)
 				return
 			}
@@ -75,3 +140,71 @@ func NewCommandsHandler(params *CommandsHandlerParams) wish.Middleware {
 		}
 	}
 }
+
+// parseCommand parses the session's command into cli,
+// aborting the session if it can't be parsed.
+func parseCommand(handler ssh.Handler, session ssh.Session, cli any) (kctx *kong.Context, ok bool) {
+	k, err := kong.New(cli,
+		kong.Writers(session, session.Stderr()),
+		kong.Name("wg-wish"),
+		kong.Description("Manage WireGuard."),
+		kong.ConfigureHelp(kong.HelpOptions{ //nolint:exhaustruct
+			NoExpandSubcommands: true,
+		}),
+		kong.Exit(func(i int) {}),
+	)
+
+	if err != nil {
+		Abortf(handler, session, "could not initialize session: %v", err)
+		return nil, false
+	}
+
+	kctx, err = k.Parse(session.Command())
+	if err != nil {
+		AbortError(handler, session, err)
+		return nil, false
+	}
+
+	session.Context().SetValue("command", commandPath(kctx))
+
+	return kctx, true
+}
+
+// commandPath returns the names of the selected command and its parents
+// without arguments and flags.
+func commandPath(kctx *kong.Context) string {
+	var b strings.Builder
+
+	for _, trace := range kctx.Path {
+		if trace.Command == nil {
+			continue
+		}
+
+		if b.Len() != 0 {
+			b.WriteByte(' ')
+		}
+		b.WriteString(trace.Command.Name)
+	}
+
+	return b.String()
+}
+
+// selectInterface binds the interface's services to the context.
+// Empty name selects the default interface.
+func (ctx *Context) selectInterface(name string) error {
+	for i := range ctx.interfaces {
+		iface := &ctx.interfaces[i]
+		if name != "" && iface.Name != name {
+			continue
+		}
+
+		ctx.iface = iface.Name
+		ctx.adminService = iface.AdminService
+		ctx.wireguardService = iface.WireGuardService
+		ctx.statsService = iface.StatsService
+
+		return nil
+	}
+
+	return errors.ErrWireGuardInterfaceNotFound
+}
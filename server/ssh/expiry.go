@@ -0,0 +1,36 @@
+package ssh
+
+import (
+	"fmt"
+	"time"
+)
+
+// Expiry is a point in time, which can be specified as an RFC 3339 timestamp,
+// as a date, meaning its start in UTC, or as a duration from now, e.g. "30d".
+type Expiry time.Time
+
+func (e *Expiry) UnmarshalText(text []byte) error {
+	t, err := parseExpiry(string(text), time.Now())
+	if err != nil {
+		return err
+	}
+	*e = Expiry(t)
+	return nil
+}
+
+func parseExpiry(s string, now time.Time) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+
+	if t, err := time.Parse(time.DateOnly, s); err == nil {
+		return t, nil
+	}
+
+	var d Duration
+	if err := d.UnmarshalText([]byte(s)); err != nil || d <= 0 {
+		return time.Time{}, fmt.Errorf("invalid expiry %q, expected a date, a timestamp or a duration", s)
+	}
+
+	return now.Add(time.Duration(d)), nil
+}
@@ -0,0 +1,249 @@
+package ssh
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/infastin/wg-wish/server/entity"
+	"github.com/infastin/wg-wish/server/errors"
+	"github.com/infastin/wg-wish/server/service"
+	"github.com/mdp/qrterminal/v3"
+)
+
+var errFederationFailed = errors.NewDomainError("federation", "some servers failed")
+
+type FederationCmd struct {
+	Interface string   `optional:"" name:"iface" placeholder:"NAME" help:"WireGuard interface on each server, the primary one by default."`
+	Server    []string `optional:"" short:"s" name:"server" sep:"none" placeholder:"NAME" help:"Server to use, can be repeated. Every server by default."`
+
+	Servers struct{} `cmd:"" name:"servers" help:"List linked servers and check that they can be reached."`
+
+	Ls struct{} `cmd:"" help:"List clients of every server."`
+
+	Add struct {
+		Name string `arg:"" help:"Client's name."`
+	} `cmd:"" help:"Add client with the key of the local one to the servers, adding the local client if needed."`
+
+	Get struct {
+		Server string `arg:"" help:"Server's name."`
+		Name   string `arg:"" help:"Client's name."`
+		QR     bool   `optional:"" name:"qr" help:"Print QR code."`
+	} `cmd:"" help:"Get client config for the server."`
+
+	Rm struct {
+		Name string `arg:"" help:"Client's name."`
+	} `cmd:"" help:"Remove client from the servers."`
+
+	Reload struct{} `cmd:"" help:"Reload the servers."`
+}
+
+func (cmd *FederationCmd) Run(ctx *Context) (err error) {
+	if ctx.federationService == nil {
+		return errors.ErrFederationDisabled
+	}
+
+	switch ctx.kctx.Command() {
+	case "federation servers":
+		err = cmd.HandleServers(ctx)
+	case "federation ls":
+		err = cmd.HandleLs(ctx)
+	case "federation add <name>":
+		err = cmd.HandleAdd(ctx)
+	case "federation get <server> <name>":
+		err = cmd.HandleGet(ctx)
+	case "federation rm <name>":
+		err = cmd.HandleRm(ctx)
+	case "federation reload":
+		err = cmd.HandleReload(ctx)
+	}
+	return err
+}
+
+func (cmd *FederationCmd) options() *service.FederationOptions {
+	return &service.FederationOptions{
+		Servers:   cmd.Server,
+		Interface: cmd.Interface,
+	}
+}
+
+func (*FederationCmd) HandleServers(ctx *Context) (err error) {
+	servers, err := ctx.federationService.GetServers(ctx)
+	if err != nil {
+		return err
+	}
+
+	var b bytes.Buffer
+	tw := tabwriter.NewWriter(&b, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "NAME\tURL\tSTATE\tINTERFACES\tCLIENTS")
+	for i := range servers {
+		server := &servers[i]
+
+		url, state := server.URL, "ok"
+		switch {
+		case server.Local:
+			url = "(local)"
+		case server.Error != "":
+			state = server.Error
+		}
+
+		interfaces := "-"
+		clients := "-"
+		if server.Error == "" {
+			interfaces = strings.Join(server.Interfaces, ",")
+			clients = fmt.Sprint(server.Clients)
+		}
+
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", server.Name, url, state, interfaces, clients)
+	}
+	_ = tw.Flush()
+
+	_, _ = ctx.session.Write(b.Bytes())
+
+	return nil
+}
+
+func (cmd *FederationCmd) HandleLs(ctx *Context) (err error) {
+	clients, err := ctx.federationService.GetClients(ctx, cmd.options())
+	if err != nil {
+		return err
+	}
+
+	const timeLayout = "_2 Jan 2006 15:04 MST"
+
+	now := time.Now()
+
+	var b bytes.Buffer
+	tw := tabwriter.NewWriter(&b, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "SERVER\tNAME\tADDRESS\tSTATE\tLATEST HANDSHAKE\tPUBLIC KEY")
+	for i := range clients {
+		client := &clients[i]
+
+		state := "offline"
+		switch {
+		case client.Disabled:
+			state = "disabled"
+		case client.LatestHandshake.Valid && now.Sub(client.LatestHandshake.Time) < entity.PeerOnlineTimeout:
+			state = "online"
+		}
+
+		handshake := "never"
+		if client.LatestHandshake.Valid {
+			handshake = client.LatestHandshake.Time.Format(timeLayout)
+		}
+
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\n",
+			client.Server,
+			client.Name,
+			client.Address.String(),
+			state,
+			handshake,
+			client.PublicKey.String())
+	}
+	_ = tw.Flush()
+
+	_, _ = ctx.session.Write(b.Bytes())
+
+	return nil
+}
+
+func (cmd *FederationCmd) HandleAdd(ctx *Context) (err error) {
+	results, err := ctx.federationService.SyncClient(ctx, cmd.Add.Name, cmd.options())
+	if err != nil {
+		return err
+	}
+
+	failed := false
+
+	var b bytes.Buffer
+	tw := tabwriter.NewWriter(&b, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "SERVER\tADDRESS\tRESULT")
+	for i := range results {
+		result := &results[i]
+
+		address, status := result.Address.String(), "exists"
+		switch {
+		case result.Error != "":
+			address, status = "-", result.Error
+			failed = true
+		case result.Created:
+			status = "added"
+		}
+
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", result.Server, address, status)
+	}
+	_ = tw.Flush()
+
+	_, _ = ctx.session.Write(b.Bytes())
+
+	if failed {
+		return errFederationFailed
+	}
+
+	return nil
+}
+
+func (cmd *FederationCmd) HandleGet(ctx *Context) (err error) {
+	cfg, err := ctx.federationService.GetClient(ctx, cmd.Get.Server, cmd.Get.Name, cmd.Interface)
+	if err != nil {
+		return err
+	}
+
+	var conf bytes.Buffer
+	if err := cfg.Encode(&conf); err != nil {
+		return err
+	}
+
+	if cmd.Get.QR {
+		qrterminal.GenerateHalfBlock(conf.String(), qrterminal.L, ctx.session)
+	} else {
+		_, _ = ctx.session.Write(conf.Bytes())
+	}
+
+	return nil
+}
+
+func (cmd *FederationCmd) HandleRm(ctx *Context) (err error) {
+	results, err := ctx.federationService.RemoveClient(ctx, cmd.Rm.Name, cmd.options())
+	if err != nil {
+		return err
+	}
+	return writeFederationResults(ctx, results)
+}
+
+func (cmd *FederationCmd) HandleReload(ctx *Context) (err error) {
+	results, err := ctx.federationService.ReloadServers(ctx, cmd.options())
+	if err != nil {
+		return err
+	}
+	return writeFederationResults(ctx, results)
+}
+
+// writeFederationResults writes the outcome on every server,
+// failing if the operation failed on any of them.
+func writeFederationResults(ctx *Context, results []entity.FederationResult) error {
+	failed := false
+
+	var b bytes.Buffer
+	tw := tabwriter.NewWriter(&b, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "SERVER\tRESULT")
+	for i := range results {
+		status := "ok"
+		if results[i].Error != "" {
+			status = results[i].Error
+			failed = true
+		}
+		fmt.Fprintf(tw, "%s\t%s\n", results[i].Server, status)
+	}
+	_ = tw.Flush()
+
+	_, _ = ctx.session.Write(b.Bytes())
+
+	if failed {
+		return errFederationFailed
+	}
+
+	return nil
+}
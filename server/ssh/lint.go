@@ -0,0 +1,51 @@
+package ssh
+
+import (
+	"bytes"
+	"fmt"
+	"text/tabwriter"
+
+	"github.com/infastin/wg-wish/pkg/wgtypes"
+	"github.com/infastin/wg-wish/server/errors"
+)
+
+var errLintFailed = errors.NewDomainError("wg", "configs have errors")
+
+func (*WireGuardCmd) HandleLint(ctx *Context) (err error) {
+	issues, err := ctx.wireguardService.LintConfigs(ctx)
+	if err != nil {
+		return err
+	}
+
+	var b bytes.Buffer
+	if len(issues) == 0 {
+		b.WriteString("No problems found\n")
+		_, _ = ctx.session.Write(b.Bytes())
+		return nil
+	}
+
+	failed := false
+
+	tw := tabwriter.NewWriter(&b, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "SEVERITY\tCONFIG\tSECTION\tMESSAGE")
+	for i := range issues {
+		issue := &issues[i]
+		config := issue.Config
+		if config == "" {
+			config = "(server)"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", issue.Severity, config, issue.Section, issue.Message)
+		if issue.Severity == wgtypes.SeverityError {
+			failed = true
+		}
+	}
+	_ = tw.Flush()
+
+	_, _ = ctx.session.Write(b.Bytes())
+
+	if failed {
+		return errLintFailed
+	}
+
+	return nil
+}
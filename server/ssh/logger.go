@@ -8,10 +8,11 @@ import (
 	"github.com/charmbracelet/ssh"
 	"github.com/charmbracelet/wish"
 	"github.com/infastin/wg-wish/server/errors"
+	"github.com/infastin/wg-wish/server/metrics"
 	"github.com/rs/zerolog"
 )
 
-func NewLoggerMiddleware(lg zerolog.Logger) wish.Middleware {
+func NewLoggerMiddleware(lg zerolog.Logger, m *metrics.Metrics) wish.Middleware {
 	return func(handler ssh.Handler) ssh.Handler {
 		return func(session ssh.Session) {
 			ct := time.Now()
@@ -24,6 +25,9 @@ func NewLoggerMiddleware(lg zerolog.Logger) wish.Middleware {
 
 			elapsed := time.Since(ct)
 
+			err, _ := session.Context().Value("error").(error)
+			m.ObserveCommand(getCommandName(session), elapsed, err)
+
 			lgCtx := lg.With().
 				Str("command", command).
 				Str("user", user).
@@ -57,6 +61,16 @@ func NewLoggerMiddleware(lg zerolog.Logger) wish.Middleware {
 	}
 }
 
+// getCommandName returns the command path resolved by the commands handler,
+// so that it can be used as a metric label. Commands which couldn't be parsed
+// share the unknown label, so that arbitrary input doesn't create new series.
+func getCommandName(session ssh.Session) string {
+	if name, _ := session.Context().Value("command").(string); name != "" {
+		return name
+	}
+	return "unknown"
+}
+
 func getCommandString(args []string) string {
 	var b strings.Builder
 
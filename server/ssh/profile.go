@@ -0,0 +1,120 @@
+package ssh
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/guregu/null/v5"
+	"github.com/infastin/wg-wish/pkg/netutils"
+	"github.com/infastin/wg-wish/server/entity"
+)
+
+type ProfileCmd struct {
+	Add struct {
+		Name                string   `arg:"" help:"Profile's name."`
+		DNS                 []string `optional:"" short:"d" help:"Clients' DNS list."`
+		AllowedIPs          []string `optional:"" short:"i" name:"ips" placeholder:"IP" help:"Clients' allowed IPs."`
+		PersistentKeepalive null.Int `optional:"" short:"k" name:"keepalive" placeholder:"SECONDS" help:"Clients' persistent keepalive."`
+	} `cmd:"" help:"Add profile."`
+
+	Set struct {
+		Name                string   `arg:"" help:"Profile's name."`
+		DNS                 []string `optional:"" short:"d" help:"Clients' DNS list."`
+		AllowedIPs          []string `optional:"" short:"i" name:"ips" placeholder:"IP" help:"Clients' allowed IPs."`
+		PersistentKeepalive null.Int `optional:"" short:"k" name:"keepalive" placeholder:"SECONDS" help:"Clients' persistent keepalive."`
+	} `cmd:"" help:"Replace profile's settings and update clients following it."`
+
+	Rm struct {
+		Name string `arg:"" help:"Profile's name."`
+	} `cmd:"" help:"Remove profile."`
+
+	Ls struct{} `cmd:"" help:"List profiles."`
+}
+
+func (cmd *ProfileCmd) Run(ctx *Context) (err error) {
+	switch ctx.kctx.Command() {
+	case "profile add <name>":
+		err = cmd.HandleAdd(ctx)
+	case "profile set <name>":
+		err = cmd.HandleSet(ctx)
+	case "profile rm <name>":
+		err = cmd.HandleRm(ctx)
+	case "profile ls":
+		err = cmd.HandleLs(ctx)
+	}
+	return err
+}
+
+func (cmd *ProfileCmd) HandleAdd(ctx *Context) (err error) {
+	profile, err := parseProfile(cmd.Add.Name, cmd.Add.DNS, cmd.Add.AllowedIPs, cmd.Add.PersistentKeepalive)
+	if err != nil {
+		return err
+	}
+	return ctx.profileService.AddProfile(ctx, &profile)
+}
+
+func (cmd *ProfileCmd) HandleSet(ctx *Context) (err error) {
+	profile, err := parseProfile(cmd.Set.Name, cmd.Set.DNS, cmd.Set.AllowedIPs, cmd.Set.PersistentKeepalive)
+	if err != nil {
+		return err
+	}
+
+	clients, err := ctx.profileService.SetProfile(ctx, &profile)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(ctx.session, "Updated clients: %d\n", clients)
+
+	return nil
+}
+
+func (cmd *ProfileCmd) HandleRm(ctx *Context) (err error) {
+	return ctx.profileService.RemoveProfile(ctx, cmd.Rm.Name)
+}
+
+func (*ProfileCmd) HandleLs(ctx *Context) (err error) {
+	profiles, err := ctx.profileService.GetProfiles(ctx)
+	if err != nil {
+		return err
+	}
+
+	var b bytes.Buffer
+	for i := range profiles {
+		profile := &profiles[i]
+		fmt.Fprintf(&b, "%d. %s\n", i+1, profile.Name)
+		if len(profile.DNS) != 0 {
+			fmt.Fprintf(&b, "DNS: %s\n", netutils.FormatIPs(profile.DNS, ", "))
+		}
+		if len(profile.AllowedIPs) != 0 {
+			fmt.Fprintf(&b, "Allowed IPs: %s\n", netutils.FormatAddresses(profile.AllowedIPs, ", "))
+		}
+		if profile.PersistentKeepalive.Valid {
+			fmt.Fprintf(&b, "Persistent keepalive: %d\n", profile.PersistentKeepalive.Int64)
+		}
+	}
+	_, _ = ctx.session.Write(b.Bytes())
+
+	return nil
+}
+
+func parseProfile(name string, dns, allowedIPs []string, keepalive null.Int) (profile entity.WireGuardProfile, err error) {
+	profile = entity.WireGuardProfile{
+		Name:                name,
+		DNS:                 nil,
+		AllowedIPs:          nil,
+		PersistentKeepalive: keepalive,
+	}
+
+	profile.DNS, err = netutils.ParseIPs(dns)
+	if err != nil {
+		return entity.WireGuardProfile{}, err
+	}
+
+	profile.AllowedIPs, err = netutils.ParseAddresses(allowedIPs)
+	if err != nil {
+		return entity.WireGuardProfile{}, err
+	}
+
+	return profile, nil
+}
@@ -0,0 +1,62 @@
+package ssh
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Rate is a bandwidth in bits per second, which can be specified
+// with a unit suffix in tc(8) notation, e.g. "512kbit" or "10mbit".
+// Suffixes ending with "bps" denote bytes per second.
+type Rate uint64
+
+var rateUnits = []struct {
+	suffix string
+	size   uint64
+}{
+	{"kbit", 1e3},
+	{"mbit", 1e6},
+	{"gbit", 1e9},
+	{"tbit", 1e12},
+	{"bit", 1},
+	{"kbps", 8e3},
+	{"mbps", 8e6},
+	{"gbps", 8e9},
+	{"tbps", 8e12},
+	{"bps", 8},
+}
+
+func (r *Rate) UnmarshalText(text []byte) error {
+	s := strings.TrimSpace(string(text))
+
+	unit := uint64(1)
+	for _, u := range rateUnits {
+		if len(s) > len(u.suffix) && strings.EqualFold(s[len(s)-len(u.suffix):], u.suffix) {
+			s, unit = strings.TrimSpace(s[:len(s)-len(u.suffix)]), u.size
+			break
+		}
+	}
+
+	n, err := strconv.ParseFloat(s, 64)
+	if err != nil || n <= 0 {
+		return fmt.Errorf("invalid rate %q", string(text))
+	}
+
+	*r = Rate(n * float64(unit))
+	return nil
+}
+
+func humanReadableRate(r uint64) string {
+	const unit = 1000
+	if r < unit {
+		return fmt.Sprintf("%d bit/s", r)
+	}
+	div, exp := uint64(unit), 0
+	for n := r / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %cbit/s",
+		float64(r)/float64(div), "kMGTPE"[exp])
+}
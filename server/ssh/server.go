@@ -0,0 +1,90 @@
+package ssh
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+type ServerCmd struct {
+	Firewall struct {
+		Show struct {
+			Diff bool `optional:"" help:"Show changes made to the rules since they were last applied."`
+		} `cmd:"" help:"Show rules programmed by the firewall backend."`
+	} `cmd:"" help:"Inspect server firewall."`
+}
+
+func (cmd *ServerCmd) Run(ctx *Context) (err error) {
+	switch ctx.kctx.Command() {
+	case "server firewall show":
+		err = cmd.HandleFirewallShow(ctx)
+	}
+	return err
+}
+
+func (cmd *ServerCmd) HandleFirewallShow(ctx *Context) (err error) {
+	state, err := ctx.firewallService.Show(ctx)
+	if err != nil {
+		return err
+	}
+
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "# Backend: %s\n", state.Backend)
+
+	if cmd.Firewall.Show.Diff {
+		writeLineDiff(&b, state.Applied, state.Current)
+	} else {
+		b.WriteString(state.Current)
+	}
+
+	_, _ = ctx.session.Write(b.Bytes())
+
+	return nil
+}
+
+// writeLineDiff writes lines removed from src with "-" prefix,
+// lines added to dst with "+" prefix and unchanged lines with " " prefix.
+func writeLineDiff(b *bytes.Buffer, src, dst string) {
+	a := strings.Split(strings.TrimSuffix(src, "\n"), "\n")
+	c := strings.Split(strings.TrimSuffix(dst, "\n"), "\n")
+
+	// lcs[i][j] is the length of the longest common subsequence of a[i:] and c[j:].
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(c)+1)
+	}
+
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(c) - 1; j >= 0; j-- {
+			if a[i] == c[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	i, j := 0, 0
+	for i < len(a) && j < len(c) {
+		switch {
+		case a[i] == c[j]:
+			fmt.Fprintf(b, " %s\n", a[i])
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			fmt.Fprintf(b, "-%s\n", a[i])
+			i++
+		default:
+			fmt.Fprintf(b, "+%s\n", c[j])
+			j++
+		}
+	}
+
+	for ; i < len(a); i++ {
+		fmt.Fprintf(b, "-%s\n", a[i])
+	}
+
+	for ; j < len(c); j++ {
+		fmt.Fprintf(b, "+%s\n", c[j])
+	}
+}
@@ -3,10 +3,14 @@ package ssh
 import (
 	"context"
 	"fmt"
+	"slices"
 	"strconv"
 
 	"github.com/charmbracelet/ssh"
 	"github.com/charmbracelet/wish"
+	"github.com/infastin/gorack/fastconv"
+	"github.com/infastin/wg-wish/server/event"
+	"github.com/infastin/wg-wish/server/metrics"
 	"github.com/infastin/wg-wish/server/service"
 	"github.com/rs/zerolog"
 )
@@ -20,8 +24,16 @@ type ServerParams struct {
 	Logger           zerolog.Logger
 	Port             int
 	HostKeyPath      string
+	Interfaces       []Interface
 	PublicKeyService service.PublicKeyService
-	WireGuardService service.WireGuardService
+	FirewallService  service.FirewallService
+	ProfileService   service.ProfileService
+	// FederationService is nil if federation is not configured.
+	FederationService service.FederationService
+	// ClusterService is nil if replication is not configured.
+	ClusterService service.ClusterService
+	Metrics        *metrics.Metrics
+	Events         *event.Bus
 }
 
 func New(params *ServerParams) (srv *Server, err error) {
@@ -40,12 +52,67 @@ func New(params *ServerParams) (srv *Server, err error) {
 		}),
 		wish.WithMiddleware(
 			NewCommandsHandler(&CommandsHandlerParams{
-				Logger:           params.Logger,
-				PublicKeyService: params.PublicKeyService,
-				WireGuardService: params.WireGuardService,
+				Logger:            params.Logger,
+				Interfaces:        params.Interfaces,
+				PublicKeyService:  params.PublicKeyService,
+				FirewallService:   params.FirewallService,
+				ProfileService:    params.ProfileService,
+				FederationService: params.FederationService,
+				ClusterService:    params.ClusterService,
+				Events:            params.Events,
 			}),
 			PanicHandler,
-			NewLoggerMiddleware(params.Logger),
+			NewLoggerMiddleware(params.Logger, params.Metrics),
+			ErrorHandler,
+		),
+	); err != nil {
+		return nil, err
+	}
+
+	return srv, nil
+}
+
+type StandbyServerParams struct {
+	Logger      zerolog.Logger
+	Port        int
+	HostKeyPath string
+	// AdminKeys are authorized instead of the keys in the database,
+	// which is replaced whenever a snapshot from the primary is applied.
+	AdminKeys      []string
+	ClusterService service.ClusterService
+}
+
+// NewStandby returns the server of a standby, which only serves the cluster commands.
+func NewStandby(params *StandbyServerParams) (srv *Server, err error) {
+	adminKeys := make([]ssh.PublicKey, len(params.AdminKeys))
+	for i := range params.AdminKeys {
+		adminKeys[i], _, _, _, err = ssh.ParseAuthorizedKey(fastconv.Bytes(params.AdminKeys[i]))
+		if err != nil {
+			return nil, fmt.Errorf("ssh: invalid admin key: %w", err)
+		}
+	}
+
+	addr := "0.0.0.0:" + strconv.Itoa(params.Port)
+	srv = &Server{
+		lg:     params.Logger,
+		server: nil,
+	}
+
+	if srv.server, err = wish.NewServer(
+		wish.WithAddress(addr),
+		wish.WithHostKeyPath(params.HostKeyPath),
+		wish.WithPublicKeyAuth(func(ctx ssh.Context, key ssh.PublicKey) bool {
+			return slices.ContainsFunc(adminKeys, func(adminKey ssh.PublicKey) bool {
+				return ssh.KeysEqual(adminKey, key)
+			})
+		}),
+		wish.WithMiddleware(
+			NewStandbyCommandsHandler(&StandbyCommandsHandlerParams{
+				Logger:         params.Logger,
+				ClusterService: params.ClusterService,
+			}),
+			PanicHandler,
+			NewLoggerMiddleware(params.Logger, nil),
 			ErrorHandler,
 		),
 	); err != nil {
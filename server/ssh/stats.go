@@ -0,0 +1,58 @@
+package ssh
+
+import (
+	"bytes"
+	"fmt"
+	"text/tabwriter"
+	"time"
+
+	"github.com/infastin/wg-wish/server/service"
+)
+
+func (cmd *WireGuardCmd) HandleStats(ctx *Context) (err error) {
+	stats, err := ctx.statsService.GetTrafficStats(ctx, cmd.Stats.Name,
+		&service.GetTrafficStatsOptions{
+			Since:    time.Duration(cmd.Stats.Since),
+			Interval: time.Duration(cmd.Stats.Interval),
+		})
+	if err != nil {
+		return err
+	}
+
+	const timeLayout = "_2 Jan 2006 15:04 MST"
+
+	period := stats.Until.Sub(stats.Since).Seconds()
+
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "Since: %s\n", stats.Since.Format(timeLayout))
+	fmt.Fprintf(&b, "Received: %s (%s/s)\n", humanReadableByteCount(stats.Received),
+		humanReadableByteCount(uint64(float64(stats.Received)/period)))
+	fmt.Fprintf(&b, "Sent: %s (%s/s)\n", humanReadableByteCount(stats.Sent),
+		humanReadableByteCount(uint64(float64(stats.Sent)/period)))
+	if stats.LatestHandshake.Valid {
+		fmt.Fprintf(&b, "Latest handshake: %s\n", stats.LatestHandshake.Time.Format(timeLayout))
+	}
+
+	if len(stats.Samples) != 0 {
+		b.WriteByte('\n')
+
+		interval := stats.Interval.Seconds()
+
+		tw := tabwriter.NewWriter(&b, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "TIME\tRECEIVED\tSENT\tRX RATE\tTX RATE")
+		for i := range stats.Samples {
+			sample := &stats.Samples[i]
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s/s\t%s/s\n",
+				sample.Start.Format(timeLayout),
+				humanReadableByteCount(sample.Received),
+				humanReadableByteCount(sample.Sent),
+				humanReadableByteCount(uint64(float64(sample.Received)/interval)),
+				humanReadableByteCount(uint64(float64(sample.Sent)/interval)))
+		}
+		_ = tw.Flush()
+	}
+
+	_, _ = ctx.session.Write(b.Bytes())
+
+	return nil
+}
@@ -0,0 +1,151 @@
+package ssh
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/infastin/wg-wish/server/entity"
+	"github.com/infastin/wg-wish/server/errors"
+	"github.com/infastin/wg-wish/server/event"
+)
+
+var errWatchInterval = errors.NewDomainError("wg", "sampling interval must be at least 1s")
+
+func (cmd *WireGuardCmd) HandleWatch(ctx *Context) (err error) {
+	if cmd.Watch.Interval < time.Second {
+		return errWatchInterval
+	}
+
+	// Management events are buffered, so that a slow client
+	// doesn't block the publisher. Events not fitting into the buffer are dropped.
+	events := make(chan event.Event, 64)
+	unsubscribe := ctx.events.Subscribe(func(e *event.Event) {
+		if e.Interface != "" && e.Interface != ctx.iface {
+			return
+		}
+		select {
+		case events <- *e:
+		default:
+		}
+	})
+	defer unsubscribe()
+
+	ticker := time.NewTicker(cmd.Watch.Interval)
+	defer ticker.Stop()
+
+	w := &watchWriter{
+		buf:  bytes.Buffer{},
+		json: cmd.Watch.JSON,
+	}
+
+	tracker := event.NewPeerTracker(entity.PeerOnlineTimeout)
+	traffic := make(map[string]entity.WireGuardPeerStats)
+	sampledAt := time.Now()
+
+	if err := cmd.sampleWatch(ctx, tracker, traffic, w, 0); err != nil {
+		return err
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case e := <-events:
+			w.write(&e)
+		case now := <-ticker.C:
+			if err := cmd.sampleWatch(ctx, tracker, traffic, w, now.Sub(sampledAt)); err != nil {
+				return err
+			}
+			sampledAt = now
+		}
+
+		if _, err := ctx.session.Write(w.buf.Bytes()); err != nil {
+			// Client has disconnected.
+			return nil
+		}
+		w.buf.Reset()
+	}
+}
+
+// sampleWatch writes connection events and traffic rates of peers
+// whose counters have changed since the previous sample taken elapsed time ago.
+func (*WireGuardCmd) sampleWatch(ctx *Context, tracker *event.PeerTracker,
+	traffic map[string]entity.WireGuardPeerStats, w *watchWriter, elapsed time.Duration,
+) (err error) {
+	infos, err := ctx.wireguardService.GetClientInfos(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, e := range tracker.Update(infos, time.Now()) {
+		e.Interface = ctx.iface
+		w.write(&e)
+	}
+
+	for i := range infos {
+		info := &infos[i]
+		if !info.Stats.Valid {
+			continue
+		}
+
+		name := info.Config.Interface.Name
+		prev, ok := traffic[name]
+		traffic[name] = info.Stats.V
+
+		// Counters go backwards when the interface is restarted.
+		if !ok || elapsed == 0 || info.Stats.V.Received < prev.Received || info.Stats.V.Sent < prev.Sent {
+			continue
+		}
+
+		received := info.Stats.V.Received - prev.Received
+		sent := info.Stats.V.Sent - prev.Sent
+		if received == 0 && sent == 0 {
+			continue
+		}
+
+		e := event.NewPeerEvent(event.PeerTraffic, &info.Config)
+		e.Interface = ctx.iface
+		e.Peer.LatestHandshake = info.Stats.V.LatestHandshake.Ptr()
+		e.Traffic = &event.Traffic{
+			Received:    info.Stats.V.Received,
+			Sent:        info.Stats.V.Sent,
+			ReceiveRate: float64(received) / elapsed.Seconds(),
+			SendRate:    float64(sent) / elapsed.Seconds(),
+		}
+		w.write(&e)
+	}
+
+	return nil
+}
+
+type watchWriter struct {
+	buf  bytes.Buffer
+	json bool
+}
+
+func (w *watchWriter) write(e *event.Event) {
+	if w.json {
+		_ = json.NewEncoder(&w.buf).Encode(e)
+		return
+	}
+
+	fmt.Fprintf(&w.buf, "%s %s", e.Time.Format(time.RFC3339), e.Type)
+
+	switch {
+	case e.Traffic != nil:
+		fmt.Fprintf(&w.buf, " %s rx %s/s tx %s/s", e.Peer.Name,
+			humanReadableByteCount(uint64(e.Traffic.ReceiveRate)),
+			humanReadableByteCount(uint64(e.Traffic.SendRate)))
+	case e.Peer != nil:
+		fmt.Fprintf(&w.buf, " %s %s", e.Peer.Name, e.Peer.Address)
+	case e.PublicKey != nil:
+		fmt.Fprintf(&w.buf, " %s", e.PublicKey.Fingerprint)
+		if e.PublicKey.Comment != "" {
+			fmt.Fprintf(&w.buf, " %s", e.PublicKey.Comment)
+		}
+	}
+
+	w.buf.WriteByte('\n')
+}
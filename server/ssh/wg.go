@@ -3,22 +3,42 @@ package ssh
 import (
 	"bytes"
 	"fmt"
+	"maps"
 	"net"
+	"slices"
+	"strings"
+	"text/tabwriter"
+	"time"
 
 	"github.com/guregu/null/v5"
 	"github.com/infastin/wg-wish/pkg/netutils"
+	"github.com/infastin/wg-wish/pkg/wgtypes"
+	"github.com/infastin/wg-wish/server/entity"
+	"github.com/infastin/wg-wish/server/errors"
 	"github.com/infastin/wg-wish/server/service"
 	"github.com/mdp/qrterminal/v3"
 )
 
 type WireGuardCmd struct {
+	Interface string `optional:"" name:"iface" placeholder:"NAME" help:"WireGuard interface, the primary one by default."`
+
 	Add struct {
-		Name                string      `arg:"" help:"Client's name."`
-		Address             null.String `optional:"" short:"a" placeholder:"ADDR" help:"Client's address."`
-		DNS                 []string    `optional:"" short:"d" help:"Client's DNS list."`
-		AllowedIPs          []string    `optional:"" short:"i" name:"ips" placeholder:"IP" help:"Client's allowed IPs."`
-		PersistentKeepalive null.Int    `optional:"" short:"k" name:"keepalive" placeholder:"SECONDS" help:"Client's persistent keepalive."`
-		QR                  bool        `optional:"" name:"qr" help:"Print QR code."`
+		Name                string            `arg:"" optional:"" help:"Client's name, omitted with --batch."`
+		Address             null.String       `optional:"" short:"a" placeholder:"ADDR" help:"Client's address."`
+		DNS                 []string          `optional:"" short:"d" help:"Client's DNS list."`
+		AllowedIPs          []string          `optional:"" short:"i" name:"ips" placeholder:"IP" help:"Client's allowed IPs."`
+		PersistentKeepalive null.Int          `optional:"" short:"k" name:"keepalive" placeholder:"SECONDS" help:"Client's persistent keepalive."`
+		RateLimit           Rate              `optional:"" placeholder:"RATE" help:"Client's bandwidth limit in each direction, e.g. 512kbit or 10mbit."`
+		Group               string            `optional:"" short:"g" placeholder:"GROUP" help:"Client's firewall group."`
+		Profile             string            `optional:"" short:"p" placeholder:"PROFILE" help:"Client's profile, whose settings are used unless given explicitly."`
+		Expires             Expiry            `optional:"" short:"e" placeholder:"TIME" help:"Disable client at the given date, timestamp or after the given duration, e.g. 2030-01-01 or 30d."`
+		Labels              map[string]string `optional:"" short:"l" name:"label" mapsep:"none" placeholder:"KEY=VALUE" help:"Client's label, can be repeated."`
+		Description         string            `optional:"" placeholder:"TEXT" help:"Client's description."`
+		Mesh                bool              `optional:"" help:"Peer directly with the other mesh clients."`
+		Endpoint            string            `optional:"" placeholder:"HOST:PORT" help:"Where the other mesh clients reach the client, the last seen one by default."`
+		QR                  bool              `optional:"" name:"qr" help:"Print QR code."`
+		Batch               bool              `optional:"" help:"Add clients read on stdin as CSV or JSON lines, writing their configs and QR codes as an archive."`
+		Archive             string            `optional:"" enum:"tar,zip" default:"tar" help:"Batch archive format (tar or zip)."`
 	} `cmd:"" help:"Add client."`
 
 	Get struct {
@@ -30,60 +50,139 @@ type WireGuardCmd struct {
 		Name string `arg:"" help:"Client's name."`
 	} `cmd:"" help:"Remove client."`
 
+	Set struct {
+		Name          string            `arg:"" help:"Client's name."`
+		Quota         ByteSize          `optional:"" xor:"quota" placeholder:"SIZE" help:"Traffic allowed per period, e.g. 500MB or 50GiB."`
+		QuotaPeriod   string            `optional:"" xor:"quota-period" placeholder:"PERIOD" help:"Quota period (daily, weekly or monthly), monthly for new quotas."`
+		NoQuota       bool              `optional:"" xor:"quota,quota-period" help:"Remove client's quota."`
+		RateLimit     Rate              `optional:"" xor:"rate" placeholder:"RATE" help:"Bandwidth limit in each direction, e.g. 512kbit or 10mbit."`
+		NoRateLimit   bool              `optional:"" xor:"rate" help:"Remove client's bandwidth limit."`
+		Group         string            `optional:"" short:"g" xor:"group" placeholder:"GROUP" help:"Firewall group."`
+		NoGroup       bool              `optional:"" xor:"group" help:"Remove client from its firewall group."`
+		Profile       string            `optional:"" short:"p" xor:"profile" placeholder:"PROFILE" help:"Follow profile and apply its settings."`
+		NoProfile     bool              `optional:"" xor:"profile" help:"Stop following profile, keeping its settings."`
+		Labels        map[string]string `optional:"" short:"l" name:"label" mapsep:"none" placeholder:"KEY=VALUE" help:"Add or change label, can be repeated."`
+		Unlabel       []string          `optional:"" sep:"none" placeholder:"KEY" help:"Remove label, can be repeated."`
+		Description   string            `optional:"" xor:"description" placeholder:"TEXT" help:"Description."`
+		NoDescription bool              `optional:"" xor:"description" help:"Remove client's description."`
+		Mesh          bool              `optional:"" xor:"mesh" help:"Peer directly with the other mesh clients."`
+		NoMesh        bool              `optional:"" xor:"mesh" help:"Stop peering with the other mesh clients."`
+		Endpoint      string            `optional:"" xor:"endpoint" placeholder:"HOST:PORT" help:"Where the other mesh clients reach the client."`
+		NoEndpoint    bool              `optional:"" xor:"endpoint" help:"Use the endpoint the client was last seen at."`
+	} `cmd:"" help:"Change client's settings."`
+
 	Reload struct{} `cmd:"" help:"Reload server."`
 
-	Ls struct{} `cmd:"" help:"List clients."`
+	Lint struct{} `cmd:"" help:"Check server and client configs for problems."`
+
+	Ls struct {
+		Labels  []string `optional:"" short:"l" name:"label" sep:"none" placeholder:"KEY[=VALUE]" help:"Only list clients with the label, can be repeated."`
+		Online  bool     `optional:"" xor:"status" help:"Only list clients with a recent handshake."`
+		Offline bool     `optional:"" xor:"status" help:"Only list clients without a recent handshake."`
+		Stale   Duration `optional:"" placeholder:"PERIOD" help:"Only list clients without a handshake for the given period, e.g. 24h or 7d."`
+		Name    string   `optional:"" short:"n" placeholder:"GLOB" help:"Only list clients whose name matches the pattern."`
+		Sort    string   `optional:"" short:"s" enum:"name,address,traffic,handshake" default:"name" help:"Sort by name, address, traffic or handshake."`
+		Limit   int      `optional:"" placeholder:"N" help:"List at most N clients."`
+		Table   bool     `optional:"" short:"t" help:"Print one line per client."`
+	} `cmd:"" help:"List clients."`
+
+	Find struct {
+		Key     string `short:"k" xor:"by" required:"" placeholder:"KEY" help:"Client's public key."`
+		Address string `short:"a" xor:"by" required:"" placeholder:"IP" help:"Client's address."`
+	} `cmd:"" help:"Find client by public key or address."`
+
+	Stats struct {
+		Name     string   `arg:"" help:"Client's name."`
+		Since    Duration `optional:"" short:"s" default:"24h" help:"Show traffic for the given period, e.g. 12h or 7d."`
+		Interval Duration `optional:"" short:"n" default:"1h" help:"Aggregation interval."`
+	} `cmd:"" help:"Show client's traffic history."`
+
+	Watch struct {
+		JSON     bool          `optional:"" name:"json" help:"Print events as JSON lines."`
+		Interval time.Duration `optional:"" short:"n" default:"5s" help:"Peer stats sampling interval."`
+	} `cmd:"" help:"Stream peer and server events until disconnected."`
 }
 
 func (cmd *WireGuardCmd) Run(ctx *Context) (err error) {
+	if err := ctx.selectInterface(cmd.Interface); err != nil {
+		return err
+	}
+
 	switch ctx.kctx.Command() {
-	case "wireguard add <name>":
+	case "wireguard add", "wireguard add <name>":
 		err = cmd.HandleAdd(ctx)
 	case "wireguard rm <name>":
 		err = cmd.HandleRm(ctx)
 	case "wireguard get <name>":
 		err = cmd.HandleGet(ctx)
+	case "wireguard set <name>":
+		err = cmd.HandleSet(ctx)
 	case "wireguard reload":
 		err = cmd.HandleReload(ctx)
+	case "wireguard lint":
+		err = cmd.HandleLint(ctx)
 	case "wireguard ls":
 		err = cmd.HandleLs(ctx)
+	case "wireguard find":
+		err = cmd.HandleFind(ctx)
+	case "wireguard stats <name>":
+		err = cmd.HandleStats(ctx)
+	case "wireguard watch":
+		err = cmd.HandleWatch(ctx)
 	}
 	return err
 }
 
 func (cmd *WireGuardCmd) HandleAdd(ctx *Context) (err error) {
-	var address null.Value[net.IPNet]
+	if (cmd.Add.Name == "") != cmd.Add.Batch {
+		return errBatchName
+	}
+
+	var opts service.AddClientOptions
+
 	if cmd.Add.Address.Valid {
+		if cmd.Add.Batch {
+			return errBatchAddress
+		}
 		addr, err := netutils.ParseAddress(cmd.Add.Address.String)
 		if err != nil {
 			return err
 		}
-		address = null.ValueFrom(addr)
+		opts.Address = null.ValueFrom(addr)
 	}
 
-	var dns []net.IP
 	if cmd.Add.DNS != nil {
-		dns, err = netutils.ParseIPs(cmd.Add.DNS)
+		opts.DNS, err = netutils.ParseIPs(cmd.Add.DNS)
 		if err != nil {
 			return err
 		}
 	}
 
-	var ips []net.IPNet
 	if cmd.Add.AllowedIPs != nil {
-		ips, err = netutils.ParseAddresses(cmd.Add.AllowedIPs)
+		opts.AllowedIPs, err = netutils.ParseAddresses(cmd.Add.AllowedIPs)
 		if err != nil {
 			return err
 		}
 	}
 
-	cfg, err := ctx.wireguardService.AddClient(ctx, cmd.Add.Name,
-		&service.AddClientOptions{
-			Address:             address,
-			DNS:                 dns,
-			AllowedIPs:          ips,
-			PersistentKeepalive: cmd.Add.PersistentKeepalive,
-		})
+	opts.PersistentKeepalive = cmd.Add.PersistentKeepalive
+	opts.RateLimit = uint64(cmd.Add.RateLimit)
+	opts.Group = cmd.Add.Group
+	opts.Profile = cmd.Add.Profile
+	opts.Labels = cmd.Add.Labels
+	opts.Description = cmd.Add.Description
+	opts.Mesh = cmd.Add.Mesh
+	opts.Endpoint = cmd.Add.Endpoint
+
+	if expiresAt := time.Time(cmd.Add.Expires); !expiresAt.IsZero() {
+		opts.ExpiresAt = null.TimeFrom(expiresAt)
+	}
+
+	if cmd.Add.Batch {
+		return cmd.handleAddBatch(ctx, &opts)
+	}
+
+	cfg, err := ctx.wireguardService.AddClient(ctx, cmd.Add.Name, &opts)
 	if err != nil {
 		return err
 	}
@@ -102,6 +201,36 @@ func (cmd *WireGuardCmd) HandleAdd(ctx *Context) (err error) {
 	return nil
 }
 
+// handleAddBatch adds the clients read on stdin, every one of them
+// with the given options unless the batch overrides them.
+func (cmd *WireGuardCmd) handleAddBatch(ctx *Context, opts *service.AddClientOptions) (err error) {
+	entries, err := readBatch(ctx.session)
+	if err != nil {
+		return err
+	}
+
+	if len(entries) == 0 {
+		return errBatchEmpty
+	}
+
+	now := time.Now()
+
+	requests := make([]service.AddClientRequest, len(entries))
+	for i := range entries {
+		requests[i], err = entries[i].request(*opts, now)
+		if err != nil {
+			return batchError(i+1, err)
+		}
+	}
+
+	configs, err := ctx.wireguardService.AddClients(ctx, requests)
+	if err != nil {
+		return err
+	}
+
+	return writeBatchArchive(ctx.session, cmd.Add.Archive, configs)
+}
+
 func (cmd *WireGuardCmd) HandleRm(ctx *Context) (err error) {
 	return ctx.wireguardService.RemoveClient(ctx, cmd.Rm.Name)
 }
@@ -126,34 +255,266 @@ func (cmd *WireGuardCmd) HandleGet(ctx *Context) (err error) {
 	return nil
 }
 
+var (
+	errNothingToSet  = errors.NewDomainError("wg", "nothing to set, specify at least one option")
+	errNegativeLimit = errors.NewDomainError("wg", "limit must not be negative")
+)
+
+func (cmd *WireGuardCmd) HandleSet(ctx *Context) (err error) {
+	opts := service.EditClientOptions{
+		Quota:       null.Value[service.ClientQuotaOptions]{},
+		RemoveQuota: cmd.Set.NoQuota,
+		RateLimit:   null.Value[uint64]{},
+		Group:       null.String{},
+		Profile:     null.String{},
+	}
+
+	if cmd.Set.Quota != 0 || cmd.Set.QuotaPeriod != "" {
+		opts.Quota = null.ValueFrom(service.ClientQuotaOptions{
+			Limit:  uint64(cmd.Set.Quota),
+			Period: entity.QuotaPeriod(cmd.Set.QuotaPeriod),
+		})
+	}
+
+	switch {
+	case cmd.Set.RateLimit != 0:
+		opts.RateLimit = null.ValueFrom(uint64(cmd.Set.RateLimit))
+	case cmd.Set.NoRateLimit:
+		opts.RateLimit = null.ValueFrom[uint64](0)
+	}
+
+	switch {
+	case cmd.Set.Group != "":
+		opts.Group = null.StringFrom(cmd.Set.Group)
+	case cmd.Set.NoGroup:
+		opts.Group = null.StringFrom("")
+	}
+
+	switch {
+	case cmd.Set.Profile != "":
+		opts.Profile = null.StringFrom(cmd.Set.Profile)
+	case cmd.Set.NoProfile:
+		opts.Profile = null.StringFrom("")
+	}
+
+	opts.Labels = cmd.Set.Labels
+	opts.RemoveLabels = cmd.Set.Unlabel
+
+	switch {
+	case cmd.Set.Description != "":
+		opts.Description = null.StringFrom(cmd.Set.Description)
+	case cmd.Set.NoDescription:
+		opts.Description = null.StringFrom("")
+	}
+
+	switch {
+	case cmd.Set.Mesh:
+		opts.Mesh = null.BoolFrom(true)
+	case cmd.Set.NoMesh:
+		opts.Mesh = null.BoolFrom(false)
+	}
+
+	switch {
+	case cmd.Set.Endpoint != "":
+		opts.Endpoint = null.StringFrom(cmd.Set.Endpoint)
+	case cmd.Set.NoEndpoint:
+		opts.Endpoint = null.StringFrom("")
+	}
+
+	if !opts.Quota.Valid && !opts.RemoveQuota && !opts.RateLimit.Valid && !opts.Group.Valid && !opts.Profile.Valid &&
+		len(opts.Labels) == 0 && len(opts.RemoveLabels) == 0 && !opts.Description.Valid &&
+		!opts.Mesh.Valid && !opts.Endpoint.Valid {
+		return errNothingToSet
+	}
+
+	return ctx.wireguardService.EditClient(ctx, cmd.Set.Name, &opts)
+}
+
 func (*WireGuardCmd) HandleReload(ctx *Context) (err error) {
 	return ctx.wireguardService.ReloadServer(ctx)
 }
 
-func (*WireGuardCmd) HandleLs(ctx *Context) (err error) {
-	infos, err := ctx.wireguardService.GetClientInfos(ctx)
+func (cmd *WireGuardCmd) HandleLs(ctx *Context) (err error) {
+	if cmd.Ls.Limit < 0 {
+		return errNegativeLimit
+	}
+
+	opts := service.ListClientsOptions{
+		Labels: nil,
+		Online: null.Bool{},
+		Stale:  time.Duration(cmd.Ls.Stale),
+		Name:   cmd.Ls.Name,
+		Sort:   service.ClientSort(cmd.Ls.Sort),
+		Limit:  cmd.Ls.Limit,
+	}
+
+	switch {
+	case cmd.Ls.Online:
+		opts.Online = null.BoolFrom(true)
+	case cmd.Ls.Offline:
+		opts.Online = null.BoolFrom(false)
+	}
+
+	if len(cmd.Ls.Labels) != 0 {
+		opts.Labels = make(map[string]null.String, len(cmd.Ls.Labels))
+		for _, label := range cmd.Ls.Labels {
+			key, value, ok := strings.Cut(label, "=")
+			opts.Labels[key] = null.NewString(value, ok)
+		}
+	}
+
+	infos, err := ctx.wireguardService.ListClients(ctx, &opts)
 	if err != nil {
 		return err
 	}
 
 	var b bytes.Buffer
+	if cmd.Ls.Table {
+		writeClientTable(&b, infos)
+	} else {
+		for i := range infos {
+			writeClientInfo(&b, i+1, &infos[i])
+		}
+	}
+	_, _ = ctx.session.Write(b.Bytes())
+
+	return nil
+}
+
+// writeClientTable writes a line with the address, state and traffic of every client.
+func writeClientTable(b *bytes.Buffer, infos []entity.WireGuardClientInfo) {
+	const timeLayout = "_2 Jan 2006 15:04 MST"
+
+	now := time.Now()
+
+	tw := tabwriter.NewWriter(b, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "NAME\tADDRESS\tSTATE\tLATEST HANDSHAKE\tRECEIVED\tSENT")
 	for i := range infos {
 		info := &infos[i]
-		fmt.Fprintf(&b, "%d. %s\n", i+1, infos[i].Config.Interface.Name)
-		fmt.Fprintf(&b, "Address: %s\n", infos[i].Config.Interface.Address.String())
-		if info.Stats.Valid {
-			fmt.Fprintf(&b, "Received: %s\n", humanReadableByteCount(info.Stats.V.Received))
-			fmt.Fprintf(&b, "Sent: %s\n", humanReadableByteCount(info.Stats.V.Sent))
-			if info.Stats.V.LatestHandshake.Valid {
-				fmt.Fprintf(&b, "Latest handshake: %v\n", info.Stats.V.LatestHandshake.Time.Format("_2 Jan 2006 15:04:05 MST"))
+
+		state := "offline"
+		switch {
+		case info.Disabled:
+			state = "disabled"
+		case info.Stats.Valid && info.Stats.V.Online(now):
+			state = "online"
+		}
+
+		handshake := "never"
+		if info.Stats.V.LatestHandshake.Valid {
+			handshake = info.Stats.V.LatestHandshake.Time.Format(timeLayout)
+		}
+
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\n",
+			info.Config.Interface.Name,
+			info.Config.Interface.Address.String(),
+			state,
+			handshake,
+			humanReadableByteCount(info.Stats.V.Received),
+			humanReadableByteCount(info.Stats.V.Sent))
+	}
+	_ = tw.Flush()
+}
+
+func (cmd *WireGuardCmd) HandleFind(ctx *Context) (err error) {
+	var opts service.FindClientOptions
+
+	switch {
+	case cmd.Find.Key != "":
+		key, err := wgtypes.ParseKey(cmd.Find.Key)
+		if err != nil {
+			return err
+		}
+		opts.PublicKey = null.ValueFrom(key)
+	case cmd.Find.Address != "":
+		ip := net.ParseIP(cmd.Find.Address)
+		if ip == nil {
+			return &net.ParseError{
+				Type: "IP address",
+				Text: cmd.Find.Address,
 			}
 		}
+		opts.Address = null.ValueFrom(ip)
+	}
+
+	info, err := ctx.wireguardService.FindClient(ctx, &opts)
+	if err != nil {
+		return err
 	}
+
+	var b bytes.Buffer
+	writeClientInfo(&b, 1, &info)
 	_, _ = ctx.session.Write(b.Bytes())
 
 	return nil
 }
 
+func writeClientInfo(b *bytes.Buffer, n int, info *entity.WireGuardClientInfo) {
+	fmt.Fprintf(b, "%d. %s\n", n, info.Config.Interface.Name)
+	fmt.Fprintf(b, "Address: %s\n", info.Config.Interface.Address.String())
+	fmt.Fprintf(b, "Public key: %s\n", info.Config.Interface.PrivateKey.PublicKey().String())
+	if info.Stats.Valid {
+		fmt.Fprintf(b, "Received: %s\n", humanReadableByteCount(info.Stats.V.Received))
+		fmt.Fprintf(b, "Sent: %s\n", humanReadableByteCount(info.Stats.V.Sent))
+		if info.Stats.V.LatestHandshake.Valid {
+			fmt.Fprintf(b, "Latest handshake: %v\n", info.Stats.V.LatestHandshake.Time.Format("_2 Jan 2006 15:04:05 MST"))
+		}
+	}
+	if info.Quota.Valid {
+		quota := &info.Quota.V
+		fmt.Fprintf(b, "Quota: %s of %s used, %s remaining (%s, resets %s)\n",
+			humanReadableByteCount(quota.Used),
+			humanReadableByteCount(quota.Limit),
+			humanReadableByteCount(quota.Remaining()),
+			quota.Period,
+			quota.Period.Next(quota.PeriodStart).Format("_2 Jan 2006 15:04:05 MST"))
+	}
+	if info.RateLimit != 0 {
+		fmt.Fprintf(b, "Rate limit: %s\n", humanReadableRate(info.RateLimit))
+	}
+	if info.Group != "" {
+		fmt.Fprintf(b, "Group: %s\n", info.Group)
+	}
+	if info.Profile != "" {
+		fmt.Fprintf(b, "Profile: %s\n", info.Profile)
+	}
+	if info.ExpiresAt.Valid {
+		fmt.Fprintf(b, "Expires: %s\n", info.ExpiresAt.Time.Format("_2 Jan 2006 15:04:05 MST"))
+	}
+	if info.Description != "" {
+		fmt.Fprintf(b, "Description: %s\n", info.Description)
+	}
+	if len(info.Labels) != 0 {
+		fmt.Fprintf(b, "Labels: %s\n", formatLabels(info.Labels))
+	}
+	if info.Mesh {
+		switch {
+		case info.Endpoint != "":
+			fmt.Fprintf(b, "Mesh: %s\n", info.Endpoint)
+		case info.LastEndpoint != "":
+			fmt.Fprintf(b, "Mesh: %s (last seen)\n", info.LastEndpoint)
+		default:
+			fmt.Fprintf(b, "Mesh: endpoint unknown\n")
+		}
+	}
+	if info.Disabled {
+		if info.ExpiresAt.Valid && !time.Now().Before(info.ExpiresAt.Time) {
+			fmt.Fprintf(b, "Disabled: expired\n")
+		} else {
+			fmt.Fprintf(b, "Disabled: quota exceeded\n")
+		}
+	}
+}
+
+// formatLabels formats the labels as comma-separated key=value pairs sorted by key.
+func formatLabels(labels map[string]string) string {
+	keys := slices.Sorted(maps.Keys(labels))
+	for i, key := range keys {
+		keys[i] = key + "=" + labels[key]
+	}
+	return strings.Join(keys, ", ")
+}
+
 // Borrowed from here: https://yourbasic.org/golang/formatting-byte-size-to-human-readable-format.
 func humanReadableByteCount(b uint64) string {
 	const unit = 1024
@@ -0,0 +1,179 @@
+package webhook
+
+import (
+	"encoding/binary"
+	"time"
+
+	"go.etcd.io/bbolt"
+)
+
+//go:generate msgp -tests=false -unexported
+
+var outboxBucketName = []byte("outbox")
+
+//msgp:tuple delivery
+
+// delivery is a pending request of a single event to a single endpoint.
+type delivery struct {
+	Endpoint    string
+	EventID     string
+	EventType   string
+	Body        []byte
+	Attempts    int
+	NextAttempt int64
+}
+
+//msgp:ignore outbox outboxEntry
+
+// outbox persists pending deliveries, so that they survive restarts.
+type outbox struct {
+	db *bbolt.DB
+}
+
+func openOutbox(path string) (ob *outbox, err error) {
+	db, err := bbolt.Open(path, 0600, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := db.Update(func(tx *bbolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(outboxBucketName)
+		return err
+	}); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return &outbox{db: db}, nil
+}
+
+func (ob *outbox) Close() error {
+	return ob.db.Close()
+}
+
+func (ob *outbox) push(deliveries []delivery) (err error) {
+	return ob.db.Update(func(tx *bbolt.Tx) error {
+		b := tx.Bucket(outboxBucketName)
+
+		for i := range deliveries {
+			seq, err := b.NextSequence()
+			if err != nil {
+				return err
+			}
+
+			valb, err := deliveries[i].MarshalMsg(nil)
+			if err != nil {
+				return err
+			}
+
+			if err := b.Put(binary.BigEndian.AppendUint64(nil, seq), valb); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+type outboxEntry struct {
+	key      []byte
+	delivery delivery
+}
+
+// due returns deliveries to the endpoint whose next attempt is not after now
+// and the time of the earliest attempt among the rest.
+func (ob *outbox) due(endpoint string, now time.Time) (entries []outboxEntry, next time.Time, err error) {
+	err = ob.db.View(func(tx *bbolt.Tx) error {
+		c := tx.Bucket(outboxBucketName).Cursor()
+
+		for key, valb := c.First(); key != nil; key, valb = c.Next() {
+			var d delivery
+			if _, err := d.UnmarshalMsg(valb); err != nil {
+				return err
+			}
+
+			if d.Endpoint != endpoint {
+				continue
+			}
+
+			at := time.Unix(0, d.NextAttempt)
+			if at.After(now) {
+				if next.IsZero() || at.Before(next) {
+					next = at
+				}
+				continue
+			}
+
+			entries = append(entries, outboxEntry{
+				key:      append([]byte(nil), key...),
+				delivery: d,
+			})
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+
+	return entries, next, nil
+}
+
+func (ob *outbox) update(key []byte, d *delivery) (err error) {
+	return ob.db.Update(func(tx *bbolt.Tx) error {
+		valb, err := d.MarshalMsg(nil)
+		if err != nil {
+			return err
+		}
+		return tx.Bucket(outboxBucketName).Put(key, valb)
+	})
+}
+
+func (ob *outbox) remove(key []byte) (err error) {
+	return ob.db.Update(func(tx *bbolt.Tx) error {
+		return tx.Bucket(outboxBucketName).Delete(key)
+	})
+}
+
+// retain removes deliveries to endpoints other than the given ones
+// and returns the number of removed deliveries.
+func (ob *outbox) retain(endpoints []string) (removed int, err error) {
+	keep := make(map[string]struct{}, len(endpoints))
+	for _, endpoint := range endpoints {
+		keep[endpoint] = struct{}{}
+	}
+
+	err = ob.db.Update(func(tx *bbolt.Tx) error {
+		b := tx.Bucket(outboxBucketName)
+
+		var keys [][]byte
+		err := b.ForEach(func(key, valb []byte) error {
+			var d delivery
+			if _, err := d.UnmarshalMsg(valb); err != nil {
+				return err
+			}
+			if _, ok := keep[d.Endpoint]; !ok {
+				keys = append(keys, append([]byte(nil), key...))
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		for _, key := range keys {
+			if err := b.Delete(key); err != nil {
+				return err
+			}
+		}
+
+		removed = len(keys)
+
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return removed, nil
+}
@@ -0,0 +1,158 @@
+package webhook
+
+// Code generated by github.com/tinylib/msgp DO NOT EDIT.
+
+import (
+	"github.com/tinylib/msgp/msgp"
+)
+
+// DecodeMsg implements msgp.Decodable
+func (z *delivery) DecodeMsg(dc *msgp.Reader) (err error) {
+	var zb0001 uint32
+	zb0001, err = dc.ReadArrayHeader()
+	if err != nil {
+		err = msgp.WrapError(err)
+		return
+	}
+	if zb0001 != 6 {
+		err = msgp.ArrayError{Wanted: 6, Got: zb0001}
+		return
+	}
+	z.Endpoint, err = dc.ReadString()
+	if err != nil {
+		err = msgp.WrapError(err, "Endpoint")
+		return
+	}
+	z.EventID, err = dc.ReadString()
+	if err != nil {
+		err = msgp.WrapError(err, "EventID")
+		return
+	}
+	z.EventType, err = dc.ReadString()
+	if err != nil {
+		err = msgp.WrapError(err, "EventType")
+		return
+	}
+	z.Body, err = dc.ReadBytes(z.Body)
+	if err != nil {
+		err = msgp.WrapError(err, "Body")
+		return
+	}
+	z.Attempts, err = dc.ReadInt()
+	if err != nil {
+		err = msgp.WrapError(err, "Attempts")
+		return
+	}
+	z.NextAttempt, err = dc.ReadInt64()
+	if err != nil {
+		err = msgp.WrapError(err, "NextAttempt")
+		return
+	}
+	return
+}
+
+// EncodeMsg implements msgp.Encodable
+func (z *delivery) EncodeMsg(en *msgp.Writer) (err error) {
+	// array header, size 6
+	err = en.Append(0x96)
+	if err != nil {
+		return
+	}
+	err = en.WriteString(z.Endpoint)
+	if err != nil {
+		err = msgp.WrapError(err, "Endpoint")
+		return
+	}
+	err = en.WriteString(z.EventID)
+	if err != nil {
+		err = msgp.WrapError(err, "EventID")
+		return
+	}
+	err = en.WriteString(z.EventType)
+	if err != nil {
+		err = msgp.WrapError(err, "EventType")
+		return
+	}
+	err = en.WriteBytes(z.Body)
+	if err != nil {
+		err = msgp.WrapError(err, "Body")
+		return
+	}
+	err = en.WriteInt(z.Attempts)
+	if err != nil {
+		err = msgp.WrapError(err, "Attempts")
+		return
+	}
+	err = en.WriteInt64(z.NextAttempt)
+	if err != nil {
+		err = msgp.WrapError(err, "NextAttempt")
+		return
+	}
+	return
+}
+
+// MarshalMsg implements msgp.Marshaler
+func (z *delivery) MarshalMsg(b []byte) (o []byte, err error) {
+	o = msgp.Require(b, z.Msgsize())
+	// array header, size 6
+	o = append(o, 0x96)
+	o = msgp.AppendString(o, z.Endpoint)
+	o = msgp.AppendString(o, z.EventID)
+	o = msgp.AppendString(o, z.EventType)
+	o = msgp.AppendBytes(o, z.Body)
+	o = msgp.AppendInt(o, z.Attempts)
+	o = msgp.AppendInt64(o, z.NextAttempt)
+	return
+}
+
+// UnmarshalMsg implements msgp.Unmarshaler
+func (z *delivery) UnmarshalMsg(bts []byte) (o []byte, err error) {
+	var zb0001 uint32
+	zb0001, bts, err = msgp.ReadArrayHeaderBytes(bts)
+	if err != nil {
+		err = msgp.WrapError(err)
+		return
+	}
+	if zb0001 != 6 {
+		err = msgp.ArrayError{Wanted: 6, Got: zb0001}
+		return
+	}
+	z.Endpoint, bts, err = msgp.ReadStringBytes(bts)
+	if err != nil {
+		err = msgp.WrapError(err, "Endpoint")
+		return
+	}
+	z.EventID, bts, err = msgp.ReadStringBytes(bts)
+	if err != nil {
+		err = msgp.WrapError(err, "EventID")
+		return
+	}
+	z.EventType, bts, err = msgp.ReadStringBytes(bts)
+	if err != nil {
+		err = msgp.WrapError(err, "EventType")
+		return
+	}
+	z.Body, bts, err = msgp.ReadBytesBytes(bts, z.Body)
+	if err != nil {
+		err = msgp.WrapError(err, "Body")
+		return
+	}
+	z.Attempts, bts, err = msgp.ReadIntBytes(bts)
+	if err != nil {
+		err = msgp.WrapError(err, "Attempts")
+		return
+	}
+	z.NextAttempt, bts, err = msgp.ReadInt64Bytes(bts)
+	if err != nil {
+		err = msgp.WrapError(err, "NextAttempt")
+		return
+	}
+	o = bts
+	return
+}
+
+// Msgsize returns an upper bound estimate of the number of bytes occupied by the serialized message
+func (z *delivery) Msgsize() (s int) {
+	s = 1 + msgp.StringPrefixSize + len(z.Endpoint) + msgp.StringPrefixSize + len(z.EventID) + msgp.StringPrefixSize + len(z.EventType) + msgp.BytesPrefixSize + len(z.Body) + msgp.IntSize + msgp.Int64Size
+	return
+}
@@ -0,0 +1,102 @@
+package webhook
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func reopenOutbox(t *testing.T, ob *outbox, path string) *outbox {
+	t.Helper()
+
+	if ob != nil {
+		if err := ob.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	ob, err := openOutbox(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return ob
+}
+
+func TestOutboxReopen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "outbox.db")
+	now := time.Now()
+
+	ob := reopenOutbox(t, nil, path)
+	t.Cleanup(func() { ob.Close() })
+
+	err := ob.push([]delivery{
+		{Endpoint: "a", EventID: "1", EventType: "peer.added", Body: []byte("{}"), Attempts: 0, NextAttempt: now.UnixNano()},
+		{Endpoint: "b", EventID: "1", EventType: "peer.added", Body: []byte("{}"), Attempts: 0, NextAttempt: now.UnixNano()},
+		{Endpoint: "a", EventID: "2", EventType: "peer.removed", Body: []byte("{}"), Attempts: 0, NextAttempt: now.UnixNano()},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ob = reopenOutbox(t, ob, path)
+
+	entries, _, err := ob.due("a", now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 || entries[0].delivery.EventID != "1" || entries[1].delivery.EventID != "2" {
+		t.Fatalf("expected deliveries 1 and 2 in order, got %+v", entries)
+	}
+
+	// Postpone the first delivery and remove the second one.
+	retry := entries[0].delivery
+	retry.Attempts = 1
+	retry.NextAttempt = now.Add(time.Minute).UnixNano()
+
+	if err := ob.update(entries[0].key, &retry); err != nil {
+		t.Fatal(err)
+	}
+	if err := ob.remove(entries[1].key); err != nil {
+		t.Fatal(err)
+	}
+
+	ob = reopenOutbox(t, ob, path)
+
+	entries, next, err := ob.due("a", now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 0 {
+		t.Fatalf("expected no due deliveries, got %+v", entries)
+	}
+	if next.UnixNano() != retry.NextAttempt {
+		t.Fatalf("expected the next attempt at %s, got %s", time.Unix(0, retry.NextAttempt), next)
+	}
+
+	entries, _, err = ob.due("a", now.Add(time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].delivery.Attempts != 1 {
+		t.Fatalf("expected the postponed delivery, got %+v", entries)
+	}
+
+	removed, err := ob.retain([]string{"a"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if removed != 1 {
+		t.Fatalf("expected 1 removed delivery, got %d", removed)
+	}
+
+	ob = reopenOutbox(t, ob, path)
+
+	entries, _, err = ob.due("b", now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 0 {
+		t.Fatalf("expected deliveries to b to be removed, got %+v", entries)
+	}
+}
@@ -0,0 +1,318 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/infastin/wg-wish/server/event"
+	"github.com/rs/zerolog"
+)
+
+const (
+	HeaderEvent     = "X-Wg-Wish-Event"
+	HeaderDelivery  = "X-Wg-Wish-Delivery"
+	HeaderTimestamp = "X-Wg-Wish-Timestamp"
+	// HeaderSignature contains "sha256=" followed by hex-encoded HMAC-SHA256
+	// of the timestamp, a dot and the request body, keyed with the endpoint secret.
+	HeaderSignature = "X-Wg-Wish-Signature"
+)
+
+type Endpoint struct {
+	URL    string
+	Secret string
+	// Events is a list of event types or glob patterns, e.g. "peer.*".
+	// Empty list matches every event.
+	Events []string
+}
+
+func (e *Endpoint) match(typ event.Type) bool {
+	if len(e.Events) == 0 {
+		return true
+	}
+	for _, pattern := range e.Events {
+		if typ.Match(pattern) {
+			return true
+		}
+	}
+	return false
+}
+
+type DispatcherParams struct {
+	Logger      zerolog.Logger
+	Bus         *event.Bus
+	OutboxPath  string
+	Endpoints   []Endpoint
+	MaxAttempts int
+	MinBackoff  time.Duration
+	MaxBackoff  time.Duration
+	Timeout     time.Duration
+}
+
+// Dispatcher stores events published on the bus in the outbox
+// and delivers them to the matching endpoints, retrying failed deliveries with exponential backoff.
+// Every endpoint is served by its own worker, so that an unresponsive endpoint doesn't delay the others.
+type Dispatcher struct {
+	lg          zerolog.Logger
+	outbox      *outbox
+	endpoints   map[string]*Endpoint
+	order       []string
+	wakeups     map[string]chan struct{}
+	client      *http.Client
+	maxAttempts int
+	minBackoff  time.Duration
+	maxBackoff  time.Duration
+	unsubscribe func()
+}
+
+func NewDispatcher(params *DispatcherParams) (d *Dispatcher, err error) {
+	ob, err := openOutbox(params.OutboxPath)
+	if err != nil {
+		return nil, fmt.Errorf("webhook: failed to open outbox: %w", err)
+	}
+
+	d = &Dispatcher{
+		lg:          params.Logger,
+		outbox:      ob,
+		endpoints:   make(map[string]*Endpoint, len(params.Endpoints)),
+		order:       make([]string, 0, len(params.Endpoints)),
+		wakeups:     make(map[string]chan struct{}, len(params.Endpoints)),
+		client:      &http.Client{Timeout: params.Timeout}, //nolint:exhaustruct
+		maxAttempts: params.MaxAttempts,
+		minBackoff:  params.MinBackoff,
+		maxBackoff:  params.MaxBackoff,
+		unsubscribe: nil,
+	}
+
+	for i := range params.Endpoints {
+		endpoint := &params.Endpoints[i]
+		if _, ok := d.endpoints[endpoint.URL]; !ok {
+			d.order = append(d.order, endpoint.URL)
+			d.wakeups[endpoint.URL] = make(chan struct{}, 1)
+		}
+		d.endpoints[endpoint.URL] = endpoint
+	}
+
+	// Deliveries to endpoints removed from the config would never be picked up by a worker.
+	dropped, err := ob.retain(d.order)
+	if err != nil {
+		ob.Close()
+		return nil, fmt.Errorf("webhook: failed to clean up outbox: %w", err)
+	}
+	if dropped != 0 {
+		d.lg.Warn().Int("deliveries", dropped).Msg("dropped webhooks for unknown endpoints")
+	}
+
+	d.unsubscribe = params.Bus.Subscribe(d.publish)
+
+	return d, nil
+}
+
+// Close closes the outbox. It must be called after Run has returned.
+func (d *Dispatcher) Close() error {
+	d.unsubscribe()
+	return d.outbox.Close()
+}
+
+// publish stores deliveries of the event in the outbox and wakes up the workers of their endpoints.
+// The event is stored before publish returns, so that it survives a crash right after being published.
+func (d *Dispatcher) publish(e *event.Event) {
+	body, err := json.Marshal(e)
+	if err != nil {
+		d.lg.Err(err).Str("event", string(e.Type)).Msg("failed to encode event")
+		return
+	}
+
+	var deliveries []delivery
+	for _, url := range d.order {
+		if !d.endpoints[url].match(e.Type) {
+			continue
+		}
+		deliveries = append(deliveries, delivery{
+			Endpoint:    url,
+			EventID:     e.ID,
+			EventType:   string(e.Type),
+			Body:        body,
+			Attempts:    0,
+			NextAttempt: e.Time.UnixNano(),
+		})
+	}
+
+	if len(deliveries) == 0 {
+		return
+	}
+
+	if err := d.outbox.push(deliveries); err != nil {
+		d.lg.Err(err).Str("event", string(e.Type)).Msg("failed to store event in outbox")
+		return
+	}
+
+	for i := range deliveries {
+		select {
+		case d.wakeups[deliveries[i].Endpoint] <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// Run starts a worker for every endpoint and waits for them to return.
+func (d *Dispatcher) Run(ctx context.Context) error {
+	var wg sync.WaitGroup
+
+	for _, url := range d.order {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			d.work(ctx, url)
+		}()
+	}
+
+	wg.Wait()
+
+	return nil
+}
+
+// work delivers events to the endpoint until the context is canceled.
+func (d *Dispatcher) work(ctx context.Context, url string) {
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-timer.C:
+		case <-d.wakeups[url]:
+		}
+
+		next, err := d.dispatch(ctx, url)
+		if err != nil {
+			d.lg.Err(err).Str("endpoint", url).Msg("failed to dispatch webhooks")
+			next = time.Now().Add(d.minBackoff)
+		}
+
+		timer.Stop()
+		if !next.IsZero() {
+			timer.Reset(time.Until(next))
+		}
+	}
+}
+
+// dispatch sends every due delivery to the endpoint and returns the time of the next pending one.
+func (d *Dispatcher) dispatch(ctx context.Context, url string) (next time.Time, err error) {
+	entries, next, err := d.outbox.due(url, time.Now())
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	for i := range entries {
+		if ctx.Err() != nil {
+			return time.Time{}, nil
+		}
+
+		at, err := d.deliver(ctx, &entries[i])
+		if err != nil {
+			return time.Time{}, err
+		}
+
+		if !at.IsZero() && (next.IsZero() || at.Before(next)) {
+			next = at
+		}
+	}
+
+	return next, nil
+}
+
+// deliver sends the delivery and updates the outbox accordingly.
+// It returns the time of the next attempt if the delivery has to be retried.
+func (d *Dispatcher) deliver(ctx context.Context, entry *outboxEntry) (next time.Time, err error) {
+	dv := &entry.delivery
+
+	lg := d.lg.With().
+		Str("endpoint", dv.Endpoint).
+		Str("event", dv.EventType).
+		Str("delivery", dv.EventID).
+		Logger()
+
+	endpoint := d.endpoints[dv.Endpoint]
+
+	sendErr := d.send(ctx, endpoint, dv)
+	if sendErr == nil {
+		lg.Debug().Msg("webhook delivered")
+		return time.Time{}, d.outbox.remove(entry.key)
+	}
+
+	if ctx.Err() != nil {
+		// Interrupted by shutdown, the delivery will be retried after restart.
+		return time.Time{}, nil
+	}
+
+	dv.Attempts++
+	if dv.Attempts >= d.maxAttempts {
+		lg.Err(sendErr).Int("attempts", dv.Attempts).Msg("dropping webhook after too many attempts")
+		return time.Time{}, d.outbox.remove(entry.key)
+	}
+
+	next = time.Now().Add(d.backoff(dv.Attempts))
+	dv.NextAttempt = next.UnixNano()
+
+	lg.Warn().Err(sendErr).Int("attempts", dv.Attempts).Time("next_attempt", next).Msg("failed to deliver webhook")
+
+	return next, d.outbox.update(entry.key, dv)
+}
+
+func (d *Dispatcher) backoff(attempts int) time.Duration {
+	backoff := d.minBackoff
+	for i := 1; i < attempts && backoff < d.maxBackoff; i++ {
+		backoff *= 2
+	}
+	return min(backoff, d.maxBackoff)
+}
+
+func (d *Dispatcher) send(ctx context.Context, endpoint *Endpoint, dv *delivery) (err error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint.URL, bytes.NewReader(dv.Body))
+	if err != nil {
+		return err
+	}
+
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "wg-wish")
+	req.Header.Set(HeaderEvent, dv.EventType)
+	req.Header.Set(HeaderDelivery, dv.EventID)
+	req.Header.Set(HeaderTimestamp, timestamp)
+
+	if endpoint.Secret != "" {
+		req.Header.Set(HeaderSignature, "sha256="+Sign(endpoint.Secret, timestamp, dv.Body))
+	}
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("unexpected status: %s", resp.Status)
+	}
+
+	return nil
+}
+
+// Sign returns hex-encoded signature of the webhook body.
+func Sign(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte{'.'})
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
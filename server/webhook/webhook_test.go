@@ -0,0 +1,288 @@
+package webhook
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/infastin/wg-wish/server/event"
+	"github.com/rs/zerolog"
+)
+
+func newDispatcher(t *testing.T, bus *event.Bus, path string, endpoints ...Endpoint) *Dispatcher {
+	t.Helper()
+
+	d, err := NewDispatcher(&DispatcherParams{
+		Logger:      zerolog.Nop(),
+		Bus:         bus,
+		OutboxPath:  path,
+		Endpoints:   endpoints,
+		MaxAttempts: 3,
+		MinBackoff:  time.Second,
+		MaxBackoff:  10 * time.Second,
+		Timeout:     5 * time.Second,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return d
+}
+
+func pending(t *testing.T, d *Dispatcher, endpoint string) []outboxEntry {
+	t.Helper()
+
+	// Deliveries waiting for a retry are due as well.
+	entries, _, err := d.outbox.due(endpoint, time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return entries
+}
+
+func TestSign(t *testing.T) {
+	got := Sign("secret", "1700000000", []byte(`{"id":"1"}`))
+	want := "086f6aff7bd084c98679825129c5a64dbad88c760016d6d2c0fb123f27951d54"
+	if got != want {
+		t.Fatalf("expected %s, got %s", want, got)
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	d := &Dispatcher{minBackoff: time.Second, maxBackoff: 10 * time.Second} //nolint:exhaustruct
+
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{1, time.Second},
+		{2, 2 * time.Second},
+		{3, 4 * time.Second},
+		{4, 8 * time.Second},
+		{5, 10 * time.Second},
+		{20, 10 * time.Second},
+	}
+
+	for _, tt := range tests {
+		if got := d.backoff(tt.attempts); got != tt.want {
+			t.Errorf("backoff after %d attempts: expected %s, got %s", tt.attempts, tt.want, got)
+		}
+	}
+}
+
+func TestDeliverSigned(t *testing.T) {
+	requests := make(chan *http.Request, 1)
+	bodies := make(chan []byte, 1)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		requests <- r
+		bodies <- body
+	}))
+	t.Cleanup(srv.Close)
+
+	bus := event.NewBus()
+	d := newDispatcher(t, bus, filepath.Join(t.TempDir(), "outbox.db"),
+		Endpoint{URL: srv.URL, Secret: "secret", Events: nil})
+	t.Cleanup(func() { d.Close() })
+
+	e := event.New(event.ServerReloaded)
+	bus.Publish(e)
+
+	entries := pending(t, d, srv.URL)
+	if len(entries) != 1 {
+		t.Fatalf("expected 1 pending delivery, got %d", len(entries))
+	}
+
+	next, err := d.deliver(context.Background(), &entries[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !next.IsZero() {
+		t.Fatalf("expected no retry, got %s", next)
+	}
+
+	r, body := <-requests, <-bodies
+
+	if got := r.Header.Get(HeaderEvent); got != string(event.ServerReloaded) {
+		t.Fatalf("expected event %s, got %s", event.ServerReloaded, got)
+	}
+	if got := r.Header.Get(HeaderDelivery); got != e.ID {
+		t.Fatalf("expected delivery %s, got %s", e.ID, got)
+	}
+
+	want := "sha256=" + Sign("secret", r.Header.Get(HeaderTimestamp), body)
+	if got := r.Header.Get(HeaderSignature); got != want {
+		t.Fatalf("expected signature %s, got %s", want, got)
+	}
+
+	if entries := pending(t, d, srv.URL); len(entries) != 0 {
+		t.Fatalf("expected the delivery to be removed, got %d pending", len(entries))
+	}
+}
+
+func TestDeliverRetries(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	t.Cleanup(srv.Close)
+
+	bus := event.NewBus()
+	d := newDispatcher(t, bus, filepath.Join(t.TempDir(), "outbox.db"),
+		Endpoint{URL: srv.URL, Secret: "", Events: nil})
+	t.Cleanup(func() { d.Close() })
+
+	bus.Publish(event.New(event.ServerReloaded))
+
+	for attempt := 1; attempt < d.maxAttempts; attempt++ {
+		entries := pending(t, d, srv.URL)
+		if len(entries) != 1 {
+			t.Fatalf("attempt %d: expected 1 pending delivery, got %d", attempt, len(entries))
+		}
+
+		before := time.Now()
+
+		next, err := d.deliver(context.Background(), &entries[0])
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		backoff := d.backoff(attempt)
+		if next.Before(before.Add(backoff)) || next.After(time.Now().Add(backoff)) {
+			t.Fatalf("attempt %d: expected retry in %s, got %s", attempt, backoff, next.Sub(before))
+		}
+
+		stored := pending(t, d, srv.URL)[0].delivery
+		if stored.Attempts != attempt || stored.NextAttempt != next.UnixNano() {
+			t.Fatalf("attempt %d: expected the retry to be stored, got %+v", attempt, stored)
+		}
+	}
+
+	entries := pending(t, d, srv.URL)
+
+	next, err := d.deliver(context.Background(), &entries[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !next.IsZero() {
+		t.Fatalf("expected no retry after %d attempts, got %s", d.maxAttempts, next)
+	}
+
+	if entries := pending(t, d, srv.URL); len(entries) != 0 {
+		t.Fatalf("expected the delivery to be dropped, got %d pending", len(entries))
+	}
+}
+
+func TestPublishPersists(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "outbox.db")
+
+	bus := event.NewBus()
+	d := newDispatcher(t, bus, path,
+		Endpoint{URL: "http://127.0.0.1:1/peers", Secret: "", Events: []string{"peer.*"}},
+		Endpoint{URL: "http://127.0.0.1:1/all", Secret: "", Events: nil})
+
+	bus.Publish(event.New(event.PeerAdded))
+	bus.Publish(event.New(event.ServerReloaded))
+
+	// The dispatcher has never run, the events must have been stored by Publish.
+	if err := d.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	d = newDispatcher(t, event.NewBus(), path,
+		Endpoint{URL: "http://127.0.0.1:1/peers", Secret: "", Events: []string{"peer.*"}},
+		Endpoint{URL: "http://127.0.0.1:1/all", Secret: "", Events: nil})
+	t.Cleanup(func() { d.Close() })
+
+	if got := len(pending(t, d, "http://127.0.0.1:1/peers")); got != 1 {
+		t.Fatalf("expected 1 delivery to the peers endpoint, got %d", got)
+	}
+	if got := len(pending(t, d, "http://127.0.0.1:1/all")); got != 2 {
+		t.Fatalf("expected 2 deliveries to the catch-all endpoint, got %d", got)
+	}
+}
+
+func TestUnresponsiveEndpoint(t *testing.T) {
+	block := make(chan struct{})
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-block
+	}))
+	t.Cleanup(slow.Close)
+	t.Cleanup(func() { close(block) })
+
+	var delivered atomic.Int32
+	fast := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		delivered.Add(1)
+	}))
+	t.Cleanup(fast.Close)
+
+	bus := event.NewBus()
+	d := newDispatcher(t, bus, filepath.Join(t.TempDir(), "outbox.db"),
+		Endpoint{URL: slow.URL, Secret: "", Events: nil},
+		Endpoint{URL: fast.URL, Secret: "", Events: nil})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		d.Run(ctx)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+		d.Close()
+	})
+
+	for range 3 {
+		bus.Publish(event.New(event.ServerReloaded))
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for delivered.Load() != 3 {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected 3 deliveries to the responsive endpoint, got %d", delivered.Load())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestUnknownEndpointDropped(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "outbox.db")
+
+	bus := event.NewBus()
+	d := newDispatcher(t, bus, path,
+		Endpoint{URL: "http://127.0.0.1:1/old", Secret: "", Events: nil})
+	bus.Publish(event.New(event.ServerReloaded))
+	if err := d.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	d = newDispatcher(t, event.NewBus(), path,
+		Endpoint{URL: "http://127.0.0.1:1/new", Secret: "", Events: nil})
+	t.Cleanup(func() { d.Close() })
+
+	if got := len(pending(t, d, "http://127.0.0.1:1/old")); got != 0 {
+		t.Fatalf("expected deliveries to the removed endpoint to be dropped, got %d", got)
+	}
+}
+
+func TestEndpointMatch(t *testing.T) {
+	endpoint := Endpoint{URL: "", Secret: "", Events: []string{"peer.*", "server.reloaded"}}
+
+	for typ, want := range map[event.Type]bool{
+		event.PeerAdded:          true,
+		event.PeerFirstConnected: true,
+		event.ServerReloaded:     true,
+		event.PublicKeyAdded:     false,
+	} {
+		if got := endpoint.match(typ); got != want {
+			t.Errorf("%s: expected %t, got %t", typ, want, got)
+		}
+	}
+}